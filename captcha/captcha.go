@@ -0,0 +1,135 @@
+package captcha
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Supported CAPTCHA_PROVIDER values
+const (
+	ProviderRecaptcha = "recaptcha"
+	ProviderFake      = "fake"
+)
+
+// DefaultFakeToken is accepted by the fake verifier when CAPTCHA_FAKE_TOKEN is not set
+const DefaultFakeToken = "fake-captcha-token"
+
+const recaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+
+// Verifier checks CAPTCHA tokens submitted by clients
+type Verifier interface {
+	// Verify returns false if token is invalid, error is returned only when verification itself failed
+	Verify(token, remoteIP string) (bool, error)
+}
+
+var verifier Verifier
+var verifierOnce sync.Once
+
+// GetVerifier returns verifier selected by CAPTCHA_PROVIDER env variable.
+// Returns nil when CAPTCHA verification is disabled
+func GetVerifier() Verifier {
+
+	verifierOnce.Do(func() {
+		switch os.Getenv("CAPTCHA_PROVIDER") {
+		case ProviderRecaptcha:
+			verifier = &RecaptchaVerifier{
+				Secret: os.Getenv("CAPTCHA_SECRET"),
+				Client: &http.Client{Timeout: 10 * time.Second},
+			}
+		case ProviderFake:
+			token := os.Getenv("CAPTCHA_FAKE_TOKEN")
+			if len(token) == 0 {
+				token = DefaultFakeToken
+			}
+			verifier = &FakeVerifier{ValidToken: token}
+		}
+	})
+	return verifier
+}
+
+// CheckConfig validates CAPTCHA_PROVIDER settings. Verification can only be
+// disabled in dev environment, elsewhere the public forms would be unprotected
+func CheckConfig(devEnv bool) error {
+
+	provider := os.Getenv("CAPTCHA_PROVIDER")
+	switch provider {
+	case ProviderRecaptcha:
+		if len(os.Getenv("CAPTCHA_SECRET")) == 0 {
+			return errors.New("CAPTCHA_SECRET is not set")
+		}
+	case ProviderFake:
+	case "":
+		if !devEnv {
+			return errors.New("CAPTCHA_PROVIDER is not set, captcha verification is disabled")
+		}
+	default:
+		return fmt.Errorf("unknown CAPTCHA_PROVIDER: %v", provider)
+	}
+	return nil
+}
+
+// RecaptchaVerifier verifies tokens with Google reCAPTCHA
+type RecaptchaVerifier struct {
+	Secret string
+	Client *http.Client
+	// VerifyURL overrides Google verification endpoint
+	VerifyURL string
+}
+
+// Verify implements Verifier interface
+func (v *RecaptchaVerifier) Verify(token, remoteIP string) (bool, error) {
+
+	if len(token) == 0 {
+		return false, nil
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.Secret)
+	form.Set("response", token)
+	if len(remoteIP) > 0 {
+		form.Set("remoteip", remoteIP)
+	}
+
+	verifyURL := v.VerifyURL
+	if len(verifyURL) == 0 {
+		verifyURL = recaptchaVerifyURL
+	}
+
+	resp, err := v.Client.PostForm(verifyURL, form)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("recaptcha: unexpected status code %v", resp.StatusCode)
+	}
+
+	result := &struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Success, nil
+}
+
+// FakeVerifier accepts only the configured token, used in dev and test environments
+type FakeVerifier struct {
+	ValidToken string
+}
+
+// Verify implements Verifier interface
+func (v *FakeVerifier) Verify(token, remoteIP string) (bool, error) {
+
+	return len(token) > 0 && token == v.ValidToken, nil
+}
@@ -0,0 +1,108 @@
+package captcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFakeVerifier(t *testing.T) {
+
+	v := &FakeVerifier{ValidToken: DefaultFakeToken}
+
+	cases := map[string]bool{
+		DefaultFakeToken: true,
+		"":               false,
+		"other-token":    false,
+	}
+	for token, expected := range cases {
+		valid, err := v.Verify(token, "127.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if valid != expected {
+			t.Errorf("token %q: expected %v, got %v", token, expected, valid)
+		}
+	}
+}
+
+func TestRecaptchaVerifier(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("secret") != "secret" || r.Form.Get("remoteip") != "10.1.1.1" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-secret"]}`))
+			return
+		}
+		if r.Form.Get("response") == "valid" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+
+	v := &RecaptchaVerifier{Secret: "secret", Client: server.Client(), VerifyURL: server.URL}
+
+	valid, err := v.Verify("valid", "10.1.1.1")
+	if err != nil || !valid {
+		t.Errorf("expected valid token, got %v %v", valid, err)
+	}
+
+	valid, err = v.Verify("invalid", "10.1.1.1")
+	if err != nil || valid {
+		t.Errorf("expected invalid token, got %v %v", valid, err)
+	}
+
+	// empty token is rejected without a request
+	v.VerifyURL = "http://127.0.0.1:0"
+	valid, err = v.Verify("", "10.1.1.1")
+	if err != nil || valid {
+		t.Errorf("expected empty token to be rejected, got %v %v", valid, err)
+	}
+}
+
+func TestRecaptchaVerifierFailure(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	v := &RecaptchaVerifier{Secret: "secret", Client: server.Client(), VerifyURL: server.URL}
+
+	valid, err := v.Verify("valid", "")
+	if err == nil || valid {
+		t.Errorf("expected verification error, got %v %v", valid, err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+
+	defer os.Unsetenv("CAPTCHA_PROVIDER")
+	defer os.Unsetenv("CAPTCHA_SECRET")
+
+	cases := []struct {
+		provider string
+		secret   string
+		devEnv   bool
+		valid    bool
+	}{
+		{"", "", true, true},
+		{"", "", false, false},
+		{ProviderRecaptcha, "", false, false},
+		{ProviderRecaptcha, "secret", false, true},
+		{ProviderFake, "", false, true},
+		{"unknown", "", true, false},
+	}
+	for _, c := range cases {
+		os.Setenv("CAPTCHA_PROVIDER", c.provider)
+		os.Setenv("CAPTCHA_SECRET", c.secret)
+
+		err := CheckConfig(c.devEnv)
+		if (err == nil) != c.valid {
+			t.Errorf("provider %q, secret %q, dev %v: unexpected result %v", c.provider, c.secret, c.devEnv, err)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AgreementDataPath path to generated subscription agreements, files are not reachable through GET media/{media_file}
+var AgreementDataPath = path.Join(UserDataPath, "agreements")
+
+type agreementTemplateRequest struct {
+	Kind     string `json:"kind"`
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+// agreementChain returns languages to pick organisation agreement template in,
+// 'lang' query parameter or Accept-Language header have priority over the default language
+func agreementChain(r *http.Request) []string {
+
+	chain := i18n.Negotiate(r, true)
+	if chain == nil {
+		chain = i18n.FallbackChain()
+	}
+	return chain
+}
+
+// createSubscriptionAgreement generates agreement from the latest organisation template, stores it
+// and sends it to the investor for signature. nil is returned if organisation has no template in any of the chain languages
+func createSubscriptionAgreement(subscription *p2pModels.Subscription, offering *models.Offering, chain []string) (*p2pModels.SubscriptionAgreement, *cigExchange.APIError) {
+
+	agreementTemplate, apiError := p2pModels.GetLatestAgreementTemplate(offering.OrganisationID, p2pModels.AgreementTemplateKindSubscription, chain)
+	if apiError != nil || agreementTemplate == nil {
+		return nil, apiError
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(subscription.UserID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	agreement, document, apiError := p2pModels.NewSubscriptionAgreement(subscription, offering, profile, agreementTemplate)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	apiError = agreement.Create(len(document))
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	// save file to agreements folder
+	err := os.MkdirAll(AgreementDataPath, 0700)
+	if err == nil {
+		err = ioutil.WriteFile(path.Join(AgreementDataPath, agreement.MediaID)+agreement.Media.FileExtension, document, 0600)
+	}
+	if err != nil {
+		apiError = agreement.Delete()
+		if apiError != nil {
+			fmt.Println("createSubscriptionAgreement: " + apiError.ToString())
+		}
+		return nil, cigExchange.NewReadError("Failed to write agreement file", err)
+	}
+
+	// agreement is signed online instead of printed, failures don't affect the subscription
+	apiError = sendSignatureRequest(p2pModels.NewAgreementSignatureRequest(agreement, subscription.UserID))
+	if apiError != nil {
+		fmt.Println("createSubscriptionAgreement: " + apiError.ToString())
+	}
+	return agreement, nil
+}
+
+// serveAgreementFile sends stored agreement PDF as attachment
+func serveAgreementFile(w http.ResponseWriter, r *http.Request, agreement *p2pModels.SubscriptionAgreement) {
+
+	w.Header().Set("Content-Type", agreement.Media.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "agreement-" + agreement.SubscriptionID + agreement.Media.FileExtension}))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeFile(w, r, path.Join(AgreementDataPath, agreement.MediaID)+agreement.Media.FileExtension)
+}
+
+// getUserSubscriptionAgreement loads agreement of the investor subscription, profile owner and admin users have access
+func getUserSubscriptionAgreement(info *cigExchange.ActivityInformation, r *http.Request) (*p2pModels.SubscriptionAgreement, *cigExchange.APIError) {
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	subscriptionID := mux.Vars(r)["subscription_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		return nil, cigExchange.NewRoutingError(err)
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		apiError := checkAdminAccess(loggedInUser.UserUUID, "No access rights for the user")
+		if apiError != nil {
+			return nil, apiError
+		}
+	}
+
+	subscription, apiError := p2pModels.GetUserSubscription(userID, subscriptionID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	agreement, apiError := p2pModels.GetSubscriptionAgreement(subscription.ID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if agreement == nil {
+		return nil, cigExchange.NewInvalidFieldError("subscription_id", "Subscription has no agreement")
+	}
+	return agreement, nil
+}
+
+// GetSubscriptionAgreement handles GET users/{user_id}/subscriptions/{subscription_id}/agreement endpoint
+var GetSubscriptionAgreement = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSubscriptionAgreement)
+	defer cigExchange.PrintAPIError(info)
+
+	agreement, apiError := getUserSubscriptionAgreement(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, agreement)
+}
+
+// GetSubscriptionAgreementFile handles GET users/{user_id}/subscriptions/{subscription_id}/agreement/file endpoint
+var GetSubscriptionAgreementFile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSubscriptionAgreement)
+	defer cigExchange.PrintAPIError(info)
+
+	agreement, apiError := getUserSubscriptionAgreement(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	serveAgreementFile(w, r, agreement)
+}
+
+// CreateSubscriptionAgreement handles POST users/{user_id}/subscriptions/{subscription_id}/agreement endpoint
+// Agreements are generated with new subscriptions, the endpoint generates missing agreement of a pending subscription
+// e.g. when organisation added its template later. Existing agreement is returned unchanged
+var CreateSubscriptionAgreement = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateSubscriptionAgreement)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	subscriptionID := mux.Vars(r)["subscription_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetUserSubscription(userID, subscriptionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreement, apiError := p2pModels.GetSubscriptionAgreement(subscription.ID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if agreement != nil {
+		cigExchange.Respond(w, agreement)
+		return
+	}
+
+	if subscription.Status != p2pModels.SubscriptionStatusPending {
+		info.APIError = cigExchange.NewInvalidFieldError("subscription_id", "Agreements are generated only for pending subscriptions")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(subscription.OfferingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreement, apiError = createSubscriptionAgreement(subscription, offering, agreementChain(r))
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if agreement == nil {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Organisation has no subscription agreement template")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, agreement)
+}
+
+// GetAgreementTemplates handles GET organisations/{organisation_id}/agreement-templates endpoint
+// All versions are returned, newest first. 'kind' and 'language' query parameters filter templates
+var GetAgreementTemplates = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetAgreementTemplates)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	kind := r.URL.Query().Get("kind")
+	language := r.URL.Query().Get("language")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	templates, apiError := p2pModels.GetAgreementTemplates(organisationID, kind, language)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, templates)
+}
+
+// CreateAgreementTemplate handles POST organisations/{organisation_id}/agreement-templates endpoint
+// Template is saved as the next version for its kind and language, existing agreements keep their template version
+var CreateAgreementTemplate = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateAgreementTemplate)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &agreementTemplateRequest{}
+	// decode template from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreementTemplate := &p2pModels.AgreementTemplate{
+		OrganisationID: organisationID,
+		Kind:           req.Kind,
+		Language:       req.Language,
+		Title:          req.Title,
+		Body:           req.Body,
+		CreatedBy:      loggedInUser.UserUUID,
+	}
+	apiError = agreementTemplate.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, agreementTemplate)
+}
+
+// GetAgreementTemplatePreview handles GET organisations/{organisation_id}/agreement-templates/{template_id}/preview endpoint
+// Template is rendered as PDF with sample investor and subscription data
+var GetAgreementTemplatePreview = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetAgreementTemplates)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	templateID := mux.Vars(r)["template_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreementTemplate, apiError := p2pModels.GetAgreementTemplate(organisationID, templateID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	body, err := agreementTemplate.Render(p2pModels.SampleAgreementData())
+	if err != nil {
+		info.APIError = cigExchange.NewInvalidFieldError("template_id", "Unable to fill agreement template: "+err.Error())
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	preview := &p2pModels.SubscriptionAgreement{
+		Title:     agreementTemplate.Title,
+		Body:      body,
+		Footer:    agreementTemplate.Title + " v" + strconv.Itoa(agreementTemplate.Version) + " - preview",
+		CreatedAt: time.Now(),
+	}
+	document, err := preview.Document().PDF()
+	if err != nil {
+		info.APIError = cigExchange.NewInvalidFieldError("template_id", "Unable to render agreement: "+err.Error())
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "agreement-template-" + agreementTemplate.Language + "-v" + strconv.Itoa(agreementTemplate.Version) + ".pdf"}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(document)
+}
+
+// GetOrganisationAgreements handles GET organisations/{organisation_id}/agreements endpoint
+// 'offering_id' query parameter filters agreements of a single offering
+var GetOrganisationAgreements = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOrganisationAgreements)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := r.URL.Query().Get("offering_id")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreements, apiError := p2pModels.GetOrganisationAgreements(organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, agreements)
+}
+
+// GetOrganisationAgreementFile handles GET organisations/{organisation_id}/agreements/{agreement_id}/file endpoint
+var GetOrganisationAgreementFile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOrganisationAgreements)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	agreementID := mux.Vars(r)["agreement_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	agreement, apiError := p2pModels.GetOrganisationAgreement(organisationID, agreementID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	serveAgreementFile(w, r, agreement)
+}
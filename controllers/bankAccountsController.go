@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type bankAccountRequest struct {
+	HolderName string `json:"holder_name"`
+	IBAN       string `json:"iban"`
+	BIC        string `json:"bic"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+type bankAccountConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type bankAccountChangeResponse struct {
+	*p2pModels.BankAccountChange
+	Code string `json:"code,omitempty"`
+}
+
+// GetUserBankAccounts handles GET users/{user_id}/bank-accounts endpoint
+// Default account goes first
+var GetUserBankAccounts = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserBankAccounts)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	accounts, apiError := p2pModels.GetUserBankAccounts(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, accounts)
+}
+
+// queueBankAccountChangeCode emails the confirmation code to the user login email
+func queueBankAccountChangeCode(change *p2pModels.BankAccountChange, code string) *cigExchange.APIError {
+
+	user, apiError := models.GetUser(change.UserID)
+	if apiError != nil {
+		return apiError
+	}
+	if user.LoginEmail == nil || len(user.LoginEmail.Value1) == 0 {
+		return cigExchange.NewInvalidFieldError("user_id", "User has no email to receive the confirmation code")
+	}
+
+	outbox := &p2pModels.EmailOutbox{
+		ReferenceType: p2pModels.EmailOutboxReferenceBankAccount,
+		ReferenceID:   change.ID,
+		ToEmail:       user.LoginEmail.Value1,
+		ToName:        user.Name,
+		Subject:       "Confirm your payout bank account change",
+		Text: "Your confirmation code is " + code + ".\n\n" +
+			"Enter it on CIG Exchange to confirm the requested change of your payout bank accounts. " +
+			"The code expires in 10 minutes.\n\n" +
+			"If you didn't request this change, don't share the code with anyone and contact support.",
+		// text contains the confirmation code
+		Sensitive: true,
+	}
+	return outbox.Create(nil)
+}
+
+// requestBankAccountChange handles create, update, delete and set default endpoints.
+// Changes aren't applied until confirmed with the code sent by email
+func requestBankAccountChange(w http.ResponseWriter, r *http.Request, action string) {
+
+	activityType := p2pModels.ActivityTypeCreateBankAccount
+	switch action {
+	case p2pModels.BankAccountChangeUpdate:
+		activityType = p2pModels.ActivityTypeUpdateBankAccount
+	case p2pModels.BankAccountChangeDelete:
+		activityType = p2pModels.ActivityTypeDeleteBankAccount
+	case p2pModels.BankAccountChangeSetDefault:
+		activityType = p2pModels.ActivityTypeSetDefaultBankAccount
+	}
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, activityType)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	bankAccountID := mux.Vars(r)["bank_account_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	change := &p2pModels.BankAccountChange{
+		UserID:        userID,
+		BankAccountID: bankAccountID,
+		Action:        action,
+	}
+	if action == p2pModels.BankAccountChangeCreate || action == p2pModels.BankAccountChangeUpdate {
+		req := &bankAccountRequest{}
+		// decode bank account from request body
+		err = json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			info.APIError = cigExchange.NewRequestDecodingError(err)
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		change.HolderName = req.HolderName
+		change.IBAN = req.IBAN
+		change.BIC = req.BIC
+		change.IsDefault = req.IsDefault
+	}
+
+	code, apiError := change.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = queueBankAccountChangeCode(change, code)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp := &bankAccountChangeResponse{BankAccountChange: change}
+	// in "DEV" environment we return confirmation code for testing purposes
+	if cigExchange.IsDevEnv() {
+		resp.Code = code
+	}
+
+	cigExchange.Respond(w, resp)
+}
+
+// CreateBankAccount handles POST users/{user_id}/bank-accounts endpoint
+// First account becomes the default one, 'is_default' moves the default to the new account
+var CreateBankAccount = func(w http.ResponseWriter, r *http.Request) {
+
+	requestBankAccountChange(w, r, p2pModels.BankAccountChangeCreate)
+}
+
+// UpdateBankAccount handles PUT users/{user_id}/bank-accounts/{bank_account_id} endpoint
+var UpdateBankAccount = func(w http.ResponseWriter, r *http.Request) {
+
+	requestBankAccountChange(w, r, p2pModels.BankAccountChangeUpdate)
+}
+
+// DeleteBankAccount handles DELETE users/{user_id}/bank-accounts/{bank_account_id} endpoint
+// Oldest remaining account becomes the default one when the default account is deleted
+var DeleteBankAccount = func(w http.ResponseWriter, r *http.Request) {
+
+	requestBankAccountChange(w, r, p2pModels.BankAccountChangeDelete)
+}
+
+// SetDefaultBankAccount handles POST users/{user_id}/bank-accounts/{bank_account_id}/default endpoint
+var SetDefaultBankAccount = func(w http.ResponseWriter, r *http.Request) {
+
+	requestBankAccountChange(w, r, p2pModels.BankAccountChangeSetDefault)
+}
+
+// ConfirmBankAccountChange handles POST users/{user_id}/bank-accounts/changes/{change_id}/confirm endpoint
+// Responds with all user bank accounts after the change
+var ConfirmBankAccountChange = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeConfirmBankAccountChange)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	changeID := mux.Vars(r)["change_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &bankAccountConfirmRequest{}
+	// decode confirmation code from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if len(req.Code) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"code"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	change, apiError := p2pModels.GetUserBankAccountChange(userID, changeID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	account, apiError := change.Confirm(req.Code)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	notifications.BankAccountChanged(change, account)
+
+	accounts, apiError := p2pModels.GetUserBankAccounts(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, accounts)
+}
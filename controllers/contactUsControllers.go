@@ -4,14 +4,18 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/captcha"
+	"cig-exchange-p2p-backend/mailer"
+	"cig-exchange-p2p-backend/middleware"
+	p2pModels "cig-exchange-p2p-backend/models"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-
-	"github.com/mattbaird/gochimp"
 )
 
+const reasonCaptchaFailure = "Captcha verification failure"
+
 // SendContactUsEmail handles POST api/contact_us endpoint
 var SendContactUsEmail = func(w http.ResponseWriter, r *http.Request) {
 
@@ -21,9 +25,12 @@ var SendContactUsEmail = func(w http.ResponseWriter, r *http.Request) {
 	defer cigExchange.PrintAPIError(info)
 
 	type contactUs struct {
-		Name    string `json:"name"`
-		Email   string `json:"email"`
-		Message string `json:"message"`
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		Message      string `json:"message"`
+		CaptchaToken string `json:"captcha_token"`
+		// honeypot field, hidden in the form and expected to be empty
+		Website string `json:"website"`
 	}
 	contactInfo := &contactUs{}
 	// decode contact us info from request body
@@ -51,7 +58,57 @@ var SendContactUsEmail = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mandrillClient := cigExchange.GetMandrill()
+	if !validateEmailFormat(contactInfo.Email) {
+		info.APIError = cigExchange.NewInvalidFieldError("email", "Invalid email format")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	submission := &p2pModels.ContactUsSubmission{
+		Name:       contactInfo.Name,
+		Email:      contactInfo.Email,
+		Message:    contactInfo.Message,
+		RemoteAddr: middleware.GetClientIP(r),
+		Status:     p2pModels.ContactUsStatusAccepted,
+	}
+
+	// verify captcha if enabled
+	verifier := captcha.GetVerifier()
+	if verifier != nil {
+		valid, err := verifier.Verify(contactInfo.CaptchaToken, submission.RemoteAddr)
+		if err != nil {
+			info.APIError = &cigExchange.APIError{}
+			info.APIError.SetErrorType(cigExchange.ErrorTypeInternalServer)
+
+			nesetedError := info.APIError.NewNestedError(reasonCaptchaFailure, "Unable to verify captcha")
+			nesetedError.OriginalError = err
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		if !valid {
+			submission.Status = p2pModels.ContactUsStatusRejected
+			submission.RejectReason = "invalid captcha"
+			saveContactUsSubmission(submission)
+
+			info.APIError = cigExchange.NewInvalidFieldError("captcha_token", "Invalid captcha")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	// silently drop spam, bots shouldn't know they were detected
+	if len(contactInfo.Website) > 0 {
+		submission.Status = p2pModels.ContactUsStatusRejected
+		submission.RejectReason = "honeypot field filled"
+	} else if reason := checkContactUsContent(contactInfo.Name, contactInfo.Message); len(reason) > 0 {
+		submission.Status = p2pModels.ContactUsStatusRejected
+		submission.RejectReason = reason
+	}
+	saveContactUsSubmission(submission)
+	if submission.Status == p2pModels.ContactUsStatusRejected {
+		w.WriteHeader(204)
+		return
+	}
 
 	targetEmail := os.Getenv("CONTACTUS_TARGET_EMAIL")
 	// blackhole contact us emails in dev env
@@ -59,37 +116,29 @@ var SendContactUsEmail = func(w http.ResponseWriter, r *http.Request) {
 		targetEmail = "blackhole+" + targetEmail
 	}
 
-	recipients := []gochimp.Recipient{
-		gochimp.Recipient{Email: targetEmail, Name: "CIG Exchange team", Type: "to"},
-	}
-
-	message := gochimp.Message{
-		Text:      fmt.Sprintf("Name:%s\nEmail:%s\n\nMessage:\n%s", contactInfo.Name, contactInfo.Email, contactInfo.Message),
-		Subject:   "Contact Us message",
+	email := &mailer.Email{
+		ToEmail:   targetEmail,
+		ToName:    "CIG Exchange team",
 		FromEmail: targetEmail,
 		FromName:  "CIG Exchange contact us",
-		To:        recipients,
+		Subject:   "Contact Us message",
+		Text:      fmt.Sprintf("Name:%s\nEmail:%s\n\nMessage:\n%s", contactInfo.Name, contactInfo.Email, contactInfo.Message),
 	}
 
-	resp, err := mandrillClient.MessageSend(message, false)
-
-	// we only have 1 recepient
-	if len(resp) == 1 {
-		if resp[0].Status == "rejected" {
-			info.APIError = &cigExchange.APIError{}
+	err = mailer.Get().Send(email)
+	if err != nil {
+		info.APIError = &cigExchange.APIError{}
+		if rejectedError, ok := err.(*mailer.RejectedError); ok {
 			info.APIError.SetErrorType(cigExchange.ErrorTypeUnprocessableEntity)
 
 			nesetedError := info.APIError.NewNestedError(cigExchange.ReasonMandrillFailure, "Invalid request")
-			nesetedError.OriginalError = fmt.Errorf("Invalid request. %v", resp[0].RejectedReason)
-			cigExchange.RespondWithAPIError(w, info.APIError)
-			return
-		}
-	} else {
-		info.APIError = &cigExchange.APIError{}
-		info.APIError.SetErrorType(cigExchange.ErrorTypeInternalServer)
+			nesetedError.OriginalError = fmt.Errorf("Invalid request. %v", rejectedError.Reason)
+		} else {
+			info.APIError.SetErrorType(cigExchange.ErrorTypeInternalServer)
 
-		nesetedError := info.APIError.NewNestedError(cigExchange.ReasonMandrillFailure, "Unable to send email")
-		nesetedError.OriginalError = fmt.Errorf("Unable to send email")
+			nesetedError := info.APIError.NewNestedError(cigExchange.ReasonMandrillFailure, "Unable to send email")
+			nesetedError.OriginalError = err
+		}
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
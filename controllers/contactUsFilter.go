@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	p2pModels "cig-exchange-p2p-backend/models"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// default values for contact us spam heuristics
+const defaultContactUsMaxLinks = 2
+
+var defaultContactUsBannedWords = []string{"viagra", "casino", "crypto giveaway", "seo services", "backlinks"}
+
+var contactUsLinkRegexp = regexp.MustCompile(`(?i)(https?://|www\.)`)
+
+// validateEmailFormat checks that value is a plain 'local@domain.tld' email address
+func validateEmailFormat(email string) bool {
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return false
+	}
+
+	parts := strings.Split(email, "@")
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return false
+	}
+	return strings.Contains(parts[1], ".") && !strings.HasSuffix(parts[1], ".")
+}
+
+// checkContactUsContent applies basic spam heuristics.
+// Returns rejection reason or empty string if content looks fine
+func checkContactUsContent(name, message string) string {
+
+	maxLinks, err := strconv.Atoi(os.Getenv("CONTACTUS_MAX_LINKS"))
+	if err != nil || maxLinks < 0 {
+		maxLinks = defaultContactUsMaxLinks
+	}
+
+	linkCount := len(contactUsLinkRegexp.FindAllString(message, -1))
+	if linkCount > maxLinks {
+		return fmt.Sprintf("too many links: %v", linkCount)
+	}
+	if contactUsLinkRegexp.MatchString(name) {
+		return "link in name"
+	}
+
+	bannedWords := defaultContactUsBannedWords
+	if envWords := os.Getenv("CONTACTUS_BANNED_WORDS"); len(envWords) > 0 {
+		bannedWords = strings.Split(envWords, ",")
+	}
+
+	text := strings.ToLower(name + " " + message)
+	for _, word := range bannedWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if len(word) > 0 && strings.Contains(text, word) {
+			return "banned word: " + word
+		}
+	}
+
+	return ""
+}
+
+// saveContactUsSubmission stores submission, rejected submissions are also logged
+func saveContactUsSubmission(submission *p2pModels.ContactUsSubmission) {
+
+	if submission.Status == p2pModels.ContactUsStatusRejected {
+		fmt.Printf("ContactUs: rejected submission from %v (%v): %v\n", submission.Email, submission.RemoteAddr, submission.RejectReason)
+	}
+
+	apiError := submission.Create()
+	if apiError != nil {
+		fmt.Println("ContactUs: unable to save submission:")
+		fmt.Println(apiError.ToString())
+	}
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"os"
+	"testing"
+)
+
+func TestValidateEmailFormat(t *testing.T) {
+
+	cases := map[string]bool{
+		"john@example.com":         true,
+		"john.doe+tag@example.com": true,
+		"":                         false,
+		"john":                     false,
+		"john@localhost":           false,
+		"john@example.":            false,
+		"@example.com":             false,
+		"John <john@example.com>":  false,
+		"john@example.com, x@y.z":  false,
+	}
+	for email, expected := range cases {
+		if validateEmailFormat(email) != expected {
+			t.Errorf("email %q: expected %v", email, expected)
+		}
+	}
+}
+
+func TestCheckContactUsContent(t *testing.T) {
+
+	os.Unsetenv("CONTACTUS_MAX_LINKS")
+	os.Unsetenv("CONTACTUS_BANNED_WORDS")
+
+	cases := []struct {
+		name     string
+		message  string
+		rejected bool
+	}{
+		{"John", "Hello, I would like to know more about your offerings", false},
+		{"John", "See https://example.com and www.example.org", false},
+		{"John", "http://a.com http://b.com http://c.com", true},
+		{"www.spam.com", "Hello", true},
+		{"John", "Best CASINO bonus", true},
+		{"Cheap backlinks", "Hello", true},
+	}
+	for _, c := range cases {
+		reason := checkContactUsContent(c.name, c.message)
+		if (len(reason) > 0) != c.rejected {
+			t.Errorf("name %q, message %q: unexpected result %q", c.name, c.message, reason)
+		}
+	}
+}
+
+func TestCheckContactUsContentEnv(t *testing.T) {
+
+	os.Setenv("CONTACTUS_MAX_LINKS", "0")
+	os.Setenv("CONTACTUS_BANNED_WORDS", "lottery, prize")
+	defer os.Unsetenv("CONTACTUS_MAX_LINKS")
+	defer os.Unsetenv("CONTACTUS_BANNED_WORDS")
+
+	if len(checkContactUsContent("John", "see www.example.com")) == 0 {
+		t.Error("expected link to be rejected")
+	}
+	if len(checkContactUsContent("John", "You won a Prize")) == 0 {
+		t.Error("expected configured banned word to be rejected")
+	}
+	if len(checkContactUsContent("John", "casino")) > 0 {
+		t.Error("expected default banned words to be replaced")
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-p2p-backend/mailer"
+	"net/http"
+)
+
+// GetCapturedEmails handles GET dev/emails endpoint (no JWT).
+// Only registered when capture mail backend is active
+var GetCapturedEmails = func(w http.ResponseWriter, r *http.Request) {
+
+	capture := mailer.GetCapture()
+	if capture == nil {
+		apiError := cigExchange.NewAccessForbiddenError("Email capture is disabled")
+		cigExchange.RespondWithAPIError(w, apiError)
+		return
+	}
+
+	emails := capture.Emails()
+
+	// optional recipient filter
+	to := r.URL.Query().Get("to")
+	if len(to) > 0 {
+		filtered := make([]*mailer.CapturedEmail, 0)
+		for _, email := range emails {
+			if email.Email.ToEmail == to {
+				filtered = append(filtered, email)
+			}
+		}
+		emails = filtered
+	}
+
+	cigExchange.Respond(w, emails)
+}
+
+// DeleteCapturedEmails handles DELETE dev/emails endpoint (no JWT).
+// Only registered when capture mail backend is active
+var DeleteCapturedEmails = func(w http.ResponseWriter, r *http.Request) {
+
+	capture := mailer.GetCapture()
+	if capture == nil {
+		apiError := cigExchange.NewAccessForbiddenError("Email capture is disabled")
+		cigExchange.RespondWithAPIError(w, apiError)
+		return
+	}
+
+	capture.Clear()
+
+	w.WriteHeader(204)
+}
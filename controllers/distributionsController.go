@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type holdingRequest struct {
+	InvestedAmount p2pModels.Decimal `json:"invested_amount"`
+}
+
+type distributionRequest struct {
+	Principal         p2pModels.Decimal `json:"principal"`
+	Interest          p2pModels.Decimal `json:"interest"`
+	PaymentDate       string            `json:"payment_date"`
+	Reference         string            `json:"reference"`
+	BankTransactionID string            `json:"bank_transaction_id"`
+}
+
+// getOrganisationOffering checks organisation access and returns the organisation offering
+func getOrganisationOffering(loggedInUser *cigExchange.LoggedInUser, organisationID, offeringID string) (*models.Offering, *cigExchange.APIError) {
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		return nil, cigExchange.NewAccessRightsError("No access rights for the organisation")
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	if offering.OrganisationID != organisationID {
+		return nil, cigExchange.NewAccessRightsError("No access rights for the organisation")
+	}
+	return offering, nil
+}
+
+// GetOfferingHoldings handles GET organisations/{organisation_id}/offerings/{offering_id}/holdings endpoint
+var GetOfferingHoldings = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingHoldings)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	holdings, apiError := p2pModels.GetOfferingHoldings(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, holdings)
+}
+
+// SetOfferingHolding handles PUT organisations/{organisation_id}/offerings/{offering_id}/holdings/{user_id} endpoint
+// Investor register can be changed only until the first distribution, for offerings not managed by the ledger
+var SetOfferingHolding = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeSetOfferingHolding)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	offering, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// check that investor exists
+	_, apiError = models.GetUser(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	hasDistributions, apiError := p2pModels.HasDistributions(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if hasDistributions {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Holdings can't be changed after distributions were recorded")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// holdings of offerings with commitments or trades are maintained by the ledger
+	hasCommitments, apiError := p2pModels.HasCommitments(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	hasTrades, apiError := p2pModels.HasTrades(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if hasCommitments || hasTrades {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Holdings of offerings with ledger commitments or trades can't be changed")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &holdingRequest{}
+	// decode holding from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	places := p2pModels.CurrencyDecimalPlaces(money.Currency)
+	if req.InvestedAmount.Sign() < 0 || req.InvestedAmount.Round(places).Cmp(req.InvestedAmount) != 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("invested_amount", "Invested amount must be a positive "+money.Currency+" amount")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	holdings, apiError := p2pModels.GetOfferingHoldings(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// sum of holdings can't exceed offering amount
+	holding := &p2pModels.Holding{OfferingID: offeringID, UserID: userID}
+	invested := req.InvestedAmount
+	for _, h := range holdings {
+		if h.UserID == userID {
+			holding = h
+			continue
+		}
+		invested = invested.Add(h.InvestedAmount)
+	}
+	if money.Amount != nil && invested.Cmp(*money.Amount) > 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("invested_amount", "Holdings exceed offering amount of "+money.Amount.String()+" "+money.Currency)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	holding.Currency = money.Currency
+	holding.InvestedAmount = req.InvestedAmount
+	holding.Principal = req.InvestedAmount
+
+	apiError = holding.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, holding)
+}
+
+// GetOfferingDistributions handles GET organisations/{organisation_id}/offerings/{offering_id}/distributions endpoint
+var GetOfferingDistributions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingDistributions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	distributions, apiError := p2pModels.GetOfferingDistributions(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, distributions)
+}
+
+// GetOfferingDistribution handles GET organisations/{organisation_id}/offerings/{offering_id}/distributions/{distribution_id} endpoint
+var GetOfferingDistribution = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingDistribution)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	distributionID := mux.Vars(r)["distribution_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	distribution, apiError := p2pModels.GetDistribution(offeringID, distributionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, distribution)
+}
+
+// CreateOfferingDistribution handles POST organisations/{organisation_id}/offerings/{offering_id}/distributions endpoint
+// Allocations are posted to investor cash accounts in the same transaction
+var CreateOfferingDistribution = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateOfferingDistribution)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	offering, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &distributionRequest{}
+	// decode distribution from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// distribution credits investor cash accounts from the platform bank account, so only platform admin
+	// confirming the repayment was received can record it. Bank receipts on the platform account can't be
+	// attributed to the organisation, so receipt funded distributions are recorded by platform admin as well
+	apiError = checkAdminAccess(loggedInUser.UserUUID, "Only admin can record distributions")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	paymentDate := time.Now()
+	if len(req.PaymentDate) > 0 {
+		paymentDate, err = time.Parse("2006-01-02", req.PaymentDate)
+		if err != nil {
+			info.APIError = cigExchange.NewInvalidFieldError("payment_date", "Payment date must be in YYYY-MM-DD format")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	distribution := &p2pModels.Distribution{
+		OfferingID:        offeringID,
+		OrganisationID:    organisationID,
+		Currency:          money.Currency,
+		Principal:         req.Principal,
+		Interest:          req.Interest,
+		PaymentDate:       paymentDate,
+		Reference:         strings.TrimSpace(req.Reference),
+		BankTransactionID: req.BankTransactionID,
+		CreatedBy:         loggedInUser.UserUUID,
+	}
+
+	apiError = distribution.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifications.DistributionPaid(distribution, offering)
+
+	cigExchange.Respond(w, distribution)
+}
+
+// GetUserHoldings handles GET users/{user_id}/holdings endpoint
+var GetUserHoldings = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserHoldings)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	holdings, apiError := p2pModels.GetUserHoldings(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, holdings)
+}
+
+// GetUserDistributions handles GET users/{user_id}/distributions endpoint
+var GetUserDistributions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserDistributions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	allocations, apiError := p2pModels.GetUserDistributionAllocations(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, allocations)
+}
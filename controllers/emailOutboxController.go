@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetEmailDeadLetters handles GET email-outbox/dead-letters endpoint
+var GetEmailDeadLetters = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetEmailDeadLetters)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// get user role
+	userRole, apiError := models.GetUserRole(loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// only admin user can see dead letters
+	if userRole != models.UserRoleAdmin {
+		info.APIError = cigExchange.NewAccessRightsError("Only admin user can see undelivered emails")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	emails, apiError := p2pModels.GetDeadEmailOutbox()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// admins must not see invitation tokens and codes of other users
+	for _, email := range emails {
+		email.Redact()
+	}
+
+	cigExchange.Respond(w, emails)
+}
+
+// RetryOutboxEmail handles POST email-outbox/{outbox_id}/retry endpoint
+var RetryOutboxEmail = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeRetryOutboxEmail)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	outboxID := mux.Vars(r)["outbox_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// get user role
+	userRole, apiError := models.GetUserRole(loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// only admin user can retry emails
+	if userRole != models.UserRoleAdmin {
+		info.APIError = cigExchange.NewAccessRightsError("Only admin user can retry undelivered emails")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	email, apiError := p2pModels.GetEmailOutbox(outboxID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if email.Status != p2pModels.EmailOutboxStatusDead {
+		info.APIError = cigExchange.NewInvalidFieldError("outbox_id", "Only undelivered emails can be retried")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// requeue with a fresh set of attempts
+	email.Status = p2pModels.EmailOutboxStatusPending
+	email.Attempts = 0
+	email.NextAttemptAt = time.Now()
+	apiError = email.Save(nil)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
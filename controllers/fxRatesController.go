@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type fxRateRequest struct {
+	BaseCurrency  string            `json:"base_currency"`
+	QuoteCurrency string            `json:"quote_currency"`
+	Rate          p2pModels.Decimal `json:"rate"`
+	ValidFrom     *time.Time        `json:"valid_from"`
+}
+
+// moneyFormatter adds exact offering money and optional display currency conversion to responses
+type moneyFormatter struct {
+	displayCurrency string
+	converter       *p2pModels.FXConverter
+}
+
+// newMoneyFormatter reads 'currency' query parameter, offerings keep their own currency if it is not set
+func newMoneyFormatter(r *http.Request) (*moneyFormatter, *cigExchange.APIError) {
+
+	formatter := &moneyFormatter{}
+
+	currency := p2pModels.NormalizeCurrency(r.URL.Query().Get("currency"))
+	if len(currency) == 0 {
+		return formatter, nil
+	}
+	if !p2pModels.IsValidCurrency(currency) {
+		return nil, cigExchange.NewInvalidFieldError("currency", "Currency must be one of: "+strings.Join(p2pModels.Currencies, ", "))
+	}
+
+	converter, apiError := p2pModels.LoadFXConverter(time.Now())
+	if apiError != nil {
+		return nil, apiError
+	}
+	formatter.displayCurrency = currency
+	formatter.converter = converter
+	return formatter, nil
+}
+
+// apply replaces float money fields of the offering map with exact decimals.
+// Only fields present in the map are replaced, teaser responses stay without amounts
+func (formatter *moneyFormatter) apply(offeringMMap map[string]interface{}, money *p2pModels.OfferingMoney) *cigExchange.APIError {
+
+	offeringMMap["currency"] = money.Currency
+
+	converted := false
+	for field, value := range money.Fields() {
+		if _, ok := offeringMMap[field]; !ok {
+			continue
+		}
+		offeringMMap[field] = value
+		if len(formatter.displayCurrency) == 0 || value == nil {
+			continue
+		}
+
+		displayValue, apiError := formatter.converter.Convert(*value, money.Currency, formatter.displayCurrency)
+		if apiError != nil {
+			return apiError
+		}
+		offeringMMap["display_"+field] = displayValue
+		converted = true
+	}
+
+	if converted {
+		rate, _ := formatter.converter.Rate(money.Currency, formatter.displayCurrency)
+		offeringMMap["display_currency"] = formatter.displayCurrency
+		offeringMMap["fx_rate"] = rate
+	}
+	return nil
+}
+
+// applyOfferingMoneyRequest updates offering money with currency and amounts from request map.
+// Amounts must be decoded with json.Decoder.UseNumber to keep them exact
+func applyOfferingMoneyRequest(offeringMap map[string]interface{}, money *p2pModels.OfferingMoney) *cigExchange.APIError {
+
+	if value, ok := offeringMap["currency"]; ok {
+		currency, _ := value.(string)
+		currency = p2pModels.NormalizeCurrency(currency)
+		if !p2pModels.IsValidCurrency(currency) {
+			return cigExchange.NewInvalidFieldError("currency", "Currency must be one of: "+strings.Join(p2pModels.Currencies, ", "))
+		}
+		if currency != money.Currency && money.AmountAlreadyTaken != nil && !money.AmountAlreadyTaken.IsZero() {
+			return cigExchange.NewInvalidFieldError("currency", "Currency can't be changed after investments were taken")
+		}
+		money.Currency = currency
+	}
+
+	places := p2pModels.CurrencyDecimalPlaces(money.Currency)
+	for _, field := range p2pModels.OfferingMoneyFields {
+		value, ok := offeringMap[field]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			money.SetField(field, nil)
+			continue
+		}
+
+		number, ok := value.(json.Number)
+		if !ok {
+			return cigExchange.NewInvalidFieldError(field, "Field '"+field+"' must be a number")
+		}
+		amount, err := p2pModels.ParseDecimal(number.String())
+		if err != nil {
+			return cigExchange.NewInvalidFieldError(field, "Field '"+field+"' must be a number")
+		}
+		if amount.Sign() < 0 {
+			return cigExchange.NewInvalidFieldError(field, "Field '"+field+"' can't be negative")
+		}
+		if amount.Round(places).Cmp(amount) != 0 {
+			return cigExchange.NewInvalidFieldError(field, "Field '"+field+"' has more decimal places than "+money.Currency+" allows")
+		}
+		money.SetField(field, &amount)
+	}
+
+	money.UpdateRemaining()
+	return nil
+}
+
+// addDashboardMoneyTotals replaces organisation dashboard totals with exact sums converted to display currency.
+// Totals are also returned per offering currency without conversion, currencies without FX rate are listed in 'unconverted_currencies'
+func addDashboardMoneyTotals(r *http.Request, organisationID string, dashboardMap map[string]interface{}) *cigExchange.APIError {
+
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		return apiError
+	}
+	if len(formatter.displayCurrency) == 0 {
+		formatter.displayCurrency = p2pModels.DefaultCurrency
+		formatter.converter, apiError = p2pModels.LoadFXConverter(time.Now())
+		if apiError != nil {
+			return apiError
+		}
+	}
+
+	offerings, apiError := models.GetOrganisationOfferings(organisationID)
+	if apiError != nil {
+		return apiError
+	}
+	moneys, apiError := p2pModels.GetOfferingsMoney(offerings)
+	if apiError != nil {
+		return apiError
+	}
+
+	totalAmount := p2pModels.Decimal{}
+	remainingAmount := p2pModels.Decimal{}
+	byCurrency := make(map[string]map[string]p2pModels.Decimal)
+	for _, money := range moneys {
+		amount := p2pModels.Decimal{}
+		if money.Amount != nil {
+			amount = *money.Amount
+		}
+
+		currencyTotals, ok := byCurrency[money.Currency]
+		if !ok {
+			currencyTotals = map[string]p2pModels.Decimal{"total_amount": {}, "remaining_amount": {}}
+			byCurrency[money.Currency] = currencyTotals
+		}
+		currencyTotals["total_amount"] = currencyTotals["total_amount"].Add(amount)
+		currencyTotals["remaining_amount"] = currencyTotals["remaining_amount"].Add(money.Remaining)
+	}
+
+	// currencies without a rate are left out of converted totals and flagged instead of failing the dashboard
+	unconverted := make([]string, 0)
+	for _, currency := range p2pModels.Currencies {
+		currencyTotals, ok := byCurrency[currency]
+		if !ok {
+			continue
+		}
+		if _, ok := formatter.converter.Rate(currency, formatter.displayCurrency); !ok {
+			unconverted = append(unconverted, currency)
+			continue
+		}
+
+		converted, apiError := formatter.converter.Convert(currencyTotals["total_amount"], currency, formatter.displayCurrency)
+		if apiError != nil {
+			return apiError
+		}
+		totalAmount = totalAmount.Add(converted)
+
+		converted, apiError = formatter.converter.Convert(currencyTotals["remaining_amount"], currency, formatter.displayCurrency)
+		if apiError != nil {
+			return apiError
+		}
+		remainingAmount = remainingAmount.Add(converted)
+	}
+
+	dashboardMap["total_amount"] = totalAmount
+	dashboardMap["remaining_amount"] = remainingAmount
+	dashboardMap["currency"] = formatter.displayCurrency
+	dashboardMap["totals_by_currency"] = byCurrency
+	dashboardMap["unconverted_currencies"] = unconverted
+	return nil
+}
+
+// GetFXRates handles GET fx-rates endpoint
+// 'at' query parameter (RFC 3339) returns rates valid at the time
+var GetFXRates = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetFXRates)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	at := time.Now()
+	if atParam := r.URL.Query().Get("at"); len(atParam) > 0 {
+		at, err = time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			info.APIError = cigExchange.NewInvalidFieldError("at", "Time must be in RFC 3339 format")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	converter, apiError := p2pModels.LoadFXConverter(at)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, converter.Rates())
+}
+
+// GetFXRateHistory handles GET fx-rates/history endpoint
+// 'base' and 'quote' query parameters select the currency pair
+var GetFXRateHistory = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetFXRateHistory)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see FX rate history")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	baseCurrency := p2pModels.NormalizeCurrency(r.URL.Query().Get("base"))
+	quoteCurrency := p2pModels.NormalizeCurrency(r.URL.Query().Get("quote"))
+	if !p2pModels.IsValidCurrency(baseCurrency) || !p2pModels.IsValidCurrency(quoteCurrency) {
+		info.APIError = cigExchange.NewInvalidFieldError("base, quote", "Currencies must be one of: "+strings.Join(p2pModels.Currencies, ", "))
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	rates, apiError := p2pModels.GetFXRateHistory(baseCurrency, quoteCurrency)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, rates)
+}
+
+// CreateFXRate handles POST fx-rates endpoint
+var CreateFXRate = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateFXRate)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can manage FX rates")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &fxRateRequest{}
+	// decode fx rate from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	rate := &p2pModels.FXRate{
+		BaseCurrency:  p2pModels.NormalizeCurrency(req.BaseCurrency),
+		QuoteCurrency: p2pModels.NormalizeCurrency(req.QuoteCurrency),
+		Rate:          req.Rate,
+		ValidFrom:     time.Now(),
+		CreatedBy:     loggedInUser.UserUUID,
+	}
+	if req.ValidFrom != nil {
+		rate.ValidFrom = *req.ValidFrom
+	}
+
+	apiError = rate.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, rate)
+}
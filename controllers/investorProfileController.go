@@ -0,0 +1,692 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// KYCDataPath path to investor identity documents, files are not reachable through GET media/{media_file}
+var KYCDataPath = path.Join(UserDataPath, "kyc")
+
+// kycDocumentMimeTypes lists accepted identity document formats
+var kycDocumentMimeTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+type investorProfileRequest struct {
+	FirstName        *string `json:"first_name"`
+	LastName         *string `json:"last_name"`
+	DateOfBirth      *string `json:"date_of_birth"`
+	Nationality      *string `json:"nationality"`
+	ResidenceCountry *string `json:"residence_country"`
+	Street           *string `json:"street"`
+	PostalCode       *string `json:"postal_code"`
+	City             *string `json:"city"`
+	Classification   *string `json:"classification"`
+}
+
+type kycReviewRequest struct {
+	KYCLevel *int   `json:"kyc_level"`
+	Reason   string `json:"reason"`
+}
+
+type kycProfileWithReviews struct {
+	*p2pModels.InvestorProfile
+	Reviews []*p2pModels.KYCReview `json:"reviews"`
+}
+
+// applyInvestorProfileRequest validates request fields and applies them to the profile
+func applyInvestorProfileRequest(req *investorProfileRequest, profile *p2pModels.InvestorProfile) *cigExchange.APIError {
+
+	if req.FirstName != nil {
+		profile.FirstName = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		profile.LastName = strings.TrimSpace(*req.LastName)
+	}
+	if req.DateOfBirth != nil {
+		dateOfBirth, err := time.Parse("2006-01-02", *req.DateOfBirth)
+		if err != nil {
+			return cigExchange.NewInvalidFieldError("date_of_birth", "Date of birth must be in YYYY-MM-DD format")
+		}
+		if dateOfBirth.After(time.Now().AddDate(-18, 0, 0)) {
+			return cigExchange.NewInvalidFieldError("date_of_birth", "Investor must be at least 18 years old")
+		}
+		profile.DateOfBirth = &dateOfBirth
+	}
+	if req.Nationality != nil {
+		nationality := p2pModels.NormalizeCountryCode(*req.Nationality)
+		if !p2pModels.IsValidCountryCode(nationality) {
+			return cigExchange.NewInvalidFieldError("nationality", "Nationality must be ISO 3166-1 alpha-2 country code")
+		}
+		profile.Nationality = nationality
+	}
+	if req.ResidenceCountry != nil {
+		residenceCountry := p2pModels.NormalizeCountryCode(*req.ResidenceCountry)
+		if !p2pModels.IsValidCountryCode(residenceCountry) {
+			return cigExchange.NewInvalidFieldError("residence_country", "Residence country must be ISO 3166-1 alpha-2 country code")
+		}
+		profile.ResidenceCountry = residenceCountry
+	}
+	if req.Street != nil {
+		profile.Street = strings.TrimSpace(*req.Street)
+	}
+	if req.PostalCode != nil {
+		profile.PostalCode = strings.TrimSpace(*req.PostalCode)
+	}
+	if req.City != nil {
+		profile.City = strings.TrimSpace(*req.City)
+	}
+	if req.Classification != nil {
+		if !p2pModels.IsValidInvestorClassification(*req.Classification) {
+			return cigExchange.NewInvalidFieldError("classification", "Classification must be one of: "+strings.Join(p2pModels.InvestorClassifications, ", "))
+		}
+		profile.Classification = *req.Classification
+	}
+	return nil
+}
+
+// GetInvestorProfile handles GET users/{user_id}/investor-profile endpoint
+var GetInvestorProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetInvestorProfile)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, profile)
+}
+
+// UpdateInvestorProfile handles PATCH users/{user_id}/investor-profile endpoint
+var UpdateInvestorProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateInvestorProfile)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !profile.IsEditable() {
+		info.APIError = cigExchange.NewInvalidFieldError("status", "Profile can't be changed while it is "+profile.Status)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &investorProfileRequest{}
+	// decode profile from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = applyInvestorProfileRequest(req, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = profile.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, profile)
+}
+
+// SubmitInvestorProfile handles POST users/{user_id}/investor-profile/submit endpoint
+var SubmitInvestorProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeSubmitInvestorProfile)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = profile.Submit()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, profile)
+}
+
+// UploadKYCDocument handles PUT users/{user_id}/investor-profile/documents endpoint
+// 'type' query parameter defines the document type
+var UploadKYCDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUploadKYCDocument)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	documentType := r.URL.Query().Get("type")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsValidKYCDocumentType(documentType) {
+		info.APIError = cigExchange.NewInvalidFieldError("type", "Type must be one of: "+strings.Join(p2pModels.KYCDocumentTypes, ", "))
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !profile.IsEditable() {
+		info.APIError = cigExchange.NewInvalidFieldError("status", "Documents can't be changed while profile is "+profile.Status)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// profile row must exist before documents reference it
+	if profile.CreatedAt.IsZero() {
+		apiError = profile.Save()
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	// Limit upload size
+	r.Body = http.MaxBytesReader(w, r.Body, 10*MB) // 10 Mb
+
+	defer r.Body.Close()
+	fileBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		info.APIError = cigExchange.NewReadError("Failed to read request body", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	filetype := http.DetectContentType(fileBytes)
+	extension, ok := kycDocumentMimeTypes[filetype]
+	if !ok {
+		info.APIError = cigExchange.NewInvalidFieldError("file", "Only PDF, JPEG and PNG documents are accepted")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// fill media size and type, url stays empty as documents are served only to the owner and admins
+	media := &models.Media{}
+	media.FileSize = len(fileBytes)
+	media.MimeType = filetype
+	media.Type = p2pModels.MediaTypeKYCDocument
+	media.Title = documentType
+	media.FileExtension = extension
+
+	db := cigExchange.GetDB().Create(media)
+	if db.Error != nil {
+		info.APIError = cigExchange.NewDatabaseError("Create media failed", db.Error)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	document := &p2pModels.KYCDocument{
+		UserID:  userID,
+		Type:    documentType,
+		MediaID: media.ID,
+	}
+	apiError = document.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	document.Media = *media
+
+	// save file to kyc folder
+	err = os.MkdirAll(KYCDataPath, 0700)
+	if err == nil {
+		err = ioutil.WriteFile(path.Join(KYCDataPath, media.ID)+media.FileExtension, fileBytes, 0600)
+	}
+	if err != nil {
+		apiError = document.Delete()
+		if apiError != nil {
+			fmt.Println("UploadKYCDocument: " + apiError.ToString())
+		}
+		info.APIError = cigExchange.NewReadError("Failed to write request body to file", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, document)
+}
+
+// GetKYCDocumentFile handles GET users/{user_id}/investor-profile/documents/{document_id}/file endpoint
+// available to the profile owner and admin users
+var GetKYCDocumentFile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetKYCDocument)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	documentID := mux.Vars(r)["document_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		apiError := checkAdminAccess(loggedInUser.UserUUID, "No access rights for the user")
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	document, apiError := profile.GetDocument(documentID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.Header().Set("Content-Type", document.Media.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.Type + document.Media.FileExtension}))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeFile(w, r, path.Join(KYCDataPath, document.MediaID)+document.Media.FileExtension)
+}
+
+// DeleteKYCDocument handles DELETE users/{user_id}/investor-profile/documents/{document_id} endpoint
+var DeleteKYCDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteKYCDocument)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	documentID := mux.Vars(r)["document_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !profile.IsEditable() {
+		info.APIError = cigExchange.NewInvalidFieldError("status", "Documents can't be changed while profile is "+profile.Status)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	document, apiError := profile.GetDocument(documentID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = document.Delete()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// file removal failure only leaves an orphaned file behind
+	err = os.Remove(path.Join(KYCDataPath, document.MediaID) + document.Media.FileExtension)
+	if err != nil {
+		fmt.Println("DeleteKYCDocument: unable to remove file:")
+		fmt.Println(err.Error())
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetKYCProfiles handles GET kyc/profiles endpoint
+// 'status' query parameter filters profiles, pending profiles are returned by default
+var GetKYCProfiles = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetKYCProfiles)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin user can review investor profiles")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if len(status) == 0 {
+		status = p2pModels.KYCStatusPending
+	}
+
+	profiles, apiError := p2pModels.GetInvestorProfilesByStatus(status)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, profiles)
+}
+
+// GetKYCProfile handles GET kyc/profiles/{user_id} endpoint
+var GetKYCProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetKYCProfile)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin user can review investor profiles")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	reviews, apiError := p2pModels.GetKYCReviews(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, &kycProfileWithReviews{InvestorProfile: profile, Reviews: reviews})
+}
+
+// reviewKYCProfile handles approve and reject endpoints
+func reviewKYCProfile(w http.ResponseWriter, r *http.Request, approve bool) {
+
+	activityType := p2pModels.ActivityTypeRejectKYCProfile
+	if approve {
+		activityType = p2pModels.ActivityTypeApproveKYCProfile
+	}
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, activityType)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin user can review investor profiles")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &kycReviewRequest{}
+	// decode review from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	level := p2pModels.KYCLevelBasic
+	if approve {
+		if req.KYCLevel != nil {
+			level = *req.KYCLevel
+		}
+		if level != p2pModels.KYCLevelBasic && level != p2pModels.KYCLevelEnhanced {
+			info.APIError = cigExchange.NewInvalidFieldError("kyc_level", "KYC level must be 1 or 2")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	} else if len(req.Reason) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"reason"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = profile.Review(loggedInUser.UserUUID, approve, level, req.Reason)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifications.KYCReviewed(profile)
+
+	cigExchange.Respond(w, profile)
+}
+
+// ApproveKYCProfile handles POST kyc/profiles/{user_id}/approve endpoint
+var ApproveKYCProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	reviewKYCProfile(w, r, true)
+}
+
+// RejectKYCProfile handles POST kyc/profiles/{user_id}/reject endpoint
+var RejectKYCProfile = func(w http.ResponseWriter, r *http.Request) {
+
+	reviewKYCProfile(w, r, false)
+}
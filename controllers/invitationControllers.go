@@ -4,8 +4,9 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	models "cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/webhooks"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -119,38 +120,69 @@ var SendInvitation = func(w http.ResponseWriter, r *http.Request) {
 		IsHome:           false,
 		OrganisationRole: models.OrganisationRoleUser,
 	}
-	apiError = orgUser.Create()
+
+	// invitation accept code
+	rediskey := cigExchange.RandomUUID()
+
+	// email parameters
+	parameters := map[string]string{
+		"ACCEPT_URL":           cigExchange.GetServerURL() + "/invest/en/#accept-invitation/" + rediskey,
+		"INVITE_FIRST_NAME":    userReq.Name,
+		"INVITER_NAME":         inviter.Name + " " + inviter.LastName,
+		"INVITER_ORGANISATION": org.Name,
+	}
+	outbox, apiError := p2pModels.NewTemplateEmailOutbox(cigExchange.EmailTypeInvitation, userReq.Email, parameters)
 	if apiError != nil {
 		info.APIError = apiError
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
+	outbox.ToName = userReq.Name
+	outbox.ReferenceType = p2pModels.EmailOutboxReferenceOrganisationUser
+	// accept url contains the invitation token
+	outbox.Sensitive = true
+
+	// organisation link and invitation email are created together, email is sent by the outbox task
+	tx := cigExchange.GetDB().Begin()
+	if tx.Error != nil {
+		info.APIError = cigExchange.NewDatabaseError("Unable to start transaction", tx.Error)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	err = tx.Create(orgUser).Error
+	if err != nil {
+		tx.Rollback()
+		info.APIError = cigExchange.NewDatabaseError("Create organisation user failed", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	outbox.ReferenceID = orgUser.ID
+	apiError = outbox.Create(tx)
+	if apiError != nil {
+		tx.Rollback()
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
 
 	// save the orgUser UUID into redis for accept workflow
-	rediskey := cigExchange.RandomUUID()
 	expiration := 30 * 24 * time.Hour
 	redisCmd := cigExchange.GetRedis().Set(rediskey, orgUser.ID, expiration)
 	if redisCmd.Err() != nil {
+		tx.Rollback()
 		info.APIError = cigExchange.NewRedisError("Set invitation accept code failure", redisCmd.Err())
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
 
-	// send invitation email async
-	go func() {
-		// email parameters
-		parameters := map[string]string{
-			"ACCEPT_URL":           cigExchange.GetServerURL() + "/invest/en/#accept-invitation/" + rediskey,
-			"INVITE_FIRST_NAME":    userReq.Name,
-			"INVITER_NAME":         inviter.Name + " " + inviter.LastName,
-			"INVITER_ORGANISATION": org.Name,
-		}
-		err = cigExchange.SendEmail(cigExchange.EmailTypeInvitation, userReq.Email, parameters)
-		if err != nil {
-			fmt.Println("InviteUser: email sending error:")
-			fmt.Println(err.Error())
-		}
-	}()
+	err = tx.Commit().Error
+	if err != nil {
+		info.APIError = cigExchange.NewDatabaseError("Unable to commit transaction", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
 
 	resp := make(map[string]string, 0)
 	resp["uuid"] = invitedUser.ID
@@ -203,7 +235,56 @@ var GetInvitations = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cigExchange.Respond(w, users)
+	// query organisation links to find invitation emails
+	orgUsers, apiError := models.GetOrganisationUsersForOrganisation(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	orgUserIDs := make(map[string]string)
+	referenceIDs := make([]string, 0)
+	for _, orgUser := range orgUsers {
+		orgUserIDs[orgUser.UserID] = orgUser.ID
+		referenceIDs = append(referenceIDs, orgUser.ID)
+	}
+
+	emails, apiError := p2pModels.GetLatestEmailOutboxForReferences(p2pModels.EmailOutboxReferenceOrganisationUser, referenceIDs)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// users are converted to maps to add invitation email delivery status
+	jsonBytes, err := json.Marshal(users)
+	if err != nil {
+		info.APIError = cigExchange.NewJSONEncodingError("Unable to encode invitations", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	invitations := make([]map[string]interface{}, 0)
+	err = json.Unmarshal(jsonBytes, &invitations)
+	if err != nil {
+		info.APIError = cigExchange.NewJSONEncodingError("Unable to decode invitations", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	for _, invitation := range invitations {
+		userID, _ := invitation["id"].(string)
+		invitation["email_status"] = nil
+		invitation["email_attempts"] = 0
+		invitation["email_sent_at"] = nil
+		if email, ok := emails[orgUserIDs[userID]]; ok {
+			invitation["email_status"] = email.Status
+			invitation["email_attempts"] = email.Attempts
+			invitation["email_sent_at"] = email.SentAt
+		}
+	}
+
+	cigExchange.Respond(w, invitations)
 }
 
 // DeleteInvitation handles DELETE organisations/{organisation_id}/invitations/{user_id} endpoint
@@ -358,6 +439,8 @@ var AcceptInvitation = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	webhooks.InvitationAccepted(orgUser)
+
 	// reply with JWT token
 	tokenString, _, apiError := auth.GenerateJWTString(orgUser.UserID, orgUser.OrganisationID)
 	if apiError != nil {
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"cig-exchange-p2p-backend/i18n"
+	"net/http"
+)
+
+// localizeResponse collapses multilang fields of response maps to the negotiated language.
+// Trading endpoints honour Accept-Language header, P2P endpoints keep full translation maps
+// for the editing UI unless 'lang' query parameter is set
+func localizeResponse(w http.ResponseWriter, r *http.Request, useHeader bool, responseMaps ...map[string]interface{}) {
+
+	if useHeader {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+
+	chain := i18n.Negotiate(r, useHeader)
+	if chain == nil {
+		return
+	}
+	w.Header().Set("Content-Language", chain[0])
+
+	for _, responseMap := range responseMaps {
+		i18n.CollapseMultilangFields(responseMap, chain)
+	}
+}
@@ -0,0 +1,464 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type ledgerEntryRequest struct {
+	IdempotencyKey string            `json:"idempotency_key"`
+	Amount         p2pModels.Decimal `json:"amount"`
+	Currency       string            `json:"currency"`
+	OfferingID     string            `json:"offering_id"`
+	Description    string            `json:"description"`
+	EffectiveDate  string            `json:"effective_date"`
+}
+
+// parseLedgerDate parses YYYY-MM-DD query parameter, end of day is used for 'to' dates
+func parseLedgerDate(r *http.Request, param string, defaultValue time.Time, endOfDay bool) (time.Time, *cigExchange.APIError) {
+
+	value := r.URL.Query().Get(param)
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return defaultValue, cigExchange.NewInvalidFieldError(param, "Field '"+param+"' must be in YYYY-MM-DD format")
+	}
+	if endOfDay {
+		date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return date, nil
+}
+
+// decodeLedgerEntryRequest decodes journal entry request and prepares the entry.
+// Idempotency keys are scoped to the investor, so keys of different investors never collide
+func decodeLedgerEntryRequest(r *http.Request, userID, createdBy string) (*ledgerEntryRequest, *p2pModels.JournalEntry, *cigExchange.APIError) {
+
+	req := &ledgerEntryRequest{}
+	// decode journal entry from request body
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, nil, cigExchange.NewRequestDecodingError(err)
+	}
+
+	req.IdempotencyKey = strings.TrimSpace(req.IdempotencyKey)
+	if len(req.IdempotencyKey) == 0 {
+		return nil, nil, cigExchange.NewRequiredFieldError([]string{"idempotency_key"})
+	}
+
+	entry := &p2pModels.JournalEntry{
+		IdempotencyKey: userID + ":" + req.IdempotencyKey,
+		Currency:       p2pModels.NormalizeCurrency(req.Currency),
+		Description:    strings.TrimSpace(req.Description),
+		CreatedBy:      createdBy,
+	}
+	if len(req.EffectiveDate) > 0 {
+		entry.EffectiveDate, err = time.Parse("2006-01-02", req.EffectiveDate)
+		if err != nil {
+			return nil, nil, cigExchange.NewInvalidFieldError("effective_date", "Effective date must be in YYYY-MM-DD format")
+		}
+	}
+	return req, entry, nil
+}
+
+// GetUserAccounts handles GET users/{user_id}/accounts endpoint
+// 'date' query parameter returns balances as of the end of the day
+var GetUserAccounts = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserAccounts)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	asOf, apiError := parseLedgerDate(r, "date", time.Now(), true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	accounts, apiError := p2pModels.GetUserAccounts(userID, asOf)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, accounts)
+}
+
+// GetUserAccount handles GET users/{user_id}/accounts/{account_id} endpoint
+// 'date' query parameter returns balance as of the end of the day
+var GetUserAccount = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserAccount)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	accountID := mux.Vars(r)["account_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	asOf, apiError := parseLedgerDate(r, "date", time.Now(), true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	account, apiError := p2pModels.GetUserAccount(userID, accountID, asOf)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, account)
+}
+
+// GetUserAccountEntries handles GET users/{user_id}/accounts/{account_id}/entries endpoint
+// 'from' and 'to' query parameters limit the period, last 90 days are returned by default
+var GetUserAccountEntries = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserAccountEntries)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	accountID := mux.Vars(r)["account_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	to, apiError := parseLedgerDate(r, "to", time.Now(), true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	from, apiError := parseLedgerDate(r, "from", to.AddDate(0, 0, -90), false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if from.After(to) {
+		info.APIError = cigExchange.NewInvalidFieldError("from", "Period start must be before period end")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	account, apiError := p2pModels.GetUserAccount(userID, accountID, to)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	entries, apiError := p2pModels.GetAccountEntries(account, from, to)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, entries)
+}
+
+// CreateDeposit handles POST users/{user_id}/accounts/deposits endpoint
+// Only admin can record money received on the platform bank account
+var CreateDeposit = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateDeposit)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can record deposits")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// check that investor exists
+	_, apiError = models.GetUser(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req, entry, apiError := decodeLedgerEntryRequest(r, userID, loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(entry.Currency) == 0 {
+		entry.Currency = p2pModels.DefaultCurrency
+	}
+
+	apiError = p2pModels.Deposit(entry, userID, req.Amount)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, entry)
+}
+
+// CreateWithdrawal handles POST users/{user_id}/accounts/withdrawals endpoint
+var CreateWithdrawal = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateWithdrawal)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req, entry, apiError := decodeLedgerEntryRequest(r, userID, loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	// withdrawals are paid out immediately
+	entry.EffectiveDate = time.Now()
+
+	if len(entry.Currency) == 0 {
+		entry.Currency = p2pModels.DefaultCurrency
+	}
+
+	apiError = p2pModels.Withdraw(entry, userID, req.Amount)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, entry)
+}
+
+// CreateCommitment handles POST users/{user_id}/accounts/commitments endpoint
+// Investor commits cash account money to a listed offering, offering currency is used if currency is not set
+var CreateCommitment = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateCommitment)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req, entry, apiError := decodeLedgerEntryRequest(r, userID, loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	entry.EffectiveDate = time.Now()
+
+	if len(req.OfferingID) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"offering_id"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(req.OfferingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(entry.Currency) == 0 {
+		money, apiError := p2pModels.GetOfferingMoney(offering)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		entry.Currency = money.Currency
+	}
+
+	apiError = p2pModels.Commit(entry, userID, offering, req.Amount)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, entry)
+}
+
+// GetOfferingAccounts handles GET organisations/{organisation_id}/offerings/{offering_id}/accounts endpoint
+// 'date' query parameter returns balances as of the end of the day
+var GetOfferingAccounts = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingAccounts)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := getOrganisationOffering(loggedInUser, organisationID, offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	asOf, apiError := parseLedgerDate(r, "date", time.Now(), true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	accounts, apiError := p2pModels.GetOfferingAccounts(offeringID, asOf)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, accounts)
+}
@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type loanTermsRequest struct {
+	InterestRate     *p2pModels.Decimal `json:"interest_rate"`
+	DayCount         *string            `json:"day_count"`
+	TenorMonths      *int               `json:"tenor_months"`
+	RepaymentType    *string            `json:"repayment_type"`
+	PaymentFrequency *string            `json:"payment_frequency"`
+	StartDate        *string            `json:"start_date"`
+}
+
+// generateOfferingSchedule generates offering schedule for 'amount' and 'start_date' query parameters.
+// Offering amount is used if amount is not set, investment amounts must respect offering investment limits
+func generateOfferingSchedule(r *http.Request, offering *models.Offering) (*p2pModels.Schedule, *cigExchange.APIError) {
+
+	terms, apiError := p2pModels.GetLoanTerms(offering)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	var principal p2pModels.Decimal
+	if amountParam := r.URL.Query().Get("amount"); len(amountParam) > 0 {
+		amount, err := p2pModels.ParseDecimal(amountParam)
+		if err != nil {
+			return nil, cigExchange.NewInvalidFieldError("amount", "Amount must be a number")
+		}
+		if money.MinimumInvestment != nil && amount.Cmp(*money.MinimumInvestment) < 0 {
+			return nil, cigExchange.NewInvalidFieldError("amount", "Amount is below minimum investment of "+money.MinimumInvestment.String()+" "+money.Currency)
+		}
+		if money.MaximumInvestment != nil && !money.MaximumInvestment.IsZero() && amount.Cmp(*money.MaximumInvestment) > 0 {
+			return nil, cigExchange.NewInvalidFieldError("amount", "Amount is above maximum investment of "+money.MaximumInvestment.String()+" "+money.Currency)
+		}
+		principal = amount
+	} else if money.Amount != nil {
+		principal = *money.Amount
+	}
+
+	// schedule starts at the loan start, offering closing date or today
+	startDate := time.Now()
+	if terms.StartDate != nil {
+		startDate = *terms.StartDate
+	} else if offering.ClosingDate != nil {
+		startDate = *offering.ClosingDate
+	}
+	if startDateParam := r.URL.Query().Get("start_date"); len(startDateParam) > 0 {
+		parsed, err := time.Parse("2006-01-02", startDateParam)
+		if err != nil {
+			return nil, cigExchange.NewInvalidFieldError("start_date", "Start date must be in YYYY-MM-DD format")
+		}
+		startDate = parsed
+	}
+
+	return terms.GenerateSchedule(principal, money.Currency, startDate)
+}
+
+// GetLoanTerms handles GET organisations/{organisation_id}/offerings/{offering_id}/terms endpoint
+var GetLoanTerms = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetLoanTerms)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	terms, apiError := p2pModels.GetLoanTerms(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, terms)
+}
+
+// UpdateLoanTerms handles PUT organisations/{organisation_id}/offerings/{offering_id}/terms endpoint
+var UpdateLoanTerms = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateLoanTerms)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	terms, apiError := p2pModels.GetLoanTerms(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &loanTermsRequest{}
+	// decode loan terms from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if req.InterestRate != nil {
+		terms.InterestRate = *req.InterestRate
+	}
+	if req.DayCount != nil {
+		terms.DayCount = *req.DayCount
+	}
+	if req.TenorMonths != nil {
+		terms.TenorMonths = *req.TenorMonths
+	}
+	if req.RepaymentType != nil {
+		terms.RepaymentType = *req.RepaymentType
+	}
+	if req.PaymentFrequency != nil {
+		terms.PaymentFrequency = *req.PaymentFrequency
+	}
+	if req.StartDate != nil {
+		if len(*req.StartDate) == 0 {
+			terms.StartDate = nil
+		} else {
+			startDate, err := time.Parse("2006-01-02", *req.StartDate)
+			if err != nil {
+				info.APIError = cigExchange.NewInvalidFieldError("start_date", "Start date must be in YYYY-MM-DD format")
+				cigExchange.RespondWithAPIError(w, info.APIError)
+				return
+			}
+			terms.StartDate = &startDate
+		}
+	}
+
+	apiError = terms.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, terms)
+}
+
+// GetOrganisationOfferingSchedule handles GET organisations/{organisation_id}/offerings/{offering_id}/schedule endpoint
+var GetOrganisationOfferingSchedule = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingSchedule)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	schedule, apiError := generateOfferingSchedule(r, offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, schedule)
+}
+
+// GetOfferingSchedule handles GET offerings/{offering_id}/schedule endpoint
+// This call doesn't require JWT, 'amount' query parameter previews cash flows of an investment
+var GetOfferingSchedule = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingSchedule)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	offeringID := mux.Vars(r)["offering_id"]
+
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// terms of restricted offerings are available only to eligible investors
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	schedule, apiError := generateOfferingSchedule(r, offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, schedule)
+}
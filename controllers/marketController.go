@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type marketOrderRequest struct {
+	OfferingID string            `json:"offering_id"`
+	Principal  p2pModels.Decimal `json:"principal"`
+	Price      p2pModels.Decimal `json:"price"`
+}
+
+type marketOrderResponse struct {
+	Order  interface{}        `json:"order"`
+	Trades []*p2pModels.Trade `json:"trades"`
+}
+
+// decodeMarketOrderRequest decodes market order and loads funded offering with its money
+func decodeMarketOrderRequest(r *http.Request) (*marketOrderRequest, *models.Offering, *p2pModels.OfferingMoney, *cigExchange.APIError) {
+
+	req := &marketOrderRequest{}
+	// decode market order from request body
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, nil, nil, cigExchange.NewRequestDecodingError(err)
+	}
+
+	if len(req.OfferingID) == 0 {
+		return nil, nil, nil, cigExchange.NewRequiredFieldError([]string{"offering_id"})
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(req.OfferingID)
+	if apiError != nil {
+		return nil, nil, nil, apiError
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		return nil, nil, nil, apiError
+	}
+	if !money.IsFunded() {
+		return nil, nil, nil, cigExchange.NewInvalidFieldError("offering_id", "Only funded offerings are traded on the secondary market")
+	}
+	return req, offering, money, nil
+}
+
+// notifyTrades notifies sellers and buyers about settled trades
+func notifyTrades(trades []*p2pModels.Trade, offering *models.Offering) {
+
+	for _, trade := range trades {
+		notifications.TradeSettled(trade, offering)
+	}
+}
+
+// GetUserListings handles GET users/{user_id}/market/listings endpoint
+var GetUserListings = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserListings)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	listings, apiError := p2pModels.GetUserListings(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, listings)
+}
+
+// CreateListing handles POST users/{user_id}/market/listings endpoint
+// Listing is matched against open bids immediately, settled trades are returned with the listing
+var CreateListing = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateListing)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req, offering, money, apiError := decodeMarketOrderRequest(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	listing := &p2pModels.Listing{
+		OfferingID: offering.ID,
+		SellerID:   userID,
+		Currency:   money.Currency,
+		Principal:  req.Principal,
+		Price:      req.Price,
+	}
+
+	trades, apiError := listing.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifyTrades(trades, offering)
+
+	cigExchange.Respond(w, &marketOrderResponse{Order: listing, Trades: trades})
+}
+
+// CancelListing handles DELETE users/{user_id}/market/listings/{listing_id} endpoint
+var CancelListing = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCancelListing)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	listingID := mux.Vars(r)["listing_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	listing, apiError := p2pModels.GetUserListing(userID, listingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = listing.Cancel()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetUserBids handles GET users/{user_id}/market/bids endpoint
+var GetUserBids = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserBids)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	bids, apiError := p2pModels.GetUserBids(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, bids)
+}
+
+// CreateBid handles POST users/{user_id}/market/bids endpoint
+// Bid is matched against open listings immediately, settled trades are returned with the bid
+var CreateBid = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateBid)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req, offering, money, apiError := decodeMarketOrderRequest(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// buyers must be eligible for the offering like primary investors
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	bid := &p2pModels.Bid{
+		OfferingID: offering.ID,
+		BuyerID:    userID,
+		Currency:   money.Currency,
+		Principal:  req.Principal,
+		Price:      req.Price,
+	}
+
+	trades, apiError := bid.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifyTrades(trades, offering)
+
+	cigExchange.Respond(w, &marketOrderResponse{Order: bid, Trades: trades})
+}
+
+// CancelBid handles DELETE users/{user_id}/market/bids/{bid_id} endpoint
+var CancelBid = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCancelBid)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	bidID := mux.Vars(r)["bid_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	bid, apiError := p2pModels.GetUserBid(userID, bidID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = bid.Cancel()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetUserTrades handles GET users/{user_id}/market/trades endpoint
+var GetUserTrades = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserTrades)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	trades, apiError := p2pModels.GetUserTrades(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, trades)
+}
+
+// getTradingMarketOffering loads listed offering for trading market endpoints
+func getTradingMarketOffering(info *cigExchange.ActivityInformation, r *http.Request) (*models.Offering, *p2pModels.OfferingMoney, *cigExchange.APIError) {
+
+	offeringID := mux.Vars(r)["offering_id"]
+
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		return nil, nil, apiError
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		return nil, nil, apiError
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		return nil, nil, cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		return nil, nil, apiError
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		return nil, nil, apiError
+	}
+	return offering, money, nil
+}
+
+// GetOfferingMarket handles GET offerings/{offering_id}/market endpoint
+// This call doesn't require JWT, open orders are aggregated by price without order owners
+var GetOfferingMarket = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingMarket)
+	defer cigExchange.PrintAPIError(info)
+
+	offering, money, apiError := getTradingMarketOffering(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	book, apiError := p2pModels.GetOrderBook(offering.ID, money.Currency)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, book)
+}
+
+// GetOfferingPriceHistory handles GET offerings/{offering_id}/price-history endpoint
+// This call doesn't require JWT
+var GetOfferingPriceHistory = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingPriceHistory)
+	defer cigExchange.PrintAPIError(info)
+
+	offering, _, apiError := getTradingMarketOffering(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	history, apiError := p2pModels.GetPriceHistory(offering.ID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, history)
+}
@@ -4,6 +4,7 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/webhooks"
 	"encoding/json"
 	"io/ioutil"
 	"mime"
@@ -140,6 +141,8 @@ var UploadMedia = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	webhooks.MediaUploaded(organisationID, offeringID, media)
+
 	cigExchange.Respond(w, media)
 }
 
@@ -154,8 +157,23 @@ var GetOfferingMedia = func(w http.ResponseWriter, r *http.Request) {
 	// get request params
 	offeringID := mux.Vars(r)["offering_id"]
 
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// query offering from db
-	_, apiError := models.GetOffering(offeringID)
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// documents of restricted offerings are available only to eligible investors
+	apiError = checkOfferingEligibility(offering, profile)
 	if apiError != nil {
 		info.APIError = apiError
 		cigExchange.RespondWithAPIError(w, info.APIError)
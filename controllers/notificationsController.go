@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// default and maximum page size for notifications list
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 200
+)
+
+// GetNotifications handles GET users/{user_id}/notifications endpoint
+// supports 'unread', 'limit' and 'offset' query parameters
+var GetNotifications = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetNotifications)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// parse query parameters
+	query := r.URL.Query()
+	unreadOnly := query.Get("unread") == "true"
+
+	limit := defaultNotificationsLimit
+	if len(query.Get("limit")) > 0 {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 || limit > maxNotificationsLimit {
+			info.APIError = cigExchange.NewInvalidFieldError("limit", "Limit must be between 1 and "+strconv.Itoa(maxNotificationsLimit))
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	offset := 0
+	if len(query.Get("offset")) > 0 {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			info.APIError = cigExchange.NewInvalidFieldError("offset", "Offset is invalid")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	notifications, apiError := p2pModels.GetNotifications(userID, unreadOnly, limit, offset)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, notifications)
+}
+
+// GetNotificationsUnreadCount handles GET users/{user_id}/notifications/unread-count endpoint
+var GetNotificationsUnreadCount = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetNotificationsUnreadCount)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	count, apiError := p2pModels.GetUnreadNotificationsCount(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp := make(map[string]int64, 0)
+	resp["count"] = count
+
+	cigExchange.Respond(w, resp)
+}
+
+// MarkNotificationRead handles POST users/{user_id}/notifications/{notification_id}/read endpoint
+var MarkNotificationRead = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeMarkNotificationRead)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	notificationID := mux.Vars(r)["notification_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// check notification id
+	if len(notificationID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("notification_id", "NotificationID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError := p2pModels.MarkNotificationRead(userID, notificationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// MarkAllNotificationsRead handles POST users/{user_id}/notifications/read endpoint
+var MarkAllNotificationsRead = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeMarkAllNotificationsRead)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError := p2pModels.MarkAllNotificationsRead(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetNotificationPreferences handles GET users/{user_id}/notifications/preferences endpoint
+var GetNotificationPreferences = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetNotificationPreferences)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	preferences, apiError := p2pModels.GetNotificationPreferences(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, preferences)
+}
+
+// UpdateNotificationPreferences handles PATCH users/{user_id}/notifications/preferences endpoint
+var UpdateNotificationPreferences = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateNotificationPreferences)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	preferences := make([]*p2pModels.NotificationPreference, 0)
+	// decode preferences from request body
+	err = json.NewDecoder(r.Body).Decode(&preferences)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// validate all preferences before saving any
+	for _, preference := range preferences {
+		if !p2pModels.IsValidNotificationType(preference.Type) {
+			info.APIError = cigExchange.NewInvalidFieldError("type", "Unknown notification type: "+preference.Type)
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	for _, preference := range preferences {
+		preference.UserID = userID
+		apiError := preference.Save()
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	// return all preferences
+	updatedPreferences, apiError := p2pModels.GetNotificationPreferences(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, updatedPreferences)
+}
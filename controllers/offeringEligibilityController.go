@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// offeringTeaserFields lists offering fields shown when investor eligibility can't be confirmed
+var offeringTeaserFields = []string{
+	"id",
+	"title",
+	"title_map",
+	"type",
+	"location",
+	"location_map",
+	"tagline1",
+	"tagline1_map",
+	"organisation_id",
+	"organisation",
+	"organisation_website",
+	"created_at",
+}
+
+type offeringEligibilityRequest struct {
+	InvestorCategories *[]string `json:"investor_categories"`
+	AllowedCountries   *[]string `json:"allowed_countries"`
+	BlockedCountries   *[]string `json:"blocked_countries"`
+	MinKYCLevel        *int      `json:"min_kyc_level"`
+}
+
+// normalizeCountryCodes validates and upper cases country list
+func normalizeCountryCodes(field string, codes []string) (pq.StringArray, *cigExchange.APIError) {
+
+	normalized := pq.StringArray{}
+	for _, code := range codes {
+		code = p2pModels.NormalizeCountryCode(code)
+		if !p2pModels.IsValidCountryCode(code) {
+			return normalized, cigExchange.NewInvalidFieldError(field, "Countries must be ISO 3166-1 alpha-2 country codes")
+		}
+		normalized = append(normalized, code)
+	}
+	return normalized, nil
+}
+
+// getTradingInvestorProfile returns investor profile of logged in user or nil for anonymous requests
+func getTradingInvestorProfile(info *cigExchange.ActivityInformation, r *http.Request) (*p2pModels.InvestorProfile, *cigExchange.APIError) {
+
+	loggedInUser, err := auth.GetContextValues(r)
+	// ignore error for anonymous users
+	if err != nil {
+		return nil, nil
+	}
+	info.LoggedInUser = loggedInUser
+	return p2pModels.GetInvestorProfile(loggedInUser.UserUUID)
+}
+
+// prepareTradingOffering converts offering for the trading platform according to the eligibility rules.
+// Offerings not available in investor residence country are not shown at all, anonymous users and
+// not eligible investors only get teaser fields of restricted offerings
+func prepareTradingOffering(offering *models.Offering, eligibility *p2pModels.OfferingEligibility, profile *p2pModels.InvestorProfile) (map[string]interface{}, bool, *cigExchange.APIError) {
+
+	reasons := make([]string, 0)
+	if profile != nil {
+		reasons = eligibility.Evaluate(profile)
+		for _, reason := range reasons {
+			if reason == p2pModels.EligibilityReasonCountry {
+				return nil, false, nil
+			}
+		}
+	}
+
+	// add multilang fields
+	offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(offering)
+	if apiError != nil {
+		return nil, false, apiError
+	}
+	offeringMMap["organisation"] = offering.Organisation.Name
+	offeringMMap["organisation_website"] = offering.Organisation.Website
+
+	restricted := eligibility.IsRestricted()
+	if restricted && (profile == nil || len(reasons) > 0) {
+		teaserMap := make(map[string]interface{})
+		for _, field := range offeringTeaserFields {
+			if value, ok := offeringMMap[field]; ok {
+				teaserMap[field] = value
+			}
+		}
+		offeringMMap = teaserMap
+	}
+
+	offeringMMap["is_restricted"] = restricted
+	if profile != nil {
+		offeringMMap["is_eligible"] = len(reasons) == 0
+		offeringMMap["eligibility_reasons"] = reasons
+	}
+	return offeringMMap, true, nil
+}
+
+// checkOfferingEligibility allows access to restricted offering details only to eligible investors
+func checkOfferingEligibility(offering *models.Offering, profile *p2pModels.InvestorProfile) *cigExchange.APIError {
+
+	eligibility, apiError := p2pModels.GetOfferingEligibility(offering.ID)
+	if apiError != nil {
+		return apiError
+	}
+	if !eligibility.IsRestricted() {
+		return nil
+	}
+	if profile == nil {
+		return cigExchange.NewAccessRightsError("Offering is available only to eligible investors")
+	}
+	reasons := eligibility.Evaluate(profile)
+	if len(reasons) > 0 {
+		return cigExchange.NewAccessRightsError("Investor is not eligible for the offering: " + strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// GetTradingOffering handles GET offerings/{offering_id} endpoint
+// This call doesn't require JWT, restricted offerings are evaluated against the logged in investor
+var GetTradingOffering = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetTradingOffering)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	offeringID := mux.Vars(r)["offering_id"]
+
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	eligibility, apiError := p2pModels.GetOfferingEligibility(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offeringMMap, ok, apiError := prepareTradingOffering(offering, eligibility, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// don't reveal offerings not available in investor country
+	if !ok {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = formatter.apply(offeringMMap, money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if profile != nil {
+		watched, apiError := p2pModels.GetWatchedOfferingIDs(profile.UserID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		offeringMMap["is_watched"] = watched[offering.ID]
+	}
+
+	localizeResponse(w, r, true, offeringMMap)
+
+	cigExchange.Respond(w, offeringMMap)
+}
+
+// GetOfferingEligibility handles GET organisations/{organisation_id}/offerings/{offering_id}/eligibility endpoint
+var GetOfferingEligibility = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingEligibility)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	eligibility, apiError := p2pModels.GetOfferingEligibility(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, eligibility)
+}
+
+// UpdateOfferingEligibility handles PUT organisations/{organisation_id}/offerings/{offering_id}/eligibility endpoint
+var UpdateOfferingEligibility = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateOfferingEligibility)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if organisationID != loggedInUser.OrganisationUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	eligibility, apiError := p2pModels.GetOfferingEligibility(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &offeringEligibilityRequest{}
+	// decode eligibility rules from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if req.InvestorCategories != nil {
+		categories := pq.StringArray{}
+		for _, category := range *req.InvestorCategories {
+			if !p2pModels.IsValidInvestorClassification(category) {
+				info.APIError = cigExchange.NewInvalidFieldError("investor_categories", "Investor categories must be one of: "+strings.Join(p2pModels.InvestorClassifications, ", "))
+				cigExchange.RespondWithAPIError(w, info.APIError)
+				return
+			}
+			categories = append(categories, category)
+		}
+		eligibility.InvestorCategories = categories
+	}
+	if req.AllowedCountries != nil {
+		eligibility.AllowedCountries, apiError = normalizeCountryCodes("allowed_countries", *req.AllowedCountries)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+	if req.BlockedCountries != nil {
+		eligibility.BlockedCountries, apiError = normalizeCountryCodes("blocked_countries", *req.BlockedCountries)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+	if req.MinKYCLevel != nil {
+		if *req.MinKYCLevel < p2pModels.KYCLevelNone || *req.MinKYCLevel > p2pModels.KYCLevelEnhanced {
+			info.APIError = cigExchange.NewInvalidFieldError("min_kyc_level", "Minimum KYC level must be between 0 and 2")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		eligibility.MinKYCLevel = *req.MinKYCLevel
+	}
+
+	apiError = eligibility.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, eligibility)
+}
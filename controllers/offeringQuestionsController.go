@@ -0,0 +1,649 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxQuestionLength limits question and reply text
+const maxQuestionLength = 2000
+
+type askQuestionRequest struct {
+	OfferingID string `json:"offering_id"`
+	Question   string `json:"question"`
+	IsPublic   bool   `json:"is_public"`
+}
+
+type questionReplyRequest struct {
+	Message string `json:"message"`
+}
+
+type moderateQuestionRequest struct {
+	IsPublic *bool   `json:"is_public"`
+	Status   *string `json:"status"`
+}
+
+// publicQuestionReply hides user ids of thread participants on the trading platform
+type publicQuestionReply struct {
+	Message        string    `json:"message"`
+	IsOrganisation bool      `json:"is_organisation"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type publicQuestion struct {
+	ID         string                 `json:"id"`
+	Question   string                 `json:"question"`
+	AnsweredAt *time.Time             `json:"answered_at"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Replies    []*publicQuestionReply `json:"replies"`
+}
+
+// validateQuestionText trims the text and checks its length
+func validateQuestionText(field, text string) (string, *cigExchange.APIError) {
+
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return text, cigExchange.NewInvalidFieldError(field, "Text can't be empty")
+	}
+	if len([]rune(text)) > maxQuestionLength {
+		return text, cigExchange.NewInvalidFieldError(field, "Text can't be longer than "+strconv.Itoa(maxQuestionLength)+" characters")
+	}
+	return text, nil
+}
+
+// getOrganisationOfferingQuestion loads question and checks that it belongs to the organisation offering
+func getOrganisationOfferingQuestion(organisationID, offeringID, questionID string) (*p2pModels.OfferingQuestion, *cigExchange.APIError) {
+
+	question, apiError := p2pModels.GetOfferingQuestion(questionID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if question.OfferingID != offeringID || question.OrganisationID != organisationID {
+		return nil, cigExchange.NewAccessRightsError("Question doesn't belong to the offering")
+	}
+	return question, nil
+}
+
+// AskOfferingQuestion handles POST users/{user_id}/questions endpoint
+var AskOfferingQuestion = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeAskOfferingQuestion)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &askQuestionRequest{}
+	// decode question from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(req.OfferingID) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"offering_id"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	text, apiError := validateQuestionText("question", req.Question)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// questions can be asked only about listed offerings
+	offering, apiError := models.GetOffering(req.OfferingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question := &p2pModels.OfferingQuestion{
+		OfferingID:     offering.ID,
+		OrganisationID: offering.OrganisationID,
+		UserID:         userID,
+		Question:       text,
+		IsPublic:       req.IsPublic,
+	}
+	apiError = question.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifications.QuestionAsked(question, offering, false)
+
+	cigExchange.Respond(w, question)
+}
+
+// GetUserOfferingQuestions handles GET users/{user_id}/questions endpoint
+var GetUserOfferingQuestions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserOfferingQuestions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	questions, apiError := p2pModels.GetUserOfferingQuestions(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, questions)
+}
+
+// ReplyToUserOfferingQuestion handles POST users/{user_id}/questions/{question_id}/replies endpoint
+var ReplyToUserOfferingQuestion = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeReplyOfferingQuestion)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	questionID := mux.Vars(r)["question_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question, apiError := p2pModels.GetOfferingQuestion(questionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if question.UserID != userID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the question")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &questionReplyRequest{}
+	// decode reply from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	text, apiError := validateQuestionText("message", req.Message)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	reply := &p2pModels.OfferingQuestionReply{
+		UserID:         userID,
+		IsOrganisation: false,
+		Message:        text,
+	}
+	apiError = reply.Create(question)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offering, apiError := models.GetOffering(question.OfferingID)
+	if apiError == nil {
+		notifications.QuestionAsked(question, offering, true)
+	}
+
+	cigExchange.Respond(w, question)
+}
+
+// GetOrganisationOfferingQuestions handles GET organisations/{organisation_id}/offerings/{offering_id}/questions endpoint
+var GetOrganisationOfferingQuestions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOfferingQuestions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db first to validate the permissions
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if offering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("Offering doesn't belong to organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	questions, apiError := p2pModels.GetOfferingQuestions(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, questions)
+}
+
+// AnswerOfferingQuestion handles POST organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}/replies endpoint
+var AnswerOfferingQuestion = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeAnswerOfferingQuestion)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	questionID := mux.Vars(r)["question_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question, apiError := getOrganisationOfferingQuestion(organisationID, offeringID, questionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &questionReplyRequest{}
+	// decode reply from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	text, apiError := validateQuestionText("message", req.Message)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	reply := &p2pModels.OfferingQuestionReply{
+		UserID:         loggedInUser.UserUUID,
+		IsOrganisation: true,
+		Message:        text,
+	}
+	apiError = reply.Create(question)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offering, apiError := models.GetOffering(question.OfferingID)
+	if apiError == nil {
+		notifications.QuestionAnswered(question, offering)
+	}
+
+	cigExchange.Respond(w, question)
+}
+
+// ModerateOfferingQuestion handles PATCH organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id} endpoint
+// org admins can change question visibility and hide it from the trading platform
+var ModerateOfferingQuestion = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeModerateOfferingQuestion)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	questionID := mux.Vars(r)["question_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question, apiError := getOrganisationOfferingQuestion(organisationID, offeringID, questionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &moderateQuestionRequest{}
+	// decode moderation fields from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if req.IsPublic != nil {
+		question.IsPublic = *req.IsPublic
+	}
+	if req.Status != nil {
+		if *req.Status != p2pModels.OfferingQuestionStatusVisible && *req.Status != p2pModels.OfferingQuestionStatusHidden {
+			info.APIError = cigExchange.NewInvalidFieldError("status", "Status must be 'visible' or 'hidden'")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		question.Status = *req.Status
+	}
+
+	apiError = question.Update()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, question)
+}
+
+// DeleteOfferingQuestion handles DELETE organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id} endpoint
+var DeleteOfferingQuestion = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteOfferingQuestion)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	questionID := mux.Vars(r)["question_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question, apiError := getOrganisationOfferingQuestion(organisationID, offeringID, questionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = question.Delete()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// DeleteOfferingQuestionReply handles DELETE organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}/replies/{reply_id} endpoint
+var DeleteOfferingQuestionReply = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteOfferingQuestionReply)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+	questionID := mux.Vars(r)["question_id"]
+	replyID := mux.Vars(r)["reply_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	question, apiError := getOrganisationOfferingQuestion(organisationID, offeringID, questionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = question.DeleteReply(replyID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetPublicOfferingQuestions handles GET offerings/{offering_id}/questions endpoint (no JWT)
+// returns answered public questions
+var GetPublicOfferingQuestions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPublicOfferingQuestions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	offeringID := mux.Vars(r)["offering_id"]
+
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	questions, apiError := p2pModels.GetPublicOfferingQuestions(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	publicQuestions := make([]*publicQuestion, 0, len(questions))
+	for _, question := range questions {
+		pq := &publicQuestion{
+			ID:         question.ID,
+			Question:   question.Question,
+			AnsweredAt: question.AnsweredAt,
+			CreatedAt:  question.CreatedAt,
+			Replies:    make([]*publicQuestionReply, 0, len(question.Replies)),
+		}
+		for _, reply := range question.Replies {
+			pq.Replies = append(pq.Replies, &publicQuestionReply{
+				Message:        reply.Message,
+				IsOrganisation: reply.IsOrganisation,
+				CreatedAt:      reply.CreatedAt,
+			})
+		}
+		publicQuestions = append(publicQuestions, pq)
+	}
+
+	cigExchange.Respond(w, publicQuestions)
+}
@@ -4,7 +4,11 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	models "cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"cig-exchange-p2p-backend/webhooks"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -37,6 +41,13 @@ var GetOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// query offering from db
 	offering, apiError := models.GetOffering(offeringID)
 	if apiError != nil {
@@ -52,6 +63,13 @@ var GetOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// add multilang fields
 	offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(offering)
 	if apiError != nil {
@@ -60,6 +78,15 @@ var GetOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiError = formatter.apply(offeringMMap, money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	localizeResponse(w, r, false, offeringMMap)
+
 	cigExchange.Respond(w, offeringMMap)
 }
 
@@ -92,8 +119,10 @@ var CreateOffering = func(w http.ResponseWriter, r *http.Request) {
 	offering := &models.Offering{}
 
 	offeringMap := make(map[string]interface{})
-	// decode map[string]interface from request body
-	err = json.NewDecoder(r.Body).Decode(&offeringMap)
+	// decode map[string]interface from request body, numbers are kept exact for money fields
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&offeringMap)
 	if err != nil {
 		info.APIError = cigExchange.NewRequestDecodingError(err)
 		cigExchange.RespondWithAPIError(w, info.APIError)
@@ -127,8 +156,30 @@ var CreateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// insert offering into db
-	apiError := offering.Create()
+	apiError := checkOfferingPublication(organisationID, nil, filteredOfferingMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	money := &p2pModels.OfferingMoney{Currency: p2pModels.DefaultCurrency}
+	apiError = applyOfferingMoneyRequest(offeringMap, money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// insert offering and its money into db
+	apiError = p2pModels.CreateOfferingWithMoney(offering, money)
 	if apiError != nil {
 		info.APIError = apiError
 		cigExchange.RespondWithAPIError(w, info.APIError)
@@ -143,6 +194,17 @@ var CreateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifications.OfferingChanged(p2pModels.NotificationTypeOfferingCreated, createdOffering, loggedInUser.UserUUID)
+	webhooks.OfferingChanged(p2pModels.WebhookEventOfferingCreated, createdOffering)
+
+	// saved search digests pick offerings by the time they were first listed
+	if p2pModels.IsOfferingListed(createdOffering) {
+		apiError = p2pModels.RecordOfferingsVisible([]string{createdOffering.ID})
+		if apiError != nil {
+			fmt.Println("RecordOfferingsVisible: " + apiError.ToString())
+		}
+	}
+
 	// add multilang fields
 	offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(createdOffering)
 	if apiError != nil {
@@ -151,6 +213,15 @@ var CreateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiError = formatter.apply(offeringMMap, money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	localizeResponse(w, r, false, offeringMMap)
+
 	cigExchange.Respond(w, offeringMMap)
 }
 
@@ -184,8 +255,10 @@ var UpdateOffering = func(w http.ResponseWriter, r *http.Request) {
 	}
 
 	offeringMap := make(map[string]interface{})
-	// decode map[string]interface from request body
-	err = json.NewDecoder(r.Body).Decode(&offeringMap)
+	// decode map[string]interface from request body, numbers are kept exact for money fields
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&offeringMap)
 	if err != nil {
 		info.APIError = cigExchange.NewRequestDecodingError(err)
 		cigExchange.RespondWithAPIError(w, info.APIError)
@@ -238,12 +311,45 @@ var UpdateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiError = checkOfferingPublication(organisationID, existingOffering, filteredOfferingMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// amounts taken are maintained by the ledger once investors commit through it
+	_, takenChanged := offeringMap["amount_already_taken"]
+	_, remainingChanged := offeringMap["remaining"]
+	if takenChanged || remainingChanged {
+		hasCommitments, apiError := p2pModels.HasCommitments(offeringID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		if hasCommitments {
+			info.APIError = cigExchange.NewInvalidFieldError("amount_already_taken", "Amount already taken is maintained by investor commitments")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// set the offering UUID
 	offering.ID = offeringID
 	filteredOfferingMap["id"] = offeringID
 
-	// update offering
-	apiError = offering.Update(filteredOfferingMap)
+	// update offering and its money, money fields of the request are applied to the locked offering money
+	money, apiError := p2pModels.UpdateOfferingWithMoney(offering, filteredOfferingMap, func(money *p2pModels.OfferingMoney) *cigExchange.APIError {
+		return applyOfferingMoneyRequest(offeringMap, money)
+	})
 	if apiError != nil {
 		info.APIError = apiError
 		cigExchange.RespondWithAPIError(w, info.APIError)
@@ -258,6 +364,17 @@ var UpdateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifications.OfferingChanged(p2pModels.NotificationTypeOfferingUpdated, existingOffering, loggedInUser.UserUUID)
+	webhooks.OfferingChanged(p2pModels.WebhookEventOfferingUpdated, existingOffering)
+
+	// saved search digests pick offerings by the time they were first listed
+	if p2pModels.IsOfferingListed(existingOffering) {
+		apiError = p2pModels.RecordOfferingsVisible([]string{existingOffering.ID})
+		if apiError != nil {
+			fmt.Println("RecordOfferingsVisible: " + apiError.ToString())
+		}
+	}
+
 	// add multilang fields
 	offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(existingOffering)
 	if apiError != nil {
@@ -266,6 +383,15 @@ var UpdateOffering = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiError = formatter.apply(offeringMMap, money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	localizeResponse(w, r, false, offeringMMap)
+
 	cigExchange.Respond(w, offeringMMap)
 }
 
@@ -317,6 +443,10 @@ var DeleteOffering = func(w http.ResponseWriter, r *http.Request) {
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
+
+	notifications.OfferingChanged(p2pModels.NotificationTypeOfferingDeleted, offering, loggedInUser.UserUUID)
+	webhooks.OfferingChanged(p2pModels.WebhookEventOfferingDeleted, offering)
+
 	w.WriteHeader(204)
 }
 
@@ -346,6 +476,13 @@ var GetOfferings = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// query all offerings from db
 	offerings, apiError := models.GetOrganisationOfferings(organisationID)
 	if apiError != nil {
@@ -354,6 +491,13 @@ var GetOfferings = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	moneys, apiError := p2pModels.GetOfferingsMoney(offerings)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// add multilang fields
 	offeringsAMap := make([]map[string]interface{}, 0)
 	for _, offering := range offerings {
@@ -363,9 +507,17 @@ var GetOfferings = func(w http.ResponseWriter, r *http.Request) {
 			cigExchange.RespondWithAPIError(w, info.APIError)
 			return
 		}
+		apiError = formatter.apply(offeringMMap, moneys[offering.ID])
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
 		offeringsAMap = append(offeringsAMap, offeringMMap)
 	}
 
+	localizeResponse(w, r, false, offeringsAMap...)
+
 	cigExchange.Respond(w, offeringsAMap)
 }
 
@@ -378,6 +530,26 @@ var GetAllOfferings = func(w http.ResponseWriter, r *http.Request) {
 	defer auth.CreateUserActivity(info, models.ActivityTypeAllOfferings)
 	defer cigExchange.PrintAPIError(info)
 
+	// check jwt, offerings are public and logged in users additionally get watchlist flags
+	// and eligibility evaluated against their investor profile
+	profile, apiError := getTradingInvestorProfile(info, r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	watchedOfferings := make(map[string]bool)
+	if profile != nil {
+		watched, apiError := p2pModels.GetWatchedOfferingIDs(profile.UserID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		watchedOfferings = watched
+	}
+
 	// query all offerings from db
 	offerings, apiError := models.GetOfferings()
 	if apiError != nil {
@@ -386,22 +558,69 @@ var GetAllOfferings = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	offeringIDs := make([]string, 0, len(offerings))
+	for _, offering := range offerings {
+		offeringIDs = append(offeringIDs, offering.ID)
+	}
+	eligibilities, apiError := p2pModels.GetOfferingsEligibility(offeringIDs)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	moneys, apiError := p2pModels.GetOfferingsMoney(offerings)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	markets, apiError := p2pModels.GetMarketSummaries(offeringIDs)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	formatter, apiError := newMoneyFormatter(r)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
 	// add organisation name to offerings structs
 	offeringsAMap := make([]map[string]interface{}, 0)
 	for _, offering := range offerings {
 		if offering.IsVisible && offering.Organisation.Status != models.OrganisationStatusUnverified {
-			// add multilang fields
-			offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(offering)
+			offeringMMap, ok, apiError := prepareTradingOffering(offering, eligibilities[offering.ID], profile)
 			if apiError != nil {
 				info.APIError = apiError
 				cigExchange.RespondWithAPIError(w, info.APIError)
 				return
 			}
-			offeringMMap["organisation"] = offering.Organisation.Name
-			offeringMMap["organisation_website"] = offering.Organisation.Website
+			if !ok {
+				continue
+			}
+			apiError = formatter.apply(offeringMMap, moneys[offering.ID])
+			if apiError != nil {
+				info.APIError = apiError
+				cigExchange.RespondWithAPIError(w, info.APIError)
+				return
+			}
+			if profile != nil {
+				offeringMMap["is_watched"] = watchedOfferings[offering.ID]
+			}
+			// secondary market summary of funded offerings
+			if market, ok := markets[offering.ID]; ok {
+				offeringMMap["market"] = market
+			}
 			offeringsAMap = append(offeringsAMap, offeringMMap)
 		}
 	}
 
+	localizeResponse(w, r, true, offeringsAMap...)
+
 	cigExchange.Respond(w, offeringsAMap)
 }
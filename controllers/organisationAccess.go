@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+)
+
+// checkOrganisationAdminAccess allows admin users and admins of the organisation
+func checkOrganisationAdminAccess(userID, organisationID string) *cigExchange.APIError {
+
+	// check admin
+	userRole, apiError := models.GetUserRole(userID)
+	if apiError != nil {
+		return apiError
+	}
+
+	// skip check for admin
+	if userRole == models.UserRoleAdmin {
+		return nil
+	}
+
+	// check organisation role
+	orgUserRole, apiError := models.GetOrgUserRole(userID, organisationID)
+	if apiError != nil {
+		// user don't belong to organisation
+		return apiError
+	}
+
+	if orgUserRole != models.OrganisationRoleAdmin {
+		return cigExchange.NewAccessRightsError("Only organisation admin has access")
+	}
+	return nil
+}
+
+// checkOrganisationMemberAccess allows admin users and all members of the organisation
+func checkOrganisationMemberAccess(userID, organisationID string) *cigExchange.APIError {
+
+	// check admin
+	userRole, apiError := models.GetUserRole(userID)
+	if apiError != nil {
+		return apiError
+	}
+
+	// skip check for admin
+	if userRole == models.UserRoleAdmin {
+		return nil
+	}
+
+	// check organisation role, error means user don't belong to organisation
+	_, apiError = models.GetOrgUserRole(userID, organisationID)
+	return apiError
+}
+
+// checkAdminAccess allows only admin users, message describes the denied action
+func checkAdminAccess(userID, message string) *cigExchange.APIError {
+
+	// get user role
+	userRole, apiError := models.GetUserRole(userID)
+	if apiError != nil {
+		return apiError
+	}
+
+	if userRole != models.UserRoleAdmin {
+		return cigExchange.NewAccessRightsError(message)
+	}
+	return nil
+}
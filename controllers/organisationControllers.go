@@ -4,6 +4,8 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	p2pModels "cig-exchange-p2p-backend/models"
 	"encoding/json"
 	"net/http"
 
@@ -66,6 +68,8 @@ var GetOrganisation = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	localizeResponse(w, r, false, orgMap)
+
 	cigExchange.Respond(w, orgMap)
 }
 
@@ -134,6 +138,8 @@ var GetOrganisations = func(w http.ResponseWriter, r *http.Request) {
 		orgsAMap = append(orgsAMap, orgMMap)
 	}
 
+	localizeResponse(w, r, false, orgsAMap...)
+
 	cigExchange.Respond(w, orgsAMap)
 }
 
@@ -231,6 +237,8 @@ var CreateOrganisation = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	localizeResponse(w, r, false, orgMap)
+
 	cigExchange.Respond(w, orgMap)
 }
 
@@ -351,6 +359,8 @@ var UpdateOrganisation = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	localizeResponse(w, r, false, orgMap)
+
 	cigExchange.Respond(w, orgMap)
 }
 
@@ -472,7 +482,22 @@ var GetDashboardInfo = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cigExchange.Respond(w, dashboardInfo)
+	dashboardMap, apiError := p2pModels.ModelToMap(dashboardInfo)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// offerings in different currencies can't be summed directly, totals are converted to display currency
+	apiError = addDashboardMoneyTotals(r, organisationID, dashboardMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, dashboardMap)
 }
 
 // GetDashboardUsersInfo handles GET organisations/{organisation_id}/dashboard/users endpoint
@@ -616,5 +641,92 @@ var GetDashboardOfferingsClicks = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cigExchange.Respond(w, dashboardInfo)
+	// convert clicks to maps to add archived clicks and watchers count
+	jsonBytes, err := json.Marshal(dashboardInfo)
+	if err != nil {
+		info.APIError = cigExchange.NewJSONEncodingError("Unable to encode offerings clicks", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	offeringsClicks := make([]map[string]interface{}, 0)
+	err = json.Unmarshal(jsonBytes, &offeringsClicks)
+	if err != nil {
+		info.APIError = cigExchange.NewJSONEncodingError("Unable to decode offerings clicks", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offeringsClicks, apiError = addArchivedClicks(organisationID, offeringsClicks)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offeringIDs := make([]string, 0)
+	for _, offeringClicks := range offeringsClicks {
+		if offeringID, ok := offeringClicks["offering_id"].(string); ok {
+			offeringIDs = append(offeringIDs, offeringID)
+		}
+	}
+
+	watchers, apiError := p2pModels.GetWatchersCount(offeringIDs)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	for _, offeringClicks := range offeringsClicks {
+		offeringID, _ := offeringClicks["offering_id"].(string)
+		offeringClicks["watchers"] = watchers[offeringID]
+	}
+
+	localizeResponse(w, r, false, offeringsClicks...)
+
+	cigExchange.Respond(w, offeringsClicks)
+}
+
+// addArchivedClicks adds rolled up clicks of purged activities to raw offering clicks,
+// offerings with archived clicks only are appended
+func addArchivedClicks(organisationID string, offeringsClicks []map[string]interface{}) ([]map[string]interface{}, *cigExchange.APIError) {
+
+	offerings, apiError := models.GetOrganisationOfferings(organisationID)
+	if apiError != nil {
+		return offeringsClicks, apiError
+	}
+	offeringIDs := make([]string, 0, len(offerings))
+	for _, offering := range offerings {
+		offeringIDs = append(offeringIDs, offering.ID)
+	}
+
+	archived, apiError := p2pModels.GetOfferingsArchivedClicks(offeringIDs)
+	if apiError != nil {
+		return offeringsClicks, apiError
+	}
+
+	for _, offeringClicks := range offeringsClicks {
+		offeringID, _ := offeringClicks["offering_id"].(string)
+		count, ok := archived[offeringID]
+		if !ok {
+			continue
+		}
+		rawCount, _ := offeringClicks["count"].(float64)
+		offeringClicks["count"] = int64(rawCount) + count
+		delete(archived, offeringID)
+	}
+
+	for _, offering := range offerings {
+		count, ok := archived[offering.ID]
+		if !ok {
+			continue
+		}
+		offeringsClicks = append(offeringsClicks, map[string]interface{}{
+			"offering_id": offering.ID,
+			"title":       i18n.TranslateJSON(offering.Title.RawMessage, i18n.FallbackChain()),
+			"title_map":   offering.Title.RawMessage,
+			"count":       count,
+		})
+	}
+	return offeringsClicks, nil
 }
@@ -4,6 +4,8 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/notifications"
+	"cig-exchange-p2p-backend/webhooks"
 	"encoding/json"
 	"net/http"
 
@@ -139,6 +141,12 @@ var DeleteOrganisationUser = func(w http.ResponseWriter, r *http.Request) {
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
+
+	if orgUserDelete.UserID != loggedInUser.UserUUID {
+		notifications.OrganisationUserRemoved(orgUserDelete.UserID, organisationID)
+	}
+	webhooks.MemberLeft(orgUserDelete)
+
 	w.WriteHeader(204)
 }
 
@@ -207,6 +215,9 @@ var AddOrganisationUser = func(w http.ResponseWriter, r *http.Request) {
 		cigExchange.RespondWithAPIError(w, info.APIError)
 		return
 	}
+
+	webhooks.MemberJoined(&orgUser)
+
 	w.WriteHeader(204)
 }
 
@@ -318,5 +329,7 @@ var ChangeOrganisationUser = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifications.OrganisationRoleChanged(targetOrgUser.UserID, organisationID, targetOrgUser.OrganisationRole)
+
 	w.WriteHeader(204)
 }
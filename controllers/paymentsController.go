@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-p2p-backend/iso20022"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type paymentResolutionRequest struct {
+	Resolution string `json:"resolution"`
+	UserID     string `json:"user_id"`
+	Note       string `json:"note"`
+}
+
+type statementImportResponse struct {
+	Statement    *p2pModels.PaymentStatement  `json:"statement"`
+	Transactions []*p2pModels.BankTransaction `json:"transactions"`
+}
+
+// ImportPaymentStatement handles POST payments/statements endpoint
+// Request body is camt.053 statement or camt.054 notification XML, only admin can import statements
+var ImportPaymentStatement = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeImportPaymentStatement)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can import payment statements")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// Limit upload size
+	r.Body = http.MaxBytesReader(w, r.Body, 10*MB) // 10 Mb
+
+	defer r.Body.Close()
+	xmlBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		info.APIError = cigExchange.NewReadError("Failed to read request body", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	parsed, err := iso20022.ParseCamt(xmlBytes)
+	if err != nil {
+		info.APIError = cigExchange.NewInvalidFieldError("file", "Unable to parse camt statement: "+err.Error())
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	statement := &p2pModels.PaymentStatement{
+		MessageID: parsed.MessageID,
+		Type:      parsed.Type,
+		CreatedBy: loggedInUser.UserUUID,
+	}
+	apiError = statement.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	for _, credit := range parsed.Credits {
+		statement.Transactions++
+
+		amount, err := p2pModels.ParseDecimal(credit.Amount)
+		if err != nil {
+			info.APIError = cigExchange.NewInvalidFieldError("file", "Invalid amount '"+credit.Amount+"' of "+credit.AccountServicerReference)
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+
+		transaction := &p2pModels.BankTransaction{
+			StatementID:              statement.ID,
+			AccountServicerReference: credit.AccountServicerReference,
+			EndToEndID:               credit.EndToEndID,
+			AccountIBAN:              credit.AccountIBAN,
+			Amount:                   amount,
+			Currency:                 p2pModels.NormalizeCurrency(credit.Currency),
+			Reference:                credit.Reference,
+			DebtorName:               credit.DebtorName,
+			DebtorIBAN:               credit.DebtorIBAN,
+			BookingDate:              credit.BookingDate,
+		}
+		imported, apiError := transaction.Import(loggedInUser.UserUUID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+
+		switch {
+		case !imported:
+			statement.Duplicates++
+		case transaction.Status == p2pModels.BankTransactionStatusMatched:
+			statement.Matched++
+		default:
+			statement.Exceptions++
+		}
+	}
+
+	apiError = statement.Update()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	transactions, apiError := p2pModels.GetStatementTransactions(statement.ID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, &statementImportResponse{Statement: statement, Transactions: transactions})
+}
+
+// GetPaymentStatements handles GET payments/statements endpoint
+var GetPaymentStatements = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPaymentStatements)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see payment statements")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	statements, apiError := p2pModels.GetPaymentStatements()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, statements)
+}
+
+// GetPaymentExceptions handles GET payments/exceptions endpoint
+// Open exceptions are returned by default, 'status' query parameter returns resolved or dismissed payments
+var GetPaymentExceptions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPaymentExceptions)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see payment exceptions")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if len(status) == 0 {
+		status = p2pModels.BankTransactionStatusException
+	}
+
+	transactions, apiError := p2pModels.GetBankTransactionsByStatus(status)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, transactions)
+}
+
+// ResolvePaymentException handles POST payments/exceptions/{transaction_id}/resolve endpoint
+var ResolvePaymentException = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeResolvePaymentException)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	transactionID := mux.Vars(r)["transaction_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can resolve payment exceptions")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &paymentResolutionRequest{}
+	// decode resolution from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	transaction, apiError := p2pModels.GetBankTransaction(transactionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = transaction.Resolve(req.Resolution, req.UserID, strings.TrimSpace(req.Note), loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, transaction)
+}
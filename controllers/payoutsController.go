@@ -0,0 +1,509 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type payoutRequest struct {
+	Amount        p2pModels.Decimal `json:"amount"`
+	Currency      string            `json:"currency"`
+	BankAccountID string            `json:"bank_account_id"`
+	Remittance    string            `json:"remittance"`
+	ExecutionDate string            `json:"execution_date"`
+}
+
+type payoutBatchRequest struct {
+	Until string `json:"until"`
+}
+
+type payoutBatchProcessRequest struct {
+	Note string `json:"note"`
+}
+
+type payoutBatchResponse struct {
+	Batch   *p2pModels.PayoutBatch `json:"batch"`
+	Invalid []*p2pModels.Payout    `json:"invalid"`
+}
+
+// GetUserPayouts handles GET users/{user_id}/payouts endpoint
+var GetUserPayouts = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserPayouts)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	payouts, apiError := p2pModels.GetUserPayouts(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, payouts)
+}
+
+// CreatePayout handles POST users/{user_id}/payouts endpoint
+// Amount is withdrawn from the cash account immediately and paid out with the next payout batch
+var CreatePayout = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreatePayout)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &payoutRequest{}
+	// decode payout from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// payouts are paid to registered bank accounts only, default account is used if not specified
+	var account *p2pModels.BankAccount
+	var apiError *cigExchange.APIError
+	if len(req.BankAccountID) > 0 {
+		account, apiError = p2pModels.GetUserBankAccount(userID, req.BankAccountID)
+	} else {
+		account, apiError = p2pModels.GetUserDefaultBankAccount(userID)
+		if apiError == nil && account == nil {
+			apiError = cigExchange.NewInvalidFieldError("bank_account_id", "User has no payout bank account")
+		}
+	}
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	payout := &p2pModels.Payout{
+		UserID:        userID,
+		Currency:      p2pModels.NormalizeCurrency(req.Currency),
+		Amount:        req.Amount,
+		BankAccountID: account.ID,
+		CreditorName:  account.HolderName,
+		IBAN:          account.IBAN,
+		BIC:           account.BIC,
+		Remittance:    req.Remittance,
+		CreatedBy:     loggedInUser.UserUUID,
+	}
+	if len(payout.Currency) == 0 {
+		payout.Currency = p2pModels.DefaultCurrency
+	}
+	if !p2pModels.IsValidCurrency(payout.Currency) {
+		info.APIError = cigExchange.NewInvalidFieldError("currency", "Unsupported currency")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if len(req.ExecutionDate) > 0 {
+		payout.ExecutionDate, err = time.ParseInLocation("2006-01-02", req.ExecutionDate, time.Local)
+		if err != nil {
+			info.APIError = cigExchange.NewInvalidFieldError("execution_date", "Execution date must be in YYYY-MM-DD format")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	apiError = payout.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, payout)
+}
+
+// CancelPayout handles DELETE users/{user_id}/payouts/{payout_id} endpoint
+// Only payouts not exported to the bank can be cancelled, amount returns to the cash account
+var CancelPayout = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCancelPayout)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	payoutID := mux.Vars(r)["payout_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	payout, apiError := p2pModels.GetUserPayout(userID, payoutID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = payout.Cancel(loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetPayouts handles GET payouts endpoint
+// Pending payouts are returned by default, 'status' query parameter returns payouts with other status
+var GetPayouts = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPayouts)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see payouts")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if len(status) == 0 {
+		status = p2pModels.PayoutStatusPending
+	}
+
+	payouts, apiError := p2pModels.GetPayoutsByStatus(status)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, payouts)
+}
+
+// CreatePayoutBatch handles POST payouts/batches endpoint
+// Pending payouts due until 'until' date, today by default, are exported as pain.001 payment order of the escrow account
+var CreatePayoutBatch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreatePayoutBatch)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can export payouts")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &payoutBatchRequest{}
+	// decode batch parameters from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if len(req.Until) > 0 {
+		until, err = time.ParseInLocation("2006-01-02", req.Until, time.Local)
+		if err != nil {
+			info.APIError = cigExchange.NewInvalidFieldError("until", "Until must be in YYYY-MM-DD format")
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	debtor := p2pModels.GetEscrowPaymentAccount()
+	if debtor == nil {
+		info.APIError = cigExchange.NewInvalidFieldError("escrow", "Escrow account is not configured")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	batch, invalid, apiError := p2pModels.CreatePayoutBatch(until, debtor, loggedInUser.UserUUID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, &payoutBatchResponse{Batch: batch, Invalid: invalid})
+}
+
+// GetPayoutBatches handles GET payouts/batches endpoint
+var GetPayoutBatches = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPayoutBatches)
+	defer cigExchange.PrintAPIError(info)
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see payout batches")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	batches, apiError := p2pModels.GetPayoutBatches()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, batches)
+}
+
+// GetPayoutBatch handles GET payouts/batches/{batch_id} endpoint
+var GetPayoutBatch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPayoutBatch)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	batchID := mux.Vars(r)["batch_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can see payout batches")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	batch, apiError := p2pModels.GetPayoutBatch(batchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, batch)
+}
+
+// GetPayoutBatchFile handles GET payouts/batches/{batch_id}/file endpoint
+// pain.001 document is downloaded for upload to e-banking
+var GetPayoutBatchFile = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetPayoutBatch)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	batchID := mux.Vars(r)["batch_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can download payout batches")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	batch, apiError := p2pModels.GetPayoutBatch(batchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "pain001-" + batch.MessageID + ".xml"}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(batch.Document))
+}
+
+// processPayoutBatch handles execute and reject endpoints
+func processPayoutBatch(w http.ResponseWriter, r *http.Request, execute bool) {
+
+	activityType := p2pModels.ActivityTypeRejectPayoutBatch
+	if execute {
+		activityType = p2pModels.ActivityTypeExecutePayoutBatch
+	}
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, activityType)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	batchID := mux.Vars(r)["batch_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkAdminAccess(loggedInUser.UserUUID, "Only admin can process payout batches")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &payoutBatchProcessRequest{}
+	// decode note from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	batch, apiError := p2pModels.GetPayoutBatch(batchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if execute {
+		apiError = batch.Execute(req.Note, loggedInUser.UserUUID)
+	} else {
+		if len(req.Note) == 0 {
+			info.APIError = cigExchange.NewRequiredFieldError([]string{"note"})
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		apiError = batch.Reject(req.Note, loggedInUser.UserUUID)
+	}
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	batch, apiError = p2pModels.GetPayoutBatch(batchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, batch)
+}
+
+// ExecutePayoutBatch handles POST payouts/batches/{batch_id}/execute endpoint
+var ExecutePayoutBatch = func(w http.ResponseWriter, r *http.Request) {
+
+	processPayoutBatch(w, r, true)
+}
+
+// RejectPayoutBatch handles POST payouts/batches/{batch_id}/reject endpoint
+// Rejection 'note' is required, payouts return to pending
+var RejectPayoutBatch = func(w http.ResponseWriter, r *http.Request) {
+
+	processPayoutBatch(w, r, false)
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type savedSearchRequest struct {
+	Name         *string                        `json:"name"`
+	Criteria     *p2pModels.SavedSearchCriteria `json:"criteria"`
+	Frequency    *string                        `json:"frequency"`
+	Language     *string                        `json:"language"`
+	EmailEnabled *bool                          `json:"email_enabled"`
+}
+
+// validateSavedSearchRequest checks request fields and applies them to saved search
+func validateSavedSearchRequest(req *savedSearchRequest, search *p2pModels.SavedSearch) *cigExchange.APIError {
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len(name) == 0 {
+			return cigExchange.NewInvalidFieldError("name", "Name can't be empty")
+		}
+		search.Name = name
+	}
+
+	if req.Criteria != nil {
+		apiError := req.Criteria.Validate()
+		if apiError != nil {
+			return apiError
+		}
+		criteriaBytes, err := json.Marshal(req.Criteria)
+		if err != nil {
+			return cigExchange.NewJSONEncodingError("Unable to encode search criteria", err)
+		}
+		search.Criteria = postgres.Jsonb{RawMessage: criteriaBytes}
+	}
+
+	if req.Frequency != nil {
+		if *req.Frequency != p2pModels.SavedSearchFrequencyDaily && *req.Frequency != p2pModels.SavedSearchFrequencyWeekly {
+			return cigExchange.NewInvalidFieldError("frequency", "Frequency must be 'daily' or 'weekly'")
+		}
+		search.Frequency = *req.Frequency
+	}
+
+	if req.Language != nil {
+		if !p2pModels.IsValidSavedSearchLanguage(*req.Language) {
+			return cigExchange.NewInvalidFieldError("language", "Language must be one of: "+strings.Join(p2pModels.SavedSearchLanguages, ", "))
+		}
+		search.Language = *req.Language
+	}
+
+	if req.EmailEnabled != nil {
+		search.EmailEnabled = *req.EmailEnabled
+	}
+	return nil
+}
+
+// GetSavedSearches handles GET users/{user_id}/saved-searches endpoint
+var GetSavedSearches = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSavedSearches)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	searches, apiError := p2pModels.GetSavedSearches(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, searches)
+}
+
+// CreateSavedSearch handles POST users/{user_id}/saved-searches endpoint
+var CreateSavedSearch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateSavedSearch)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &savedSearchRequest{}
+	// decode saved search from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	missingFields := make([]string, 0)
+	if req.Name == nil {
+		missingFields = append(missingFields, "name")
+	}
+	if req.Criteria == nil {
+		missingFields = append(missingFields, "criteria")
+	}
+	if len(missingFields) > 0 {
+		info.APIError = cigExchange.NewRequiredFieldError(missingFields)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	search := &p2pModels.SavedSearch{
+		UserID:           userID,
+		Frequency:        p2pModels.SavedSearchFrequencyDaily,
+		Language:         "en",
+		EmailEnabled:     true,
+		UnsubscribeToken: cigExchange.RandomUUID(),
+	}
+	apiError := validateSavedSearchRequest(req, search)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// remember offerings already listed, so the first digest only contains new ones
+	_, apiError = p2pModels.RefreshOfferingsVisibility()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = search.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, search)
+}
+
+// UpdateSavedSearch handles PATCH users/{user_id}/saved-searches/{search_id} endpoint
+var UpdateSavedSearch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateSavedSearch)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	searchID := mux.Vars(r)["search_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	search, apiError := p2pModels.GetSavedSearch(userID, searchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &savedSearchRequest{}
+	// decode saved search from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = validateSavedSearchRequest(req, search)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = search.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, search)
+}
+
+// DeleteSavedSearch handles DELETE users/{user_id}/saved-searches/{search_id} endpoint
+var DeleteSavedSearch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteSavedSearch)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	searchID := mux.Vars(r)["search_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	// check user id
+	if len(userID) == 0 {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "UserID is invalid")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	search, apiError := p2pModels.GetSavedSearch(userID, searchID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = search.Delete()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// UnsubscribeSavedSearch handles GET saved-searches/unsubscribe endpoint (no JWT)
+// 'token' query parameter comes from digest email, 'all' unsubscribes all searches of the user
+var UnsubscribeSavedSearch = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUnsubscribeSavedSearch)
+	defer cigExchange.PrintAPIError(info)
+
+	token := r.URL.Query().Get("token")
+	if len(token) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"token"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError := p2pModels.UnsubscribeSavedSearches(token, r.URL.Query().Get("all") == "true")
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, cigExchange.Message(true, "You have been unsubscribed"))
+}
@@ -0,0 +1,712 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/middleware"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"cig-exchange-p2p-backend/signatures"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SignatureDataPath path to signed documents, files are not reachable through GET media/{media_file}
+var SignatureDataPath = path.Join(UserDataPath, "signatures")
+
+type signatureRequestRequest struct {
+	DocumentType string `json:"document_type"`
+	AgreementID  string `json:"agreement_id"`
+	TemplateID   string `json:"template_id"`
+	UserID       string `json:"user_id"`
+	OfferingID   string `json:"offering_id"`
+}
+
+type signatureRequestResponse struct {
+	*p2pModels.SignatureRequest
+	Events []*p2pModels.SignatureEvent `json:"events"`
+}
+
+type signatureCodeResponse struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Code      string     `json:"code,omitempty"`
+}
+
+type signatureSignRequest struct {
+	Code string `json:"code"`
+}
+
+type signatureDeclineRequest struct {
+	Reason string `json:"reason"`
+}
+
+// sendSignatureRequest creates the request with the active provider and notifies the signer
+func sendSignatureRequest(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+
+	provider := signatures.Get()
+	apiError := request.Create(provider.Name())
+	if apiError != nil {
+		return apiError
+	}
+
+	// request stays pending if the provider fails, reminders notify the signer later
+	apiError = provider.Send(request)
+	if apiError != nil {
+		fmt.Println("sendSignatureRequest: " + apiError.ToString())
+	}
+	notifications.SignatureRequested(request)
+	return nil
+}
+
+// cancelAgreementSignatureRequest withdraws pending signature request of the cancelled subscription agreement
+func cancelAgreementSignatureRequest(subscriptionID, userID, remoteAddr string) *cigExchange.APIError {
+
+	agreement, apiError := p2pModels.GetSubscriptionAgreement(subscriptionID)
+	if apiError != nil || agreement == nil {
+		return apiError
+	}
+	request, apiError := p2pModels.GetPendingSignatureRequest(p2pModels.SignatureDocumentSubscriptionAgreement, agreement.ID)
+	if apiError != nil || request == nil {
+		return apiError
+	}
+
+	apiError = request.Cancel(userID, remoteAddr)
+	if apiError != nil {
+		return apiError
+	}
+	return signatures.ForRequest(request).Withdraw(request)
+}
+
+// newSignatureRequestResponse adds the audit trail to the request
+func newSignatureRequestResponse(request *p2pModels.SignatureRequest) (*signatureRequestResponse, *cigExchange.APIError) {
+
+	events, apiError := p2pModels.GetSignatureEvents(request.ID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return &signatureRequestResponse{SignatureRequest: request, Events: events}, nil
+}
+
+// serveSignedDocument sends stored signed PDF as attachment
+func serveSignedDocument(w http.ResponseWriter, r *http.Request, request *p2pModels.SignatureRequest) {
+
+	w.Header().Set("Content-Type", request.SignedMedia.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "signed-" + request.ID + request.SignedMedia.FileExtension}))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeFile(w, r, path.Join(SignatureDataPath, request.SignedMedia.ID)+request.SignedMedia.FileExtension)
+}
+
+// getUserSignatureRequest loads signature request of the user, admin users have read access when allowAdmin is set
+func getUserSignatureRequest(info *cigExchange.ActivityInformation, r *http.Request, allowAdmin bool) (*p2pModels.SignatureRequest, *cigExchange.APIError) {
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	requestID := mux.Vars(r)["request_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		return nil, cigExchange.NewRoutingError(err)
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		if !allowAdmin {
+			return nil, cigExchange.NewAccessRightsError("No access rights for the user")
+		}
+		apiError := checkAdminAccess(loggedInUser.UserUUID, "No access rights for the user")
+		if apiError != nil {
+			return nil, apiError
+		}
+	}
+
+	return p2pModels.GetUserSignatureRequest(userID, requestID)
+}
+
+// GetUserSignatureRequests handles GET users/{user_id}/signature-requests endpoint
+// 'status' query parameter filters requests
+var GetUserSignatureRequests = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	status := r.URL.Query().Get("status")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	requests, apiError := p2pModels.GetUserSignatureRequests(userID, status)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, requests)
+}
+
+// GetUserSignatureRequest handles GET users/{user_id}/signature-requests/{request_id} endpoint
+// Response contains the audit trail
+var GetUserSignatureRequest = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp, apiError := newSignatureRequestResponse(request)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, resp)
+}
+
+// GetUserSignatureDocument handles GET users/{user_id}/signature-requests/{request_id}/document endpoint
+// Unsigned document is rendered from the request and checked against its hash
+var GetUserSignatureDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	document, apiError := request.VerifyDocument()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "document-" + request.ID + ".pdf"}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(document)
+}
+
+// GetUserSignedDocument handles GET users/{user_id}/signature-requests/{request_id}/file endpoint
+var GetUserSignedDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if request.Status != p2pModels.SignatureStatusSigned {
+		info.APIError = cigExchange.NewInvalidFieldError("request_id", "Document isn't signed")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	serveSignedDocument(w, r, request)
+}
+
+// RequestSignatureCode handles POST users/{user_id}/signature-requests/{request_id}/code endpoint
+// New code replaces the previous one
+var RequestSignatureCode = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeRequestSignatureCode)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	code, apiError := signatures.ForRequest(request).RequestCode(request, middleware.GetClientIP(r))
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp := &signatureCodeResponse{ExpiresAt: request.CodeExpiresAt}
+	// in "DEV" environment we return signature code for testing purposes
+	if cigExchange.IsDevEnv() {
+		resp.Code = code
+	}
+
+	cigExchange.Respond(w, resp)
+}
+
+// SignDocument handles POST users/{user_id}/signature-requests/{request_id}/sign endpoint
+// Signed document with the signature certificate page is stored as private media
+var SignDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeSignDocument)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &signatureSignRequest{}
+	// decode signature code from request body
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if len(req.Code) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"code"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	remoteAddr := middleware.GetClientIP(r)
+	apiError = signatures.ForRequest(request).Verify(request, req.Code, remoteAddr)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// signer must sign exactly the document he was able to review
+	_, apiError = request.VerifyDocument()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	user, apiError := models.GetUser(request.UserID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	profile, apiError := p2pModels.GetInvestorProfile(request.UserID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	signedAt := time.Now().Truncate(time.Second)
+	request.SignerName = strings.TrimSpace(profile.FirstName + " " + profile.LastName)
+	if len(request.SignerName) == 0 {
+		request.SignerName = user.Name
+	}
+	if user.LoginEmail != nil {
+		request.SignerEmail = user.LoginEmail.Value1
+	}
+	request.SignerAddr = remoteAddr
+	request.SignedAt = &signedAt
+
+	events, apiError := p2pModels.GetSignatureEvents(request.ID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	events = append(events, request.SignedEvent())
+
+	signed, err := request.SignedDocument(events).PDF()
+	if err != nil {
+		info.APIError = cigExchange.NewInvalidFieldError("request_id", "Unable to render signed document: "+err.Error())
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	hash := sha256.Sum256(signed)
+
+	tx := cigExchange.GetDB().Begin()
+	apiError = request.MarkSigned(tx, len(signed), hex.EncodeToString(hash[:]))
+	if apiError != nil {
+		tx.Rollback()
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// save file to signatures folder before commit, the request stays pending if it fails
+	filePath := path.Join(SignatureDataPath, request.SignedMedia.ID) + request.SignedMedia.FileExtension
+	err = os.MkdirAll(SignatureDataPath, 0700)
+	if err == nil {
+		err = ioutil.WriteFile(filePath, signed, 0600)
+	}
+	if err != nil {
+		tx.Rollback()
+		info.APIError = cigExchange.NewReadError("Failed to write signed document", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		os.Remove(filePath)
+		info.APIError = cigExchange.NewDatabaseError("Unable to commit transaction", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	notifications.SignatureCompleted(request)
+
+	cigExchange.Respond(w, request)
+}
+
+// DeclineSignatureRequest handles POST users/{user_id}/signature-requests/{request_id}/decline endpoint
+var DeclineSignatureRequest = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeclineSignatureRequest)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getUserSignatureRequest(info, r, false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &signatureDeclineRequest{}
+	// decode optional reason from request body
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = request.Decline(req.Reason, middleware.GetClientIP(r))
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	notifications.SignatureCompleted(request)
+
+	cigExchange.Respond(w, request)
+}
+
+// GetOrganisationSignatureRequests handles GET organisations/{organisation_id}/signature-requests endpoint
+// 'status' query parameter filters requests
+var GetOrganisationSignatureRequests = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOrganisationSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	status := r.URL.Query().Get("status")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	requests, apiError := p2pModels.GetOrganisationSignatureRequests(organisationID, status)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, requests)
+}
+
+// CreateSignatureRequest handles POST organisations/{organisation_id}/signature-requests endpoint
+// 'subscription_agreement' documents need 'agreement_id', 'nda' documents are rendered
+// from the NDA template 'template_id' for 'user_id' with optional 'offering_id'
+var CreateSignatureRequest = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateSignatureRequest)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &signatureRequestRequest{}
+	// decode signature request from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	var request *p2pModels.SignatureRequest
+	switch req.DocumentType {
+	case p2pModels.SignatureDocumentSubscriptionAgreement:
+		if len(req.AgreementID) == 0 {
+			info.APIError = cigExchange.NewRequiredFieldError([]string{"agreement_id"})
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		agreement, apiError := p2pModels.GetOrganisationAgreement(organisationID, req.AgreementID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		request = p2pModels.NewAgreementSignatureRequest(agreement, loggedInUser.UserUUID)
+	case p2pModels.SignatureDocumentNDA:
+		missingFields := make([]string, 0)
+		if len(req.TemplateID) == 0 {
+			missingFields = append(missingFields, "template_id")
+		}
+		if len(req.UserID) == 0 {
+			missingFields = append(missingFields, "user_id")
+		}
+		if len(missingFields) > 0 {
+			info.APIError = cigExchange.NewRequiredFieldError(missingFields)
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		request, apiError = newNDASignatureRequest(organisationID, req, loggedInUser.UserUUID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	default:
+		info.APIError = cigExchange.NewInvalidFieldError("document_type", "Document type must be one of: "+p2pModels.SignatureDocumentSubscriptionAgreement+", "+p2pModels.SignatureDocumentNDA)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = sendSignatureRequest(request)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, request)
+}
+
+// newNDASignatureRequest renders NDA template for the investor, offering must belong to the organisation
+func newNDASignatureRequest(organisationID string, req *signatureRequestRequest, createdBy string) (*p2pModels.SignatureRequest, *cigExchange.APIError) {
+
+	agreementTemplate, apiError := p2pModels.GetAgreementTemplate(organisationID, req.TemplateID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	user, apiError := models.GetUser(req.UserID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	profile, apiError := p2pModels.GetInvestorProfile(req.UserID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	var offering *models.Offering
+	if len(req.OfferingID) > 0 {
+		offering, apiError = models.GetOffering(req.OfferingID)
+		if apiError != nil {
+			return nil, apiError
+		}
+		if offering.OrganisationID != organisationID {
+			return nil, cigExchange.NewInvalidFieldError("offering_id", "Offering doesn't belong to the organisation")
+		}
+	}
+
+	data, apiError := p2pModels.NewNDAAgreementData(organisationID, offering, profile, agreementTemplate.Language)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if len(data.Investor.Name) == 0 {
+		data.Investor.Name = user.Name
+	}
+
+	return p2pModels.NewNDASignatureRequest(agreementTemplate, data, req.UserID, req.OfferingID, createdBy)
+}
+
+// getOrganisationSignatureRequest loads organisation signature request, admin access is checked when requireAdmin is set
+func getOrganisationSignatureRequest(info *cigExchange.ActivityInformation, r *http.Request, requireAdmin bool) (*p2pModels.SignatureRequest, *cigExchange.APIError) {
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	requestID := mux.Vars(r)["request_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		return nil, cigExchange.NewRoutingError(err)
+	}
+	info.LoggedInUser = loggedInUser
+
+	var apiError *cigExchange.APIError
+	if requireAdmin {
+		apiError = checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	} else {
+		apiError = checkOrganisationMemberAccess(loggedInUser.UserUUID, organisationID)
+	}
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	return p2pModels.GetOrganisationSignatureRequest(organisationID, requestID)
+}
+
+// GetOrganisationSignatureRequest handles GET organisations/{organisation_id}/signature-requests/{request_id} endpoint
+// Response contains the audit trail
+var GetOrganisationSignatureRequest = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOrganisationSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getOrganisationSignatureRequest(info, r, false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp, apiError := newSignatureRequestResponse(request)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, resp)
+}
+
+// GetOrganisationSignedDocument handles GET organisations/{organisation_id}/signature-requests/{request_id}/file endpoint
+var GetOrganisationSignedDocument = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetOrganisationSignatureRequests)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getOrganisationSignatureRequest(info, r, false)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if request.Status != p2pModels.SignatureStatusSigned {
+		info.APIError = cigExchange.NewInvalidFieldError("request_id", "Document isn't signed")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	serveSignedDocument(w, r, request)
+}
+
+// CancelSignatureRequest handles DELETE organisations/{organisation_id}/signature-requests/{request_id} endpoint
+// Only pending requests can be cancelled, the signer is notified
+var CancelSignatureRequest = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCancelSignatureRequest)
+	defer cigExchange.PrintAPIError(info)
+
+	request, apiError := getOrganisationSignatureRequest(info, r, true)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = request.Cancel(info.LoggedInUser.UserUUID, middleware.GetClientIP(r))
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = signatures.ForRequest(request).Withdraw(request)
+	if apiError != nil {
+		fmt.Println("CancelSignatureRequest: " + apiError.ToString())
+	}
+
+	w.WriteHeader(204)
+}
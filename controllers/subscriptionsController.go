@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	"cig-exchange-p2p-backend/middleware"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type subscriptionRequest struct {
+	OfferingID string            `json:"offering_id"`
+	Amount     p2pModels.Decimal `json:"amount"`
+}
+
+// GetUserSubscriptions handles GET users/{user_id}/subscriptions endpoint
+var GetUserSubscriptions = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetUserSubscriptions)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscriptions, apiError := p2pModels.GetUserSubscriptions(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, subscriptions)
+}
+
+// CreateSubscription handles POST users/{user_id}/subscriptions endpoint
+// Subscription stays pending until the bank transfer with its payment reference is imported
+var CreateSubscription = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateSubscription)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &subscriptionRequest{}
+	// decode subscription from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(req.OfferingID) == 0 {
+		info.APIError = cigExchange.NewRequiredFieldError([]string{"offering_id"})
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(req.OfferingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !p2pModels.IsOfferingListed(offering) {
+		info.APIError = cigExchange.NewInvalidFieldError("offering_id", "Offering is not available")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = checkOfferingEligibility(offering, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	money, apiError := p2pModels.GetOfferingMoney(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription := &p2pModels.Subscription{
+		OfferingID: offering.ID,
+		UserID:     userID,
+		Amount:     req.Amount,
+	}
+
+	apiError = subscription.Create(money)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// agreement failure doesn't fail the subscription, investor can generate it later
+	_, apiError = createSubscriptionAgreement(subscription, offering, agreementChain(r))
+	if apiError != nil {
+		fmt.Println("CreateSubscription: " + apiError.ToString())
+	}
+
+	cigExchange.Respond(w, subscription)
+}
+
+// CancelSubscription handles DELETE users/{user_id}/subscriptions/{subscription_id} endpoint
+// Only pending subscriptions can be cancelled
+var CancelSubscription = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCancelSubscription)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	subscriptionID := mux.Vars(r)["subscription_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetUserSubscription(userID, subscriptionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = subscription.Cancel()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = cancelAgreementSignatureRequest(subscription.ID, loggedInUser.UserUUID, middleware.GetClientIP(r))
+	if apiError != nil {
+		fmt.Println("CancelSubscription: " + apiError.ToString())
+	}
+
+	w.WriteHeader(204)
+}
+
+// qrBillResponse is the JSON representation of subscription QR-bill
+type qrBillResponse struct {
+	SubscriptionID string `json:"subscription_id"`
+	IBAN           string `json:"iban"`
+	Creditor       string `json:"creditor"`
+	Amount         string `json:"amount"`
+	Currency       string `json:"currency"`
+	ReferenceType  string `json:"reference_type"`
+	Reference      string `json:"reference"`
+	Payload        string `json:"payload"`
+}
+
+// GetSubscriptionQRBill handles GET users/{user_id}/subscriptions/{subscription_id}/qr-bill endpoint
+// 'format' query parameter 'pdf' or 'svg' downloads the payment part, QR-bill data and payload are returned by default
+var GetSubscriptionQRBill = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetSubscriptionQRBill)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	userID := mux.Vars(r)["user_id"]
+	subscriptionID := mux.Vars(r)["subscription_id"]
+	format := r.URL.Query().Get("format")
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	if userID != loggedInUser.UserUUID {
+		info.APIError = cigExchange.NewAccessRightsError("No access rights for the user")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(format) > 0 && format != "pdf" && format != "svg" {
+		info.APIError = cigExchange.NewInvalidFieldError("format", "Format must be one of: pdf, svg")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetUserSubscription(userID, subscriptionID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// query offering from db
+	offering, apiError := models.GetOffering(subscription.OfferingID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	account, apiError := p2pModels.GetOfferingPaymentAccount(offering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	profile, apiError := p2pModels.GetInvestorProfile(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	bill, apiError := subscription.QRBill(account, profile)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if len(format) == 0 {
+		cigExchange.Respond(w, &qrBillResponse{
+			SubscriptionID: subscription.ID,
+			IBAN:           bill.Account,
+			Creditor:       bill.Creditor.Name,
+			Amount:         bill.Amount,
+			Currency:       bill.Currency,
+			ReferenceType:  bill.ReferenceType,
+			Reference:      bill.Reference,
+			Payload:        bill.Payload(),
+		})
+		return
+	}
+
+	// payment part texts follow 'lang' query parameter or Accept-Language header
+	language := i18n.DefaultLanguage
+	if chain := i18n.Negotiate(r, true); chain != nil {
+		language = chain[0]
+	}
+
+	contentType := "application/pdf"
+	var document []byte
+	if format == "pdf" {
+		document, err = bill.PDF(language)
+	} else {
+		contentType = "image/svg+xml"
+		document, err = bill.SVG(language)
+	}
+	if err != nil {
+		info.APIError = cigExchange.NewInvalidFieldError("qr_bill", "Unable to render QR-bill: "+err.Error())
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "qr-bill-" + subscription.ID + "." + format}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(document)
+}
@@ -0,0 +1,794 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/notifications"
+	"cig-exchange-p2p-backend/webhooks"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Constants defining translated model types
+const (
+	translationModelOrganisation = "organisation"
+	translationModelOffering     = "offering"
+)
+
+type translationCoverageResponse struct {
+	Organisation *p2pModels.TranslationCoverage `json:"organisation"`
+	Offerings    []*offeringTranslationCoverage `json:"offerings"`
+	Settings     *p2pModels.TranslationSettings `json:"settings"`
+}
+
+type offeringTranslationCoverage struct {
+	OfferingID string                         `json:"offering_id"`
+	Title      string                         `json:"title"`
+	IsVisible  bool                           `json:"is_visible"`
+	Coverage   *p2pModels.TranslationCoverage `json:"coverage"`
+}
+
+type translationGap struct {
+	ModelType string            `json:"model_type"`
+	ModelID   string            `json:"model_id"`
+	Field     string            `json:"field"`
+	Language  string            `json:"language"`
+	Source    map[string]string `json:"source"`
+}
+
+type translationsRequest struct {
+	Language string            `json:"language"`
+	Fields   map[string]string `json:"fields"`
+}
+
+type translationSettingsRequest struct {
+	RequiredLanguages *[]string `json:"required_languages"`
+	BlockPublication  *bool     `json:"block_publication"`
+}
+
+type translatorRequest struct {
+	Languages []string `json:"languages"`
+}
+
+// checkTranslationAccess allows admin users, organisation members and organisation translators.
+// Returned translator is nil for users allowed to translate all languages
+func checkTranslationAccess(userID, organisationID string) (*p2pModels.Translator, *cigExchange.APIError) {
+
+	translator, apiError := p2pModels.GetTranslator(organisationID, userID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if translator != nil {
+		return translator, nil
+	}
+	return nil, checkOrganisationMemberAccess(userID, organisationID)
+}
+
+// validateLanguages checks that all languages are supported
+func validateLanguages(field string, languages []string) (pq.StringArray, *cigExchange.APIError) {
+
+	validated := pq.StringArray{}
+	for _, language := range languages {
+		if !i18n.IsSupportedLanguage(language) {
+			return validated, cigExchange.NewInvalidFieldError(field, "Languages must be one of: "+strings.Join(i18n.SupportedLanguages, ", "))
+		}
+		validated = append(validated, language)
+	}
+	return validated, nil
+}
+
+// prepareTranslationsUpdate validates translations request and merges it into multilang fields of the model.
+// Returned map is ready for model Update
+func prepareTranslationsUpdate(req *translationsRequest, translator *p2pModels.Translator, model interface{}, fields []string) (map[string]interface{}, *cigExchange.APIError) {
+
+	if !i18n.IsSupportedLanguage(req.Language) {
+		return nil, cigExchange.NewInvalidFieldError("language", "Language must be one of: "+strings.Join(i18n.SupportedLanguages, ", "))
+	}
+	if translator != nil && !translator.CanTranslate(req.Language) {
+		return nil, cigExchange.NewAccessRightsError("No access rights for the language")
+	}
+	if len(req.Fields) == 0 {
+		return nil, cigExchange.NewRequiredFieldError([]string{"fields"})
+	}
+
+	modelMap, apiError := p2pModels.ModelToMap(model)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	updateMap := make(map[string]interface{})
+	for field, text := range req.Fields {
+		if !containsField(fields, field) {
+			return nil, cigExchange.NewInvalidFieldError("fields", "Field '"+field+"' is not translatable")
+		}
+
+		translations := p2pModels.GetTranslations(modelMap, field)
+		text = strings.TrimSpace(text)
+		if len(text) == 0 {
+			delete(translations, req.Language)
+		} else {
+			translations[req.Language] = text
+		}
+		updateMap[field] = translations
+	}
+	return updateMap, nil
+}
+
+func containsField(fields []string, field string) bool {
+
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOfferingPublication merges offering changes into existing offering and
+// checks organisation translation requirements for visible offerings
+func checkOfferingPublication(organisationID string, existingOffering *models.Offering, changes map[string]interface{}) *cigExchange.APIError {
+
+	settings, apiError := p2pModels.GetTranslationSettings(organisationID)
+	if apiError != nil {
+		return apiError
+	}
+	if !settings.BlockPublication {
+		return nil
+	}
+
+	mergedMap := make(map[string]interface{})
+	if existingOffering != nil {
+		mergedMap, apiError = p2pModels.ModelToMap(existingOffering)
+		if apiError != nil {
+			return apiError
+		}
+	}
+	for key, value := range changes {
+		mergedMap[key] = value
+	}
+
+	// multilang values may be jsonb, convert everything to plain json
+	mergedMap, apiError = p2pModels.ModelToMap(mergedMap)
+	if apiError != nil {
+		return apiError
+	}
+	return settings.CheckPublication(mergedMap, p2pModels.OfferingMultilangFields)
+}
+
+// addTranslationGaps appends missing translations of the model in the languages
+func addTranslationGaps(gaps []*translationGap, modelType, modelID string, modelMap map[string]interface{}, fields, languages []string) []*translationGap {
+
+	coverage := p2pModels.ComputeTranslationCoverage(modelMap, fields)
+	for language, missingFields := range coverage.MissingIn(languages) {
+		for _, field := range missingFields {
+			gaps = append(gaps, &translationGap{
+				ModelType: modelType,
+				ModelID:   modelID,
+				Field:     field,
+				Language:  language,
+				Source:    p2pModels.GetTranslations(modelMap, field),
+			})
+		}
+	}
+	return gaps
+}
+
+// GetTranslationCoverage handles GET organisations/{organisation_id}/translations endpoint
+var GetTranslationCoverage = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetTranslationCoverage)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := checkTranslationAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	settings, apiError := p2pModels.GetTranslationSettings(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	organisation, apiError := models.GetOrganisation(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	orgMap, apiError := p2pModels.ModelToMap(organisation)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offerings, apiError := models.GetOrganisationOfferings(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	resp := &translationCoverageResponse{
+		Organisation: p2pModels.ComputeTranslationCoverage(orgMap, p2pModels.OrganisationMultilangFields),
+		Offerings:    make([]*offeringTranslationCoverage, 0, len(offerings)),
+		Settings:     settings,
+	}
+	for _, offering := range offerings {
+		offeringMap, apiError := p2pModels.ModelToMap(offering)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		resp.Offerings = append(resp.Offerings, &offeringTranslationCoverage{
+			OfferingID: offering.ID,
+			Title:      i18n.TranslateJSON(offering.Title.RawMessage, i18n.FallbackChain()),
+			IsVisible:  offering.IsVisible,
+			Coverage:   p2pModels.ComputeTranslationCoverage(offeringMap, p2pModels.OfferingMultilangFields),
+		})
+	}
+
+	cigExchange.Respond(w, resp)
+}
+
+// GetTranslationGaps handles GET organisations/{organisation_id}/translations/gaps endpoint
+// 'lang' query parameter limits gaps to a single language
+var GetTranslationGaps = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetTranslationGaps)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	translator, apiError := checkTranslationAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	languages := make([]string, 0)
+	lang := r.URL.Query().Get("lang")
+	for _, language := range i18n.SupportedLanguages {
+		if len(lang) > 0 && language != lang {
+			continue
+		}
+		if translator == nil || translator.CanTranslate(language) {
+			languages = append(languages, language)
+		}
+	}
+
+	organisation, apiError := models.GetOrganisation(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	orgMap, apiError := p2pModels.ModelToMap(organisation)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	gaps := addTranslationGaps(make([]*translationGap, 0), translationModelOrganisation, organisationID, orgMap, p2pModels.OrganisationMultilangFields, languages)
+
+	offerings, apiError := models.GetOrganisationOfferings(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	for _, offering := range offerings {
+		offeringMap, apiError := p2pModels.ModelToMap(offering)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+		gaps = addTranslationGaps(gaps, translationModelOffering, offering.ID, offeringMap, p2pModels.OfferingMultilangFields, languages)
+	}
+
+	cigExchange.Respond(w, gaps)
+}
+
+// UpdateOfferingTranslations handles PATCH organisations/{organisation_id}/offerings/{offering_id}/translations endpoint
+var UpdateOfferingTranslations = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateOfferingTranslations)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	offeringID := mux.Vars(r)["offering_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	translator, apiError := checkTranslationAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	existingOffering, apiError := models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if existingOffering.OrganisationID != organisationID {
+		info.APIError = cigExchange.NewAccessRightsError("Offering doesn't belong to organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &translationsRequest{}
+	// decode translations from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	updateMap, apiError := prepareTranslationsUpdate(req, translator, existingOffering, p2pModels.OfferingMultilangFields)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// removing translations must not break publication requirements
+	apiError = checkOfferingPublication(organisationID, existingOffering, updateMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	offering := &models.Offering{}
+	// convert multilang fields to jsonb
+	cigExchange.ConvertRequestMapToJSONB(&updateMap, offering)
+
+	// set the offering UUID
+	offering.ID = offeringID
+	updateMap["id"] = offeringID
+
+	// update offering
+	apiError = offering.Update(updateMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// return updated offering
+	existingOffering, apiError = models.GetOffering(offeringID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	notifications.OfferingChanged(p2pModels.NotificationTypeOfferingUpdated, existingOffering, loggedInUser.UserUUID)
+	webhooks.OfferingChanged(p2pModels.WebhookEventOfferingUpdated, existingOffering)
+
+	// add multilang fields
+	offeringMMap, apiError := cigExchange.PrepareResponseForMultilangModel(existingOffering)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, offeringMMap)
+}
+
+// UpdateOrganisationTranslations handles PATCH organisations/{organisation_id}/translations endpoint
+var UpdateOrganisationTranslations = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateOrganisationTranslations)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	translator, apiError := checkTranslationAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// organisation members can translate, but only admins can edit organisation
+	if translator == nil {
+		apiError = checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	existingOrganisation, apiError := models.GetOrganisation(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &translationsRequest{}
+	// decode translations from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	updateMap, apiError := prepareTranslationsUpdate(req, translator, existingOrganisation, p2pModels.OrganisationMultilangFields)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	organisation := &models.Organisation{}
+	// convert multilang fields to jsonb
+	cigExchange.ConvertRequestMapToJSONB(&updateMap, organisation)
+
+	// set the organisation UUID
+	organisation.ID = organisationID
+	updateMap["id"] = organisationID
+
+	// update organisation
+	apiError = organisation.Update(updateMap)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// return updated organisation
+	existingOrganisation, apiError = models.GetOrganisation(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// add multilang fields
+	orgMap, apiError := cigExchange.PrepareResponseForMultilangModel(existingOrganisation)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, orgMap)
+}
+
+// GetTranslationSettings handles GET organisations/{organisation_id}/translations/settings endpoint
+var GetTranslationSettings = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetTranslationSettings)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	_, apiError := checkTranslationAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	settings, apiError := p2pModels.GetTranslationSettings(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, settings)
+}
+
+// UpdateTranslationSettings handles PATCH organisations/{organisation_id}/translations/settings endpoint
+var UpdateTranslationSettings = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateTranslationSettings)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	settings, apiError := p2pModels.GetTranslationSettings(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &translationSettingsRequest{}
+	// decode settings from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if req.RequiredLanguages != nil {
+		settings.RequiredLanguages, apiError = validateLanguages("required_languages", *req.RequiredLanguages)
+		if apiError != nil {
+			info.APIError = apiError
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+	if req.BlockPublication != nil {
+		settings.BlockPublication = *req.BlockPublication
+	}
+
+	apiError = settings.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, settings)
+}
+
+// GetTranslators handles GET organisations/{organisation_id}/translators endpoint
+var GetTranslators = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetTranslators)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	translators, apiError := p2pModels.GetTranslators(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, translators)
+}
+
+// SetTranslator handles PUT organisations/{organisation_id}/translators/{user_id} endpoint
+var SetTranslator = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeSetTranslator)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// check that user exists
+	_, apiError = models.GetUser(userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &translatorRequest{}
+	// decode translator from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	languages, apiError := validateLanguages("languages", req.Languages)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	translator, apiError := p2pModels.GetTranslator(organisationID, userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if translator == nil {
+		translator = &p2pModels.Translator{OrganisationID: organisationID, UserID: userID}
+	}
+	translator.Languages = languages
+
+	apiError = translator.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, translator)
+}
+
+// DeleteTranslator handles DELETE organisations/{organisation_id}/translators/{user_id} endpoint
+var DeleteTranslator = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteTranslator)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	userID := mux.Vars(r)["user_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	translator, apiError := p2pModels.GetTranslator(organisationID, userID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	if translator == nil {
+		info.APIError = cigExchange.NewInvalidFieldError("user_id", "User is not a translator of the organisation")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = translator.Delete()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
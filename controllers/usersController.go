@@ -4,12 +4,30 @@ import (
 	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
 	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
 	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
+// userWithVerification adds investor profile verification state to user response
+type userWithVerification struct {
+	*models.User
+	KYCStatus string `json:"kyc_status"`
+	KYCLevel  int    `json:"kyc_level"`
+}
+
+// addUserVerification loads investor profile verification state for the user
+func addUserVerification(user *models.User) (*userWithVerification, *cigExchange.APIError) {
+
+	profile, apiError := p2pModels.GetInvestorProfile(user.ID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return &userWithVerification{User: user, KYCStatus: profile.Status, KYCLevel: profile.KYCLevel}, nil
+}
+
 // GetUser handles GET users/{user_id} endpoint
 var GetUser = func(w http.ResponseWriter, r *http.Request) {
 
@@ -51,7 +69,14 @@ var GetUser = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cigExchange.Respond(w, existingUser)
+	userResponse, apiError := addUserVerification(existingUser)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, userResponse)
 }
 
 // UpdateUser handles PATCH users/{user_id} endpoint
@@ -119,5 +144,12 @@ var UpdateUser = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cigExchange.Respond(w, existingUser)
+	userResponse, apiError := addUserVerification(existingUser)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, userResponse)
 }
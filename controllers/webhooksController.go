@@ -0,0 +1,404 @@
+package controllers
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/webhooks"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// default and maximum size of webhook delivery log
+const (
+	defaultWebhookDeliveriesLimit = 50
+	maxWebhookDeliveriesLimit     = 500
+)
+
+type webhookRequest struct {
+	URL      *string  `json:"url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// webhookWithSecret is returned only once, when webhook is created
+type webhookWithSecret struct {
+	*p2pModels.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// validateWebhookRequest checks url and events and applies them to subscription
+func validateWebhookRequest(req *webhookRequest, subscription *p2pModels.WebhookSubscription) *cigExchange.APIError {
+
+	if req.URL != nil {
+		parsedURL, err := url.Parse(*req.URL)
+		if err != nil || len(parsedURL.Host) == 0 {
+			return cigExchange.NewInvalidFieldError("url", "Webhook url is invalid")
+		}
+		// plain http is allowed only for local development
+		if parsedURL.Scheme != "https" && !(parsedURL.Scheme == "http" && cigExchange.IsDevEnv()) {
+			return cigExchange.NewInvalidFieldError("url", "Webhook url must use https")
+		}
+		// webhooks must not reach internal services, address is checked again on every delivery
+		err = webhooks.CheckHost(parsedURL.Hostname())
+		if err != nil {
+			return cigExchange.NewInvalidFieldError("url", "Webhook url must point to a public address")
+		}
+		subscription.URL = *req.URL
+	}
+
+	if req.Events != nil {
+		if len(req.Events) == 0 {
+			return cigExchange.NewInvalidFieldError("events", "At least one event is required")
+		}
+		for _, event := range req.Events {
+			if !p2pModels.IsValidWebhookEvent(event) {
+				return cigExchange.NewInvalidFieldError("events", "Unknown webhook event: "+event)
+			}
+		}
+		subscription.Events = req.Events
+	}
+
+	if req.IsActive != nil {
+		subscription.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// GetWebhooks handles GET organisations/{organisation_id}/webhooks endpoint
+var GetWebhooks = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetWebhooks)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscriptions, apiError := p2pModels.GetWebhookSubscriptions(organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, subscriptions)
+}
+
+// CreateWebhook handles POST organisations/{organisation_id}/webhooks endpoint
+var CreateWebhook = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeCreateWebhook)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &webhookRequest{}
+	// decode webhook request from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	missingFields := make([]string, 0)
+	if req.URL == nil {
+		missingFields = append(missingFields, "url")
+	}
+	if req.Events == nil {
+		missingFields = append(missingFields, "events")
+	}
+	if len(missingFields) > 0 {
+		info.APIError = cigExchange.NewRequiredFieldError(missingFields)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription := &p2pModels.WebhookSubscription{
+		OrganisationID: organisationID,
+		IsActive:       true,
+	}
+	apiError = validateWebhookRequest(req, subscription)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription.Secret, err = webhooks.GenerateSecret()
+	if err != nil {
+		info.APIError = cigExchange.NewReadError("Unable to generate webhook secret", err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = subscription.Create()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	// secret is needed to verify signatures, it isn't returned anywhere else
+	cigExchange.Respond(w, &webhookWithSecret{WebhookSubscription: subscription, Secret: subscription.Secret})
+}
+
+// UpdateWebhook handles PATCH organisations/{organisation_id}/webhooks/{webhook_id} endpoint
+var UpdateWebhook = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeUpdateWebhook)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	webhookID := mux.Vars(r)["webhook_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetWebhookSubscription(organisationID, webhookID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	req := &webhookRequest{}
+	// decode webhook request from request body
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		info.APIError = cigExchange.NewRequestDecodingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = validateWebhookRequest(req, subscription)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = subscription.Save()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, subscription)
+}
+
+// DeleteWebhook handles DELETE organisations/{organisation_id}/webhooks/{webhook_id} endpoint
+var DeleteWebhook = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeDeleteWebhook)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	webhookID := mux.Vars(r)["webhook_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetWebhookSubscription(organisationID, webhookID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	apiError = subscription.Delete()
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetWebhookDeliveries handles GET organisations/{organisation_id}/webhooks/{webhook_id}/deliveries endpoint
+// supports 'limit' query parameter
+var GetWebhookDeliveries = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeGetWebhookDeliveries)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	webhookID := mux.Vars(r)["webhook_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetWebhookSubscription(organisationID, webhookID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	limit := defaultWebhookDeliveriesLimit
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxWebhookDeliveriesLimit {
+			info.APIError = cigExchange.NewInvalidFieldError("limit", "Limit must be between 1 and "+strconv.Itoa(maxWebhookDeliveriesLimit))
+			cigExchange.RespondWithAPIError(w, info.APIError)
+			return
+		}
+	}
+
+	deliveries, apiError := p2pModels.GetWebhookDeliveries(subscription.ID, limit)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, deliveries)
+}
+
+// SendWebhookTestEvent handles POST organisations/{organisation_id}/webhooks/{webhook_id}/test endpoint
+var SendWebhookTestEvent = func(w http.ResponseWriter, r *http.Request) {
+
+	// create user activity record and print error with defer
+	info := cigExchange.PrepareActivityInformation(r)
+	defer auth.CreateUserActivity(info, p2pModels.ActivityTypeSendWebhookTestEvent)
+	defer cigExchange.PrintAPIError(info)
+
+	// get request params
+	organisationID := mux.Vars(r)["organisation_id"]
+	webhookID := mux.Vars(r)["webhook_id"]
+
+	// load context user info
+	loggedInUser, err := auth.GetContextValues(r)
+	if err != nil {
+		info.APIError = cigExchange.NewRoutingError(err)
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+	info.LoggedInUser = loggedInUser
+
+	apiError := checkOrganisationAdminAccess(loggedInUser.UserUUID, organisationID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	subscription, apiError := p2pModels.GetWebhookSubscription(organisationID, webhookID)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	if !subscription.IsActive {
+		info.APIError = cigExchange.NewInvalidFieldError("webhook_id", "Webhook is disabled")
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"message": "Test event sent from CIG Exchange",
+		"sent_by": loggedInUser.UserUUID,
+		"sent_at": time.Now().UTC(),
+	}
+	delivery, apiError := webhooks.SendTestEvent(subscription, data)
+	if apiError != nil {
+		info.APIError = apiError
+		cigExchange.RespondWithAPIError(w, info.APIError)
+		return
+	}
+
+	cigExchange.Respond(w, delivery)
+}
@@ -0,0 +1,67 @@
+package documents
+
+import (
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// certificateTimeFormat is used for all certificate timestamps, always in UTC
+const certificateTimeFormat = "2006-01-02 15:04:05 UTC"
+
+// CertificateField is a labelled value printed in the certificate summary
+type CertificateField struct {
+	Label string
+	Value string
+}
+
+// CertificateEvent is a single audit trail entry
+type CertificateEvent struct {
+	Time        time.Time
+	Description string
+}
+
+// Certificate is the audit page appended to signed documents
+type Certificate struct {
+	Title  string
+	Fields []CertificateField
+	Events []CertificateEvent
+}
+
+// render prints the certificate on a new page
+func (certificate *Certificate) render(pdf *gofpdf.Fpdf, translate func(string) string) {
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.MultiCell(0, 7, translate(certificate.Title), "", "L", false)
+	pdf.Ln(4)
+
+	for _, field := range certificate.Fields {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(45, 5, translate(field.Label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 5, translate(field.Value), "", "L", false)
+		pdf.Ln(1)
+	}
+
+	if len(certificate.Events) == 0 {
+		return
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.MultiCell(0, 6, "Audit trail", "", "L", false)
+	pdf.Ln(1)
+	for _, event := range certificate.Events {
+		pdf.SetFont("Courier", "", 8)
+		pdf.CellFormat(45, 5, event.Time.UTC().Format(certificateTimeFormat), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 5, translate(event.Description), "", "L", false)
+	}
+}
+
+// FormatCertificateTime formats timestamps the way certificate prints them
+func FormatCertificateTime(t time.Time) string {
+
+	return t.UTC().Format(certificateTimeFormat)
+}
@@ -0,0 +1,109 @@
+package documents
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// headingPrefix marks body lines printed as section headings
+const headingPrefix = "# "
+
+// Document is a plain text document rendered as A4 PDF.
+// Body paragraphs are separated by blank lines, lines starting with "# " are section headings.
+// Rendering is deterministic, the same document always produces the same PDF bytes.
+// Optional certificate is appended as the last page of signed documents
+type Document struct {
+	Title       string
+	Body        string
+	Footer      string
+	CreatedAt   time.Time
+	Certificate *Certificate
+}
+
+// paragraphs splits body into paragraphs, line breaks inside paragraphs are kept
+func paragraphs(body string) []string {
+
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	result := make([]string, 0)
+	current := make([]string, 0)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if len(line) == 0 || strings.HasPrefix(line, headingPrefix) {
+			if len(current) > 0 {
+				result = append(result, strings.Join(current, "\n"))
+				current = current[:0]
+			}
+			if len(line) > 0 {
+				result = append(result, line)
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		result = append(result, strings.Join(current, "\n"))
+	}
+	return result
+}
+
+// PDF renders the document with title on the first page and footer with page numbers on every page
+func (document *Document) PDF() ([]byte, error) {
+
+	if len(strings.TrimSpace(document.Body)) == 0 {
+		return nil, errors.New("document body is empty")
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 25)
+	pdf.SetCatalogSort(true)
+	pdf.SetCreationDate(document.CreatedAt.UTC())
+	pdf.SetModificationDate(document.CreatedAt.UTC())
+	pdf.SetTitle(document.Title, true)
+	pdf.AliasNbPages("")
+	// core fonts use cp1252 encoding
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-18)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(96, 96, 96)
+		pdf.CellFormat(140, 5, translate(document.Footer), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo())+" / {nb}", "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.MultiCell(0, 8, translate(document.Title), "", "L", false)
+	pdf.Ln(6)
+
+	for _, paragraph := range paragraphs(document.Body) {
+		if strings.HasPrefix(paragraph, headingPrefix) {
+			pdf.Ln(2)
+			pdf.SetFont("Helvetica", "B", 12)
+			pdf.MultiCell(0, 6, translate(strings.TrimSpace(strings.TrimPrefix(paragraph, headingPrefix))), "", "L", false)
+			pdf.Ln(1)
+			continue
+		}
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, translate(paragraph), "", "J", false)
+		pdf.Ln(3)
+	}
+
+	if document.Certificate != nil {
+		document.Certificate.render(pdf, translate)
+	}
+
+	buffer := &bytes.Buffer{}
+	err := pdf.Output(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -3,8 +3,12 @@ package main
 import (
 	"bytes"
 	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/signatures"
 	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	cigExchange "cig-exchange-libs"
 
@@ -40,6 +44,24 @@ func main() {
 	invitedUserUUID := ""
 	invitationCode := ""
 	contactUUID := ""
+	webhookUUID := ""
+	savedSearchUUID := ""
+	questionUUID := ""
+	kycDocumentUUID := ""
+	distributionUUID := ""
+	ledgerAccountUUID := ""
+	listingUUID := ""
+	bidUUID := ""
+	subscriptionUUID := ""
+	subscriptionReference := ""
+	bankTransactionUUID := ""
+	payoutUUID := ""
+	payoutBatchUUID := ""
+	bankAccountUUID := ""
+	pendingSubscriptionUUID := ""
+	agreementTemplateUUID := ""
+	agreementUUID := ""
+	signatureRequestUUID := ""
 
 	// prepare the database:
 	// 1. delete 'dredd' users if it exists (first name  = 'dredd', 'dredd2', 'dredd3', 'dredd4')
@@ -190,6 +212,16 @@ func main() {
 		t.Fail = "Pre-created offering is missing"
 	})
 
+	h.Before("Trading/Offerings > invest/api/offerings/{offering} > Offering", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/invest/api/offerings/" + offeringID
+		t.FullPath = "/invest/api/offerings/" + offeringID
+	})
+
 	h.Before("Trading/Users > invest/api/users/signup > Signup", func(t *trans.Transaction) {
 
 		if t.Request == nil {
@@ -908,53 +940,2043 @@ func main() {
 		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/users/" + dredd4.ID
 	})
 
-	server.Serve()
-	defer server.Listener.Close()
-}
+	// captured emails are only available in DEV with the capture mail backend
+	h.Before("Trading/Dev > invest/api/dev/emails > Get captured emails", func(t *trans.Transaction) {
 
-func setBodyValue(body *string, key, value string) {
+		if t.Request == nil {
+			return
+		}
+		if !cigExchange.IsDevEnv() || os.Getenv("MAIL_BACKEND") != "capture" {
+			t.Skip = true
+			return
+		}
 
-	if body == nil {
-		return
-	}
+		t.Request.URI = "/invest/api/dev/emails?to=blackhole%2Bdev%2Btest%2Bdredd%40cig-exchange.ch"
+		t.FullPath = "/invest/api/dev/emails?to=blackhole%2Bdev%2Btest%2Bdredd%40cig-exchange.ch"
+	})
 
-	bodyMap := map[string]interface{}{}
+	h.Before("Trading/Dev > invest/api/dev/emails > Delete captured emails", func(t *trans.Transaction) {
 
-	err := json.Unmarshal([]byte(*body), &bodyMap)
-	if err != nil {
-		return
-	}
+		if t.Request == nil {
+			return
+		}
+		if !cigExchange.IsDevEnv() || os.Getenv("MAIL_BACKEND") != "capture" {
+			t.Skip = true
+		}
+	})
 
-	bodyMap[key] = value
-	b, err := json.Marshal(bodyMap)
-	if err != nil {
-		return
-	}
+	h.Before("P2P/Email Outbox > p2p/api/email-outbox/{outbox}/retry > Retry undelivered email", func(t *trans.Transaction) {
 
-	*body = string(b)
-}
+		if t.Request == nil {
+			return
+		}
 
-func getBodyValue(body *string, key string) (value string) {
+		// create undelivered email to retry
+		outbox := &p2pModels.EmailOutbox{
+			ToEmail:   "blackhole+dev+test+dredd@cig-exchange.ch",
+			ToName:    dredd,
+			Subject:   dredd,
+			Text:      dredd,
+			Status:    p2pModels.EmailOutboxStatusDead,
+			Attempts:  8,
+			LastError: dredd,
+		}
+		err := dbClient.Create(outbox).Error
+		if err != nil {
+			t.Fail = fmt.Sprintf("Unable to create outbox email: %v", err.Error())
+			return
+		}
 
-	if body == nil {
-		return
-	}
+		t.Request.URI = "/p2p/api/email-outbox/" + outbox.ID + "/retry"
+		t.FullPath = "/p2p/api/email-outbox/" + outbox.ID + "/retry"
+	})
 
-	bodyMap := map[string]interface{}{}
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications > Retrieve user notifications", func(t *trans.Transaction) {
 
-	err := json.Unmarshal([]byte(*body), &bodyMap)
-	if err != nil {
-		return
-	}
+		if t.Request == nil {
+			return
+		}
 
-	v, ok := bodyMap[key]
-	if ok {
-		// make sure it's a string
-		vs, ok := v.(string)
-		if ok {
-			value = vs
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications?unread=false&limit=50&offset=0"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications?unread=false&limit=50&offset=0"
+	})
+
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications/unread-count > Retrieve unread notifications count", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
 		}
-	}
 
-	return
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications/unread-count"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications/unread-count"
+	})
+
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications/preferences > Retrieve notification preferences", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications/preferences"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications/preferences"
+	})
+
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications/preferences > Update notification preferences", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications/preferences"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications/preferences"
+	})
+
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications/read > Mark all notifications read", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications/read"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications/read"
+	})
+
+	h.Before("P2P/Notifications > p2p/api/users/{user}/notifications/{notification}/read > Mark notification read", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// create notification to mark as read
+		notification := &p2pModels.Notification{
+			UserID:  userUUID,
+			Type:    p2pModels.NotificationTypeOfferingCreated,
+			Title:   dredd,
+			Message: dredd,
+		}
+		apiError := notification.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create notification: " + apiError.ToString()
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/notifications/" + notification.ID + "/read"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/notifications/" + notification.ID + "/read"
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks > Create webhook", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(orgUUID) == 0 {
+			t.Fail = "Organisation UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks"
+	})
+
+	h.After("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks > Create webhook", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		webhookUUID = getBodyValue(&t.Real.Body, "id")
+		if len(webhookUUID) == 0 {
+			t.Fail = "Unable to save webhook UUID"
+		}
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks > Retrieve webhooks", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks"
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks/{webhook}/deliveries > Retrieve webhook deliveries", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(webhookUUID) == 0 {
+			t.Fail = "Webhook UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID + "/deliveries?limit=50"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID + "/deliveries?limit=50"
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks/{webhook}/test > Send webhook test event", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(webhookUUID) == 0 {
+			t.Fail = "Webhook UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID + "/test"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID + "/test"
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks/{webhook} > Update webhook", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(webhookUUID) == 0 {
+			t.Fail = "Webhook UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID
+	})
+
+	h.Before("P2P/Webhooks > p2p/api/organisations/{organisation}/webhooks/{webhook} > Delete webhook", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(webhookUUID) == 0 {
+			t.Fail = "Webhook UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/webhooks/" + webhookUUID
+	})
+
+	h.Before("P2P/Watchlist > p2p/api/users/{user}/watchlist > Retrieve watchlist", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/watchlist"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/watchlist"
+	})
+
+	h.Before("P2P/Watchlist > p2p/api/users/{user}/watchlist/{offering} > Add offering to watchlist", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/watchlist/" + offeringID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/watchlist/" + offeringID
+	})
+
+	h.Before("P2P/Watchlist > p2p/api/users/{user}/watchlist/{offering} > Remove offering from watchlist", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/watchlist/" + offeringID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/watchlist/" + offeringID
+	})
+
+	h.Before("P2P/Saved Searches > p2p/api/users/{user}/saved-searches > Create saved search", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/saved-searches"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/saved-searches"
+	})
+
+	h.After("P2P/Saved Searches > p2p/api/users/{user}/saved-searches > Create saved search", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		savedSearchUUID = getBodyValue(&t.Real.Body, "id")
+		if len(savedSearchUUID) == 0 {
+			t.Fail = "Unable to save saved search UUID"
+		}
+	})
+
+	h.Before("P2P/Saved Searches > p2p/api/users/{user}/saved-searches > Retrieve saved searches", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/saved-searches"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/saved-searches"
+	})
+
+	h.Before("P2P/Saved Searches > p2p/api/users/{user}/saved-searches/{search} > Update saved search", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(savedSearchUUID) == 0 {
+			t.Fail = "Saved search UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/saved-searches/" + savedSearchUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/saved-searches/" + savedSearchUUID
+	})
+
+	h.Before("P2P/Saved Searches > p2p/api/users/{user}/saved-searches/{search} > Delete saved search", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(savedSearchUUID) == 0 {
+			t.Fail = "Saved search UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/saved-searches/" + savedSearchUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/saved-searches/" + savedSearchUUID
+	})
+
+	h.Before("Trading/Saved Searches > invest/api/saved-searches/unsubscribe > Unsubscribe saved search", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// saved search created above is deleted already, create a new one
+		search := &p2pModels.SavedSearch{
+			UserID:           userUUID,
+			Name:             dredd,
+			Criteria:         postgres.Jsonb{RawMessage: json.RawMessage(`{}`)},
+			Frequency:        p2pModels.SavedSearchFrequencyDaily,
+			Language:         "en",
+			EmailEnabled:     true,
+			UnsubscribeToken: cigExchange.RandomUUID(),
+		}
+		apiError := search.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create saved search: " + apiError.ToString()
+			return
+		}
+
+		t.Request.URI = "/invest/api/saved-searches/unsubscribe?token=" + search.UnsubscribeToken + "&all=false"
+		t.FullPath = "/invest/api/saved-searches/unsubscribe?token=" + search.UnsubscribeToken + "&all=false"
+	})
+
+	h.Before("P2P/Questions > p2p/api/users/{user}/questions > Ask question", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/questions"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/questions"
+
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+	})
+
+	h.After("P2P/Questions > p2p/api/users/{user}/questions > Ask question", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		questionUUID = getBodyValue(&t.Real.Body, "id")
+		if len(questionUUID) == 0 {
+			t.Fail = "Unable to save question UUID"
+		}
+	})
+
+	h.Before("P2P/Questions > p2p/api/users/{user}/questions > Retrieve user questions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/questions"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/questions"
+	})
+
+	h.Before("P2P/Questions > p2p/api/users/{user}/questions/{question}/replies > Reply to question", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(questionUUID) == 0 {
+			t.Fail = "Question UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/questions/" + questionUUID + "/replies"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/questions/" + questionUUID + "/replies"
+	})
+
+	h.Before("P2P/Questions > p2p/api/organisations/{organisation}/offerings/{offering}/questions > Retrieve offering questions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions"
+	})
+
+	h.Before("P2P/Questions > p2p/api/organisations/{organisation}/offerings/{offering}/questions/{question}/replies > Answer question", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(questionUUID) == 0 {
+			t.Fail = "Question UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID + "/replies"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID + "/replies"
+	})
+
+	h.Before("P2P/Questions > p2p/api/organisations/{organisation}/offerings/{offering}/questions/{question}/replies/{reply} > Delete question reply", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(questionUUID) == 0 {
+			t.Fail = "Question UUID missing"
+			return
+		}
+
+		// reply id isn't returned on the top level, take the latest reply of the question
+		reply := &p2pModels.OfferingQuestionReply{}
+		err := dbClient.Where(&p2pModels.OfferingQuestionReply{QuestionID: questionUUID}).Order("created_at desc").First(reply).Error
+		if err != nil {
+			t.Fail = "Unable to find question reply: " + err.Error()
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID + "/replies/" + reply.ID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID + "/replies/" + reply.ID
+	})
+
+	h.Before("P2P/Questions > p2p/api/organisations/{organisation}/offerings/{offering}/questions/{question} > Moderate question", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(questionUUID) == 0 {
+			t.Fail = "Question UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID
+	})
+
+	h.Before("P2P/Questions > p2p/api/organisations/{organisation}/offerings/{offering}/questions/{question} > Delete question", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(questionUUID) == 0 {
+			t.Fail = "Question UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/questions/" + questionUUID
+	})
+
+	h.Before("Trading/Questions > invest/api/offerings/{offering}/questions > Retrieve public offering questions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/invest/api/offerings/" + offeringID + "/questions"
+		t.FullPath = "/invest/api/offerings/" + offeringID + "/questions"
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile > Retrieve investor profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile"
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile > Update investor profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile"
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile/documents > Upload KYC document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile/documents?type=" + p2pModels.KYCDocumentTypePassport
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile/documents?type=" + p2pModels.KYCDocumentTypePassport
+
+		// minimal pdf header is enough for the content type detection
+		t.Request.Body = "%PDF-1.4\n%dredd\n"
+	})
+
+	h.After("P2P/Investor Profile > p2p/api/users/{user}/investor-profile/documents > Upload KYC document", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		kycDocumentUUID = getBodyValue(&t.Real.Body, "id")
+		if len(kycDocumentUUID) == 0 {
+			t.Fail = "Unable to save KYC document UUID"
+		}
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile/documents/{document}/file > Download KYC document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(kycDocumentUUID) == 0 {
+			t.Fail = "KYC document UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile/documents/" + kycDocumentUUID + "/file"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile/documents/" + kycDocumentUUID + "/file"
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile/documents/{document} > Delete KYC document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// passport is required for the submission, delete an additional document
+		media := &models.Media{
+			Type:          p2pModels.MediaTypeKYCDocument,
+			Title:         p2pModels.KYCDocumentTypeOther,
+			MimeType:      "application/pdf",
+			FileExtension: ".pdf",
+		}
+		err := dbClient.Create(media).Error
+		if err != nil {
+			t.Fail = "Unable to create KYC document media: " + err.Error()
+			return
+		}
+		document := &p2pModels.KYCDocument{
+			UserID:  userUUID,
+			Type:    p2pModels.KYCDocumentTypeOther,
+			MediaID: media.ID,
+		}
+		apiError := document.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create KYC document: " + apiError.ToString()
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile/documents/" + document.ID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile/documents/" + document.ID
+	})
+
+	h.Before("P2P/Investor Profile > p2p/api/users/{user}/investor-profile/submit > Submit investor profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/investor-profile/submit"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/investor-profile/submit"
+	})
+
+	h.Before("P2P/KYC > p2p/api/kyc/profiles > Retrieve KYC profiles", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/kyc/profiles?status=" + p2pModels.KYCStatusPending
+		t.FullPath = "/p2p/api/kyc/profiles?status=" + p2pModels.KYCStatusPending
+	})
+
+	h.Before("P2P/KYC > p2p/api/kyc/profiles/{user} > Retrieve KYC profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/kyc/profiles/" + userUUID
+		t.FullPath = "/p2p/api/kyc/profiles/" + userUUID
+	})
+
+	h.Before("P2P/KYC > p2p/api/kyc/profiles/{user}/reject > Reject KYC profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/kyc/profiles/" + userUUID + "/reject"
+		t.FullPath = "/p2p/api/kyc/profiles/" + userUUID + "/reject"
+	})
+
+	h.Before("P2P/KYC > p2p/api/kyc/profiles/{user}/approve > Approve KYC profile", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// profile was rejected above, submit it again
+		profile, apiError := p2pModels.GetInvestorProfile(userUUID)
+		if apiError == nil {
+			apiError = profile.Submit()
+		}
+		if apiError != nil {
+			t.Fail = "Unable to submit investor profile: " + apiError.ToString()
+			return
+		}
+
+		t.Request.URI = "/p2p/api/kyc/profiles/" + userUUID + "/approve"
+		t.FullPath = "/p2p/api/kyc/profiles/" + userUUID + "/approve"
+	})
+
+	h.Before("P2P/Offering Eligibility > p2p/api/organisations/{organisation}/offerings/{offering}/eligibility > Retrieve offering eligibility", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/eligibility"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/eligibility"
+	})
+
+	h.Before("P2P/Offering Eligibility > p2p/api/organisations/{organisation}/offerings/{offering}/eligibility > Update offering eligibility", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/eligibility"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/eligibility"
+
+		// pre-created offering is used by other tests, keep it available to everybody
+		t.Request.Body = `{"investor_categories":[],"allowed_countries":[],"blocked_countries":[],"min_kyc_level":0}`
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translations > Retrieve translation coverage", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translations"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translations"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translations > Update organisation translations", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translations"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translations"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translations/gaps > Retrieve translation gaps", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translations/gaps?lang=fr"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translations/gaps?lang=fr"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translations/settings > Retrieve translation settings", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translations/settings"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translations/settings"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translations/settings > Update translation settings", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translations/settings"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translations/settings"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/offerings/{offering}/translations > Update offering translations", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/translations"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/translations"
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translators/{user} > Set translator", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translators/" + dredd4.ID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translators/" + dredd4.ID
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translators/{user} > Delete translator", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translators/" + dredd4.ID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translators/" + dredd4.ID
+	})
+
+	h.Before("P2P/Translations > p2p/api/organisations/{organisation}/translators > Retrieve translators", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/translators"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/translators"
+	})
+
+	h.Before("P2P/FX Rates > p2p/api/fx-rates > Retrieve FX rates", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/fx-rates"
+		t.FullPath = "/p2p/api/fx-rates"
+	})
+
+	h.Before("P2P/Loan Terms > p2p/api/organisations/{organisation}/offerings/{offering}/terms > Update loan terms", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/terms"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/terms"
+	})
+
+	h.Before("P2P/Loan Terms > p2p/api/organisations/{organisation}/offerings/{offering}/terms > Retrieve loan terms", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/terms"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/terms"
+	})
+
+	h.Before("P2P/Loan Terms > p2p/api/organisations/{organisation}/offerings/{offering}/schedule > Retrieve offering schedule", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/schedule?amount=1000&start_date=2019-01-31"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/schedule?amount=1000&start_date=2019-01-31"
+	})
+
+	h.Before("Trading/Loan Terms > invest/api/offerings/{offering}/schedule > Retrieve offering schedule preview", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/invest/api/offerings/" + offeringID + "/schedule?amount=1000"
+		t.FullPath = "/invest/api/offerings/" + offeringID + "/schedule?amount=1000"
+	})
+
+	h.Before("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/holdings/{user} > Set offering holding", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/holdings/" + userUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/holdings/" + userUUID
+	})
+
+	h.Before("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/holdings > Retrieve offering holdings", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/holdings"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/holdings"
+	})
+
+	h.Before("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/distributions > Create offering distribution", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions"
+
+		// dredd user is admin, distribution is recorded without bank receipt
+		setBodyValue(&t.Request.Body, "bank_transaction_id", "")
+	})
+
+	h.After("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/distributions > Create offering distribution", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		distributionUUID = getBodyValue(&t.Real.Body, "id")
+		if len(distributionUUID) == 0 {
+			t.Fail = "Unable to save distribution UUID"
+		}
+	})
+
+	h.Before("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/distributions > Retrieve offering distributions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions"
+	})
+
+	h.Before("P2P/Distributions > p2p/api/organisations/{organisation}/offerings/{offering}/distributions/{distribution} > Retrieve offering distribution", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(distributionUUID) == 0 {
+			t.Fail = "Distribution UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions/" + distributionUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/distributions/" + distributionUUID
+	})
+
+	h.Before("P2P/Distributions > p2p/api/users/{user}/holdings > Retrieve user holdings", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/holdings"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/holdings"
+	})
+
+	h.Before("P2P/Distributions > p2p/api/users/{user}/distributions > Retrieve user distributions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/distributions"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/distributions"
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts/deposits > Create deposit", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts/deposits"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts/deposits"
+
+		// idempotency keys must be unique between test runs
+		setBodyValue(&t.Request.Body, "idempotency_key", cigExchange.RandomUUID())
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts/withdrawals > Create withdrawal", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts/withdrawals"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts/withdrawals"
+
+		setBodyValue(&t.Request.Body, "idempotency_key", cigExchange.RandomUUID())
+		// deposit above covers both withdrawal and commitment
+		setBodyValue(&t.Request.Body, "amount", "10")
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts/commitments > Create commitment", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts/commitments"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts/commitments"
+
+		setBodyValue(&t.Request.Body, "idempotency_key", cigExchange.RandomUUID())
+		// deposit above covers both withdrawal and commitment
+		setBodyValue(&t.Request.Body, "amount", "10")
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts > Retrieve user accounts", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts"
+	})
+
+	h.After("P2P/Ledger > p2p/api/users/{user}/accounts > Retrieve user accounts", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		accounts := make([]*p2pModels.Account, 0)
+		err := json.Unmarshal([]byte(t.Real.Body), &accounts)
+		if err != nil || len(accounts) == 0 {
+			t.Fail = "Unable to save ledger account UUID"
+			return
+		}
+		ledgerAccountUUID = accounts[0].ID
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts/{account} > Retrieve user account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(ledgerAccountUUID) == 0 {
+			t.Fail = "Ledger account UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts/" + ledgerAccountUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts/" + ledgerAccountUUID
+	})
+
+	h.Before("P2P/Ledger > p2p/api/users/{user}/accounts/{account}/entries > Retrieve user account entries", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(ledgerAccountUUID) == 0 {
+			t.Fail = "Ledger account UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/accounts/" + ledgerAccountUUID + "/entries"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/accounts/" + ledgerAccountUUID + "/entries"
+	})
+
+	h.Before("P2P/Ledger > p2p/api/organisations/{organisation}/offerings/{offering}/accounts > Retrieve offering accounts", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/accounts"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/offerings/" + offeringID + "/accounts"
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/listings > Create listing", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// only funded offerings are traded, commitment above created offering money
+		err := dbClient.Model(&p2pModels.OfferingMoney{}).Where(&p2pModels.OfferingMoney{OfferingID: offeringID}).Update("remaining", p2pModels.NewDecimal(0)).Error
+		if err != nil {
+			t.Fail = "Unable to fund offering"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/listings"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/listings"
+
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+	})
+
+	h.After("P2P/Market > p2p/api/users/{user}/market/listings > Create listing", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		response := struct {
+			Order *p2pModels.Listing `json:"order"`
+		}{}
+		err := json.Unmarshal([]byte(t.Real.Body), &response)
+		if err != nil || response.Order == nil {
+			t.Fail = "Unable to save listing UUID"
+			return
+		}
+		listingUUID = response.Order.ID
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/listings > Retrieve user listings", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/listings"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/listings"
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/bids > Create bid", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/bids"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/bids"
+
+		// own listing above is never matched with the bid
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+	})
+
+	h.After("P2P/Market > p2p/api/users/{user}/market/bids > Create bid", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		response := struct {
+			Order *p2pModels.Bid `json:"order"`
+		}{}
+		err := json.Unmarshal([]byte(t.Real.Body), &response)
+		if err != nil || response.Order == nil {
+			t.Fail = "Unable to save bid UUID"
+			return
+		}
+		bidUUID = response.Order.ID
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/bids > Retrieve user bids", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/bids"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/bids"
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/trades > Retrieve user trades", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/trades"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/trades"
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/listings/{listing} > Cancel listing", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(listingUUID) == 0 {
+			t.Fail = "Listing UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/listings/" + listingUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/listings/" + listingUUID
+	})
+
+	h.Before("P2P/Market > p2p/api/users/{user}/market/bids/{bid} > Cancel bid", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(bidUUID) == 0 {
+			t.Fail = "Bid UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/market/bids/" + bidUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/market/bids/" + bidUUID
+	})
+
+	h.Before("Trading/Market > invest/api/offerings/{offering}/market > Retrieve offering order book", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/invest/api/offerings/" + offeringID + "/market"
+		t.FullPath = "/invest/api/offerings/" + offeringID + "/market"
+	})
+
+	h.Before("Trading/Market > invest/api/offerings/{offering}/price-history > Retrieve offering price history", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/invest/api/offerings/" + offeringID + "/price-history"
+		t.FullPath = "/invest/api/offerings/" + offeringID + "/price-history"
+	})
+
+	h.After("Trading/Market > invest/api/offerings/{offering}/price-history > Retrieve offering price history", func(t *trans.Transaction) {
+
+		// restore offering remaining amount funded for the market above
+		money := &p2pModels.OfferingMoney{}
+		err := dbClient.Where(&p2pModels.OfferingMoney{OfferingID: offeringID}).First(money).Error
+		if err != nil {
+			t.Fail = "Unable to restore offering remaining amount"
+			return
+		}
+		money.UpdateRemaining()
+		err = dbClient.Save(money).Error
+		if err != nil {
+			t.Fail = "Unable to restore offering remaining amount"
+		}
+	})
+
+	h.Before("P2P/Subscriptions > p2p/api/users/{user}/subscriptions > Create subscription", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions"
+
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+	})
+
+	h.After("P2P/Subscriptions > p2p/api/users/{user}/subscriptions > Create subscription", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		subscriptionUUID = getBodyValue(&t.Real.Body, "id")
+		subscriptionReference = getBodyValue(&t.Real.Body, "reference")
+		if len(subscriptionUUID) == 0 || len(subscriptionReference) == 0 {
+			t.Fail = "Unable to save subscription UUID"
+		}
+	})
+
+	h.Before("P2P/Subscriptions > p2p/api/users/{user}/subscriptions > Retrieve user subscriptions", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions"
+	})
+
+	h.Before("P2P/Subscriptions > p2p/api/users/{user}/subscriptions/{subscription} > Cancel subscription", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// subscription created above is paid with the statement, cancel a new one
+		offering, apiError := models.GetOffering(offeringID)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+		money, apiError := p2pModels.GetOfferingMoney(offering)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+		subscription := &p2pModels.Subscription{
+			OfferingID: offeringID,
+			UserID:     userUUID,
+			Amount:     p2pModels.NewDecimal(10),
+		}
+		apiError = subscription.Create(money)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions/" + subscription.ID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions/" + subscription.ID
+	})
+
+	h.Before("P2P/Payments > p2p/api/payments/statements > Import payment statement", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(subscriptionReference) == 0 {
+			t.Fail = "Subscription reference missing"
+			return
+		}
+
+		// notification pays the subscription created above, payment without reference goes to the exceptions queue
+		messageID := "dredd-" + cigExchange.RandomUUID()[24:]
+		t.Request.Body = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.04">
+  <BkToCstmrDbtCdtNtfctn>
+    <GrpHdr>
+      <MsgId>` + messageID + `</MsgId>
+    </GrpHdr>
+    <Ntfctn>
+      <Id>` + messageID + `</Id>
+      <Ntry>
+        <Amt Ccy="CHF">20.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt>
+          <Dt>2019-03-01</Dt>
+        </BookgDt>
+        <AcctSvcrRef>` + messageID + `-1</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <RmtInf>
+              <Strd>
+                <CdtrRefInf>
+                  <Ref>` + subscriptionReference + `</Ref>
+                </CdtrRefInf>
+              </Strd>
+            </RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CHF">25.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt>
+          <Dt>2019-03-01</Dt>
+        </BookgDt>
+        <AcctSvcrRef>` + messageID + `-2</AcctSvcrRef>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>`
+	})
+
+	h.After("P2P/Payments > p2p/api/payments/statements > Import payment statement", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		response := struct {
+			Transactions []*p2pModels.BankTransaction `json:"transactions"`
+		}{}
+		err := json.Unmarshal([]byte(t.Real.Body), &response)
+		if err != nil {
+			t.Fail = "Unable to save bank transaction UUID"
+			return
+		}
+		for _, transaction := range response.Transactions {
+			if transaction.Status == p2pModels.BankTransactionStatusException {
+				bankTransactionUUID = transaction.ID
+			}
+		}
+		if len(bankTransactionUUID) == 0 {
+			t.Fail = "Unable to save bank transaction UUID"
+		}
+	})
+
+	h.Before("P2P/Payments > p2p/api/payments/exceptions/{transaction}/resolve > Resolve payment exception", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(bankTransactionUUID) == 0 {
+			t.Fail = "Bank transaction UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/payments/exceptions/" + bankTransactionUUID + "/resolve"
+		t.FullPath = "/p2p/api/payments/exceptions/" + bankTransactionUUID + "/resolve"
+
+		setBodyValue(&t.Request.Body, "user_id", userUUID)
+	})
+
+	h.Before("P2P/QR-bills > p2p/api/organisations/{organisation}/payment-account > Update organisation payment account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/payment-account"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/payment-account"
+	})
+
+	h.Before("P2P/QR-bills > p2p/api/organisations/{organisation}/payment-account > Retrieve organisation payment account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/payment-account"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/payment-account"
+	})
+
+	h.Before("P2P/QR-bills > p2p/api/users/{user}/subscriptions/{subscription}/qr-bill > Retrieve subscription QR-bill", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// QR-bill is available only for pending subscriptions, subscriptions above are paid or cancelled
+		offering, apiError := models.GetOffering(offeringID)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+		money, apiError := p2pModels.GetOfferingMoney(offering)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+		subscription := &p2pModels.Subscription{
+			OfferingID: offeringID,
+			UserID:     userUUID,
+			Amount:     p2pModels.NewDecimal(10),
+		}
+		apiError = subscription.Create(money)
+		if apiError != nil {
+			t.Fail = "Unable to create subscription"
+			return
+		}
+		// subscription stays pending for the agreement hooks
+		pendingSubscriptionUUID = subscription.ID
+
+		// QR-bill data are checked, pdf and svg payment parts aren't json
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions/" + subscription.ID + "/qr-bill"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions/" + subscription.ID + "/qr-bill"
+	})
+
+	h.Before("P2P/Payouts > p2p/api/users/{user}/payouts > Create payout", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// bank account changes are confirmed with OTP, payout account is created directly
+		account := &p2pModels.BankAccount{
+			UserID:     userUUID,
+			HolderName: dredd,
+			IBAN:       "CH9300762011623852957",
+			IsDefault:  true,
+		}
+		err := dbClient.Create(account).Error
+		if err != nil {
+			t.Fail = "Unable to create bank account"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/payouts"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/payouts"
+
+		setBodyValue(&t.Request.Body, "bank_account_id", account.ID)
+		setBodyValue(&t.Request.Body, "execution_date", "")
+	})
+
+	h.After("P2P/Payouts > p2p/api/users/{user}/payouts > Create payout", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		payoutUUID = getBodyValue(&t.Real.Body, "id")
+		if len(payoutUUID) == 0 {
+			t.Fail = "Unable to save payout UUID"
+		}
+	})
+
+	h.Before("P2P/Payouts > p2p/api/users/{user}/payouts > Retrieve user payouts", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/payouts"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/payouts"
+	})
+
+	h.Before("P2P/Payouts > p2p/api/payouts/batches > Create payout batch", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		// payment orders debit the escrow account
+		if p2pModels.GetEscrowPaymentAccount() == nil {
+			t.Skip = true
+			return
+		}
+
+		// payout created above is due today
+		setBodyValue(&t.Request.Body, "until", "")
+	})
+
+	h.After("P2P/Payouts > p2p/api/payouts/batches > Create payout batch", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		response := struct {
+			Batch *p2pModels.PayoutBatch `json:"batch"`
+		}{}
+		err := json.Unmarshal([]byte(t.Real.Body), &response)
+		if err != nil || response.Batch == nil {
+			t.Fail = "Unable to save payout batch UUID"
+			return
+		}
+		payoutBatchUUID = response.Batch.ID
+	})
+
+	h.Before("P2P/Payouts > p2p/api/payouts/batches/{batch} > Retrieve payout batch", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(payoutBatchUUID) == 0 {
+			t.Skip = true
+			return
+		}
+
+		t.Request.URI = "/p2p/api/payouts/batches/" + payoutBatchUUID
+		t.FullPath = "/p2p/api/payouts/batches/" + payoutBatchUUID
+	})
+
+	h.Before("P2P/Payouts > p2p/api/payouts/batches/{batch}/file > Download payout batch", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(payoutBatchUUID) == 0 {
+			t.Skip = true
+			return
+		}
+
+		t.Request.URI = "/p2p/api/payouts/batches/" + payoutBatchUUID + "/file"
+		t.FullPath = "/p2p/api/payouts/batches/" + payoutBatchUUID + "/file"
+	})
+
+	h.Before("P2P/Payouts > p2p/api/payouts/batches/{batch}/reject > Reject payout batch", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(payoutBatchUUID) == 0 {
+			t.Skip = true
+			return
+		}
+
+		t.Request.URI = "/p2p/api/payouts/batches/" + payoutBatchUUID + "/reject"
+		t.FullPath = "/p2p/api/payouts/batches/" + payoutBatchUUID + "/reject"
+	})
+
+	h.Before("P2P/Payouts > p2p/api/payouts/batches/{batch}/execute > Execute payout batch", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		escrow := p2pModels.GetEscrowPaymentAccount()
+		if escrow == nil {
+			t.Skip = true
+			return
+		}
+
+		// payouts of the rejected batch above are pending again and exported with a new batch
+		batch, _, apiError := p2pModels.CreatePayoutBatch(time.Now(), escrow, userUUID)
+		if apiError != nil {
+			t.Fail = "Unable to create payout batch"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/payouts/batches/" + batch.ID + "/execute"
+		t.FullPath = "/p2p/api/payouts/batches/" + batch.ID + "/execute"
+	})
+
+	h.Before("P2P/Payouts > p2p/api/users/{user}/payouts/{payout} > Cancel payout", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(payoutUUID) == 0 {
+			t.Fail = "Payout UUID missing"
+			return
+		}
+
+		// payout created above may be executed already, cancel a new one to the same account
+		paid, apiError := p2pModels.GetUserPayout(userUUID, payoutUUID)
+		if apiError != nil {
+			t.Fail = "Unable to create payout"
+			return
+		}
+		payout := &p2pModels.Payout{
+			UserID:        userUUID,
+			Currency:      paid.Currency,
+			Amount:        paid.Amount,
+			BankAccountID: paid.BankAccountID,
+			CreditorName:  paid.CreditorName,
+			IBAN:          paid.IBAN,
+			CreatedBy:     userUUID,
+		}
+		apiError = payout.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create payout"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/payouts/" + payout.ID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/payouts/" + payout.ID
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts > Create bank account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts"
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts > Retrieve user bank accounts", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts"
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts/changes/{change}/confirm > Confirm bank account change", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// code is returned in DEV environment only, change is requested directly to get the code
+		change := &p2pModels.BankAccountChange{
+			UserID:     userUUID,
+			Action:     p2pModels.BankAccountChangeCreate,
+			HolderName: dredd,
+			IBAN:       "DE89370400440532013000",
+		}
+		code, apiError := change.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create bank account change"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts/changes/" + change.ID + "/confirm"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts/changes/" + change.ID + "/confirm"
+
+		setBodyValue(&t.Request.Body, "code", code)
+	})
+
+	h.After("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts/changes/{change}/confirm > Confirm bank account change", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		accounts := make([]*p2pModels.BankAccount, 0)
+		err := json.Unmarshal([]byte(t.Real.Body), &accounts)
+		if err != nil {
+			t.Fail = "Unable to save bank account UUID"
+			return
+		}
+		for _, account := range accounts {
+			if account.IBAN == "DE89370400440532013000" {
+				bankAccountUUID = account.ID
+			}
+		}
+		if len(bankAccountUUID) == 0 {
+			t.Fail = "Unable to save bank account UUID"
+		}
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts/{bank_account} > Update bank account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(bankAccountUUID) == 0 {
+			t.Fail = "Bank account UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts/{bank_account} > Delete bank account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(bankAccountUUID) == 0 {
+			t.Fail = "Bank account UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID
+	})
+
+	h.Before("P2P/Bank Accounts > p2p/api/users/{user}/bank-accounts/{bank_account}/default > Set default bank account", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(bankAccountUUID) == 0 {
+			t.Fail = "Bank account UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID + "/default"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/bank-accounts/" + bankAccountUUID + "/default"
+	})
+
+	h.Before("P2P/Agreements > p2p/api/organisations/{organisation}/agreement-templates > Create agreement template", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/agreement-templates"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/agreement-templates"
+	})
+
+	h.After("P2P/Agreements > p2p/api/organisations/{organisation}/agreement-templates > Create agreement template", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		agreementTemplateUUID = getBodyValue(&t.Real.Body, "id")
+		if len(agreementTemplateUUID) == 0 {
+			t.Fail = "Unable to save agreement template UUID"
+		}
+	})
+
+	h.Before("P2P/Agreements > p2p/api/organisations/{organisation}/agreement-templates > Retrieve agreement templates", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/agreement-templates?kind=" + p2pModels.AgreementTemplateKindSubscription + "&language=en"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/agreement-templates?kind=" + p2pModels.AgreementTemplateKindSubscription + "&language=en"
+	})
+
+	h.Before("P2P/Agreements > p2p/api/organisations/{organisation}/agreement-templates/{template}/preview > Preview agreement template", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(agreementTemplateUUID) == 0 {
+			t.Fail = "Agreement template UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/agreement-templates/" + agreementTemplateUUID + "/preview"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/agreement-templates/" + agreementTemplateUUID + "/preview"
+	})
+
+	h.Before("P2P/Agreements > p2p/api/users/{user}/subscriptions/{subscription}/agreement > Create subscription agreement", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(pendingSubscriptionUUID) == 0 {
+			t.Fail = "Subscription UUID missing"
+			return
+		}
+
+		// subscription was created before the template, so it has no agreement yet
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement?lang=en"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement?lang=en"
+	})
+
+	h.After("P2P/Agreements > p2p/api/users/{user}/subscriptions/{subscription}/agreement > Create subscription agreement", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		agreementUUID = getBodyValue(&t.Real.Body, "id")
+		if len(agreementUUID) == 0 {
+			t.Fail = "Unable to save agreement UUID"
+		}
+	})
+
+	h.Before("P2P/Agreements > p2p/api/users/{user}/subscriptions/{subscription}/agreement > Retrieve subscription agreement", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(pendingSubscriptionUUID) == 0 {
+			t.Fail = "Subscription UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement"
+	})
+
+	h.Before("P2P/Agreements > p2p/api/users/{user}/subscriptions/{subscription}/agreement/file > Download subscription agreement", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(pendingSubscriptionUUID) == 0 {
+			t.Fail = "Subscription UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement/file"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/subscriptions/" + pendingSubscriptionUUID + "/agreement/file"
+	})
+
+	h.Before("P2P/Agreements > p2p/api/organisations/{organisation}/agreements > Retrieve organisation agreements", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/agreements?offering_id=" + offeringID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/agreements?offering_id=" + offeringID
+	})
+
+	h.Before("P2P/Agreements > p2p/api/organisations/{organisation}/agreements/{agreement}/file > Download organisation agreement", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(agreementUUID) == 0 {
+			t.Fail = "Agreement UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/agreements/" + agreementUUID + "/file"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/agreements/" + agreementUUID + "/file"
+	})
+
+	h.Before("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests > Create signature request", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		ndaTemplate := &p2pModels.AgreementTemplate{
+			OrganisationID: orgUUID,
+			Kind:           p2pModels.AgreementTemplateKindNDA,
+			Language:       "en",
+			Title:          "Non-disclosure agreement",
+			Body:           "{{.Investor.Name}} keeps information about {{.Offering.Title}} of {{.Organisation}} confidential.",
+			CreatedBy:      userUUID,
+		}
+		apiError := ndaTemplate.Create()
+		if apiError != nil {
+			t.Fail = "Unable to create NDA template"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/signature-requests"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/signature-requests"
+
+		setBodyValue(&t.Request.Body, "template_id", ndaTemplate.ID)
+		setBodyValue(&t.Request.Body, "user_id", userUUID)
+		setBodyValue(&t.Request.Body, "offering_id", offeringID)
+		setBodyValue(&t.Request.Body, "agreement_id", "")
+	})
+
+	h.After("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests > Create signature request", func(t *trans.Transaction) {
+
+		if t.Real == nil {
+			return
+		}
+
+		signatureRequestUUID = getBodyValue(&t.Real.Body, "id")
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Unable to save signature request UUID"
+		}
+	})
+
+	h.Before("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests > Retrieve organisation signature requests", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/signature-requests?status=" + p2pModels.SignatureStatusPending
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/signature-requests?status=" + p2pModels.SignatureStatusPending
+	})
+
+	h.Before("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests/{request} > Retrieve organisation signature request", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + signatureRequestUUID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + signatureRequestUUID
+	})
+
+	h.Before("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests/{request} > Cancel signature request", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// NDA request above is signed later, agreement request is cancelled
+		request, apiError := getAgreementSignatureRequest(orgUUID, agreementUUID, userUUID)
+		if apiError != nil {
+			t.Fail = "Unable to create signature request"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + request.ID
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + request.ID
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests > Retrieve user signature requests", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests?status=" + p2pModels.SignatureStatusPending
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests?status=" + p2pModels.SignatureStatusPending
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request} > Retrieve user signature request", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request}/document > Download signature document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/document"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/document"
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request}/code > Request signature code", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/code"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/code"
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request}/sign > Sign document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		// code is returned in DEV environment only, new code is issued directly
+		request, apiError := p2pModels.GetUserSignatureRequest(userUUID, signatureRequestUUID)
+		if apiError != nil {
+			t.Fail = "Unable to load signature request"
+			return
+		}
+		code, apiError := request.NewCode("127.0.0.1")
+		if apiError != nil {
+			t.Fail = "Unable to create signature code"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/sign"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/sign"
+
+		setBodyValue(&t.Request.Body, "code", code)
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request}/file > Download signed document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/file"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + signatureRequestUUID + "/file"
+	})
+
+	h.Before("P2P/Signatures > p2p/api/users/{user}/signature-requests/{request}/decline > Decline signature request", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+
+		// agreement request was cancelled above, it's sent again to be declined
+		request, apiError := getAgreementSignatureRequest(orgUUID, agreementUUID, userUUID)
+		if apiError != nil {
+			t.Fail = "Unable to create signature request"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/users/" + userUUID + "/signature-requests/" + request.ID + "/decline"
+		t.FullPath = "/p2p/api/users/" + userUUID + "/signature-requests/" + request.ID + "/decline"
+	})
+
+	h.Before("P2P/Signatures > p2p/api/organisations/{organisation}/signature-requests/{request}/file > Download organisation signed document", func(t *trans.Transaction) {
+
+		if t.Request == nil {
+			return
+		}
+		if len(signatureRequestUUID) == 0 {
+			t.Fail = "Signature request UUID missing"
+			return
+		}
+
+		t.Request.URI = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + signatureRequestUUID + "/file"
+		t.FullPath = "/p2p/api/organisations/" + orgUUID + "/signature-requests/" + signatureRequestUUID + "/file"
+	})
+
+	server.Serve()
+	defer server.Listener.Close()
+}
+
+func setBodyValue(body *string, key, value string) {
+
+	if body == nil {
+		return
+	}
+
+	bodyMap := map[string]interface{}{}
+
+	err := json.Unmarshal([]byte(*body), &bodyMap)
+	if err != nil {
+		return
+	}
+
+	bodyMap[key] = value
+	b, err := json.Marshal(bodyMap)
+	if err != nil {
+		return
+	}
+
+	*body = string(b)
+}
+
+func getBodyValue(body *string, key string) (value string) {
+
+	if body == nil {
+		return
+	}
+
+	bodyMap := map[string]interface{}{}
+
+	err := json.Unmarshal([]byte(*body), &bodyMap)
+	if err != nil {
+		return
+	}
+
+	v, ok := bodyMap[key]
+	if ok {
+		// make sure it's a string
+		vs, ok := v.(string)
+		if ok {
+			value = vs
+		}
+	}
+
+	return
+}
+
+// getAgreementSignatureRequest returns pending signature request of the subscription agreement,
+// agreement is sent for signature again if it has none
+func getAgreementSignatureRequest(organisationID, agreementID, userID string) (*p2pModels.SignatureRequest, *cigExchange.APIError) {
+
+	agreement, apiError := p2pModels.GetOrganisationAgreement(organisationID, agreementID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	request, apiError := p2pModels.GetPendingSignatureRequest(p2pModels.SignatureDocumentSubscriptionAgreement, agreement.ID)
+	if apiError != nil || request != nil {
+		return request, apiError
+	}
+
+	request = p2pModels.NewAgreementSignatureRequest(agreement, userID)
+	apiError = request.Create(signatures.Get().Name())
+	if apiError != nil {
+		return nil, apiError
+	}
+	return request, nil
 }
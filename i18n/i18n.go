@@ -0,0 +1,209 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the last language of every fallback chain
+const DefaultLanguage = "en"
+
+// LanguageAll query value requests full translation maps, used by the P2P editing UI
+const LanguageAll = "all"
+
+// multilangMapSuffix is appended to field names by PrepareResponseForMultilangModel
+const multilangMapSuffix = "_map"
+
+// SupportedLanguages lists languages of multilang fields
+var SupportedLanguages = []string{"en", "fr", "it", "de"}
+
+var (
+	fallbackChains     map[string][]string
+	fallbackChainsOnce sync.Once
+)
+
+// loadFallbackChains parses LANGUAGE_FALLBACK env variable.
+// Format is comma separated chains, e.g. "fr:en,it:fr:en" means fr→en and it→fr→en.
+// Languages without a chain fall back to the default language only
+func loadFallbackChains() {
+
+	fallbackChains = make(map[string][]string)
+
+	config := strings.Replace(os.Getenv("LANGUAGE_FALLBACK"), "\"", "", -1)
+	for _, chain := range strings.Split(config, ",") {
+		languages := make([]string, 0)
+		for _, language := range strings.Split(chain, ":") {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if !IsSupportedLanguage(language) {
+				if len(language) > 0 {
+					fmt.Println("LANGUAGE_FALLBACK: unsupported language " + language)
+				}
+				continue
+			}
+			languages = append(languages, language)
+		}
+		if len(languages) > 1 {
+			fallbackChains[languages[0]] = languages[1:]
+		}
+	}
+}
+
+// IsSupportedLanguage checks language code
+func IsSupportedLanguage(language string) bool {
+
+	for _, l := range SupportedLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackChain returns languages to try for the requested languages in order of preference
+func FallbackChain(languages ...string) []string {
+
+	fallbackChainsOnce.Do(loadFallbackChains)
+
+	chain := make([]string, 0)
+	added := make(map[string]bool)
+	add := func(language string) {
+		if !added[language] {
+			added[language] = true
+			chain = append(chain, language)
+		}
+	}
+
+	for _, language := range languages {
+		add(language)
+		for _, fallback := range fallbackChains[language] {
+			add(fallback)
+		}
+	}
+	add(DefaultLanguage)
+	return chain
+}
+
+// Negotiate returns fallback chain for the request, 'lang' query parameter has priority over Accept-Language header.
+// Header is used only if useHeader is set. Returns nil if full translation maps should be kept
+func Negotiate(r *http.Request, useHeader bool) []string {
+
+	lang := strings.ToLower(r.URL.Query().Get("lang"))
+	if lang == LanguageAll {
+		return nil
+	}
+	if IsSupportedLanguage(lang) {
+		return FallbackChain(lang)
+	}
+
+	header := r.Header.Get("Accept-Language")
+	if !useHeader || len(header) == 0 {
+		return nil
+	}
+	return FallbackChain(parseAcceptLanguage(header)...)
+}
+
+type weightedLanguage struct {
+	language string
+	quality  float64
+}
+
+// parseAcceptLanguage returns supported languages from Accept-Language header ordered by quality
+func parseAcceptLanguage(header string) []string {
+
+	weighted := make([]weightedLanguage, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(strings.TrimSpace(part), ";")
+		// only primary subtag is used, "fr-CH" is "fr"
+		language := strings.ToLower(strings.SplitN(strings.TrimSpace(params[0]), "-", 2)[0])
+		if !IsSupportedLanguage(language) {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		weighted = append(weighted, weightedLanguage{language: language, quality: quality})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].quality > weighted[j].quality
+	})
+
+	languages := make([]string, 0, len(weighted))
+	for _, w := range weighted {
+		languages = append(languages, w.language)
+	}
+	return languages
+}
+
+// Translate returns the first non empty translation from the chain or any available translation
+func Translate(translations map[string]string, chain []string) string {
+
+	for _, language := range chain {
+		if value := translations[language]; len(value) > 0 {
+			return value
+		}
+	}
+
+	// keep the result stable if none of the chain languages is translated
+	languages := make([]string, 0, len(translations))
+	for language := range translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		if value := translations[language]; len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// TranslateJSON decodes multilang json field and translates it
+func TranslateJSON(raw json.RawMessage, chain []string) string {
+
+	translations := make(map[string]string)
+	if len(raw) == 0 || json.Unmarshal(raw, &translations) != nil {
+		return ""
+	}
+	return Translate(translations, chain)
+}
+
+// CollapseMultilangFields replaces every "<field>_map" translation map with the "<field>" value in the chain language
+func CollapseMultilangFields(model map[string]interface{}, chain []string) {
+
+	for key, value := range model {
+		if !strings.HasSuffix(key, multilangMapSuffix) {
+			continue
+		}
+
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		translations := make(map[string]string)
+		if json.Unmarshal(valueBytes, &translations) != nil {
+			// not a translation map
+			continue
+		}
+
+		model[strings.TrimSuffix(key, multilangMapSuffix)] = Translate(translations, chain)
+		delete(model, key)
+	}
+}
@@ -0,0 +1,235 @@
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Constants defining supported cash management messages
+const (
+	MessageTypeCamt053 = "camt.053" // bank to customer statement
+	MessageTypeCamt054 = "camt.054" // bank to customer debit credit notification
+)
+
+// creditorReferenceRegexp finds creditor reference in unstructured remittance information
+var creditorReferenceRegexp = regexp.MustCompile(`RF[0-9]{2}[A-Z0-9]{1,21}`)
+
+// Credit is a booked incoming payment of a statement
+type Credit struct {
+	AccountServicerReference string
+	EndToEndID               string
+	AccountIBAN              string
+	Amount                   string
+	Currency                 string
+	Reference                string
+	DebtorName               string
+	DebtorIBAN               string
+	BookingDate              time.Time
+}
+
+// Statement is a parsed camt.053 statement or camt.054 notification, only booked credits are kept
+type Statement struct {
+	MessageID string
+	Type      string
+	CreatedAt time.Time
+	Credits   []*Credit
+}
+
+type camtDocument struct {
+	Statement    *camtMessage `xml:"BkToCstmrStmt"`
+	Notification *camtMessage `xml:"BkToCstmrDbtCdtNtfctn"`
+}
+
+type camtMessage struct {
+	MessageID     string        `xml:"GrpHdr>MsgId"`
+	CreatedAt     string        `xml:"GrpHdr>CreDtTm"`
+	Statements    []*camtReport `xml:"Stmt"`
+	Notifications []*camtReport `xml:"Ntfctn"`
+}
+
+type camtReport struct {
+	ID          string       `xml:"Id"`
+	AccountIBAN string       `xml:"Acct>Id>IBAN"`
+	Entries     []*camtEntry `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus supports plain status of older versions and status code of camt versions 8 and later
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Reference                string             `xml:"NtryRef"`
+	Amount                   camtAmount         `xml:"Amt"`
+	CreditDebit              string             `xml:"CdtDbtInd"`
+	Status                   camtStatus         `xml:"Sts"`
+	BookingDate              camtDate           `xml:"BookgDt"`
+	AccountServicerReference string             `xml:"AcctSvcrRef"`
+	Transactions             []*camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	AccountServicerReference string      `xml:"Refs>AcctSvcrRef"`
+	EndToEndID               string      `xml:"Refs>EndToEndId"`
+	Amount                   *camtAmount `xml:"Amt"`
+	CreditDebit              string      `xml:"CdtDbtInd"`
+	DebtorName               string      `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPartyName          string      `xml:"RltdPties>Dbtr>Pty>Nm"`
+	DebtorIBAN               string      `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	References               []string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Unstructured             []string    `xml:"RmtInf>Ustrd"`
+}
+
+// value returns booked, pending or information status of the entry
+func (status camtStatus) value() string {
+
+	if code := strings.TrimSpace(status.Code); len(code) > 0 {
+		return code
+	}
+	return strings.TrimSpace(status.Value)
+}
+
+// parse parses date or date time of the entry
+func (date camtDate) parse() time.Time {
+
+	if len(date.Date) > 0 {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(date.Date))
+		if err == nil {
+			return parsed
+		}
+	}
+	if len(date.DateTime) > 0 {
+		parsed, err := parseDateTime(date.DateTime)
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// parseDateTime parses ISO date time with or without time zone
+func parseDateTime(value string) (time.Time, error) {
+
+	value = strings.TrimSpace(value)
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.Parse("2006-01-02T15:04:05", value)
+	}
+	return parsed, err
+}
+
+// reference returns structured creditor reference or creditor reference found in unstructured remittance information
+func (transaction *camtTransaction) reference() string {
+
+	for _, reference := range transaction.References {
+		if reference = strings.TrimSpace(reference); len(reference) > 0 {
+			return reference
+		}
+	}
+	for _, unstructured := range transaction.Unstructured {
+		compact := strings.ToUpper(strings.Join(strings.Fields(unstructured), ""))
+		if match := creditorReferenceRegexp.FindString(compact); len(match) > 0 {
+			return match
+		}
+	}
+	return ""
+}
+
+// ParseCamt parses camt.053 statement or camt.054 notification.
+// Entries without transaction details are treated as single credits, debits and entries not booked are skipped
+func ParseCamt(data []byte) (*Statement, error) {
+
+	document := &camtDocument{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{}
+	message := document.Statement
+	reports := make([]*camtReport, 0)
+	switch {
+	case document.Statement != nil:
+		statement.Type = MessageTypeCamt053
+		reports = document.Statement.Statements
+	case document.Notification != nil:
+		statement.Type = MessageTypeCamt054
+		message = document.Notification
+		reports = document.Notification.Notifications
+	default:
+		return nil, errors.New("document is not a camt.053 statement or camt.054 notification")
+	}
+
+	statement.MessageID = strings.TrimSpace(message.MessageID)
+	if len(statement.MessageID) == 0 {
+		return nil, errors.New("message id is missing")
+	}
+	statement.CreatedAt, _ = parseDateTime(message.CreatedAt)
+
+	statement.Credits = make([]*Credit, 0)
+	for _, report := range reports {
+		for entryIndex, entry := range report.Entries {
+			if strings.TrimSpace(entry.CreditDebit) != "CRDT" || entry.Status.value() != "BOOK" {
+				continue
+			}
+
+			transactions := entry.Transactions
+			if len(transactions) == 0 {
+				transactions = []*camtTransaction{{}}
+			}
+			for transactionIndex, transaction := range transactions {
+				if len(transaction.CreditDebit) > 0 && strings.TrimSpace(transaction.CreditDebit) != "CRDT" {
+					continue
+				}
+
+				credit := &Credit{
+					AccountServicerReference: strings.TrimSpace(transaction.AccountServicerReference),
+					EndToEndID:               strings.TrimSpace(transaction.EndToEndID),
+					AccountIBAN:              strings.TrimSpace(report.AccountIBAN),
+					Amount:                   strings.TrimSpace(entry.Amount.Value),
+					Currency:                 strings.TrimSpace(entry.Amount.Currency),
+					Reference:                transaction.reference(),
+					DebtorName:               strings.TrimSpace(transaction.DebtorName),
+					DebtorIBAN:               strings.TrimSpace(transaction.DebtorIBAN),
+					BookingDate:              entry.BookingDate.parse(),
+				}
+				if transaction.Amount != nil && len(strings.TrimSpace(transaction.Amount.Value)) > 0 {
+					credit.Amount = strings.TrimSpace(transaction.Amount.Value)
+					credit.Currency = strings.TrimSpace(transaction.Amount.Currency)
+				}
+				if len(credit.DebtorName) == 0 {
+					credit.DebtorName = strings.TrimSpace(transaction.DebtorPartyName)
+				}
+				// banks don't always send transaction references, position in the message keeps credits unique
+				if len(credit.AccountServicerReference) == 0 {
+					credit.AccountServicerReference = strings.TrimSpace(entry.AccountServicerReference)
+					if len(credit.AccountServicerReference) == 0 {
+						credit.AccountServicerReference = statement.MessageID + "/" + report.ID + "/" + strconv.Itoa(entryIndex)
+					}
+					if len(transactions) > 1 {
+						credit.AccountServicerReference += "/" + strconv.Itoa(transactionIndex)
+					}
+				}
+				statement.Credits = append(statement.Credits, credit)
+			}
+		}
+	}
+	return statement, nil
+}
@@ -0,0 +1,173 @@
+package iso20022
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func parseTestdata(t *testing.T, name string) *Statement {
+
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("unable to read %s: %v", name, err)
+	}
+	statement, err := ParseCamt(data)
+	if err != nil {
+		t.Fatalf("unable to parse %s: %v", name, err)
+	}
+	return statement
+}
+
+func TestParseCamt053(t *testing.T) {
+
+	statement := parseTestdata(t, "camt053.xml")
+
+	if statement.Type != MessageTypeCamt053 || statement.MessageID != "STMT-20190301-0001" {
+		t.Fatalf("unexpected statement %s %s", statement.Type, statement.MessageID)
+	}
+	if !statement.CreatedAt.Equal(time.Date(2019, 3, 1, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected creation time %v", statement.CreatedAt)
+	}
+
+	// debit entry is skipped, batch booking is split into its transactions
+	expected := []Credit{
+		{
+			AccountServicerReference: "ZKB-20190301-0001-1",
+			EndToEndID:               "NOTPROVIDED",
+			AccountIBAN:              "CH4431999123000889012",
+			Amount:                   "1000.00",
+			Currency:                 "CHF",
+			Reference:                "210000000003139471430009017",
+			DebtorName:               "Hans Muster",
+			DebtorIBAN:               "CH9300762011623852957",
+		},
+		{
+			AccountServicerReference: "ZKB-20190301-0002-1",
+			AccountIBAN:              "CH4431999123000889012",
+			Amount:                   "500.00",
+			Currency:                 "CHF",
+			Reference:                "RF941234567890123456",
+			DebtorName:               "Anna Beispiel",
+		},
+		{
+			AccountServicerReference: "ZKB-20190301-0002-2",
+			AccountIBAN:              "CH4431999123000889012",
+			Amount:                   "250.00",
+			Currency:                 "CHF",
+			DebtorName:               "Peter Unbekannt",
+		},
+	}
+	if len(statement.Credits) != len(expected) {
+		t.Fatalf("expected %d credits, got %d", len(expected), len(statement.Credits))
+	}
+	bookingDate := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, credit := range statement.Credits {
+		expected[i].BookingDate = bookingDate
+		if *credit != expected[i] {
+			t.Errorf("credit %d: expected %+v, got %+v", i, expected[i], *credit)
+		}
+	}
+}
+
+func TestParseCamt054(t *testing.T) {
+
+	statement := parseTestdata(t, "camt054.xml")
+
+	if statement.Type != MessageTypeCamt054 || statement.MessageID != "NTFCTN-20190302-0001" {
+		t.Fatalf("unexpected statement %s %s", statement.Type, statement.MessageID)
+	}
+
+	// pending entry is skipped
+	if len(statement.Credits) != 1 {
+		t.Fatalf("expected 1 credit, got %d", len(statement.Credits))
+	}
+	credit := statement.Credits[0]
+
+	// transaction without reference falls back to the entry reference
+	if credit.AccountServicerReference != "ZKB-20190302-0001" {
+		t.Errorf("unexpected account servicer reference %q", credit.AccountServicerReference)
+	}
+	// amount of the entry is used for transaction without amount
+	if credit.Amount != "1200.00" || credit.Currency != "CHF" {
+		t.Errorf("unexpected amount %s %s", credit.Amount, credit.Currency)
+	}
+	// creditor reference is found in unstructured remittance information
+	if credit.Reference != "RF941234567890123456" {
+		t.Errorf("unexpected reference %q", credit.Reference)
+	}
+	if credit.EndToEndID != "E2E-4711" || credit.DebtorName != "Maria Muster" || credit.DebtorIBAN != "DE89370400440532013000" {
+		t.Errorf("unexpected debtor %+v", *credit)
+	}
+	if !credit.BookingDate.Equal(time.Date(2019, 3, 2, 8, 15, 0, 0, time.UTC)) {
+		t.Errorf("unexpected booking date %v", credit.BookingDate)
+	}
+}
+
+func TestParseCamtWithoutAccountServicerReference(t *testing.T) {
+
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.04">
+  <BkToCstmrDbtCdtNtfctn>
+    <GrpHdr>
+      <MsgId>NTFCTN-1</MsgId>
+    </GrpHdr>
+    <Ntfctn>
+      <Id>N1</Id>
+      <Ntry>
+        <Amt Ccy="CHF">100.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">30.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <NtryDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">10.00</Amt>
+          </TxDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">20.00</Amt>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>`)
+
+	statement, err := ParseCamt(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// position in the message keeps credits unique
+	expected := map[string]string{
+		"NTFCTN-1/N1/0":   "100.00",
+		"NTFCTN-1/N1/1/0": "10.00",
+		"NTFCTN-1/N1/1/1": "20.00",
+	}
+	if len(statement.Credits) != len(expected) {
+		t.Fatalf("expected %d credits, got %d", len(expected), len(statement.Credits))
+	}
+	for _, credit := range statement.Credits {
+		if expected[credit.AccountServicerReference] != credit.Amount {
+			t.Errorf("unexpected credit %s %s", credit.AccountServicerReference, credit.Amount)
+		}
+	}
+}
+
+func TestParseCamtInvalid(t *testing.T) {
+
+	cases := map[string]string{
+		"not xml":        "statement",
+		"other message":  `<Document><CstmrCdtTrfInitn></CstmrCdtTrfInitn></Document>`,
+		"missing msg id": `<Document><BkToCstmrStmt><GrpHdr></GrpHdr></BkToCstmrStmt></Document>`,
+	}
+	for name, data := range cases {
+		_, err := ParseCamt([]byte(data))
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pain001Namespace is the namespace of customer credit transfer initiation version 9
+const pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+
+// notProvided is the financial institution id used when BIC is unknown
+const notProvided = "NOTPROVIDED"
+
+// Party is a debtor or creditor with the bank account, BIC is optional
+type Party struct {
+	Name string
+	IBAN string
+	BIC  string
+}
+
+// CreditTransfer is a single payment of the payment order
+type CreditTransfer struct {
+	EndToEndID string
+	Amount     string
+	Currency   string
+	Creditor   Party
+	Remittance string
+}
+
+// PaymentGroup contains credit transfers requested for the same execution date in the same currency, id is generated from the message id if not set
+type PaymentGroup struct {
+	ID            string
+	ExecutionDate time.Time
+	Transfers     []*CreditTransfer
+}
+
+// PaymentOrder is a customer credit transfer initiation debiting the debtor account
+type PaymentOrder struct {
+	MessageID string
+	CreatedAt time.Time
+	Debtor    Party
+	Groups    []*PaymentGroup
+}
+
+type painDocument struct {
+	XMLName    xml.Name       `xml:"Document"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Initiation painInitiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type painInitiation struct {
+	MessageID            string              `xml:"GrpHdr>MsgId"`
+	CreatedAt            string              `xml:"GrpHdr>CreDtTm"`
+	NumberOfTransactions int                 `xml:"GrpHdr>NbOfTxs"`
+	ControlSum           string              `xml:"GrpHdr>CtrlSum"`
+	InitiatingParty      string              `xml:"GrpHdr>InitgPty>Nm"`
+	PaymentInformation   []*painPaymentGroup `xml:"PmtInf"`
+}
+
+type painOther struct {
+	ID string `xml:"Id"`
+}
+
+type painInstitution struct {
+	BIC   string     `xml:"BICFI,omitempty"`
+	Other *painOther `xml:"Othr,omitempty"`
+}
+
+type painAgent struct {
+	Institution painInstitution `xml:"FinInstnId"`
+}
+
+type painRemittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+type painPaymentGroup struct {
+	ID                   string          `xml:"PmtInfId"`
+	Method               string          `xml:"PmtMtd"`
+	BatchBooking         bool            `xml:"BtchBookg"`
+	NumberOfTransactions int             `xml:"NbOfTxs"`
+	ControlSum           string          `xml:"CtrlSum"`
+	ExecutionDate        string          `xml:"ReqdExctnDt>Dt"`
+	DebtorName           string          `xml:"Dbtr>Nm"`
+	DebtorIBAN           string          `xml:"DbtrAcct>Id>IBAN"`
+	DebtorAgent          painAgent       `xml:"DbtrAgt"`
+	Transfers            []*painTransfer `xml:"CdtTrfTxInf"`
+}
+
+type painAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type painTransfer struct {
+	EndToEndID    string          `xml:"PmtId>EndToEndId"`
+	Amount        painAmount      `xml:"Amt>InstdAmt"`
+	CreditorAgent *painAgent      `xml:"CdtrAgt,omitempty"`
+	CreditorName  string          `xml:"Cdtr>Nm"`
+	CreditorIBAN  string          `xml:"CdtrAcct>Id>IBAN"`
+	Remittance    *painRemittance `xml:"RmtInf,omitempty"`
+}
+
+// truncate limits text to the maximum number of characters allowed by the message field
+func truncate(text string, length int) string {
+
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > length {
+		return string(runes[:length])
+	}
+	return text
+}
+
+// controlSum adds decimal amounts
+type controlSum struct {
+	sum    *big.Rat
+	places int
+}
+
+func newControlSum() *controlSum {
+	return &controlSum{sum: new(big.Rat)}
+}
+
+func (control *controlSum) add(amount string) error {
+
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok || value.Sign() <= 0 {
+		return errors.New("invalid amount '" + amount + "'")
+	}
+	if dot := strings.Index(amount, "."); dot >= 0 && len(amount)-dot-1 > control.places {
+		control.places = len(amount) - dot - 1
+	}
+	control.sum.Add(control.sum, value)
+	return nil
+}
+
+func (control *controlSum) String() string {
+	return control.sum.FloatString(control.places)
+}
+
+// Pain001 renders the payment order as pain.001 customer credit transfer initiation XML
+func (order *PaymentOrder) Pain001() ([]byte, error) {
+
+	if len(order.MessageID) == 0 || len(order.MessageID) > 35 {
+		return nil, errors.New("message id must have 1 to 35 characters")
+	}
+	if len(order.Debtor.IBAN) == 0 || len(order.Debtor.Name) == 0 {
+		return nil, errors.New("debtor name and IBAN are required")
+	}
+
+	initiation := painInitiation{
+		MessageID:          order.MessageID,
+		CreatedAt:          order.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+		InitiatingParty:    truncate(order.Debtor.Name, 70),
+		PaymentInformation: make([]*painPaymentGroup, 0, len(order.Groups)),
+	}
+	debtorAgent := painAgent{Institution: painInstitution{BIC: order.Debtor.BIC}}
+	if len(order.Debtor.BIC) == 0 {
+		debtorAgent.Institution.Other = &painOther{ID: notProvided}
+	}
+
+	total := newControlSum()
+	for index, group := range order.Groups {
+		if len(group.Transfers) == 0 {
+			continue
+		}
+		paymentGroup := &painPaymentGroup{
+			ID:                   group.ID,
+			Method:               "TRF",
+			BatchBooking:         true,
+			NumberOfTransactions: len(group.Transfers),
+			ExecutionDate:        group.ExecutionDate.Format("2006-01-02"),
+			DebtorName:           truncate(order.Debtor.Name, 70),
+			DebtorIBAN:           order.Debtor.IBAN,
+			DebtorAgent:          debtorAgent,
+			Transfers:            make([]*painTransfer, 0, len(group.Transfers)),
+		}
+
+		if len(paymentGroup.ID) == 0 {
+			paymentGroup.ID = truncate(order.MessageID, 30) + "-" + strconv.Itoa(index+1)
+		}
+
+		groupSum := newControlSum()
+		for _, transfer := range group.Transfers {
+			err := groupSum.add(transfer.Amount)
+			if err == nil {
+				err = total.add(transfer.Amount)
+			}
+			if err != nil {
+				return nil, errors.New("transfer " + transfer.EndToEndID + ": " + err.Error())
+			}
+
+			painTransfer := &painTransfer{
+				EndToEndID:   transfer.EndToEndID,
+				Amount:       painAmount{Value: transfer.Amount, Currency: transfer.Currency},
+				CreditorName: truncate(transfer.Creditor.Name, 70),
+				CreditorIBAN: transfer.Creditor.IBAN,
+			}
+			if len(transfer.Creditor.BIC) > 0 {
+				painTransfer.CreditorAgent = &painAgent{Institution: painInstitution{BIC: transfer.Creditor.BIC}}
+			}
+			if remittance := truncate(transfer.Remittance, 140); len(remittance) > 0 {
+				painTransfer.Remittance = &painRemittance{Unstructured: remittance}
+			}
+			paymentGroup.Transfers = append(paymentGroup.Transfers, painTransfer)
+		}
+		paymentGroup.ControlSum = groupSum.String()
+
+		initiation.NumberOfTransactions += len(group.Transfers)
+		initiation.PaymentInformation = append(initiation.PaymentInformation, paymentGroup)
+	}
+	if initiation.NumberOfTransactions == 0 {
+		return nil, errors.New("payment order has no transfers")
+	}
+	initiation.ControlSum = total.String()
+
+	document := &painDocument{Xmlns: pain001Namespace, Initiation: initiation}
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.NewBufferString(xml.Header)
+	buffer.Write(data)
+	buffer.WriteString("\n")
+	return buffer.Bytes(), nil
+}
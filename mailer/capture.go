@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	cigExchange "cig-exchange-libs"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// maximum amount of emails kept in memory by capture backend
+const captureLimit = 200
+
+// CapturedEmail is an email stored by capture backend
+type CapturedEmail struct {
+	ID           string            `json:"id"`
+	Email        *Email            `json:"email"`
+	TemplateType *int              `json:"template_type,omitempty"`
+	Parameters   map[string]string `json:"parameters,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// CaptureMailer doesn't deliver anything, emails are kept in memory
+// and optionally written to a folder as json files. Used in dev and CI environments
+type CaptureMailer struct {
+	Path string
+
+	mutex  sync.Mutex
+	emails []*CapturedEmail
+}
+
+// NewCaptureMailer creates capture mailer, empty path disables writing to files
+func NewCaptureMailer(capturePath string) *CaptureMailer {
+
+	if len(capturePath) > 0 {
+		err := os.MkdirAll(capturePath, 0755)
+		if err != nil {
+			fmt.Println("CaptureMailer: unable to create capture folder:")
+			fmt.Println(err.Error())
+			capturePath = ""
+		}
+	}
+
+	return &CaptureMailer{
+		Path:   capturePath,
+		emails: make([]*CapturedEmail, 0),
+	}
+}
+
+// Send implements Mailer interface
+func (m *CaptureMailer) Send(email *Email) error {
+
+	return m.capture(&CapturedEmail{Email: email})
+}
+
+// SendTemplate implements Mailer interface
+func (m *CaptureMailer) SendTemplate(emailType int, toEmail string, parameters map[string]string) error {
+
+	email, err := renderTemplate(emailType, toEmail, parameters)
+	if err != nil {
+		return err
+	}
+	return m.capture(&CapturedEmail{Email: email, TemplateType: &emailType, Parameters: parameters})
+}
+
+// Emails returns captured emails, newest first
+func (m *CaptureMailer) Emails() []*CapturedEmail {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	emails := make([]*CapturedEmail, 0, len(m.emails))
+	for i := len(m.emails) - 1; i >= 0; i-- {
+		emails = append(emails, m.emails[i])
+	}
+	return emails
+}
+
+// Clear removes all captured emails from memory
+func (m *CaptureMailer) Clear() {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emails = make([]*CapturedEmail, 0)
+}
+
+func (m *CaptureMailer) capture(captured *CapturedEmail) error {
+
+	captured.ID = cigExchange.RandomUUID()
+	captured.CreatedAt = time.Now()
+
+	m.mutex.Lock()
+	m.emails = append(m.emails, captured)
+	if len(m.emails) > captureLimit {
+		m.emails = m.emails[len(m.emails)-captureLimit:]
+	}
+	m.mutex.Unlock()
+
+	if len(m.Path) == 0 {
+		return nil
+	}
+
+	jsonBytes, err := json.MarshalIndent(captured, "", "  ")
+	if err != nil {
+		return err
+	}
+	fileName := captured.CreatedAt.Format("20060102T150405") + "_" + captured.ID + ".json"
+	return ioutil.WriteFile(path.Join(m.Path, fileName), jsonBytes, 0644)
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Supported MAIL_BACKEND values
+const (
+	BackendMandrill = "mandrill"
+	BackendSMTP     = "smtp"
+	BackendCapture  = "capture"
+)
+
+// Email is a single plain email message
+type Email struct {
+	ToEmail   string `json:"to_email"`
+	ToName    string `json:"to_name"`
+	FromEmail string `json:"from_email"`
+	FromName  string `json:"from_name"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	HTML      string `json:"html,omitempty"`
+}
+
+// Mailer sends emails through some provider
+type Mailer interface {
+	// Send delivers a plain email
+	Send(email *Email) error
+	// SendTemplate delivers one of cigExchange.EmailType* template emails
+	SendTemplate(emailType int, toEmail string, parameters map[string]string) error
+}
+
+// RejectedError is returned when provider refused to deliver the email
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("email rejected: %v", e.Reason)
+}
+
+var mailer Mailer
+var mailerOnce sync.Once
+
+// Get returns mailer selected by MAIL_BACKEND env variable, mandrill is used by default
+func Get() Mailer {
+
+	mailerOnce.Do(func() {
+		switch os.Getenv("MAIL_BACKEND") {
+		case BackendSMTP:
+			mailer = NewSMTPMailer()
+		case BackendCapture:
+			mailer = NewCaptureMailer(os.Getenv("MAIL_CAPTURE_PATH"))
+		default:
+			mailer = &MandrillMailer{}
+		}
+		fmt.Printf("Mailer: using %T\n", mailer)
+	})
+	return mailer
+}
+
+// GetCapture returns capture mailer if it's the active backend, nil otherwise
+func GetCapture() *CaptureMailer {
+
+	capture, _ := Get().(*CaptureMailer)
+	return capture
+}
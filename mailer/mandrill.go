@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	cigExchange "cig-exchange-libs"
+	"fmt"
+
+	"github.com/mattbaird/gochimp"
+)
+
+// MandrillMailer sends emails through Mandrill, templates are stored in Mandrill
+type MandrillMailer struct{}
+
+// Send implements Mailer interface
+func (m *MandrillMailer) Send(email *Email) error {
+
+	recipients := []gochimp.Recipient{
+		gochimp.Recipient{Email: email.ToEmail, Name: email.ToName, Type: "to"},
+	}
+
+	message := gochimp.Message{
+		Text:      email.Text,
+		Html:      email.HTML,
+		Subject:   email.Subject,
+		FromEmail: email.FromEmail,
+		FromName:  email.FromName,
+		To:        recipients,
+	}
+
+	resp, err := cigExchange.GetMandrill().MessageSend(message, false)
+	if err != nil {
+		return err
+	}
+
+	// we only have 1 recepient
+	if len(resp) != 1 {
+		return fmt.Errorf("Unable to send email")
+	}
+	if resp[0].Status == "rejected" {
+		return &RejectedError{Reason: resp[0].RejectedReason}
+	}
+	return nil
+}
+
+// SendTemplate implements Mailer interface
+func (m *MandrillMailer) SendTemplate(emailType int, toEmail string, parameters map[string]string) error {
+
+	return cigExchange.SendEmail(emailType, toEmail, parameters)
+}
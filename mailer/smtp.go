@@ -0,0 +1,88 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"time"
+)
+
+// SMTPMailer sends emails through a plain SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// NewSMTPMailer creates mailer from SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD env variables
+func NewSMTPMailer() *SMTPMailer {
+
+	port := os.Getenv("SMTP_PORT")
+	if len(port) == 0 {
+		port = "25"
+	}
+
+	return &SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// Send implements Mailer interface
+func (m *SMTPMailer) Send(email *Email) error {
+
+	var auth smtp.Auth
+	if len(m.Username) > 0 {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, email.FromEmail, []string{email.ToEmail}, buildMIMEMessage(email))
+}
+
+// SendTemplate implements Mailer interface
+func (m *SMTPMailer) SendTemplate(emailType int, toEmail string, parameters map[string]string) error {
+
+	email, err := renderTemplate(emailType, toEmail, parameters)
+	if err != nil {
+		return err
+	}
+	return m.Send(email)
+}
+
+// buildMIMEMessage formats email as RFC 5322 message
+func buildMIMEMessage(email *Email) []byte {
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "From: %s\r\n", formatAddress(email.FromName, email.FromEmail))
+	fmt.Fprintf(buffer, "To: %s\r\n", formatAddress(email.ToName, email.ToEmail))
+	fmt.Fprintf(buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(email.HTML) == 0 {
+		buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buffer.WriteString(email.Text)
+		return buffer.Bytes()
+	}
+
+	boundary := "cig-exchange-" + fmt.Sprint(time.Now().UnixNano())
+	fmt.Fprintf(buffer, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+	fmt.Fprintf(buffer, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, email.Text)
+	fmt.Fprintf(buffer, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, email.HTML)
+	fmt.Fprintf(buffer, "--%s--\r\n", boundary)
+	return buffer.Bytes()
+}
+
+func formatAddress(name, email string) string {
+
+	if len(name) == 0 {
+		return "<" + email + ">"
+	}
+	return mime.QEncoding.Encode("utf-8", name) + " <" + email + ">"
+}
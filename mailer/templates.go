@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	cigExchange "cig-exchange-libs"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// local plain text versions of Mandrill templates, used by smtp and capture backends
+var localTemplates = map[int]*template.Template{
+	cigExchange.EmailTypeInvitation: template.Must(template.New("invitation").Parse(
+		`Hello {{.INVITE_FIRST_NAME}},
+
+{{.INVITER_NAME}} invited you to join {{.INVITER_ORGANISATION}} on CIG Exchange.
+
+Accept the invitation here:
+{{.ACCEPT_URL}}
+`)),
+}
+
+var localTemplateSubjects = map[int]string{
+	cigExchange.EmailTypeInvitation: "You have been invited to CIG Exchange",
+}
+
+// renderTemplate builds plain email from template type and parameters.
+// Unknown templates are rendered as a list of parameters
+func renderTemplate(emailType int, toEmail string, parameters map[string]string) (*Email, error) {
+
+	email := &Email{
+		ToEmail:   toEmail,
+		FromEmail: GetDefaultFromEmail(),
+		FromName:  "CIG Exchange",
+		Subject:   localTemplateSubjects[emailType],
+	}
+
+	tmpl, ok := localTemplates[emailType]
+	if !ok {
+		email.Subject = fmt.Sprintf("CIG Exchange email (template %v)", emailType)
+
+		keys := make([]string, 0, len(parameters))
+		for key := range parameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		lines := make([]string, 0, len(keys))
+		for _, key := range keys {
+			lines = append(lines, key+": "+parameters[key])
+		}
+		email.Text = strings.Join(lines, "\n")
+		return email, nil
+	}
+
+	builder := &strings.Builder{}
+	err := tmpl.Execute(builder, parameters)
+	if err != nil {
+		return nil, err
+	}
+	email.Text = builder.String()
+	return email, nil
+}
+
+// GetDefaultFromEmail reads MAIL_FROM_EMAIL env variable
+func GetDefaultFromEmail() string {
+
+	from := os.Getenv("MAIL_FROM_EMAIL")
+	if len(from) == 0 {
+		return "noreply@cig-exchange.ch"
+	}
+	return from
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	cigExchange "cig-exchange-libs"
 	"cig-exchange-libs/auth"
+	"cig-exchange-p2p-backend/captcha"
 	"cig-exchange-p2p-backend/controllers"
+	"cig-exchange-p2p-backend/mailer"
+	"cig-exchange-p2p-backend/middleware"
 	"cig-exchange-p2p-backend/tasks"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -28,6 +33,13 @@ func main() {
 	tradingBaseURI = strings.Replace(tradingBaseURI, "\"", "", -1)
 	fmt.Println("trading base URI set to " + tradingBaseURI)
 
+	// refuse to start with unprotected public forms
+	err := captcha.CheckConfig(cigExchange.IsDevEnv())
+	if err != nil {
+		fmt.Println("Captcha: " + err.Error())
+		os.Exit(1)
+	}
+
 	router := mux.NewRouter()
 
 	userAPI := auth.UserAPI{
@@ -47,11 +59,83 @@ func main() {
 	router.HandleFunc(p2pBaseURI+"users/{user_id}", controllers.UpdateUser).Methods("PATCH")
 	router.HandleFunc(p2pBaseURI+"users/{user_id}/activities", controllers.GetUserActivities).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"users/{user_id}/activities", controllers.CreateUserActivity).Methods("POST")
-	router.HandleFunc(p2pBaseURI+"organisations", controllers.CreateOrganisation).Methods("POST")                     // only admin can create organisation. Organisation will be empty
-	router.HandleFunc(p2pBaseURI+"organisations", controllers.GetOrganisations).Methods("GET")                        // all user will receive list of their organisations, admin will receive all organisations
-	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.GetOrganisation).Methods("GET")       // users can get organisation that they belongs to, admin can get any organisation
-	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.UpdateOrganisation).Methods("PATCH")  // org admins can change all except 'status', admin can change all
-	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.DeleteOrganisation).Methods("DELETE") // admin can delete organisation
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications", controllers.GetNotifications).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications/unread-count", controllers.GetNotificationsUnreadCount).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications/preferences", controllers.GetNotificationPreferences).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications/preferences", controllers.UpdateNotificationPreferences).Methods("PATCH")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications/read", controllers.MarkAllNotificationsRead).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/notifications/{notification_id}/read", controllers.MarkNotificationRead).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/watchlist", controllers.GetWatchlist).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/watchlist/{offering_id}", controllers.AddToWatchlist).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/watchlist/{offering_id}", controllers.RemoveFromWatchlist).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/saved-searches", controllers.GetSavedSearches).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/saved-searches", controllers.CreateSavedSearch).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/saved-searches/{search_id}", controllers.UpdateSavedSearch).Methods("PATCH")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/saved-searches/{search_id}", controllers.DeleteSavedSearch).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/questions", controllers.GetUserOfferingQuestions).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/questions", controllers.AskOfferingQuestion).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/questions/{question_id}/replies", controllers.ReplyToUserOfferingQuestion).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/holdings", controllers.GetUserHoldings).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/distributions", controllers.GetUserDistributions).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts", controllers.GetUserAccounts).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts/deposits", controllers.CreateDeposit).Methods("POST") // only admin can record deposits
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts/withdrawals", controllers.CreateWithdrawal).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts/commitments", controllers.CreateCommitment).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts/{account_id}", controllers.GetUserAccount).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/accounts/{account_id}/entries", controllers.GetUserAccountEntries).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/listings", controllers.GetUserListings).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/listings", controllers.CreateListing).Methods("POST") // listings and bids are matched immediately
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/listings/{listing_id}", controllers.CancelListing).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/bids", controllers.GetUserBids).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/bids", controllers.CreateBid).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/bids/{bid_id}", controllers.CancelBid).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/market/trades", controllers.GetUserTrades).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions", controllers.GetUserSubscriptions).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions", controllers.CreateSubscription).Methods("POST") // subscription is paid by bank transfer with its payment reference
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions/{subscription_id}", controllers.CancelSubscription).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions/{subscription_id}/qr-bill", controllers.GetSubscriptionQRBill).Methods("GET") // 'format' pdf or svg downloads the payment part
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions/{subscription_id}/agreement", controllers.GetSubscriptionAgreement).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions/{subscription_id}/agreement", controllers.CreateSubscriptionAgreement).Methods("POST") // generates missing agreement from the latest organisation template
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/subscriptions/{subscription_id}/agreement/file", controllers.GetSubscriptionAgreementFile).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts", controllers.GetUserBankAccounts).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts", controllers.CreateBankAccount).Methods("POST") // bank account changes wait for confirmation code sent by email
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts/{bank_account_id}", controllers.UpdateBankAccount).Methods("PUT")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts/{bank_account_id}", controllers.DeleteBankAccount).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts/{bank_account_id}/default", controllers.SetDefaultBankAccount).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/bank-accounts/changes/{change_id}/confirm", controllers.ConfirmBankAccountChange).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests", controllers.GetUserSignatureRequests).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}", controllers.GetUserSignatureRequest).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/document", controllers.GetUserSignatureDocument).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/file", controllers.GetUserSignedDocument).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/code", controllers.RequestSignatureCode).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/sign", controllers.SignDocument).Methods("POST") // click-to-sign with the code sent by email
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/decline", controllers.DeclineSignatureRequest).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/payouts", controllers.GetUserPayouts).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/payouts", controllers.CreatePayout).Methods("POST") // amount is withdrawn from the cash account until the payout is cancelled
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/payouts/{payout_id}", controllers.CancelPayout).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile", controllers.GetInvestorProfile).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile", controllers.UpdateInvestorProfile).Methods("PATCH")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile/submit", controllers.SubmitInvestorProfile).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile/documents", controllers.UploadKYCDocument).Methods("PUT")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile/documents/{document_id}", controllers.DeleteKYCDocument).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"users/{user_id}/investor-profile/documents/{document_id}/file", controllers.GetKYCDocumentFile).Methods("GET") // profile owner and admin can download documents
+	router.HandleFunc(p2pBaseURI+"organisations", controllers.CreateOrganisation).Methods("POST")                                                // only admin can create organisation. Organisation will be empty
+	router.HandleFunc(p2pBaseURI+"organisations", controllers.GetOrganisations).Methods("GET")                                                   // all user will receive list of their organisations, admin will receive all organisations
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.GetOrganisation).Methods("GET")                                  // users can get organisation that they belongs to, admin can get any organisation
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.UpdateOrganisation).Methods("PATCH")                             // org admins can change all except 'status', admin can change all
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}", controllers.DeleteOrganisation).Methods("DELETE")                            // admin can delete organisation
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/payment-account", controllers.GetOrganisationPaymentAccount).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/payment-account", controllers.UpdateOrganisationPaymentAccount).Methods("PUT") // without account subscriptions are paid to the escrow account
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/agreement-templates", controllers.GetAgreementTemplates).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/agreement-templates", controllers.CreateAgreementTemplate).Methods("POST") // saving a template creates its next version
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/agreement-templates/{template_id}/preview", controllers.GetAgreementTemplatePreview).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/agreements", controllers.GetOrganisationAgreements).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/agreements/{agreement_id}/file", controllers.GetOrganisationAgreementFile).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/signature-requests", controllers.GetOrganisationSignatureRequests).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/signature-requests", controllers.CreateSignatureRequest).Methods("POST") // subscription agreements are sent automatically, NDAs are sent by organisation admins
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/signature-requests/{request_id}", controllers.GetOrganisationSignatureRequest).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/signature-requests/{request_id}", controllers.CancelSignatureRequest).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/signature-requests/{request_id}/file", controllers.GetOrganisationSignedDocument).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/dashboard", controllers.GetDashboardInfo).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/dashboard/users", controllers.GetDashboardUsersInfo).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/dashboard/offerings", controllers.GetDashboardOfferingsBreakdown).Methods("GET")
@@ -61,10 +145,35 @@ func main() {
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}", controllers.GetOffering).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}", controllers.UpdateOffering).Methods("PATCH")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}", controllers.DeleteOffering).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/accounts", controllers.GetOfferingAccounts).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/holdings", controllers.GetOfferingHoldings).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/holdings/{user_id}", controllers.SetOfferingHolding).Methods("PUT") // org admins maintain investor register until the first distribution
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/distributions", controllers.GetOfferingDistributions).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/distributions", controllers.CreateOfferingDistribution).Methods("POST") // distributions are immutable
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/distributions/{distribution_id}", controllers.GetOfferingDistribution).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/eligibility", controllers.GetOfferingEligibility).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/eligibility", controllers.UpdateOfferingEligibility).Methods("PUT") // empty lists and level 0 remove restrictions
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/terms", controllers.GetLoanTerms).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/terms", controllers.UpdateLoanTerms).Methods("PUT")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/schedule", controllers.GetOrganisationOfferingSchedule).Methods("GET")  // 'amount' and 'start_date' override offering amount and loan start
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/translations", controllers.UpdateOfferingTranslations).Methods("PATCH") // translators can fill only their languages
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/media/upload", controllers.UploadMedia).Methods("PUT")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/media/ordering", controllers.UpdateMediaOrdering).Methods("POST")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/media/{media_id}", controllers.UpdateOfferingMedia).Methods("PATCH")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/media/{media_id}", controllers.DeleteOfferingMedia).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/questions", controllers.GetOrganisationOfferingQuestions).Methods("GET")         // organisation members see all questions including private ones
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}", controllers.ModerateOfferingQuestion).Methods("PATCH") // org admins can hide questions and change visibility
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}", controllers.DeleteOfferingQuestion).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}/replies", controllers.AnswerOfferingQuestion).Methods("POST") // any organisation member can answer
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/offerings/{offering_id}/questions/{question_id}/replies/{reply_id}", controllers.DeleteOfferingQuestionReply).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translations", controllers.GetTranslationCoverage).Methods("GET") // organisation members and translators can see translation coverage
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translations", controllers.UpdateOrganisationTranslations).Methods("PATCH")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translations/gaps", controllers.GetTranslationGaps).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translations/settings", controllers.GetTranslationSettings).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translations/settings", controllers.UpdateTranslationSettings).Methods("PATCH") // org admins can require languages before publication
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translators", controllers.GetTranslators).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translators/{user_id}", controllers.SetTranslator).Methods("PUT") // empty languages allow all languages
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/translators/{user_id}", controllers.DeleteTranslator).Methods("DELETE")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/users", controllers.GetOrganisationUsers).Methods("GET")                // admin can receive users for any organisation, any user from organisation can see other members
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/users/{user_id}", controllers.DeleteOrganisationUser).Methods("DELETE") // admin can delete any user, org admin can't delete himself
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/users/{user_id}", controllers.AddOrganisationUser).Methods("POST")      // admin can add user to organisation
@@ -72,6 +181,32 @@ func main() {
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/invitations", controllers.GetInvitations).Methods("GET")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/invitations", controllers.SendInvitation).Methods("POST")
 	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/invitations/{user_id}", controllers.DeleteInvitation).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks", controllers.GetWebhooks).Methods("GET")    // admin and org admins can manage organisation webhooks
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks", controllers.CreateWebhook).Methods("POST") // signing secret is returned only in this response
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks/{webhook_id}", controllers.UpdateWebhook).Methods("PATCH")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks/{webhook_id}", controllers.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks/{webhook_id}/deliveries", controllers.GetWebhookDeliveries).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"organisations/{organisation_id}/webhooks/{webhook_id}/test", controllers.SendWebhookTestEvent).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"fx-rates", controllers.GetFXRates).Methods("GET")    // current rates of all currency pairs
+	router.HandleFunc(p2pBaseURI+"fx-rates", controllers.CreateFXRate).Methods("POST") // admin can add rates, previous rates are kept as history
+	router.HandleFunc(p2pBaseURI+"fx-rates/history", controllers.GetFXRateHistory).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"payments/statements", controllers.GetPaymentStatements).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"payments/statements", controllers.ImportPaymentStatement).Methods("POST") // admin uploads camt.053/054 XML, credits are matched to pending subscriptions
+	router.HandleFunc(p2pBaseURI+"payments/exceptions", controllers.GetPaymentExceptions).Methods("GET")    // unmatched, partial and over-payments by default
+	router.HandleFunc(p2pBaseURI+"payments/exceptions/{transaction_id}/resolve", controllers.ResolvePaymentException).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"payouts", controllers.GetPayouts).Methods("GET")                 // pending payouts by default
+	router.HandleFunc(p2pBaseURI+"payouts/batches", controllers.CreatePayoutBatch).Methods("POST") // admin exports pending payouts as pain.001 payment order
+	router.HandleFunc(p2pBaseURI+"payouts/batches", controllers.GetPayoutBatches).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"payouts/batches/{batch_id}", controllers.GetPayoutBatch).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"payouts/batches/{batch_id}/file", controllers.GetPayoutBatchFile).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"payouts/batches/{batch_id}/execute", controllers.ExecutePayoutBatch).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"payouts/batches/{batch_id}/reject", controllers.RejectPayoutBatch).Methods("POST") // payouts return to pending
+	router.HandleFunc(p2pBaseURI+"kyc/profiles", controllers.GetKYCProfiles).Methods("GET")                          // admin review queue, pending profiles by default
+	router.HandleFunc(p2pBaseURI+"kyc/profiles/{user_id}", controllers.GetKYCProfile).Methods("GET")
+	router.HandleFunc(p2pBaseURI+"kyc/profiles/{user_id}/approve", controllers.ApproveKYCProfile).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"kyc/profiles/{user_id}/reject", controllers.RejectKYCProfile).Methods("POST")
+	router.HandleFunc(p2pBaseURI+"email-outbox/dead-letters", controllers.GetEmailDeadLetters).Methods("GET")    // admin can see emails which failed all delivery attempts
+	router.HandleFunc(p2pBaseURI+"email-outbox/{outbox_id}/retry", controllers.RetryOutboxEmail).Methods("POST") // admin can requeue undelivered email
 
 	// trading
 	router.HandleFunc(tradingBaseURI+"ping", controllers.Ping).Methods("GET")
@@ -86,12 +221,42 @@ func main() {
 	router.HandleFunc(tradingBaseURI+"users/accept-invitation", controllers.AcceptInvitation).Methods("POST")
 	router.HandleFunc(tradingBaseURI+"organisations/signup", userAPI.CreateOrganisationHandler).Methods("POST")
 	router.HandleFunc(tradingBaseURI+"offerings", controllers.GetAllOfferings).Methods("GET")
+	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}", controllers.GetTradingOffering).Methods("GET")
+	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}/market", controllers.GetOfferingMarket).Methods("GET") // secondary market order book
+	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}/price-history", controllers.GetOfferingPriceHistory).Methods("GET")
 	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}/media", controllers.GetOfferingMedia).Methods("GET")
+	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}/schedule", controllers.GetOfferingSchedule).Methods("GET") // investors preview cash flows with 'amount'
+	router.HandleFunc(tradingBaseURI+"offerings/{offering_id}/questions", controllers.GetPublicOfferingQuestions).Methods("GET")
 	router.HandleFunc(tradingBaseURI+"media/{media_file}", controllers.GetMedia).Methods("GET")
 	router.HandleFunc(tradingBaseURI+"contact_us", controllers.SendContactUsEmail).Methods("POST")
+	router.HandleFunc(tradingBaseURI+"saved-searches/unsubscribe", controllers.UnsubscribeSavedSearch).Methods("GET") // link from saved search digest emails
+
+	// dev environment can inspect captured emails, the routes are not authenticated
+	if mailer.GetCapture() != nil && cigExchange.IsDevEnv() {
+		router.HandleFunc(tradingBaseURI+"dev/emails", controllers.GetCapturedEmails).Methods("GET")
+		router.HandleFunc(tradingBaseURI+"dev/emails", controllers.DeleteCapturedEmails).Methods("DELETE")
+	}
+
+	// rate limits for public endpoints
+	rateLimiter := middleware.NewRateLimiter()
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"contact_us", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 5, Window: time.Hour})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/activities", &middleware.Policy{Algorithm: middleware.AlgorithmTokenBucket, Scope: middleware.ScopeIP, Limit: 60, Window: time.Minute})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/signup", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 10, Window: time.Hour})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/send_otp", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 5, Window: 15 * time.Minute, FailClosed: true})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/send_otp", &middleware.Policy{Algorithm: middleware.AlgorithmTokenBucket, Scope: middleware.ScopeRoute, Limit: 300, Window: time.Minute})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/signin", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 20, Window: 15 * time.Minute, FailClosed: true})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/verify_otp", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 10, Window: 15 * time.Minute, FailClosed: true})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"users/accept-invitation", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 10, Window: time.Hour})
+	rateLimiter.AddRoutePolicy(tradingBaseURI+"saved-searches/unsubscribe", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeIP, Limit: 30, Window: time.Hour})
+	rateLimiter.AddRoutePolicy(p2pBaseURI+"users/{user_id}/activities", &middleware.Policy{Algorithm: middleware.AlgorithmTokenBucket, Scope: middleware.ScopeUser, Limit: 120, Window: time.Minute})
+	rateLimiter.AddRoutePolicy(p2pBaseURI+"users/{user_id}/bank-accounts/changes/{change_id}/confirm", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeUser, Limit: 10, Window: 15 * time.Minute, FailClosed: true})
+	rateLimiter.AddRoutePolicy(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/code", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeUser, Limit: 5, Window: 15 * time.Minute, FailClosed: true})
+	rateLimiter.AddRoutePolicy(p2pBaseURI+"users/{user_id}/signature-requests/{request_id}/sign", &middleware.Policy{Algorithm: middleware.AlgorithmSlidingWindow, Scope: middleware.ScopeUser, Limit: 10, Window: 15 * time.Minute, FailClosed: true})
 
 	// attach JWT auth middleware
 	router.Use(userAPI.JwtAuthenticationHandler)
+	// attach rate limit middleware, must go after JWT middleware
+	router.Use(rateLimiter.Handler)
 
 	//router.NotFoundHandler = app.NotFoundHandler
 
@@ -103,7 +268,7 @@ func main() {
 	tasks.ScheduleTasks()
 
 	// launch the app
-	err := http.ListenAndServe(":"+port, router)
+	err = http.ListenAndServe(":"+port, router)
 	if err != nil {
 		fmt.Print(err)
 	}
@@ -0,0 +1,357 @@
+package middleware
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/auth"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Rate limit algorithms
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+// Rate limit scopes
+const (
+	// ScopeIP counts requests per client IP
+	ScopeIP = "ip"
+	// ScopeUser counts requests per JWT user, requests without JWT are counted per IP
+	ScopeUser = "user"
+	// ScopeRoute counts all requests to the route together
+	ScopeRoute = "route"
+)
+
+// Policy describes a single rate limit: at most Limit requests per Window
+type Policy struct {
+	Algorithm string
+	Scope     string
+	Limit     int
+	Window    time.Duration
+	// FailClosed rejects requests when redis is unavailable instead of letting them through.
+	// Used for login and OTP endpoints where an unlimited window allows brute forcing codes
+	FailClosed bool
+}
+
+// RateLimiter is a redis backed rate limiting middleware
+type RateLimiter struct {
+	// DefaultPolicies apply to all routes without own policies
+	DefaultPolicies []*Policy
+	// RoutePolicies are keyed by route path template
+	RoutePolicies map[string][]*Policy
+	// AllowList contains networks that are never limited (e.g. monitoring)
+	AllowList []*net.IPNet
+}
+
+// NewRateLimiter creates rate limiter, allow list is read from RATE_LIMIT_ALLOW_LIST env variable
+// containing comma separated IPs or CIDRs
+func NewRateLimiter() *RateLimiter {
+
+	rl := &RateLimiter{
+		DefaultPolicies: make([]*Policy, 0),
+		RoutePolicies:   make(map[string][]*Policy),
+		AllowList:       make([]*net.IPNet, 0),
+	}
+
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMIT_ALLOW_LIST"), ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		err := rl.Allow(entry)
+		if err != nil {
+			fmt.Println("RateLimiter: invalid allow list entry:")
+			fmt.Println(err.Error())
+		}
+	}
+	return rl
+}
+
+// Allow adds IP or CIDR to the allow list
+func (rl *RateLimiter) Allow(ipOrCIDR string) error {
+
+	ipNet, err := parseNetwork(ipOrCIDR)
+	if err != nil {
+		return err
+	}
+	rl.AllowList = append(rl.AllowList, ipNet)
+	return nil
+}
+
+// parseNetwork parses IP or CIDR, single IP is converted to a host network
+func parseNetwork(ipOrCIDR string) (*net.IPNet, error) {
+
+	if !strings.Contains(ipOrCIDR, "/") {
+		if strings.Contains(ipOrCIDR, ":") {
+			ipOrCIDR += "/128"
+		} else {
+			ipOrCIDR += "/32"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(ipOrCIDR)
+	return ipNet, err
+}
+
+// AddRoutePolicy adds policy for the route path template
+func (rl *RateLimiter) AddRoutePolicy(route string, policy *Policy) {
+
+	rl.RoutePolicies[route] = append(rl.RoutePolicies[route], policy)
+}
+
+// Handler is the rate limiting middleware. Must be attached after the JWT middleware for user scope to work
+func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		clientIP := GetClientIP(r)
+		if rl.isAllowListed(clientIP) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if currentRoute := mux.CurrentRoute(r); currentRoute != nil {
+			if template, err := currentRoute.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		policies, ok := rl.RoutePolicies[route]
+		if !ok {
+			policies = rl.DefaultPolicies
+		}
+
+		for _, policy := range policies {
+			key := rl.policyKey(policy, route, clientIP, r)
+			allowed, retryAfter, err := policy.take(key)
+			if err != nil {
+				fmt.Println("RateLimiter: redis error:")
+				fmt.Println(err.Error())
+				if policy.FailClosed {
+					respondServiceUnavailable(w)
+					return
+				}
+				// don't block users when redis is unavailable
+				continue
+			}
+			if !allowed {
+				respondTooManyRequests(w, retryAfter)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rl *RateLimiter) isAllowListed(clientIP string) bool {
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range rl.AllowList {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var trustedProxies []*net.IPNet
+var trustedProxiesOnce sync.Once
+
+// getTrustedProxies reads TRUSTED_PROXIES env variable containing comma separated IPs or CIDRs
+// of the reverse proxies (e.g. nginx) allowed to set forwarding headers
+func getTrustedProxies() []*net.IPNet {
+
+	trustedProxiesOnce.Do(func() {
+		for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+			entry = strings.TrimSpace(entry)
+			if len(entry) == 0 {
+				continue
+			}
+			ipNet, err := parseNetwork(entry)
+			if err != nil {
+				fmt.Println("RateLimiter: invalid trusted proxy entry:")
+				fmt.Println(err.Error())
+				continue
+			}
+			trustedProxies = append(trustedProxies, ipNet)
+		}
+	})
+	return trustedProxies
+}
+
+func isTrustedProxy(address string) bool {
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range getTrustedProxies() {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rl *RateLimiter) policyKey(policy *Policy, route, clientIP string, r *http.Request) string {
+
+	// include method and policy shape, so several policies on one route don't share counters
+	key := fmt.Sprintf("rate_limit:%s:%s:%s:%d:%d:%s", policy.Algorithm, r.Method, route, policy.Limit, policy.Window/time.Millisecond, policy.Scope)
+
+	switch policy.Scope {
+	case ScopeRoute:
+		return key
+	case ScopeUser:
+		loggedInUser, err := auth.GetContextValues(r)
+		if err == nil && len(loggedInUser.UserUUID) > 0 {
+			return key + ":" + loggedInUser.UserUUID
+		}
+	}
+	return key + ":" + clientIP
+}
+
+// GetClientIP returns request IP. Forwarding headers are only respected when the
+// connection comes from a trusted proxy, clients can put anything into them
+func GetClientIP(r *http.Request) string {
+
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	// proxies append the address they received the request from,
+	// the right-most hop that isn't a trusted proxy is the client
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if len(forwarded) > 0 {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if len(hop) == 0 {
+				continue
+			}
+			if i == 0 || !isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+
+	realIP := r.Header.Get("X-Real-IP")
+	if len(realIP) > 0 {
+		return strings.TrimSpace(realIP)
+	}
+	return remoteIP
+}
+
+func respondTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(cigExchange.Message(false, "Too many requests"))
+}
+
+func respondServiceUnavailable(w http.ResponseWriter) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(cigExchange.Message(false, "Service temporarily unavailable"))
+}
+
+// take consumes one request from the policy counter.
+// Returns whether request is allowed and how long to wait otherwise
+func (policy *Policy) take(key string) (bool, time.Duration, error) {
+
+	script := slidingWindowScript
+	if policy.Algorithm == AlgorithmTokenBucket {
+		script = tokenBucketScript
+	}
+
+	nowMs := time.Now().UnixNano() / int64(time.Millisecond)
+	windowMs := int64(policy.Window / time.Millisecond)
+
+	res, err := cigExchange.GetRedis().Eval(script, []string{key}, nowMs, windowMs, policy.Limit, cigExchange.RandomUUID()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+	allowed, _ := values[0].(int64)
+	retryAfterMs, _ := values[1].(int64)
+
+	return allowed == 1, time.Duration(retryAfterMs) * time.Millisecond, nil
+}
+
+// slidingWindowScript keeps request timestamps in a sorted set
+// ARGV: now ms, window ms, limit, unique member
+const slidingWindowScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count >= limit then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	local retry = window
+	if oldest[2] then
+		retry = tonumber(oldest[2]) + window - now
+	end
+	return {0, retry}
+end
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], window)
+return {1, 0}
+`
+
+// tokenBucketScript refills 'limit' tokens per window continuously
+// ARGV: now ms, window ms, limit (bucket size)
+const tokenBucketScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local rate = limit / window
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil then
+	tokens = limit
+	ts = now
+end
+tokens = math.min(limit, tokens + (now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, retry}
+`
@@ -0,0 +1,9 @@
+-- daily aggregates of rolled up user activities
+CREATE TABLE IF NOT EXISTS user_activity_daily (
+    day             DATE         NOT NULL,
+    organisation_id VARCHAR(36)  NOT NULL DEFAULT '',
+    offering_id     VARCHAR(36)  NOT NULL DEFAULT '',
+    type            VARCHAR(255) NOT NULL,
+    count           BIGINT       NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, organisation_id, offering_id, type)
+);
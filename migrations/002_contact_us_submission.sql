@@ -0,0 +1,13 @@
+-- stored contact us form submissions, including rejected spam
+CREATE TABLE IF NOT EXISTS contact_us_submission (
+    id            VARCHAR(36)  PRIMARY KEY,
+    name          TEXT         NOT NULL,
+    email         TEXT         NOT NULL,
+    message       TEXT         NOT NULL,
+    remote_addr   VARCHAR(64)  NOT NULL DEFAULT '',
+    status        VARCHAR(16)  NOT NULL,
+    reject_reason TEXT         NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS contact_us_submission_created_at_idx ON contact_us_submission (created_at);
@@ -0,0 +1,23 @@
+-- emails waiting for delivery by the outbox task
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              VARCHAR(36)  PRIMARY KEY,
+    reference_type  VARCHAR(64)  NOT NULL DEFAULT '',
+    reference_id    VARCHAR(36)  NOT NULL DEFAULT '',
+    to_email        TEXT         NOT NULL,
+    to_name         TEXT         NOT NULL DEFAULT '',
+    template_type   INTEGER,
+    parameters      JSONB,
+    subject         TEXT         NOT NULL DEFAULT '',
+    text            TEXT         NOT NULL DEFAULT '',
+    html            TEXT         NOT NULL DEFAULT '',
+    status          VARCHAR(16)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS email_outbox_reference_idx ON email_outbox (reference_type, reference_id);
@@ -0,0 +1,23 @@
+-- in-app user notifications
+CREATE TABLE IF NOT EXISTS notification (
+    id         VARCHAR(36)  PRIMARY KEY,
+    user_id    VARCHAR(36)  NOT NULL,
+    type       VARCHAR(64)  NOT NULL,
+    title      TEXT         NOT NULL,
+    message    TEXT         NOT NULL,
+    data       JSONB,
+    read_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_user_idx ON notification (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS notification_unread_idx ON notification (user_id) WHERE read_at IS NULL;
+
+-- per user notification channels, missing rows mean defaults (in-app on, email off)
+CREATE TABLE IF NOT EXISTS notification_preference (
+    user_id VARCHAR(36) NOT NULL,
+    type    VARCHAR(64) NOT NULL,
+    in_app  BOOLEAN     NOT NULL DEFAULT TRUE,
+    email   BOOLEAN     NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (user_id, type)
+);
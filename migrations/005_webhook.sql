@@ -0,0 +1,34 @@
+-- organisation webhook endpoints, deleted_at keeps delivery log of removed webhooks readable
+CREATE TABLE IF NOT EXISTS webhook_subscription (
+    id              VARCHAR(36)   PRIMARY KEY,
+    organisation_id VARCHAR(36)   NOT NULL,
+    url             TEXT          NOT NULL,
+    secret          VARCHAR(64)   NOT NULL,
+    events          VARCHAR(64)[] NOT NULL DEFAULT '{}',
+    is_active       BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    deleted_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscription_organisation_idx ON webhook_subscription (organisation_id) WHERE deleted_at IS NULL;
+
+-- webhook delivery log and retry queue
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id              VARCHAR(36)  PRIMARY KEY,
+    subscription_id VARCHAR(36)  NOT NULL REFERENCES webhook_subscription (id),
+    organisation_id VARCHAR(36)  NOT NULL,
+    event           VARCHAR(64)  NOT NULL,
+    payload         JSONB        NOT NULL,
+    status          VARCHAR(16)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    response_code   INTEGER      NOT NULL DEFAULT 0,
+    last_error      TEXT         NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_delivery_subscription_idx ON webhook_delivery (subscription_id, created_at DESC);
@@ -0,0 +1,9 @@
+-- offerings bookmarked by investors
+CREATE TABLE IF NOT EXISTS watchlist_item (
+    user_id     VARCHAR(36) NOT NULL,
+    offering_id VARCHAR(36) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, offering_id)
+);
+
+CREATE INDEX IF NOT EXISTS watchlist_item_offering_idx ON watchlist_item (offering_id);
@@ -0,0 +1,22 @@
+-- investor saved searches with digest email settings
+CREATE TABLE IF NOT EXISTS saved_search (
+    id                VARCHAR(36)  PRIMARY KEY,
+    user_id           VARCHAR(36)  NOT NULL,
+    name              TEXT         NOT NULL,
+    criteria          JSONB        NOT NULL DEFAULT '{}',
+    frequency         VARCHAR(16)  NOT NULL DEFAULT 'daily',
+    language          VARCHAR(8)   NOT NULL DEFAULT 'en',
+    email_enabled     BOOLEAN      NOT NULL DEFAULT TRUE,
+    unsubscribe_token VARCHAR(36)  NOT NULL UNIQUE,
+    last_digest_at    TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_user_idx ON saved_search (user_id);
+
+-- first time offering was seen listed on the trading platform, digests only include newer offerings
+CREATE TABLE IF NOT EXISTS offering_visibility (
+    offering_id      VARCHAR(36) PRIMARY KEY,
+    first_visible_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
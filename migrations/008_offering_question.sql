@@ -0,0 +1,28 @@
+-- investor question threads on offerings
+CREATE TABLE IF NOT EXISTS offering_question (
+    id              VARCHAR(36)  PRIMARY KEY,
+    offering_id     VARCHAR(36)  NOT NULL,
+    organisation_id VARCHAR(36)  NOT NULL,
+    user_id         VARCHAR(36)  NOT NULL,
+    question        TEXT         NOT NULL,
+    is_public       BOOLEAN      NOT NULL DEFAULT FALSE,
+    status          VARCHAR(16)  NOT NULL DEFAULT 'visible',
+    answered_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS offering_question_offering_idx ON offering_question (offering_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS offering_question_user_idx ON offering_question (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS offering_question_reply (
+    id              VARCHAR(36)  PRIMARY KEY,
+    question_id     VARCHAR(36)  NOT NULL REFERENCES offering_question (id) ON DELETE CASCADE,
+    user_id         VARCHAR(36)  NOT NULL,
+    is_organisation BOOLEAN      NOT NULL DEFAULT FALSE,
+    message         TEXT         NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS offering_question_reply_question_idx ON offering_question_reply (question_id, created_at);
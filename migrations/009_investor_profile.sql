@@ -0,0 +1,47 @@
+-- investor identity data and verification state
+CREATE TABLE IF NOT EXISTS investor_profile (
+    user_id           VARCHAR(36)  PRIMARY KEY,
+    first_name        VARCHAR(255) NOT NULL DEFAULT '',
+    last_name         VARCHAR(255) NOT NULL DEFAULT '',
+    date_of_birth     DATE,
+    nationality       CHAR(2)      NOT NULL DEFAULT '',
+    residence_country CHAR(2)      NOT NULL DEFAULT '',
+    street            VARCHAR(255) NOT NULL DEFAULT '',
+    postal_code       VARCHAR(32)  NOT NULL DEFAULT '',
+    city              VARCHAR(255) NOT NULL DEFAULT '',
+    classification    VARCHAR(32)  NOT NULL DEFAULT '',
+    status            VARCHAR(16)  NOT NULL DEFAULT 'draft',
+    kyc_level         INTEGER      NOT NULL DEFAULT 0,
+    rejection_reason  TEXT         NOT NULL DEFAULT '',
+    submitted_at      TIMESTAMPTZ,
+    reviewed_at       TIMESTAMPTZ,
+    reviewed_by       VARCHAR(36)  NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS investor_profile_status_idx ON investor_profile (status, submitted_at);
+
+-- identity documents, files are stored as media
+CREATE TABLE IF NOT EXISTS kyc_document (
+    id         VARCHAR(36) PRIMARY KEY,
+    user_id    VARCHAR(36) NOT NULL REFERENCES investor_profile (user_id) ON DELETE CASCADE,
+    type       VARCHAR(32) NOT NULL,
+    media_id   VARCHAR(36) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS kyc_document_user_idx ON kyc_document (user_id, created_at);
+
+-- review history, never updated
+CREATE TABLE IF NOT EXISTS kyc_review (
+    id          VARCHAR(36) PRIMARY KEY,
+    user_id     VARCHAR(36) NOT NULL,
+    reviewer_id VARCHAR(36) NOT NULL,
+    decision    VARCHAR(16) NOT NULL,
+    kyc_level   INTEGER     NOT NULL DEFAULT 0,
+    reason      TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS kyc_review_user_idx ON kyc_review (user_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- investor restrictions of offerings, offerings without a row are unrestricted
+CREATE TABLE IF NOT EXISTS offering_eligibility (
+    offering_id         VARCHAR(36) PRIMARY KEY,
+    investor_categories TEXT[]      NOT NULL DEFAULT '{}',
+    allowed_countries   TEXT[]      NOT NULL DEFAULT '{}',
+    blocked_countries   TEXT[]      NOT NULL DEFAULT '{}',
+    min_kyc_level       INTEGER     NOT NULL DEFAULT 0,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
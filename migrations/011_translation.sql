@@ -0,0 +1,19 @@
+-- organisation translation requirements, organisations without a row require english only
+CREATE TABLE IF NOT EXISTS translation_settings (
+    organisation_id    VARCHAR(36) PRIMARY KEY,
+    required_languages TEXT[]      NOT NULL DEFAULT '{en}',
+    block_publication  BOOLEAN     NOT NULL DEFAULT false,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- users allowed to translate organisation content, empty languages allow all languages
+CREATE TABLE IF NOT EXISTS translator (
+    id              VARCHAR(36) PRIMARY KEY,
+    organisation_id VARCHAR(36) NOT NULL,
+    user_id         VARCHAR(36) NOT NULL,
+    languages       TEXT[]      NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, user_id)
+);
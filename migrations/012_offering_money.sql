@@ -0,0 +1,32 @@
+-- offering currency and exact money amounts, offering float columns are kept for legacy queries
+CREATE TABLE IF NOT EXISTS offering_money (
+    offering_id          VARCHAR(36)    PRIMARY KEY,
+    currency             VARCHAR(3)     NOT NULL DEFAULT 'CHF',
+    amount               NUMERIC(20, 2),
+    remaining            NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    amount_already_taken NUMERIC(20, 2),
+    minimum_investment   NUMERIC(20, 2),
+    maximum_investment   NUMERIC(20, 2),
+    created_at           TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+-- existing offerings were listed in CHF
+INSERT INTO offering_money (offering_id, currency, amount, remaining, amount_already_taken, minimum_investment, maximum_investment)
+SELECT id, 'CHF', amount::NUMERIC(20, 2), COALESCE(remaining, 0)::NUMERIC(20, 2), amount_already_taken::NUMERIC(20, 2),
+       minimum_investment::NUMERIC(20, 2), maximum_investment::NUMERIC(20, 2)
+FROM offering
+ON CONFLICT DO NOTHING;
+
+-- exchange rates, 1 base currency = rate quote currency, rows are never updated
+CREATE TABLE IF NOT EXISTS fx_rate (
+    id             VARCHAR(36)    PRIMARY KEY,
+    base_currency  VARCHAR(3)     NOT NULL,
+    quote_currency VARCHAR(3)     NOT NULL,
+    rate           NUMERIC(24, 10) NOT NULL CHECK (rate > 0),
+    valid_from     TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    created_by     VARCHAR(36)    NOT NULL,
+    created_at     TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS fx_rate_pair_idx ON fx_rate (base_currency, quote_currency, valid_from DESC);
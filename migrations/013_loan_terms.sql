@@ -0,0 +1,12 @@
+-- structured financial terms of lending offerings
+CREATE TABLE IF NOT EXISTS loan_terms (
+    offering_id       VARCHAR(36)    PRIMARY KEY,
+    interest_rate     NUMERIC(9, 6)  NOT NULL DEFAULT 0,
+    day_count         VARCHAR(10)    NOT NULL DEFAULT '30E/360',
+    tenor_months      INTEGER        NOT NULL,
+    repayment_type    VARCHAR(16)    NOT NULL,
+    payment_frequency VARCHAR(16)    NOT NULL,
+    start_date        DATE,
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
@@ -0,0 +1,64 @@
+-- investor positions in offerings, principal is the outstanding part of the invested amount
+CREATE TABLE IF NOT EXISTS offering_holding (
+    id              VARCHAR(36)    PRIMARY KEY,
+    offering_id     VARCHAR(36)    NOT NULL,
+    user_id         VARCHAR(36)    NOT NULL,
+    currency        VARCHAR(3)     NOT NULL,
+    invested_amount NUMERIC(20, 2) NOT NULL,
+    principal       NUMERIC(20, 2) NOT NULL CHECK (principal >= 0),
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    UNIQUE (offering_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS offering_holding_user_idx ON offering_holding (user_id);
+
+-- repayment events of offerings
+CREATE TABLE IF NOT EXISTS distribution (
+    id              VARCHAR(36)    PRIMARY KEY,
+    offering_id     VARCHAR(36)    NOT NULL,
+    organisation_id VARCHAR(36)    NOT NULL,
+    currency        VARCHAR(3)     NOT NULL,
+    principal       NUMERIC(20, 2) NOT NULL,
+    interest        NUMERIC(20, 2) NOT NULL,
+    payment_date    DATE           NOT NULL,
+    reference       VARCHAR(255)   NOT NULL DEFAULT '',
+    created_by      VARCHAR(36)    NOT NULL,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS distribution_offering_idx ON distribution (offering_id, payment_date DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS distribution_reference_idx ON distribution (offering_id, reference) WHERE reference <> '';
+
+-- investor parts of distributions
+CREATE TABLE IF NOT EXISTS distribution_allocation (
+    id              VARCHAR(36)    PRIMARY KEY,
+    distribution_id VARCHAR(36)    NOT NULL REFERENCES distribution (id),
+    offering_id     VARCHAR(36)    NOT NULL,
+    user_id         VARCHAR(36)    NOT NULL,
+    currency        VARCHAR(3)     NOT NULL,
+    holding_amount  NUMERIC(20, 2) NOT NULL,
+    principal       NUMERIC(20, 2) NOT NULL,
+    interest        NUMERIC(20, 2) NOT NULL,
+    total           NUMERIC(20, 2) NOT NULL,
+    payment_date    DATE           NOT NULL,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS distribution_allocation_distribution_idx ON distribution_allocation (distribution_id);
+CREATE INDEX IF NOT EXISTS distribution_allocation_user_idx ON distribution_allocation (user_id, payment_date DESC);
+
+-- distribution ledger is append only
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS distribution_immutable ON distribution;
+CREATE TRIGGER distribution_immutable BEFORE UPDATE OR DELETE ON distribution
+    FOR EACH ROW EXECUTE PROCEDURE reject_ledger_change();
+
+DROP TRIGGER IF EXISTS distribution_allocation_immutable ON distribution_allocation;
+CREATE TRIGGER distribution_allocation_immutable BEFORE UPDATE OR DELETE ON distribution_allocation
+    FOR EACH ROW EXECUTE PROCEDURE reject_ledger_change();
@@ -0,0 +1,62 @@
+-- double-entry ledger accounts of investors, offerings and the platform
+CREATE TABLE IF NOT EXISTS ledger_account (
+    id         VARCHAR(36) PRIMARY KEY,
+    owner_type VARCHAR(16) NOT NULL,
+    owner_id   VARCHAR(36) NOT NULL,
+    type       VARCHAR(32) NOT NULL,
+    currency   VARCHAR(3)  NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (owner_type, owner_id, type, currency)
+);
+
+-- balanced ledger transactions, idempotency key makes repeated requests post only once
+CREATE TABLE IF NOT EXISTS journal_entry (
+    id              VARCHAR(36)    PRIMARY KEY,
+    idempotency_key VARCHAR(255)   NOT NULL UNIQUE,
+    type            VARCHAR(32)    NOT NULL,
+    currency        VARCHAR(3)     NOT NULL,
+    amount          NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    offering_id     VARCHAR(36)    NOT NULL DEFAULT '',
+    description     TEXT           NOT NULL DEFAULT '',
+    effective_date  TIMESTAMPTZ    NOT NULL,
+    created_by      VARCHAR(36)    NOT NULL,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS journal_entry_offering_idx ON journal_entry (offering_id, type);
+
+CREATE TABLE IF NOT EXISTS journal_posting (
+    id             VARCHAR(36)    PRIMARY KEY,
+    entry_id       VARCHAR(36)    NOT NULL REFERENCES journal_entry (id),
+    account_id     VARCHAR(36)    NOT NULL REFERENCES ledger_account (id),
+    amount         NUMERIC(20, 2) NOT NULL CHECK (amount <> 0),
+    effective_date TIMESTAMPTZ    NOT NULL,
+    created_at     TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS journal_posting_account_idx ON journal_posting (account_id, effective_date);
+CREATE INDEX IF NOT EXISTS journal_posting_entry_idx ON journal_posting (entry_id);
+
+-- postings of every entry sum up to zero, checked when the transaction commits
+CREATE OR REPLACE FUNCTION check_journal_entry_balance() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT COALESCE(SUM(amount), 0) FROM journal_posting WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS journal_posting_balanced ON journal_posting;
+CREATE CONSTRAINT TRIGGER journal_posting_balanced AFTER INSERT ON journal_posting
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE PROCEDURE check_journal_entry_balance();
+
+-- journal is append only
+DROP TRIGGER IF EXISTS journal_entry_immutable ON journal_entry;
+CREATE TRIGGER journal_entry_immutable BEFORE UPDATE OR DELETE ON journal_entry
+    FOR EACH ROW EXECUTE PROCEDURE reject_ledger_change();
+
+DROP TRIGGER IF EXISTS journal_posting_immutable ON journal_posting;
+CREATE TRIGGER journal_posting_immutable BEFORE UPDATE OR DELETE ON journal_posting
+    FOR EACH ROW EXECUTE PROCEDURE reject_ledger_change();
@@ -0,0 +1,57 @@
+-- secondary market sell orders, price is in percent of principal
+CREATE TABLE IF NOT EXISTS market_listing (
+    id                  VARCHAR(36)    PRIMARY KEY,
+    offering_id         VARCHAR(36)    NOT NULL,
+    seller_id           VARCHAR(36)    NOT NULL,
+    currency            VARCHAR(3)     NOT NULL,
+    principal           NUMERIC(20, 2) NOT NULL CHECK (principal > 0),
+    remaining_principal NUMERIC(20, 2) NOT NULL CHECK (remaining_principal >= 0),
+    price               NUMERIC(7, 2)  NOT NULL CHECK (price > 0),
+    status              VARCHAR(16)    NOT NULL DEFAULT 'open',
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS market_listing_book_idx ON market_listing (offering_id, status, price, created_at);
+CREATE INDEX IF NOT EXISTS market_listing_seller_idx ON market_listing (seller_id, created_at DESC);
+
+-- secondary market buy orders
+CREATE TABLE IF NOT EXISTS market_bid (
+    id                  VARCHAR(36)    PRIMARY KEY,
+    offering_id         VARCHAR(36)    NOT NULL,
+    buyer_id            VARCHAR(36)    NOT NULL,
+    currency            VARCHAR(3)     NOT NULL,
+    principal           NUMERIC(20, 2) NOT NULL CHECK (principal > 0),
+    remaining_principal NUMERIC(20, 2) NOT NULL CHECK (remaining_principal >= 0),
+    price               NUMERIC(7, 2)  NOT NULL CHECK (price > 0),
+    status              VARCHAR(16)    NOT NULL DEFAULT 'open',
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS market_bid_book_idx ON market_bid (offering_id, status, price DESC, created_at);
+CREATE INDEX IF NOT EXISTS market_bid_buyer_idx ON market_bid (buyer_id, status);
+
+-- settled trades, cash leg is the referenced journal entry
+CREATE TABLE IF NOT EXISTS market_trade (
+    id               VARCHAR(36)    PRIMARY KEY,
+    offering_id      VARCHAR(36)    NOT NULL,
+    listing_id       VARCHAR(36)    NOT NULL REFERENCES market_listing (id),
+    bid_id           VARCHAR(36)    NOT NULL REFERENCES market_bid (id),
+    seller_id        VARCHAR(36)    NOT NULL,
+    buyer_id         VARCHAR(36)    NOT NULL,
+    currency         VARCHAR(3)     NOT NULL,
+    principal        NUMERIC(20, 2) NOT NULL,
+    price            NUMERIC(7, 2)  NOT NULL,
+    amount           NUMERIC(20, 2) NOT NULL,
+    journal_entry_id VARCHAR(36)    NOT NULL REFERENCES journal_entry (id),
+    created_at       TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS market_trade_offering_idx ON market_trade (offering_id, created_at);
+CREATE INDEX IF NOT EXISTS market_trade_seller_idx ON market_trade (seller_id);
+CREATE INDEX IF NOT EXISTS market_trade_buyer_idx ON market_trade (buyer_id);
+
+DROP TRIGGER IF EXISTS market_trade_immutable ON market_trade;
+CREATE TRIGGER market_trade_immutable BEFORE UPDATE OR DELETE ON market_trade
+    FOR EACH ROW EXECUTE PROCEDURE reject_ledger_change();
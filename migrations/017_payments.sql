@@ -0,0 +1,59 @@
+-- investor subscriptions paid by bank transfer, reference is the structured payment reference
+CREATE TABLE IF NOT EXISTS subscription (
+    id                  VARCHAR(36)    PRIMARY KEY,
+    offering_id         VARCHAR(36)    NOT NULL,
+    user_id             VARCHAR(36)    NOT NULL,
+    currency            VARCHAR(3)     NOT NULL,
+    amount              NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    status              VARCHAR(16)    NOT NULL DEFAULT 'pending',
+    reference           VARCHAR(27)    NOT NULL UNIQUE,
+    reference_type      VARCHAR(4)     NOT NULL,
+    bank_transaction_id VARCHAR(36),
+    paid_at             TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS subscription_user_idx ON subscription (user_id, created_at);
+CREATE INDEX IF NOT EXISTS subscription_offering_idx ON subscription (offering_id, status);
+
+-- imported camt.053 statements and camt.054 notifications
+CREATE TABLE IF NOT EXISTS payment_statement (
+    id           VARCHAR(36)  PRIMARY KEY,
+    message_id   VARCHAR(35)  NOT NULL UNIQUE,
+    type         VARCHAR(8)   NOT NULL,
+    transactions INTEGER      NOT NULL DEFAULT 0,
+    matched      INTEGER      NOT NULL DEFAULT 0,
+    exceptions   INTEGER      NOT NULL DEFAULT 0,
+    duplicates   INTEGER      NOT NULL DEFAULT 0,
+    created_by   VARCHAR(36)  NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- booked bank credits, exceptions wait for admin resolution
+CREATE TABLE IF NOT EXISTS bank_transaction (
+    id                         VARCHAR(36)    PRIMARY KEY,
+    statement_id               VARCHAR(36)    NOT NULL REFERENCES payment_statement (id),
+    account_servicer_reference VARCHAR(140)   NOT NULL UNIQUE,
+    end_to_end_id              VARCHAR(35)    NOT NULL DEFAULT '',
+    account_iban               VARCHAR(34)    NOT NULL DEFAULT '',
+    amount                     NUMERIC(20, 2) NOT NULL,
+    currency                   VARCHAR(3)     NOT NULL,
+    reference                  VARCHAR(35)    NOT NULL DEFAULT '',
+    debtor_name                VARCHAR(140)   NOT NULL DEFAULT '',
+    debtor_iban                VARCHAR(34)    NOT NULL DEFAULT '',
+    booking_date               DATE,
+    status                     VARCHAR(16)    NOT NULL,
+    reason                     VARCHAR(32)    NOT NULL DEFAULT '',
+    message                    TEXT           NOT NULL DEFAULT '',
+    subscription_id            VARCHAR(36)    NOT NULL DEFAULT '',
+    journal_entry_id           VARCHAR(36)    NOT NULL DEFAULT '',
+    note                       TEXT           NOT NULL DEFAULT '',
+    resolved_by                VARCHAR(36)    NOT NULL DEFAULT '',
+    resolved_at                TIMESTAMPTZ,
+    created_at                 TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at                 TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS bank_transaction_status_idx ON bank_transaction (status, booking_date);
+CREATE INDEX IF NOT EXISTS bank_transaction_statement_idx ON bank_transaction (statement_id);
@@ -0,0 +1,17 @@
+-- QR reference of subscriptions payable to QR-IBAN
+ALTER TABLE subscription ADD COLUMN IF NOT EXISTS qr_reference VARCHAR(27);
+
+CREATE UNIQUE INDEX IF NOT EXISTS subscription_qr_reference_idx ON subscription (qr_reference) WHERE qr_reference IS NOT NULL AND qr_reference <> '';
+
+-- bank accounts receiving subscription payments of organisation offerings
+CREATE TABLE IF NOT EXISTS organisation_payment_account (
+    organisation_id VARCHAR(36)  PRIMARY KEY,
+    iban            VARCHAR(34)  NOT NULL,
+    name            VARCHAR(70)  NOT NULL,
+    street          VARCHAR(70)  NOT NULL DEFAULT '',
+    building_number VARCHAR(16)  NOT NULL DEFAULT '',
+    postal_code     VARCHAR(16)  NOT NULL DEFAULT '',
+    town            VARCHAR(35)  NOT NULL,
+    country         VARCHAR(2)   NOT NULL,
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
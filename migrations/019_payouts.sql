@@ -0,0 +1,39 @@
+-- exported pain.001 payment orders
+CREATE TABLE IF NOT EXISTS payout_batch (
+    id           VARCHAR(36)  PRIMARY KEY,
+    message_id   VARCHAR(35)  NOT NULL UNIQUE,
+    status       VARCHAR(16)  NOT NULL,
+    transactions INTEGER      NOT NULL,
+    document     TEXT         NOT NULL,
+    note         TEXT         NOT NULL DEFAULT '',
+    created_by   VARCHAR(36)  NOT NULL,
+    processed_by VARCHAR(36)  NOT NULL DEFAULT '',
+    processed_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- investor payouts, amount is withdrawn from the cash account by the referenced journal entry
+CREATE TABLE IF NOT EXISTS payout (
+    id               VARCHAR(36)    PRIMARY KEY,
+    user_id          VARCHAR(36)    NOT NULL,
+    currency         VARCHAR(3)     NOT NULL,
+    amount           NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    creditor_name    VARCHAR(140)   NOT NULL,
+    iban             VARCHAR(34)    NOT NULL,
+    bic              VARCHAR(11)    NOT NULL DEFAULT '',
+    remittance       VARCHAR(140)   NOT NULL DEFAULT '',
+    execution_date   DATE           NOT NULL,
+    status           VARCHAR(16)    NOT NULL,
+    batch_id         VARCHAR(36)    NOT NULL DEFAULT '',
+    end_to_end_id    VARCHAR(35)    NOT NULL UNIQUE,
+    journal_entry_id VARCHAR(36)    NOT NULL DEFAULT '',
+    validation_error TEXT           NOT NULL DEFAULT '',
+    created_by       VARCHAR(36)    NOT NULL,
+    created_at       TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payout_status_idx ON payout (status, execution_date);
+CREATE INDEX IF NOT EXISTS payout_user_idx ON payout (user_id, created_at);
+CREATE INDEX IF NOT EXISTS payout_batch_idx ON payout (batch_id);
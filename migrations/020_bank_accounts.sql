@@ -0,0 +1,36 @@
+-- investor bank accounts receiving payouts, each investor has one default account
+CREATE TABLE IF NOT EXISTS user_bank_account (
+    id          VARCHAR(36)  PRIMARY KEY,
+    user_id     VARCHAR(36)  NOT NULL,
+    holder_name VARCHAR(70)  NOT NULL,
+    iban        VARCHAR(34)  NOT NULL,
+    bic         VARCHAR(11)  NOT NULL DEFAULT '',
+    is_default  BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (user_id, iban)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_bank_account_default_idx ON user_bank_account (user_id) WHERE is_default;
+
+-- requested bank account changes waiting for confirmation code
+CREATE TABLE IF NOT EXISTS bank_account_change (
+    id              VARCHAR(36)  PRIMARY KEY,
+    user_id         VARCHAR(36)  NOT NULL,
+    bank_account_id VARCHAR(36)  NOT NULL DEFAULT '',
+    action          VARCHAR(16)  NOT NULL,
+    holder_name     VARCHAR(70)  NOT NULL DEFAULT '',
+    iban            VARCHAR(34)  NOT NULL DEFAULT '',
+    bic             VARCHAR(11)  NOT NULL DEFAULT '',
+    is_default      BOOLEAN      NOT NULL DEFAULT FALSE,
+    code_hash       VARCHAR(64)  NOT NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    expires_at      TIMESTAMPTZ  NOT NULL,
+    confirmed_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS bank_account_change_user_idx ON bank_account_change (user_id, created_at);
+
+-- payouts keep the bank account they were requested to
+ALTER TABLE payout ADD COLUMN IF NOT EXISTS bank_account_id VARCHAR(36) NOT NULL DEFAULT '';
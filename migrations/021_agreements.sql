@@ -0,0 +1,32 @@
+-- versioned subscription agreement templates per organisation and language
+CREATE TABLE IF NOT EXISTS agreement_template (
+    id              VARCHAR(36)  PRIMARY KEY,
+    organisation_id VARCHAR(36)  NOT NULL,
+    language        VARCHAR(2)   NOT NULL,
+    version         INTEGER      NOT NULL,
+    title           VARCHAR(255) NOT NULL,
+    body            TEXT         NOT NULL,
+    created_by      VARCHAR(36)  NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, language, version)
+);
+
+-- generated agreements, the PDF is stored as private media and hash is SHA-256 of the file
+CREATE TABLE IF NOT EXISTS subscription_agreement (
+    id               VARCHAR(36)  PRIMARY KEY,
+    subscription_id  VARCHAR(36)  NOT NULL UNIQUE,
+    user_id          VARCHAR(36)  NOT NULL,
+    organisation_id  VARCHAR(36)  NOT NULL,
+    offering_id      VARCHAR(36)  NOT NULL,
+    template_id      VARCHAR(36)  NOT NULL REFERENCES agreement_template (id),
+    template_version INTEGER      NOT NULL,
+    language         VARCHAR(2)   NOT NULL,
+    title            VARCHAR(255) NOT NULL,
+    body             TEXT         NOT NULL,
+    footer           TEXT         NOT NULL,
+    media_id         VARCHAR(36)  NOT NULL,
+    hash             VARCHAR(64)  NOT NULL,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS subscription_agreement_organisation_idx ON subscription_agreement (organisation_id, offering_id, created_at);
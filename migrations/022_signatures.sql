@@ -0,0 +1,56 @@
+-- agreement templates are either subscription agreements or NDAs, versions are counted per kind
+ALTER TABLE agreement_template ADD COLUMN IF NOT EXISTS kind VARCHAR(32) NOT NULL DEFAULT 'subscription_agreement';
+ALTER TABLE agreement_template DROP CONSTRAINT IF EXISTS agreement_template_organisation_id_language_version_key;
+CREATE UNIQUE INDEX IF NOT EXISTS agreement_template_version_idx ON agreement_template (organisation_id, kind, language, version);
+
+-- documents waiting for the signature of a user, document text is copied so the signed PDF reproduces it
+CREATE TABLE IF NOT EXISTS signature_request (
+    id                  VARCHAR(36)  PRIMARY KEY,
+    organisation_id     VARCHAR(36)  NOT NULL,
+    user_id             VARCHAR(36)  NOT NULL,
+    document_type       VARCHAR(32)  NOT NULL,
+    document_id         VARCHAR(36)  NOT NULL,
+    offering_id         VARCHAR(36)  NOT NULL DEFAULT '',
+    title               VARCHAR(255) NOT NULL,
+    body                TEXT         NOT NULL,
+    footer              TEXT         NOT NULL,
+    document_created_at TIMESTAMPTZ  NOT NULL,
+    document_hash       VARCHAR(64)  NOT NULL,
+    provider            VARCHAR(32)  NOT NULL,
+    status              VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    expires_at          TIMESTAMPTZ  NOT NULL,
+    reminders_sent      INTEGER      NOT NULL DEFAULT 0,
+    reminded_at         TIMESTAMPTZ,
+    code_hash           VARCHAR(64)  NOT NULL DEFAULT '',
+    code_attempts       INTEGER      NOT NULL DEFAULT 0,
+    code_expires_at     TIMESTAMPTZ,
+    signer_name         VARCHAR(255) NOT NULL DEFAULT '',
+    signer_email        VARCHAR(255) NOT NULL DEFAULT '',
+    signer_addr         VARCHAR(64)  NOT NULL DEFAULT '',
+    signed_at           TIMESTAMPTZ,
+    signed_media_id     VARCHAR(36),
+    signed_hash         VARCHAR(64)  NOT NULL DEFAULT '',
+    decline_reason      TEXT         NOT NULL DEFAULT '',
+    created_by          VARCHAR(36)  NOT NULL,
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS signature_request_user_idx ON signature_request (user_id, created_at);
+CREATE INDEX IF NOT EXISTS signature_request_organisation_idx ON signature_request (organisation_id, created_at);
+CREATE INDEX IF NOT EXISTS signature_request_pending_idx ON signature_request (expires_at) WHERE status = 'pending';
+-- a document has at most one pending or signed request per signer
+CREATE UNIQUE INDEX IF NOT EXISTS signature_request_document_idx ON signature_request (user_id, document_type, document_id)
+    WHERE status IN ('pending', 'signed');
+
+-- audit trail printed on the signature certificate
+CREATE TABLE IF NOT EXISTS signature_event (
+    id          VARCHAR(36) PRIMARY KEY,
+    request_id  VARCHAR(36) NOT NULL REFERENCES signature_request (id),
+    type        VARCHAR(32) NOT NULL,
+    details     TEXT        NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS signature_event_request_idx ON signature_event (request_id, created_at);
@@ -0,0 +1,2 @@
+-- sensitive emails (invitation tokens, codes) are redacted after delivery
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE;
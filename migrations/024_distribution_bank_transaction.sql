@@ -0,0 +1,4 @@
+-- distributions recorded by organisation admins are funded by a received bank payment
+ALTER TABLE distribution ADD COLUMN IF NOT EXISTS bank_transaction_id VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS distribution_bank_transaction_idx ON distribution (bank_transaction_id) WHERE bank_transaction_id <> '';
@@ -0,0 +1,106 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ActivityDailyCount is a struct to represent aggregated user activities for one day
+type ActivityDailyCount struct {
+	Day            time.Time `json:"day" gorm:"column:day;primary_key"`
+	OrganisationID string    `json:"organisation_id" gorm:"column:organisation_id;primary_key"`
+	OfferingID     string    `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	Type           string    `json:"type" gorm:"column:type;primary_key"`
+	Count          int64     `json:"count" gorm:"column:count"`
+}
+
+// TableName returns table name for struct
+func (*ActivityDailyCount) TableName() string {
+	return "user_activity_daily"
+}
+
+// GetOldestActivityDay returns the start of the day of the oldest raw activity created before 'before'.
+// Returns nil if there are no such activities
+func GetOldestActivityDay(before time.Time) (*time.Time, *cigExchange.APIError) {
+
+	activity := &models.UserActivity{}
+	db := cigExchange.GetDB().Where("created_at < ?", before).Order("created_at asc").First(activity)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch oldest user activity failed", db.Error)
+	}
+
+	created := activity.CreatedAt.UTC()
+	day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+	return &day, nil
+}
+
+// GetActivitiesForDay returns all raw activities created during the given day (UTC)
+func GetActivitiesForDay(tx *gorm.DB, day time.Time) ([]*models.UserActivity, *cigExchange.APIError) {
+
+	activities := make([]*models.UserActivity, 0)
+	db := tx.Where("created_at >= ? AND created_at < ?", day, day.Add(24*time.Hour)).Order("created_at asc").Find(&activities)
+	if db.Error != nil {
+		return activities, cigExchange.NewDatabaseError("Fetch user activities failed", db.Error)
+	}
+	return activities, nil
+}
+
+// RollupActivitiesForDay adds counts of raw activities created during the given day (UTC)
+// to the daily aggregates. Organisation and offering are taken from the saved jwt and info jsonb fields
+func RollupActivitiesForDay(tx *gorm.DB, day time.Time) *cigExchange.APIError {
+
+	db := tx.Exec(`INSERT INTO user_activity_daily (day, organisation_id, offering_id, type, count)
+		SELECT ?::date, COALESCE(jwt->>'organisation_id', ''), COALESCE(info->>'offering_id', ''), type, COUNT(*)
+		FROM user_activity
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY 2, 3, 4
+		ON CONFLICT (day, organisation_id, offering_id, type)
+		DO UPDATE SET count = user_activity_daily.count + EXCLUDED.count`,
+		day, day, day.Add(24*time.Hour))
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Rollup user activities failed", db.Error)
+	}
+	return nil
+}
+
+// DeleteActivitiesForDay removes raw activities created during the given day (UTC)
+func DeleteActivitiesForDay(tx *gorm.DB, day time.Time) *cigExchange.APIError {
+
+	db := tx.Where("created_at >= ? AND created_at < ?", day, day.Add(24*time.Hour)).Delete(&models.UserActivity{})
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete user activities failed", db.Error)
+	}
+	return nil
+}
+
+// ActivityTypeOfferingClick is the activity type posted by the frontend for offering clicks.
+// Dashboard click counts add its daily aggregates to the raw activities
+const ActivityTypeOfferingClick = "offering_click"
+
+// GetOfferingsArchivedClicks sums rolled up offering clicks by offering id.
+// Raw activities are deleted together with their rollup, so the sums never overlap raw counts
+func GetOfferingsArchivedClicks(offeringIDs []string) (map[string]int64, *cigExchange.APIError) {
+
+	clicks := make(map[string]int64)
+	if len(offeringIDs) == 0 {
+		return clicks, nil
+	}
+
+	counts := make([]*ActivityDailyCount, 0)
+	db := cigExchange.GetDB().Select("offering_id, SUM(count) AS count").
+		Where("type = ? AND offering_id IN (?)", ActivityTypeOfferingClick, offeringIDs).
+		Group("offering_id").Find(&counts)
+	if db.Error != nil {
+		return clicks, cigExchange.NewDatabaseError("Fetch archived offering clicks failed", db.Error)
+	}
+	for _, count := range counts {
+		clicks[count.OfferingID] = count.Count
+	}
+	return clicks, nil
+}
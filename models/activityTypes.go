@@ -0,0 +1,133 @@
+package models
+
+// Constants defining the user activity types of p2p backend endpoints
+const (
+	ActivityTypeGetEmailDeadLetters = "get_email_dead_letters"
+	ActivityTypeRetryOutboxEmail    = "retry_outbox_email"
+
+	ActivityTypeGetNotifications              = "get_notifications"
+	ActivityTypeGetNotificationsUnreadCount   = "get_notifications_unread_count"
+	ActivityTypeMarkNotificationRead          = "mark_notification_read"
+	ActivityTypeMarkAllNotificationsRead      = "mark_all_notifications_read"
+	ActivityTypeGetNotificationPreferences    = "get_notification_preferences"
+	ActivityTypeUpdateNotificationPreferences = "update_notification_preferences"
+
+	ActivityTypeGetWebhooks          = "get_webhooks"
+	ActivityTypeCreateWebhook        = "create_webhook"
+	ActivityTypeUpdateWebhook        = "update_webhook"
+	ActivityTypeDeleteWebhook        = "delete_webhook"
+	ActivityTypeGetWebhookDeliveries = "get_webhook_deliveries"
+	ActivityTypeSendWebhookTestEvent = "send_webhook_test_event"
+
+	ActivityTypeGetWatchlist        = "get_watchlist"
+	ActivityTypeAddToWatchlist      = "add_to_watchlist"
+	ActivityTypeRemoveFromWatchlist = "remove_from_watchlist"
+
+	ActivityTypeGetSavedSearches       = "get_saved_searches"
+	ActivityTypeCreateSavedSearch      = "create_saved_search"
+	ActivityTypeUpdateSavedSearch      = "update_saved_search"
+	ActivityTypeDeleteSavedSearch      = "delete_saved_search"
+	ActivityTypeUnsubscribeSavedSearch = "unsubscribe_saved_search"
+
+	ActivityTypeAskOfferingQuestion            = "ask_offering_question"
+	ActivityTypeGetUserOfferingQuestions       = "get_user_offering_questions"
+	ActivityTypeReplyOfferingQuestion          = "reply_offering_question"
+	ActivityTypeGetOfferingQuestions           = "get_offering_questions"
+	ActivityTypeAnswerOfferingQuestion         = "answer_offering_question"
+	ActivityTypeModerateOfferingQuestion       = "moderate_offering_question"
+	ActivityTypeDeleteOfferingQuestion         = "delete_offering_question"
+	ActivityTypeDeleteOfferingQuestionReply    = "delete_offering_question_reply"
+	ActivityTypeGetPublicOfferingQuestions     = "get_public_offering_questions"
+	ActivityTypeGetInvestorProfile             = "get_investor_profile"
+	ActivityTypeUpdateInvestorProfile          = "update_investor_profile"
+	ActivityTypeSubmitInvestorProfile          = "submit_investor_profile"
+	ActivityTypeUploadKYCDocument              = "upload_kyc_document"
+	ActivityTypeGetKYCDocument                 = "get_kyc_document"
+	ActivityTypeDeleteKYCDocument              = "delete_kyc_document"
+	ActivityTypeGetKYCProfiles                 = "get_kyc_profiles"
+	ActivityTypeGetKYCProfile                  = "get_kyc_profile"
+	ActivityTypeApproveKYCProfile              = "approve_kyc_profile"
+	ActivityTypeRejectKYCProfile               = "reject_kyc_profile"
+	ActivityTypeGetTradingOffering             = "get_trading_offering"
+	ActivityTypeGetOfferingEligibility         = "get_offering_eligibility"
+	ActivityTypeUpdateOfferingEligibility      = "update_offering_eligibility"
+	ActivityTypeGetTranslationCoverage         = "get_translation_coverage"
+	ActivityTypeGetTranslationGaps             = "get_translation_gaps"
+	ActivityTypeUpdateOfferingTranslations     = "update_offering_translations"
+	ActivityTypeUpdateOrganisationTranslations = "update_organisation_translations"
+	ActivityTypeGetTranslationSettings         = "get_translation_settings"
+	ActivityTypeUpdateTranslationSettings      = "update_translation_settings"
+	ActivityTypeGetTranslators                 = "get_translators"
+	ActivityTypeSetTranslator                  = "set_translator"
+	ActivityTypeDeleteTranslator               = "delete_translator"
+	ActivityTypeGetFXRates                     = "get_fx_rates"
+	ActivityTypeGetFXRateHistory               = "get_fx_rate_history"
+	ActivityTypeCreateFXRate                   = "create_fx_rate"
+	ActivityTypeGetLoanTerms                   = "get_loan_terms"
+	ActivityTypeUpdateLoanTerms                = "update_loan_terms"
+	ActivityTypeGetOfferingSchedule            = "get_offering_schedule"
+	ActivityTypeGetOfferingHoldings            = "get_offering_holdings"
+	ActivityTypeSetOfferingHolding             = "set_offering_holding"
+	ActivityTypeGetOfferingDistributions       = "get_offering_distributions"
+	ActivityTypeGetOfferingDistribution        = "get_offering_distribution"
+	ActivityTypeCreateOfferingDistribution     = "create_offering_distribution"
+	ActivityTypeGetUserHoldings                = "get_user_holdings"
+	ActivityTypeGetUserDistributions           = "get_user_distributions"
+	ActivityTypeGetUserAccounts                = "get_user_accounts"
+	ActivityTypeGetUserAccount                 = "get_user_account"
+	ActivityTypeGetUserAccountEntries          = "get_user_account_entries"
+	ActivityTypeCreateDeposit                  = "create_deposit"
+	ActivityTypeCreateWithdrawal               = "create_withdrawal"
+	ActivityTypeCreateCommitment               = "create_commitment"
+	ActivityTypeGetOfferingAccounts            = "get_offering_accounts"
+	ActivityTypeGetUserListings                = "get_user_listings"
+	ActivityTypeCreateListing                  = "create_listing"
+	ActivityTypeCancelListing                  = "cancel_listing"
+	ActivityTypeGetUserBids                    = "get_user_bids"
+	ActivityTypeCreateBid                      = "create_bid"
+	ActivityTypeCancelBid                      = "cancel_bid"
+	ActivityTypeGetUserTrades                  = "get_user_trades"
+	ActivityTypeGetOfferingMarket              = "get_offering_market"
+	ActivityTypeGetOfferingPriceHistory        = "get_offering_price_history"
+	ActivityTypeGetUserSubscriptions           = "get_user_subscriptions"
+	ActivityTypeCreateSubscription             = "create_subscription"
+	ActivityTypeCancelSubscription             = "cancel_subscription"
+	ActivityTypeImportPaymentStatement         = "import_payment_statement"
+	ActivityTypeGetPaymentStatements           = "get_payment_statements"
+	ActivityTypeGetPaymentExceptions           = "get_payment_exceptions"
+	ActivityTypeResolvePaymentException        = "resolve_payment_exception"
+	ActivityTypeGetSubscriptionQRBill          = "get_subscription_qr_bill"
+	ActivityTypeGetPaymentAccount              = "get_payment_account"
+	ActivityTypeUpdatePaymentAccount           = "update_payment_account"
+	ActivityTypeGetUserPayouts                 = "get_user_payouts"
+	ActivityTypeCreatePayout                   = "create_payout"
+	ActivityTypeCancelPayout                   = "cancel_payout"
+	ActivityTypeGetPayouts                     = "get_payouts"
+	ActivityTypeCreatePayoutBatch              = "create_payout_batch"
+	ActivityTypeGetPayoutBatches               = "get_payout_batches"
+	ActivityTypeGetPayoutBatch                 = "get_payout_batch"
+	ActivityTypeExecutePayoutBatch             = "execute_payout_batch"
+	ActivityTypeRejectPayoutBatch              = "reject_payout_batch"
+	ActivityTypeGetUserBankAccounts            = "get_user_bank_accounts"
+	ActivityTypeCreateBankAccount              = "create_bank_account"
+	ActivityTypeUpdateBankAccount              = "update_bank_account"
+	ActivityTypeDeleteBankAccount              = "delete_bank_account"
+	ActivityTypeSetDefaultBankAccount          = "set_default_bank_account"
+	ActivityTypeConfirmBankAccountChange       = "confirm_bank_account_change"
+	ActivityTypeGetSubscriptionAgreement       = "get_subscription_agreement"
+	ActivityTypeCreateSubscriptionAgreement    = "create_subscription_agreement"
+	ActivityTypeGetAgreementTemplates          = "get_agreement_templates"
+	ActivityTypeCreateAgreementTemplate        = "create_agreement_template"
+	ActivityTypeGetOrganisationAgreements      = "get_organisation_agreements"
+)
+
+// Constants defining signature request activity types
+const (
+	ActivityTypeGetSignatureRequests             = "get_signature_requests"
+	ActivityTypeRequestSignatureCode             = "request_signature_code"
+	ActivityTypeSignDocument                     = "sign_document"
+	ActivityTypeDeclineSignatureRequest          = "decline_signature_request"
+	ActivityTypeCreateSignatureRequest           = "create_signature_request"
+	ActivityTypeCancelSignatureRequest           = "cancel_signature_request"
+	ActivityTypeGetOrganisationSignatureRequests = "get_organisation_signature_requests"
+)
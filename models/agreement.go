@@ -0,0 +1,507 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/documents"
+	"cig-exchange-p2p-backend/i18n"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MediaTypeSubscriptionAgreement is media type of generated subscription agreements
+const MediaTypeSubscriptionAgreement = "subscription-agreement"
+
+// Constants defining agreement template kinds
+const (
+	AgreementTemplateKindSubscription = "subscription_agreement"
+	AgreementTemplateKindNDA          = "nda"
+)
+
+// maxAgreementTemplateSize limits template body size in bytes
+const maxAgreementTemplateSize = 64 * 1024
+
+// AgreementTemplate is a struct to represent a version of organisation subscription agreement or NDA template.
+// Templates are immutable, saving a template creates a new version for the organisation, kind and language.
+// Body is a text/template filled with AgreementData, e.g. {{.Investor.Name}}
+type AgreementTemplate struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	OrganisationID string    `json:"organisation_id" gorm:"column:organisation_id"`
+	Kind           string    `json:"kind" gorm:"column:kind"`
+	Language       string    `json:"language" gorm:"column:language"`
+	Version        int       `json:"version" gorm:"column:version"`
+	Title          string    `json:"title" gorm:"column:title"`
+	Body           string    `json:"body" gorm:"column:body"`
+	CreatedBy      string    `json:"created_by" gorm:"column:created_by"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*AgreementTemplate) TableName() string {
+	return "agreement_template"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*AgreementTemplate) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// AgreementInvestor contains investor data available in agreement templates
+type AgreementInvestor struct {
+	Name        string
+	FirstName   string
+	LastName    string
+	DateOfBirth string
+	Nationality string
+	Street      string
+	PostalCode  string
+	City        string
+	Country     string
+}
+
+// AgreementOffering contains offering data available in agreement templates
+type AgreementOffering struct {
+	ID           string
+	Title        string
+	InterestRate string
+	TenorMonths  string
+}
+
+// AgreementSubscription contains subscription data available in agreement templates
+type AgreementSubscription struct {
+	ID        string
+	Amount    string
+	Currency  string
+	Reference string
+	Date      string
+}
+
+// AgreementData is the data agreement templates are filled with, all values are formatted strings
+type AgreementData struct {
+	Investor     AgreementInvestor
+	Offering     AgreementOffering
+	Organisation string
+	Subscription AgreementSubscription
+}
+
+// SampleAgreementData returns placeholder data used to validate and preview templates
+func SampleAgreementData() *AgreementData {
+
+	return &AgreementData{
+		Investor: AgreementInvestor{
+			Name:        "Jane Doe",
+			FirstName:   "Jane",
+			LastName:    "Doe",
+			DateOfBirth: "1980-01-31",
+			Nationality: "CH",
+			Street:      "Bahnhofstrasse 1",
+			PostalCode:  "8001",
+			City:        "Zurich",
+			Country:     "CH",
+		},
+		Offering: AgreementOffering{
+			ID:           "00000000-0000-0000-0000-000000000000",
+			Title:        "Sample offering",
+			InterestRate: "5.00",
+			TenorMonths:  "24",
+		},
+		Organisation: "Sample organisation",
+		Subscription: AgreementSubscription{
+			ID:        "00000000-0000-0000-0000-000000000000",
+			Amount:    "10000.00",
+			Currency:  DefaultCurrency,
+			Reference: "RF18539007547034",
+			Date:      time.Now().Format("2006-01-02"),
+		},
+	}
+}
+
+// NewAgreementData collects subscription data for agreement templates, offering title uses the template language
+func NewAgreementData(subscription *Subscription, offering *models.Offering, profile *InvestorProfile, language string) (*AgreementData, *cigExchange.APIError) {
+
+	organisation, apiError := models.GetOrganisation(offering.OrganisationID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	terms, apiError := GetLoanTerms(offering)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	data := &AgreementData{
+		Investor: AgreementInvestor{
+			Name:        strings.TrimSpace(profile.FirstName + " " + profile.LastName),
+			FirstName:   profile.FirstName,
+			LastName:    profile.LastName,
+			Nationality: profile.Nationality,
+			Street:      profile.Street,
+			PostalCode:  profile.PostalCode,
+			City:        profile.City,
+			Country:     profile.ResidenceCountry,
+		},
+		Offering: AgreementOffering{
+			ID:           offering.ID,
+			Title:        i18n.TranslateJSON(offering.Title.RawMessage, i18n.FallbackChain(language)),
+			InterestRate: terms.InterestRate.StringFixed(2),
+		},
+		Organisation: organisation.Name,
+		Subscription: AgreementSubscription{
+			ID:        subscription.ID,
+			Amount:    subscription.Amount.StringFixed(CurrencyDecimalPlaces(subscription.Currency)),
+			Currency:  subscription.Currency,
+			Reference: subscription.Reference,
+			Date:      subscription.CreatedAt.Format("2006-01-02"),
+		},
+	}
+	if profile.DateOfBirth != nil {
+		data.Investor.DateOfBirth = profile.DateOfBirth.Format("2006-01-02")
+	}
+	if terms.TenorMonths > 0 {
+		data.Offering.TenorMonths = strconv.Itoa(terms.TenorMonths)
+	}
+	return data, nil
+}
+
+// NewNDAAgreementData collects investor data for NDA templates, offering is optional and subscription fields stay empty
+func NewNDAAgreementData(organisationID string, offering *models.Offering, profile *InvestorProfile, language string) (*AgreementData, *cigExchange.APIError) {
+
+	organisation, apiError := models.GetOrganisation(organisationID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	data := &AgreementData{
+		Investor: AgreementInvestor{
+			Name:        strings.TrimSpace(profile.FirstName + " " + profile.LastName),
+			FirstName:   profile.FirstName,
+			LastName:    profile.LastName,
+			Nationality: profile.Nationality,
+			Street:      profile.Street,
+			PostalCode:  profile.PostalCode,
+			City:        profile.City,
+			Country:     profile.ResidenceCountry,
+		},
+		Organisation: organisation.Name,
+	}
+	if profile.DateOfBirth != nil {
+		data.Investor.DateOfBirth = profile.DateOfBirth.Format("2006-01-02")
+	}
+	if offering != nil {
+		data.Offering.ID = offering.ID
+		data.Offering.Title = i18n.TranslateJSON(offering.Title.RawMessage, i18n.FallbackChain(language))
+	}
+	return data, nil
+}
+
+// Render fills template body with the data
+func (agreementTemplate *AgreementTemplate) Render(data *AgreementData) (string, error) {
+
+	tmpl, err := template.New(agreementTemplate.ID).Option("missingkey=error").Parse(agreementTemplate.Body)
+	if err != nil {
+		return "", err
+	}
+
+	builder := &strings.Builder{}
+	err = tmpl.Execute(builder, data)
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// Create validates template and inserts it as the next version for the organisation, kind and language.
+// Empty kind creates a subscription agreement template
+func (agreementTemplate *AgreementTemplate) Create() *cigExchange.APIError {
+
+	if len(agreementTemplate.Kind) == 0 {
+		agreementTemplate.Kind = AgreementTemplateKindSubscription
+	}
+	agreementTemplate.Language = strings.ToLower(strings.TrimSpace(agreementTemplate.Language))
+	agreementTemplate.Title = strings.TrimSpace(agreementTemplate.Title)
+
+	missingFields := make([]string, 0)
+	if len(agreementTemplate.Language) == 0 {
+		missingFields = append(missingFields, "language")
+	}
+	if len(agreementTemplate.Title) == 0 {
+		missingFields = append(missingFields, "title")
+	}
+	if len(strings.TrimSpace(agreementTemplate.Body)) == 0 {
+		missingFields = append(missingFields, "body")
+	}
+	if len(missingFields) > 0 {
+		return cigExchange.NewRequiredFieldError(missingFields)
+	}
+
+	if agreementTemplate.Kind != AgreementTemplateKindSubscription && agreementTemplate.Kind != AgreementTemplateKindNDA {
+		return cigExchange.NewInvalidFieldError("kind", "Kind must be one of: "+AgreementTemplateKindSubscription+", "+AgreementTemplateKindNDA)
+	}
+	if !i18n.IsSupportedLanguage(agreementTemplate.Language) {
+		return cigExchange.NewInvalidFieldError("language", "Language must be one of: "+strings.Join(i18n.SupportedLanguages, ", "))
+	}
+	if len([]rune(agreementTemplate.Title)) > 255 {
+		return cigExchange.NewInvalidFieldError("title", "Title must have at most 255 characters")
+	}
+	if len(agreementTemplate.Body) > maxAgreementTemplateSize {
+		return cigExchange.NewInvalidFieldError("body", "Template must have at most 64 KB")
+	}
+	// rendering sample data catches syntax errors and unknown fields
+	_, err := agreementTemplate.Render(SampleAgreementData())
+	if err != nil {
+		return cigExchange.NewInvalidFieldError("body", "Invalid template: "+err.Error())
+	}
+
+	tx := cigExchange.GetDB().Begin()
+
+	latest := &AgreementTemplate{}
+	db := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where(&AgreementTemplate{OrganisationID: agreementTemplate.OrganisationID, Kind: agreementTemplate.Kind, Language: agreementTemplate.Language}).
+		Order("version desc").First(latest)
+	if db.Error != nil && !db.RecordNotFound() {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Fetch agreement template failed", db.Error)
+	}
+
+	// invalidate the uuid
+	agreementTemplate.ID = ""
+	agreementTemplate.Version = latest.Version + 1
+
+	db = tx.Create(agreementTemplate)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create agreement template failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create agreement template failed", db.Error)
+	}
+	return nil
+}
+
+// GetAgreementTemplates queries organisation templates, newest versions first. Empty kind or language returns all of them
+func GetAgreementTemplates(organisationID, kind, language string) ([]*AgreementTemplate, *cigExchange.APIError) {
+
+	templates := make([]*AgreementTemplate, 0)
+	db := cigExchange.GetDB().Where(&AgreementTemplate{OrganisationID: organisationID, Kind: kind, Language: language}).
+		Order("kind asc, language asc, version desc").Find(&templates)
+	if db.Error != nil {
+		return templates, cigExchange.NewDatabaseError("Fetch agreement templates failed", db.Error)
+	}
+	return templates, nil
+}
+
+// GetAgreementTemplate queries single organisation template version
+func GetAgreementTemplate(organisationID, templateID string) (*AgreementTemplate, *cigExchange.APIError) {
+
+	agreementTemplate := &AgreementTemplate{}
+	db := cigExchange.GetDB().Where(&AgreementTemplate{ID: templateID, OrganisationID: organisationID}).First(agreementTemplate)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("template_id", "Agreement template doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch agreement template failed", db.Error)
+	}
+	return agreementTemplate, nil
+}
+
+// GetLatestAgreementTemplate queries latest template version of the kind in the first language of the chain organisation has,
+// nil is returned if organisation has no templates in these languages
+func GetLatestAgreementTemplate(organisationID, kind string, chain []string) (*AgreementTemplate, *cigExchange.APIError) {
+
+	for _, language := range chain {
+		agreementTemplate := &AgreementTemplate{}
+		db := cigExchange.GetDB().Where(&AgreementTemplate{OrganisationID: organisationID, Kind: kind, Language: language}).
+			Order("version desc").First(agreementTemplate)
+		if db.Error != nil {
+			if db.RecordNotFound() {
+				continue
+			}
+			return nil, cigExchange.NewDatabaseError("Fetch agreement template failed", db.Error)
+		}
+		return agreementTemplate, nil
+	}
+	return nil, nil
+}
+
+// SubscriptionAgreement is a struct to represent agreement document generated for a subscription.
+// PDF is stored as organisation private media, rendered body is kept to reproduce the document
+type SubscriptionAgreement struct {
+	ID              string       `json:"id" gorm:"column:id;primary_key"`
+	SubscriptionID  string       `json:"subscription_id" gorm:"column:subscription_id"`
+	UserID          string       `json:"user_id" gorm:"column:user_id"`
+	OrganisationID  string       `json:"organisation_id" gorm:"column:organisation_id"`
+	OfferingID      string       `json:"offering_id" gorm:"column:offering_id"`
+	TemplateID      string       `json:"template_id" gorm:"column:template_id"`
+	TemplateVersion int          `json:"template_version" gorm:"column:template_version"`
+	Language        string       `json:"language" gorm:"column:language"`
+	Title           string       `json:"title" gorm:"column:title"`
+	Body            string       `json:"-" gorm:"column:body"`
+	Footer          string       `json:"-" gorm:"column:footer"`
+	MediaID         string       `json:"media_id" gorm:"column:media_id"`
+	Media           models.Media `json:"media" gorm:"foreignkey:MediaID;association_foreignkey:ID"`
+	Hash            string       `json:"hash" gorm:"column:hash"`
+	CreatedAt       time.Time    `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*SubscriptionAgreement) TableName() string {
+	return "subscription_agreement"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*SubscriptionAgreement) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// NewSubscriptionAgreement fills the template with subscription data and renders agreement PDF.
+// Returned agreement isn't saved yet, its hash is the SHA-256 of the returned PDF
+func NewSubscriptionAgreement(subscription *Subscription, offering *models.Offering, profile *InvestorProfile, agreementTemplate *AgreementTemplate) (*SubscriptionAgreement, []byte, *cigExchange.APIError) {
+
+	data, apiError := NewAgreementData(subscription, offering, profile, agreementTemplate.Language)
+	if apiError != nil {
+		return nil, nil, apiError
+	}
+
+	body, err := agreementTemplate.Render(data)
+	if err != nil {
+		return nil, nil, cigExchange.NewInvalidFieldError("template_id", "Unable to fill agreement template: "+err.Error())
+	}
+
+	agreement := &SubscriptionAgreement{
+		SubscriptionID:  subscription.ID,
+		UserID:          subscription.UserID,
+		OrganisationID:  offering.OrganisationID,
+		OfferingID:      offering.ID,
+		TemplateID:      agreementTemplate.ID,
+		TemplateVersion: agreementTemplate.Version,
+		Language:        agreementTemplate.Language,
+		Title:           agreementTemplate.Title,
+		Body:            body,
+		Footer:          data.Organisation + " - " + agreementTemplate.Title + " v" + strconv.Itoa(agreementTemplate.Version) + " - " + subscription.Reference,
+		CreatedAt:       time.Now().Truncate(time.Second),
+	}
+
+	document, err := agreement.Document().PDF()
+	if err != nil {
+		return nil, nil, cigExchange.NewInvalidFieldError("template_id", "Unable to render agreement: "+err.Error())
+	}
+	hash := sha256.Sum256(document)
+	agreement.Hash = hex.EncodeToString(hash[:])
+	return agreement, document, nil
+}
+
+// Document returns printable agreement document
+func (agreement *SubscriptionAgreement) Document() *documents.Document {
+
+	return &documents.Document{
+		Title:     agreement.Title,
+		Body:      agreement.Body,
+		Footer:    agreement.Footer,
+		CreatedAt: agreement.CreatedAt,
+	}
+}
+
+// Create inserts agreement together with its media record, file must be stored by the caller
+func (agreement *SubscriptionAgreement) Create(size int) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+
+	// url stays empty as agreements are served only to the investor and organisation members
+	agreement.Media = models.Media{
+		Type:          MediaTypeSubscriptionAgreement,
+		Title:         agreement.Title,
+		MimeType:      "application/pdf",
+		FileExtension: ".pdf",
+		FileSize:      size,
+	}
+	db := tx.Create(&agreement.Media)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create media failed", db.Error)
+	}
+	agreement.MediaID = agreement.Media.ID
+
+	// invalidate the uuid
+	agreement.ID = ""
+	db = tx.Set("gorm:save_associations", false).Create(agreement)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create subscription agreement failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create subscription agreement failed", db.Error)
+	}
+	return nil
+}
+
+// Delete removes agreement and its media record from db
+func (agreement *SubscriptionAgreement) Delete() *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Delete(agreement)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete subscription agreement failed", db.Error)
+	}
+	db = tx.Delete(&models.Media{ID: agreement.MediaID})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete subscription agreement media failed", db.Error)
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete subscription agreement failed", db.Error)
+	}
+	return nil
+}
+
+// GetSubscriptionAgreement queries agreement of the subscription, nil is returned if there is none
+func GetSubscriptionAgreement(subscriptionID string) (*SubscriptionAgreement, *cigExchange.APIError) {
+
+	agreement := &SubscriptionAgreement{}
+	db := cigExchange.GetDB().Preload("Media").Where(&SubscriptionAgreement{SubscriptionID: subscriptionID}).First(agreement)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch subscription agreement failed", db.Error)
+	}
+	return agreement, nil
+}
+
+// GetOrganisationAgreement queries single agreement of organisation offerings
+func GetOrganisationAgreement(organisationID, agreementID string) (*SubscriptionAgreement, *cigExchange.APIError) {
+
+	agreement := &SubscriptionAgreement{}
+	db := cigExchange.GetDB().Preload("Media").Where(&SubscriptionAgreement{ID: agreementID, OrganisationID: organisationID}).First(agreement)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("agreement_id", "Subscription agreement doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch subscription agreement failed", db.Error)
+	}
+	return agreement, nil
+}
+
+// GetOrganisationAgreements queries agreements of organisation offerings, newest first. Empty offering id returns all offerings
+func GetOrganisationAgreements(organisationID, offeringID string) ([]*SubscriptionAgreement, *cigExchange.APIError) {
+
+	agreements := make([]*SubscriptionAgreement, 0)
+	db := cigExchange.GetDB().Preload("Media").Where(&SubscriptionAgreement{OrganisationID: organisationID, OfferingID: offeringID}).
+		Order("created_at desc").Find(&agreements)
+	if db.Error != nil {
+		return agreements, cigExchange.NewDatabaseError("Fetch subscription agreements failed", db.Error)
+	}
+	return agreements, nil
+}
@@ -0,0 +1,369 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining bank account change actions
+const (
+	BankAccountChangeCreate     = "create"
+	BankAccountChangeUpdate     = "update"
+	BankAccountChangeDelete     = "delete"
+	BankAccountChangeSetDefault = "set_default"
+)
+
+// Constants defining bank account change confirmation
+const (
+	BankAccountChangeCodeLength  = 6
+	BankAccountChangeExpiration  = 10 * time.Minute
+	BankAccountChangeMaxAttempts = 5
+)
+
+// BankAccount is a struct to represent investor bank account receiving payouts.
+// Each investor with bank accounts has exactly one default account
+type BankAccount struct {
+	ID         string    `json:"id" gorm:"column:id;primary_key"`
+	UserID     string    `json:"user_id" gorm:"column:user_id"`
+	HolderName string    `json:"holder_name" gorm:"column:holder_name"`
+	IBAN       string    `json:"iban" gorm:"column:iban"`
+	BIC        string    `json:"bic" gorm:"column:bic"`
+	IsDefault  bool      `json:"is_default" gorm:"column:is_default"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*BankAccount) TableName() string {
+	return "user_bank_account"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*BankAccount) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// BankAccountChange is a struct to represent requested bank account change waiting for OTP confirmation.
+// Only the hash of the confirmation code is stored
+type BankAccountChange struct {
+	ID            string     `json:"id" gorm:"column:id;primary_key"`
+	UserID        string     `json:"user_id" gorm:"column:user_id"`
+	BankAccountID string     `json:"bank_account_id" gorm:"column:bank_account_id"`
+	Action        string     `json:"action" gorm:"column:action"`
+	HolderName    string     `json:"holder_name" gorm:"column:holder_name"`
+	IBAN          string     `json:"iban" gorm:"column:iban"`
+	BIC           string     `json:"bic" gorm:"column:bic"`
+	IsDefault     bool       `json:"is_default" gorm:"column:is_default"`
+	CodeHash      string     `json:"-" gorm:"column:code_hash"`
+	Attempts      int        `json:"attempts" gorm:"column:attempts"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"column:expires_at"`
+	ConfirmedAt   *time.Time `json:"confirmed_at" gorm:"column:confirmed_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*BankAccountChange) TableName() string {
+	return "bank_account_change"
+}
+
+// Validate normalizes and validates holder name, IBAN and optional BIC
+func (account *BankAccount) Validate() *cigExchange.APIError {
+
+	account.HolderName = strings.Join(strings.Fields(account.HolderName), " ")
+	account.IBAN = NormalizeIBAN(account.IBAN)
+	account.BIC = NormalizeIBAN(account.BIC)
+
+	missingFields := make([]string, 0)
+	if len(account.HolderName) == 0 {
+		missingFields = append(missingFields, "holder_name")
+	}
+	if len(account.IBAN) == 0 {
+		missingFields = append(missingFields, "iban")
+	}
+	if len(missingFields) > 0 {
+		return cigExchange.NewRequiredFieldError(missingFields)
+	}
+
+	// payment orders limit creditor name to 70 characters
+	if len([]rune(account.HolderName)) > 70 {
+		return cigExchange.NewInvalidFieldError("holder_name", "Holder name must have at most 70 characters")
+	}
+	if reason := CheckIBAN(account.IBAN); len(reason) > 0 {
+		return cigExchange.NewInvalidFieldError("iban", reason)
+	}
+	if len(account.BIC) > 0 {
+		return validateBIC(account.BIC)
+	}
+	return nil
+}
+
+// hashBankAccountChangeCode hashes confirmation code together with change id
+func hashBankAccountChangeCode(changeID, code string) string {
+
+	hash := sha256.Sum256([]byte(changeID + ":" + code))
+	return hex.EncodeToString(hash[:])
+}
+
+// Create validates requested change and inserts it, the returned confirmation code must be sent to the user
+func (change *BankAccountChange) Create() (string, *cigExchange.APIError) {
+
+	switch change.Action {
+	case BankAccountChangeCreate, BankAccountChangeUpdate:
+		account := &BankAccount{HolderName: change.HolderName, IBAN: change.IBAN, BIC: change.BIC}
+		apiError := account.Validate()
+		if apiError != nil {
+			return "", apiError
+		}
+		change.HolderName = account.HolderName
+		change.IBAN = account.IBAN
+		change.BIC = account.BIC
+	case BankAccountChangeDelete, BankAccountChangeSetDefault:
+		change.HolderName = ""
+		change.IBAN = ""
+		change.BIC = ""
+	default:
+		return "", cigExchange.NewInvalidFieldError("action", "Unsupported bank account change")
+	}
+
+	if change.Action == BankAccountChangeCreate {
+		change.BankAccountID = ""
+	} else {
+		_, apiError := GetUserBankAccount(change.UserID, change.BankAccountID)
+		if apiError != nil {
+			return "", apiError
+		}
+		change.IsDefault = change.Action == BankAccountChangeSetDefault
+	}
+
+	code, err := randomDigits(BankAccountChangeCodeLength)
+	if err != nil {
+		return "", cigExchange.NewInvalidFieldError("code", "Unable to generate confirmation code")
+	}
+
+	// code hash depends on change id, so id is set before insert
+	change.ID = cigExchange.RandomUUID()
+	change.CodeHash = hashBankAccountChangeCode(change.ID, code)
+	change.Attempts = 0
+	change.ExpiresAt = time.Now().Add(BankAccountChangeExpiration)
+	change.ConfirmedAt = nil
+
+	db := cigExchange.GetDB().Create(change)
+	if db.Error != nil {
+		return "", cigExchange.NewDatabaseError("Create bank account change failed", db.Error)
+	}
+	return code, nil
+}
+
+// Confirm checks the confirmation code and applies the change to user bank accounts, the changed account is returned.
+// Wrong codes are counted, the change can't be confirmed after too many attempts or when expired
+func (change *BankAccountChange) Confirm(code string) (*BankAccount, *cigExchange.APIError) {
+
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&BankAccountChange{ID: change.ID}).First(change)
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, cigExchange.NewDatabaseError("Fetch bank account change failed", db.Error)
+	}
+	if change.ConfirmedAt != nil {
+		tx.Rollback()
+		return nil, cigExchange.NewInvalidFieldError("change_id", "Change is already confirmed")
+	}
+	if time.Now().After(change.ExpiresAt) {
+		tx.Rollback()
+		return nil, cigExchange.NewInvalidFieldError("change_id", "Confirmation code expired, request the change again")
+	}
+	if change.Attempts >= BankAccountChangeMaxAttempts {
+		tx.Rollback()
+		return nil, cigExchange.NewInvalidFieldError("change_id", "Too many wrong confirmation codes, request the change again")
+	}
+
+	hash := hashBankAccountChangeCode(change.ID, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(change.CodeHash)) != 1 {
+		change.Attempts++
+		db = tx.Model(change).Update("attempts", change.Attempts)
+		if db.Error != nil {
+			tx.Rollback()
+			return nil, cigExchange.NewDatabaseError("Update bank account change failed", db.Error)
+		}
+		db = tx.Commit()
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Update bank account change failed", db.Error)
+		}
+		return nil, cigExchange.NewInvalidFieldError("code", "Invalid confirmation code")
+	}
+
+	account, apiError := change.apply(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return nil, apiError
+	}
+
+	now := time.Now()
+	change.ConfirmedAt = &now
+	change.BankAccountID = account.ID
+	db = tx.Model(change).Updates(map[string]interface{}{"confirmed_at": change.ConfirmedAt, "bank_account_id": change.BankAccountID})
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, cigExchange.NewDatabaseError("Update bank account change failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Confirm bank account change failed", db.Error)
+	}
+	return account, nil
+}
+
+// apply performs confirmed change in the transaction
+func (change *BankAccountChange) apply(tx *gorm.DB) (*BankAccount, *cigExchange.APIError) {
+
+	count := 0
+	if len(change.IBAN) > 0 {
+		db := tx.Model(&BankAccount{}).Where("user_id = ? AND iban = ? AND id <> ?", change.UserID, change.IBAN, change.BankAccountID).Count(&count)
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Fetch bank accounts failed", db.Error)
+		}
+		if count > 0 {
+			return nil, cigExchange.NewInvalidFieldError("iban", "Bank account is already registered")
+		}
+	}
+
+	if change.Action == BankAccountChangeCreate {
+		// first account becomes the default one
+		db := tx.Model(&BankAccount{}).Where(&BankAccount{UserID: change.UserID}).Count(&count)
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Fetch bank accounts failed", db.Error)
+		}
+
+		account := &BankAccount{
+			UserID:     change.UserID,
+			HolderName: change.HolderName,
+			IBAN:       change.IBAN,
+			BIC:        change.BIC,
+		}
+		db = tx.Create(account)
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Create bank account failed", db.Error)
+		}
+
+		if change.IsDefault || count == 0 {
+			account.IsDefault = true
+			return account, setDefaultBankAccount(tx, change.UserID, account.ID)
+		}
+		return account, nil
+	}
+
+	account := &BankAccount{}
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&BankAccount{ID: change.BankAccountID, UserID: change.UserID}).First(account)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("bank_account_id", "Bank account doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank account failed", db.Error)
+	}
+
+	switch change.Action {
+	case BankAccountChangeUpdate:
+		db = tx.Model(account).Updates(map[string]interface{}{"holder_name": change.HolderName, "iban": change.IBAN, "bic": change.BIC})
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Update bank account failed", db.Error)
+		}
+	case BankAccountChangeDelete:
+		db = tx.Delete(account)
+		if db.Error != nil {
+			return nil, cigExchange.NewDatabaseError("Delete bank account failed", db.Error)
+		}
+		if !account.IsDefault {
+			return account, nil
+		}
+		// the oldest remaining account becomes the default one
+		next := &BankAccount{}
+		db = tx.Where(&BankAccount{UserID: change.UserID}).Order("created_at asc").First(next)
+		if db.Error != nil {
+			if db.RecordNotFound() {
+				return account, nil
+			}
+			return nil, cigExchange.NewDatabaseError("Fetch bank account failed", db.Error)
+		}
+		return account, setDefaultBankAccount(tx, change.UserID, next.ID)
+	case BankAccountChangeSetDefault:
+		account.IsDefault = true
+		return account, setDefaultBankAccount(tx, change.UserID, account.ID)
+	}
+	return account, nil
+}
+
+// setDefaultBankAccount marks the account as default and unmarks other user accounts
+func setDefaultBankAccount(tx *gorm.DB, userID, bankAccountID string) *cigExchange.APIError {
+
+	db := tx.Model(&BankAccount{}).Where("user_id = ? AND is_default AND id <> ?", userID, bankAccountID).Update("is_default", false)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update bank accounts failed", db.Error)
+	}
+	db = tx.Model(&BankAccount{}).Where(&BankAccount{ID: bankAccountID, UserID: userID}).Update("is_default", true)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update bank account failed", db.Error)
+	}
+	return nil
+}
+
+// GetUserBankAccounts queries all investor bank accounts, default account first
+func GetUserBankAccounts(userID string) ([]*BankAccount, *cigExchange.APIError) {
+
+	accounts := make([]*BankAccount, 0)
+	db := cigExchange.GetDB().Where(&BankAccount{UserID: userID}).Order("is_default desc, created_at asc").Find(&accounts)
+	if db.Error != nil {
+		return accounts, cigExchange.NewDatabaseError("Fetch bank accounts failed", db.Error)
+	}
+	return accounts, nil
+}
+
+// GetUserBankAccount queries single investor bank account
+func GetUserBankAccount(userID, bankAccountID string) (*BankAccount, *cigExchange.APIError) {
+
+	account := &BankAccount{}
+	db := cigExchange.GetDB().Where(&BankAccount{ID: bankAccountID, UserID: userID}).First(account)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("bank_account_id", "Bank account doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank account failed", db.Error)
+	}
+	return account, nil
+}
+
+// GetUserDefaultBankAccount queries investor default bank account, nil is returned if there is none
+func GetUserDefaultBankAccount(userID string) (*BankAccount, *cigExchange.APIError) {
+
+	account := &BankAccount{}
+	db := cigExchange.GetDB().Where("user_id = ? AND is_default", userID).First(account)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank account failed", db.Error)
+	}
+	return account, nil
+}
+
+// GetUserBankAccountChange queries single bank account change requested by the user
+func GetUserBankAccountChange(userID, changeID string) (*BankAccountChange, *cigExchange.APIError) {
+
+	change := &BankAccountChange{}
+	db := cigExchange.GetDB().Where(&BankAccountChange{ID: changeID, UserID: userID}).First(change)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("change_id", "Bank account change doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank account change failed", db.Error)
+	}
+	return change, nil
+}
@@ -0,0 +1,406 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining bank transaction statuses
+const (
+	BankTransactionStatusMatched   = "matched"
+	BankTransactionStatusException = "exception"
+	BankTransactionStatusResolved  = "resolved"
+	BankTransactionStatusDismissed = "dismissed"
+)
+
+// Constants defining reasons of payment exceptions
+const (
+	PaymentExceptionUnmatched        = "unmatched"
+	PaymentExceptionNotPending       = "subscription_not_pending"
+	PaymentExceptionDuplicate        = "duplicate_payment"
+	PaymentExceptionCurrencyMismatch = "currency_mismatch"
+	PaymentExceptionPartialPayment   = "partial_payment"
+	PaymentExceptionOverPayment      = "over_payment"
+	PaymentExceptionSettlementFailed = "settlement_failed"
+)
+
+// Constants defining payment exception resolutions
+const (
+	PaymentResolutionCreditWallet = "credit_wallet"
+	PaymentResolutionDismiss      = "dismiss"
+)
+
+// PaymentStatement is a struct to represent an imported bank statement or notification
+type PaymentStatement struct {
+	ID           string    `json:"id" gorm:"column:id;primary_key"`
+	MessageID    string    `json:"message_id" gorm:"column:message_id"`
+	Type         string    `json:"type" gorm:"column:type"`
+	Transactions int       `json:"transactions" gorm:"column:transactions"`
+	Matched      int       `json:"matched" gorm:"column:matched"`
+	Exceptions   int       `json:"exceptions" gorm:"column:exceptions"`
+	Duplicates   int       `json:"duplicates" gorm:"column:duplicates"`
+	CreatedBy    string    `json:"created_by" gorm:"column:created_by"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*PaymentStatement) TableName() string {
+	return "payment_statement"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*PaymentStatement) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts statement, statement messages can be imported only once
+func (statement *PaymentStatement) Create() *cigExchange.APIError {
+
+	count := 0
+	db := cigExchange.GetDB().Model(&PaymentStatement{}).Where(&PaymentStatement{MessageID: statement.MessageID}).Count(&count)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Fetch statements failed", db.Error)
+	}
+	if count > 0 {
+		return cigExchange.NewInvalidFieldError("message_id", "Statement "+statement.MessageID+" was already imported")
+	}
+
+	db = cigExchange.GetDB().Create(statement)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create statement failed", db.Error)
+	}
+	return nil
+}
+
+// Update saves statement counters
+func (statement *PaymentStatement) Update() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(statement)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update statement failed", db.Error)
+	}
+	return nil
+}
+
+// BankTransaction is a struct to represent an incoming bank payment and its reconciliation.
+// Transactions which couldn't be matched to a subscription wait in the exceptions queue
+type BankTransaction struct {
+	ID                       string     `json:"id" gorm:"column:id;primary_key"`
+	StatementID              string     `json:"statement_id" gorm:"column:statement_id"`
+	AccountServicerReference string     `json:"account_servicer_reference" gorm:"column:account_servicer_reference"`
+	EndToEndID               string     `json:"end_to_end_id" gorm:"column:end_to_end_id"`
+	AccountIBAN              string     `json:"account_iban" gorm:"column:account_iban"`
+	Amount                   Decimal    `json:"amount" gorm:"column:amount"`
+	Currency                 string     `json:"currency" gorm:"column:currency"`
+	Reference                string     `json:"reference" gorm:"column:reference"`
+	DebtorName               string     `json:"debtor_name" gorm:"column:debtor_name"`
+	DebtorIBAN               string     `json:"debtor_iban" gorm:"column:debtor_iban"`
+	BookingDate              time.Time  `json:"booking_date" gorm:"column:booking_date"`
+	Status                   string     `json:"status" gorm:"column:status"`
+	Reason                   string     `json:"reason" gorm:"column:reason"`
+	Message                  string     `json:"message" gorm:"column:message"`
+	SubscriptionID           string     `json:"subscription_id" gorm:"column:subscription_id"`
+	JournalEntryID           string     `json:"journal_entry_id" gorm:"column:journal_entry_id"`
+	Note                     string     `json:"note" gorm:"column:note"`
+	ResolvedBy               string     `json:"resolved_by" gorm:"column:resolved_by"`
+	ResolvedAt               *time.Time `json:"resolved_at" gorm:"column:resolved_at"`
+	CreatedAt                time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt                time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*BankTransaction) TableName() string {
+	return "bank_transaction"
+}
+
+// exception moves transaction to the exceptions queue
+func (transaction *BankTransaction) exception(reason, message string) {
+
+	transaction.Status = BankTransactionStatusException
+	transaction.Reason = reason
+	transaction.Message = message
+}
+
+// Import reconciles incoming payment and inserts it. Payment matching pending subscription by reference,
+// currency and amount settles the subscription, other payments go to the exceptions queue.
+// False is returned for payments already imported with another statement
+func (transaction *BankTransaction) Import(createdBy string) (bool, *cigExchange.APIError) {
+
+	count := 0
+	db := cigExchange.GetDB().Model(&BankTransaction{}).
+		Where(&BankTransaction{AccountServicerReference: transaction.AccountServicerReference}).
+		Count(&count)
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Fetch bank transactions failed", db.Error)
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	// transaction id is known before insert, so settlement can reference it
+	transaction.ID = cigExchange.RandomUUID()
+	transaction.Reference = NormalizeReference(transaction.Reference)
+
+	subscription := (*Subscription)(nil)
+	if len(transaction.Reference) > 0 {
+		var apiError *cigExchange.APIError
+		subscription, apiError = GetSubscriptionByReference(transaction.Reference)
+		if apiError != nil {
+			return false, apiError
+		}
+	}
+
+	if subscription == nil {
+		transaction.exception(PaymentExceptionUnmatched, "No subscription with the payment reference")
+	} else if transaction.match(subscription) {
+		settled, apiError := transaction.settle(subscription, createdBy)
+		if apiError != nil {
+			// settlement was rolled back, payment waits in the exceptions queue
+			transaction.JournalEntryID = ""
+			transaction.exception(PaymentExceptionSettlementFailed, apiError.ToString())
+		} else if settled {
+			return true, nil
+		}
+	}
+
+	db = cigExchange.GetDB().Create(transaction)
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Create bank transaction failed", db.Error)
+	}
+	return true, nil
+}
+
+// match checks payment currency and amount against the subscription, payments which can't settle
+// the subscription are moved to the exceptions queue. Credits of paid subscriptions are duplicates
+func (transaction *BankTransaction) match(subscription *Subscription) bool {
+
+	transaction.SubscriptionID = subscription.ID
+	switch {
+	case subscription.Status == SubscriptionStatusPaid:
+		transaction.exception(PaymentExceptionDuplicate, "Subscription was already paid")
+	case subscription.Status != SubscriptionStatusPending:
+		transaction.exception(PaymentExceptionNotPending, "Subscription is "+subscription.Status)
+	case subscription.Currency != transaction.Currency:
+		transaction.exception(PaymentExceptionCurrencyMismatch, "Subscription currency is "+subscription.Currency)
+	case transaction.Amount.Cmp(subscription.Amount) < 0:
+		transaction.exception(PaymentExceptionPartialPayment, "Subscription amount is "+subscription.Amount.String()+" "+subscription.Currency)
+	case transaction.Amount.Cmp(subscription.Amount) > 0:
+		transaction.exception(PaymentExceptionOverPayment, "Subscription amount is "+subscription.Amount.String()+" "+subscription.Currency)
+	default:
+		return true
+	}
+	return false
+}
+
+// settle settles the subscription and inserts matched transaction in one database transaction,
+// so subscription is never paid without the bank transaction and vice versa. Subscription is locked
+// and matched again, false is returned if a concurrent payment settled it and the payment becomes an exception
+func (transaction *BankTransaction) settle(subscription *Subscription, createdBy string) (bool, *cigExchange.APIError) {
+
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&Subscription{ID: subscription.ID}).First(subscription)
+	if db.Error != nil {
+		tx.Rollback()
+		return false, cigExchange.NewDatabaseError("Fetch subscription failed", db.Error)
+	}
+	if !transaction.match(subscription) {
+		tx.Rollback()
+		return false, nil
+	}
+
+	deposit, apiError := subscription.settle(tx, transaction.ID, transaction.BookingDate, createdBy)
+	if apiError != nil {
+		tx.Rollback()
+		return false, apiError
+	}
+
+	transaction.Status = BankTransactionStatusMatched
+	transaction.JournalEntryID = deposit.ID
+	db = tx.Create(transaction)
+	if db.Error != nil {
+		tx.Rollback()
+		return false, cigExchange.NewDatabaseError("Create bank transaction failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Create bank transaction failed", db.Error)
+	}
+	return true, nil
+}
+
+// Resolve resolves payment exception. Credit wallet deposits the payment to the investor cash account,
+// investor of the matched subscription is used if user id is not set. Transaction is locked and checked again
+// in the database transaction, so a payment funding a distribution concurrently is never deposited twice
+func (transaction *BankTransaction) Resolve(resolution, userID, note, resolvedBy string) *cigExchange.APIError {
+
+	if resolution != PaymentResolutionCreditWallet && resolution != PaymentResolutionDismiss {
+		return cigExchange.NewInvalidFieldError("resolution", "Resolution must be one of: "+PaymentResolutionCreditWallet+", "+PaymentResolutionDismiss)
+	}
+
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&BankTransaction{ID: transaction.ID}).First(transaction)
+	if db.Error != nil {
+		tx.Rollback()
+		if db.RecordNotFound() {
+			return cigExchange.NewInvalidFieldError("transaction_id", "Bank transaction doesn't exist")
+		}
+		return cigExchange.NewDatabaseError("Fetch bank transaction failed", db.Error)
+	}
+	if transaction.Status != BankTransactionStatusException {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("transaction_id", "Only payment exceptions can be resolved")
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      BankTransactionStatusDismissed,
+		"note":        note,
+		"resolved_by": resolvedBy,
+		"resolved_at": now,
+	}
+
+	if resolution == PaymentResolutionCreditWallet {
+		if len(transaction.JournalEntryID) > 0 {
+			tx.Rollback()
+			return cigExchange.NewInvalidFieldError("resolution", "Payment was already deposited to the investor cash account")
+		}
+		if len(userID) == 0 && len(transaction.SubscriptionID) > 0 {
+			subscription := &Subscription{}
+			db = tx.Where(&Subscription{ID: transaction.SubscriptionID}).First(subscription)
+			if db.Error != nil {
+				tx.Rollback()
+				return cigExchange.NewDatabaseError("Fetch subscription failed", db.Error)
+			}
+			userID = subscription.UserID
+		}
+		if len(userID) == 0 {
+			tx.Rollback()
+			return cigExchange.NewRequiredFieldError([]string{"user_id"})
+		}
+
+		entry := &JournalEntry{
+			IdempotencyKey: "bank_transaction:" + transaction.ID,
+			Currency:       transaction.Currency,
+			Description:    strings.TrimSpace("Bank payment " + transaction.Reference),
+			EffectiveDate:  transaction.BookingDate,
+			CreatedBy:      resolvedBy,
+		}
+		apiError := deposit(tx, entry, userID, transaction.Amount)
+		if apiError != nil {
+			tx.Rollback()
+			return apiError
+		}
+		updates["status"] = BankTransactionStatusResolved
+		updates["journal_entry_id"] = entry.ID
+	}
+
+	// only changed columns are written, columns set by other resolutions are kept
+	db = tx.Model(transaction).Where("status = ?", BankTransactionStatusException).Updates(updates)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update bank transaction failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("transaction_id", "Only payment exceptions can be resolved")
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update bank transaction failed", db.Error)
+	}
+	return nil
+}
+
+// lockDistributionReceipt locks bank payment funding the distribution. Payment must be an unmatched exception
+// without ledger entry and match distribution currency and amount
+func lockDistributionReceipt(tx *gorm.DB, distribution *Distribution) (*BankTransaction, *cigExchange.APIError) {
+
+	receipt := &BankTransaction{}
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&BankTransaction{ID: distribution.BankTransactionID}).First(receipt)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("bank_transaction_id", "Bank transaction doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank transaction failed", db.Error)
+	}
+	if receipt.Status != BankTransactionStatusException || receipt.Reason != PaymentExceptionUnmatched || len(receipt.JournalEntryID) > 0 {
+		return nil, cigExchange.NewInvalidFieldError("bank_transaction_id", "Only unmatched payments waiting in the exceptions queue can fund distributions")
+	}
+	amount := distribution.Principal.Add(distribution.Interest)
+	if receipt.Currency != distribution.Currency || receipt.Amount.Cmp(amount) != 0 {
+		return nil, cigExchange.NewInvalidFieldError("bank_transaction_id", "Payment of "+receipt.Amount.String()+" "+receipt.Currency+" doesn't match distribution amount of "+amount.String()+" "+distribution.Currency)
+	}
+	return receipt, nil
+}
+
+// resolveDistributionReceipt resolves bank payment funding the distribution with the distribution journal entry
+func (transaction *BankTransaction) resolveDistributionReceipt(tx *gorm.DB, distribution *Distribution, entry *JournalEntry) *cigExchange.APIError {
+
+	now := time.Now()
+	transaction.Status = BankTransactionStatusResolved
+	transaction.JournalEntryID = entry.ID
+	transaction.Note = strings.TrimSpace("Distribution " + distribution.Reference)
+	transaction.ResolvedBy = distribution.CreatedBy
+	transaction.ResolvedAt = &now
+
+	db := tx.Save(transaction)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update bank transaction failed", db.Error)
+	}
+	return nil
+}
+
+// GetBankTransaction queries bank transaction
+func GetBankTransaction(transactionID string) (*BankTransaction, *cigExchange.APIError) {
+
+	transaction := &BankTransaction{}
+	db := cigExchange.GetDB().Where(&BankTransaction{ID: transactionID}).First(transaction)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("transaction_id", "Bank transaction doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bank transaction failed", db.Error)
+	}
+	return transaction, nil
+}
+
+// GetBankTransactionsByStatus queries bank transactions with the status, oldest first
+func GetBankTransactionsByStatus(status string) ([]*BankTransaction, *cigExchange.APIError) {
+
+	transactions := make([]*BankTransaction, 0)
+	db := cigExchange.GetDB().Where(&BankTransaction{Status: status}).Order("booking_date asc, created_at asc").Find(&transactions)
+	if db.Error != nil {
+		return transactions, cigExchange.NewDatabaseError("Fetch bank transactions failed", db.Error)
+	}
+	return transactions, nil
+}
+
+// GetStatementTransactions queries bank transactions imported with the statement
+func GetStatementTransactions(statementID string) ([]*BankTransaction, *cigExchange.APIError) {
+
+	transactions := make([]*BankTransaction, 0)
+	db := cigExchange.GetDB().Where(&BankTransaction{StatementID: statementID}).Order("booking_date asc, created_at asc").Find(&transactions)
+	if db.Error != nil {
+		return transactions, cigExchange.NewDatabaseError("Fetch bank transactions failed", db.Error)
+	}
+	return transactions, nil
+}
+
+// GetPaymentStatements queries imported statements, newest first
+func GetPaymentStatements() ([]*PaymentStatement, *cigExchange.APIError) {
+
+	statements := make([]*PaymentStatement, 0)
+	db := cigExchange.GetDB().Order("created_at desc").Find(&statements)
+	if db.Error != nil {
+		return statements, cigExchange.NewDatabaseError("Fetch statements failed", db.Error)
+	}
+	return statements, nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining the contact us submission status
+const (
+	ContactUsStatusAccepted = "accepted"
+	ContactUsStatusRejected = "rejected"
+)
+
+// ContactUsSubmission is a struct to represent a contact us form submission
+type ContactUsSubmission struct {
+	ID           string    `json:"id" gorm:"column:id;primary_key"`
+	Name         string    `json:"name" gorm:"column:name"`
+	Email        string    `json:"email" gorm:"column:email"`
+	Message      string    `json:"message" gorm:"column:message"`
+	RemoteAddr   string    `json:"remote_addr" gorm:"column:remote_addr"`
+	Status       string    `json:"status" gorm:"column:status"`
+	RejectReason string    `json:"reject_reason" gorm:"column:reject_reason"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*ContactUsSubmission) TableName() string {
+	return "contact_us_submission"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*ContactUsSubmission) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new contact us submission into db
+func (submission *ContactUsSubmission) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	submission.ID = ""
+
+	db := cigExchange.GetDB().Create(submission)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create contact us submission failed", db.Error)
+	}
+	return nil
+}
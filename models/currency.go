@@ -0,0 +1,428 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining supported currencies
+const (
+	CurrencyCHF = "CHF"
+	CurrencyEUR = "EUR"
+	CurrencyUSD = "USD"
+	CurrencyGBP = "GBP"
+)
+
+// DefaultCurrency is used for offerings created before currencies were introduced and for dashboard totals
+const DefaultCurrency = CurrencyCHF
+
+// Currencies lists supported currencies
+var Currencies = []string{CurrencyCHF, CurrencyEUR, CurrencyUSD, CurrencyGBP}
+
+// currencyDecimalPlaces defines minor units of currencies, currencies not listed use 2
+var currencyDecimalPlaces = map[string]int{
+	CurrencyCHF: 2,
+	CurrencyEUR: 2,
+	CurrencyUSD: 2,
+	CurrencyGBP: 2,
+}
+
+// OfferingMoneyFields lists offering money fields accepted in requests, remaining is calculated
+var OfferingMoneyFields = []string{
+	"amount",
+	"amount_already_taken",
+	"minimum_investment",
+	"maximum_investment",
+}
+
+// IsValidCurrency checks currency code
+func IsValidCurrency(currency string) bool {
+
+	return containsString(Currencies, currency)
+}
+
+// NormalizeCurrency upper cases currency code
+func NormalizeCurrency(currency string) string {
+
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// CurrencyDecimalPlaces returns number of minor unit digits of the currency
+func CurrencyDecimalPlaces(currency string) int {
+
+	if places, ok := currencyDecimalPlaces[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+// OfferingMoney is a struct to represent offering currency and exact money amounts.
+// Offering float columns are kept in sync for legacy queries, this table is the source of truth
+type OfferingMoney struct {
+	OfferingID         string    `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	Currency           string    `json:"currency" gorm:"column:currency"`
+	Amount             *Decimal  `json:"amount" gorm:"column:amount"`
+	Remaining          Decimal   `json:"remaining" gorm:"column:remaining"`
+	AmountAlreadyTaken *Decimal  `json:"amount_already_taken" gorm:"column:amount_already_taken"`
+	MinimumInvestment  *Decimal  `json:"minimum_investment" gorm:"column:minimum_investment"`
+	MaximumInvestment  *Decimal  `json:"maximum_investment" gorm:"column:maximum_investment"`
+	CreatedAt          time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*OfferingMoney) TableName() string {
+	return "offering_money"
+}
+
+// CreateOfferingWithMoney inserts offering and its money in one transaction
+func CreateOfferingWithMoney(offering *models.Offering, money *OfferingMoney) *cigExchange.APIError {
+
+	// invalidate the uuid
+	offering.ID = ""
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Create(offering)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create offering failed", db.Error)
+	}
+
+	money.OfferingID = offering.ID
+	db = tx.Save(money)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Save offering money failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create offering failed", db.Error)
+	}
+	return nil
+}
+
+// UpdateOfferingWithMoney updates offering fields from the map and its money in one transaction. Offering money is locked
+// and the request is applied to its current values, so amounts taken by concurrent commitments are kept.
+// Only money columns changed by the request are written, updated money is returned
+func UpdateOfferingWithMoney(offering *models.Offering, update map[string]interface{}, apply func(money *OfferingMoney) *cigExchange.APIError) (*OfferingMoney, *cigExchange.APIError) {
+
+	tx := cigExchange.GetDB().Begin()
+
+	money := &OfferingMoney{}
+	exists := true
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&OfferingMoney{OfferingID: offering.ID}).First(money)
+	if db.Error != nil {
+		if !db.RecordNotFound() {
+			tx.Rollback()
+			return nil, cigExchange.NewDatabaseError("Fetch offering money failed", db.Error)
+		}
+		existingOffering := &models.Offering{}
+		db = tx.Where("id = ?", offering.ID).First(existingOffering)
+		if db.Error != nil {
+			tx.Rollback()
+			return nil, cigExchange.NewDatabaseError("Fetch offering failed", db.Error)
+		}
+		money = newOfferingMoney(existingOffering)
+		exists = false
+	}
+
+	previous := *money
+	apiError := apply(money)
+	if apiError != nil {
+		tx.Rollback()
+		return nil, apiError
+	}
+	changes := money.changedColumns(&previous)
+
+	// legacy offering column follows the calculated remaining amount
+	if _, ok := changes["remaining"]; ok {
+		update["remaining"] = money.Remaining.Float64()
+	}
+	db = tx.Model(offering).Updates(update)
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, cigExchange.NewDatabaseError("Update offering failed", db.Error)
+	}
+
+	if !exists {
+		db = tx.Create(money)
+	} else if len(changes) > 0 {
+		db = tx.Model(money).Updates(changes)
+	}
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, cigExchange.NewDatabaseError("Save offering money failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Update offering failed", db.Error)
+	}
+	return money, nil
+}
+
+// changedColumns returns money columns which differ from the previous money
+func (money *OfferingMoney) changedColumns(previous *OfferingMoney) map[string]interface{} {
+
+	changes := make(map[string]interface{})
+	if money.Currency != previous.Currency {
+		changes["currency"] = money.Currency
+	}
+
+	previousFields := previous.Fields()
+	for field, value := range money.Fields() {
+		previousValue := previousFields[field]
+		switch {
+		case value == nil && previousValue == nil:
+		case value == nil:
+			changes[field] = nil
+		case previousValue == nil || value.Cmp(*previousValue) != 0:
+			changes[field] = *value
+		}
+	}
+	return changes
+}
+
+// Fields returns money fields as a map with json field names
+func (money *OfferingMoney) Fields() map[string]*Decimal {
+
+	remaining := money.Remaining
+	return map[string]*Decimal{
+		"amount":               money.Amount,
+		"remaining":            &remaining,
+		"amount_already_taken": money.AmountAlreadyTaken,
+		"minimum_investment":   money.MinimumInvestment,
+		"maximum_investment":   money.MaximumInvestment,
+	}
+}
+
+// SetField sets money field by json field name
+func (money *OfferingMoney) SetField(field string, value *Decimal) {
+
+	switch field {
+	case "amount":
+		money.Amount = value
+	case "amount_already_taken":
+		money.AmountAlreadyTaken = value
+	case "minimum_investment":
+		money.MinimumInvestment = value
+	case "maximum_investment":
+		money.MaximumInvestment = value
+	}
+}
+
+// UpdateRemaining recalculates remaining amount from amount and amount already taken
+func (money *OfferingMoney) UpdateRemaining() {
+
+	remaining := Decimal{}
+	if money.Amount != nil {
+		remaining = *money.Amount
+	}
+	if money.AmountAlreadyTaken != nil {
+		remaining = remaining.Sub(*money.AmountAlreadyTaken)
+	}
+	if remaining.Sign() < 0 {
+		remaining = Decimal{}
+	}
+	money.Remaining = remaining
+}
+
+// newOfferingMoney converts legacy offering float columns to decimals
+func newOfferingMoney(offering *models.Offering) *OfferingMoney {
+
+	decimalPointer := func(value *float64) *Decimal {
+		if value == nil {
+			return nil
+		}
+		d := DecimalFromFloat(*value)
+		return &d
+	}
+
+	return &OfferingMoney{
+		OfferingID:         offering.ID,
+		Currency:           DefaultCurrency,
+		Amount:             decimalPointer(offering.Amount),
+		Remaining:          DecimalFromFloat(offering.Remaining),
+		AmountAlreadyTaken: decimalPointer(offering.AmountAlreadyTaken),
+		MinimumInvestment:  decimalPointer(offering.MinimumInvestment),
+		MaximumInvestment:  decimalPointer(offering.MaximumInvestment),
+	}
+}
+
+// GetOfferingMoney queries offering money, values of offering float columns are returned if offering has none
+func GetOfferingMoney(offering *models.Offering) (*OfferingMoney, *cigExchange.APIError) {
+
+	money := &OfferingMoney{}
+	db := cigExchange.GetDB().Where(&OfferingMoney{OfferingID: offering.ID}).First(money)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return newOfferingMoney(offering), nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch offering money failed", db.Error)
+	}
+	return money, nil
+}
+
+// GetOfferingsMoney queries money of multiple offerings mapped by offering id
+func GetOfferingsMoney(offerings []*models.Offering) (map[string]*OfferingMoney, *cigExchange.APIError) {
+
+	result := make(map[string]*OfferingMoney)
+	offeringIDs := make([]string, 0, len(offerings))
+	for _, offering := range offerings {
+		result[offering.ID] = newOfferingMoney(offering)
+		offeringIDs = append(offeringIDs, offering.ID)
+	}
+	if len(offeringIDs) == 0 {
+		return result, nil
+	}
+
+	rows := make([]*OfferingMoney, 0)
+	db := cigExchange.GetDB().Where("offering_id IN (?)", offeringIDs).Find(&rows)
+	if db.Error != nil {
+		return result, cigExchange.NewDatabaseError("Fetch offering money failed", db.Error)
+	}
+	for _, row := range rows {
+		result[row.OfferingID] = row
+	}
+	return result, nil
+}
+
+// FXRate is a struct to represent exchange rate, 1 base currency = rate quote currency.
+// Rates are never updated, a new rate with later valid_from replaces the previous one
+type FXRate struct {
+	ID            string    `json:"id" gorm:"column:id;primary_key"`
+	BaseCurrency  string    `json:"base_currency" gorm:"column:base_currency"`
+	QuoteCurrency string    `json:"quote_currency" gorm:"column:quote_currency"`
+	Rate          Decimal   `json:"rate" gorm:"column:rate"`
+	ValidFrom     time.Time `json:"valid_from" gorm:"column:valid_from"`
+	CreatedBy     string    `json:"created_by" gorm:"column:created_by"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*FXRate) TableName() string {
+	return "fx_rate"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*FXRate) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new fx rate into db
+func (rate *FXRate) Create() *cigExchange.APIError {
+
+	if !IsValidCurrency(rate.BaseCurrency) {
+		return cigExchange.NewInvalidFieldError("base_currency", "Currency must be one of: "+strings.Join(Currencies, ", "))
+	}
+	if !IsValidCurrency(rate.QuoteCurrency) {
+		return cigExchange.NewInvalidFieldError("quote_currency", "Currency must be one of: "+strings.Join(Currencies, ", "))
+	}
+	if rate.BaseCurrency == rate.QuoteCurrency {
+		return cigExchange.NewInvalidFieldError("quote_currency", "Base and quote currencies must be different")
+	}
+	if rate.Rate.Sign() <= 0 {
+		return cigExchange.NewInvalidFieldError("rate", "Rate must be positive")
+	}
+
+	db := cigExchange.GetDB().Create(rate)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create fx rate failed", db.Error)
+	}
+	return nil
+}
+
+// GetFXRateHistory queries all rates of the currency pair in both directions, newest first
+func GetFXRateHistory(baseCurrency, quoteCurrency string) ([]*FXRate, *cigExchange.APIError) {
+
+	rates := make([]*FXRate, 0)
+	db := cigExchange.GetDB().
+		Where("(base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?)", baseCurrency, quoteCurrency, quoteCurrency, baseCurrency).
+		Order("valid_from desc, created_at desc").
+		Find(&rates)
+	if db.Error != nil {
+		return rates, cigExchange.NewDatabaseError("Fetch fx rates failed", db.Error)
+	}
+	return rates, nil
+}
+
+// FXConverter converts amounts between currencies using rates valid at the same moment
+type FXConverter struct {
+	At    time.Time
+	rates map[string]*FXRate
+}
+
+// LoadFXConverter queries the latest rate of every currency pair valid at the time
+func LoadFXConverter(at time.Time) (*FXConverter, *cigExchange.APIError) {
+
+	converter := &FXConverter{At: at, rates: make(map[string]*FXRate)}
+
+	rates := make([]*FXRate, 0)
+	db := cigExchange.GetDB().Raw(`SELECT DISTINCT ON (base_currency, quote_currency) * FROM fx_rate
+		WHERE valid_from <= ? ORDER BY base_currency, quote_currency, valid_from DESC, created_at DESC`, at).Scan(&rates)
+	if db.Error != nil {
+		return converter, cigExchange.NewDatabaseError("Fetch fx rates failed", db.Error)
+	}
+	for _, rate := range rates {
+		converter.rates[rate.BaseCurrency+rate.QuoteCurrency] = rate
+	}
+	return converter, nil
+}
+
+// Rates returns current rates of all currency pairs
+func (converter *FXConverter) Rates() []*FXRate {
+
+	rates := make([]*FXRate, 0, len(converter.rates))
+	for _, base := range Currencies {
+		for _, quote := range Currencies {
+			if rate, ok := converter.rates[base+quote]; ok {
+				rates = append(rates, rate)
+			}
+		}
+	}
+	return rates
+}
+
+// Rate returns exchange rate from one currency to another.
+// Inverse rates are used if only the opposite pair exists, other pairs are crossed via the default currency
+func (converter *FXConverter) Rate(from, to string) (Decimal, bool) {
+
+	if from == to {
+		return NewDecimal(1), true
+	}
+	if rate, ok := converter.rates[from+to]; ok {
+		return rate.Rate, true
+	}
+	if rate, ok := converter.rates[to+from]; ok {
+		return NewDecimal(1).Div(rate.Rate), true
+	}
+	if from == DefaultCurrency || to == DefaultCurrency {
+		return Decimal{}, false
+	}
+
+	fromDefault, ok := converter.Rate(from, DefaultCurrency)
+	if !ok {
+		return Decimal{}, false
+	}
+	defaultTo, ok := converter.Rate(DefaultCurrency, to)
+	if !ok {
+		return Decimal{}, false
+	}
+	return fromDefault.Mul(defaultTo), true
+}
+
+// Convert converts amount and rounds it to minor units of the target currency
+func (converter *FXConverter) Convert(amount Decimal, from, to string) (Decimal, *cigExchange.APIError) {
+
+	rate, ok := converter.Rate(from, to)
+	if !ok {
+		return Decimal{}, cigExchange.NewInvalidFieldError("currency", "No FX rate for "+from+"/"+to)
+	}
+	return amount.Mul(rate).Round(CurrencyDecimalPlaces(to)), nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestOfferingMoneyChangedColumns(t *testing.T) {
+
+	amount, _ := ParseDecimal("1000")
+	taken, _ := ParseDecimal("250")
+	minimum, _ := ParseDecimal("100")
+	money := &OfferingMoney{Currency: CurrencyCHF, Amount: &amount, AmountAlreadyTaken: &taken, MinimumInvestment: &minimum}
+	money.UpdateRemaining()
+
+	// unchanged values aren't written, so concurrent updates of amount taken are kept
+	previous := *money
+	same, _ := ParseDecimal("1000.00")
+	money.SetField("amount", &same)
+	money.UpdateRemaining()
+	if changes := money.changedColumns(&previous); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	larger, _ := ParseDecimal("1500")
+	money.SetField("amount", &larger)
+	money.SetField("minimum_investment", nil)
+	money.UpdateRemaining()
+	changes := money.changedColumns(&previous)
+	if len(changes) != 3 {
+		t.Fatalf("expected amount, remaining and minimum investment to change, got %v", changes)
+	}
+	if value, ok := changes["amount"].(Decimal); !ok || value.Cmp(larger) != 0 {
+		t.Errorf("unexpected amount %v", changes["amount"])
+	}
+	if value, ok := changes["remaining"].(Decimal); !ok || value.String() != "1250" {
+		t.Errorf("unexpected remaining %v", changes["remaining"])
+	}
+	if value, ok := changes["minimum_investment"]; !ok || value != nil {
+		t.Errorf("expected minimum investment to be cleared, got %v", value)
+	}
+	if _, ok := changes["amount_already_taken"]; ok {
+		t.Errorf("expected amount already taken to be kept")
+	}
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// maxDecimalPlaces limits decimal places of non terminating results, e.g. inverse FX rates
+const maxDecimalPlaces = 18
+
+// Decimal is an exact decimal number used for money amounts and FX rates.
+// It is stored as NUMERIC in db and encoded as json number without float conversion
+type Decimal struct {
+	rat *big.Rat
+}
+
+// NewDecimal creates decimal from integer
+func NewDecimal(value int64) Decimal {
+
+	return Decimal{rat: new(big.Rat).SetInt64(value)}
+}
+
+// ParseDecimal parses decimal from string, e.g. "1250.50"
+func ParseDecimal(value string) (Decimal, error) {
+
+	value = strings.TrimSpace(value)
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok || strings.Contains(value, "/") {
+		return Decimal{}, fmt.Errorf("invalid decimal '%v'", value)
+	}
+	return Decimal{rat: rat}, nil
+}
+
+// DecimalFromFloat converts float to decimal using shortest representation, used for legacy float columns
+func DecimalFromFloat(value float64) Decimal {
+
+	d, err := ParseDecimal(fmt.Sprintf("%v", value))
+	if err != nil {
+		return Decimal{rat: new(big.Rat).SetFloat64(value)}
+	}
+	return d
+}
+
+func (d Decimal) value() *big.Rat {
+
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+
+	return Decimal{rat: new(big.Rat).Add(d.value(), other.value())}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+
+	return Decimal{rat: new(big.Rat).Sub(d.value(), other.value())}
+}
+
+// Mul returns d * other
+func (d Decimal) Mul(other Decimal) Decimal {
+
+	return Decimal{rat: new(big.Rat).Mul(d.value(), other.value())}
+}
+
+// Div returns d / other rounded to maxDecimalPlaces, other must not be zero
+func (d Decimal) Div(other Decimal) Decimal {
+
+	return Decimal{rat: new(big.Rat).Quo(d.value(), other.value())}.Round(maxDecimalPlaces)
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+
+	return Decimal{rat: new(big.Rat).Neg(d.value())}
+}
+
+// Cmp compares d and other, returns -1, 0 or +1
+func (d Decimal) Cmp(other Decimal) int {
+
+	return d.value().Cmp(other.value())
+}
+
+// Sign returns -1, 0 or +1
+func (d Decimal) Sign() int {
+
+	return d.value().Sign()
+}
+
+// IsZero checks if d is 0
+func (d Decimal) IsZero() bool {
+
+	return d.Sign() == 0
+}
+
+// Round rounds half away from zero to the number of decimal places
+func (d Decimal) Round(places int) Decimal {
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(d.value(), new(big.Rat).SetInt(scale))
+
+	// add half and truncate towards zero
+	half := big.NewRat(1, 2)
+	if scaled.Sign() < 0 {
+		scaled.Sub(scaled, half)
+	} else {
+		scaled.Add(scaled, half)
+	}
+	truncated := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+
+	return Decimal{rat: new(big.Rat).SetFrac(truncated, scale)}
+}
+
+// Truncate drops decimal places after the number of places, rounds towards zero
+func (d Decimal) Truncate(places int) Decimal {
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(d.value(), new(big.Rat).SetInt(scale))
+	truncated := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+
+	return Decimal{rat: new(big.Rat).SetFrac(truncated, scale)}
+}
+
+// MinorUnit returns the smallest amount with the number of decimal places, e.g. 0.01 for 2
+func MinorUnit(places int) Decimal {
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	return Decimal{rat: new(big.Rat).SetFrac(big.NewInt(1), scale)}
+}
+
+// Float64 returns the nearest float, used only to keep legacy float columns in sync
+func (d Decimal) Float64() float64 {
+
+	f, _ := d.value().Float64()
+	return f
+}
+
+// String returns exact decimal representation without trailing zeros
+func (d Decimal) String() string {
+
+	rat := d.value()
+	if rat.IsInt() {
+		return rat.Num().String()
+	}
+
+	// find the shortest exact representation, non terminating fractions are rounded
+	for places := 1; places < maxDecimalPlaces; places++ {
+		if d.Round(places).Cmp(d) == 0 {
+			return rat.FloatString(places)
+		}
+	}
+	return d.Round(maxDecimalPlaces).value().FloatString(maxDecimalPlaces)
+}
+
+// StringFixed returns decimal representation with fixed number of decimal places
+func (d Decimal) StringFixed(places int) string {
+
+	return d.Round(places).value().FloatString(places)
+}
+
+// MarshalJSON encodes decimal as json number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes decimal from json number or string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+
+	data = bytes.Trim(bytes.TrimSpace(data), "\"")
+	if string(data) == "null" || len(data) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	parsed, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Scan implements the sql.Scanner interface
+func (d *Decimal) Scan(value interface{}) error {
+
+	switch v := value.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		return d.UnmarshalJSON(v)
+	case string:
+		return d.UnmarshalJSON([]byte(v))
+	case float64:
+		*d = DecimalFromFloat(v)
+		return nil
+	case int64:
+		*d = NewDecimal(v)
+		return nil
+	}
+	return fmt.Errorf("unable to scan %T into decimal", value)
+}
+
+// Value implements the driver.Valuer interface
+func (d Decimal) Value() (driver.Value, error) {
+
+	return d.String(), nil
+}
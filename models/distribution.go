@@ -0,0 +1,283 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Distribution is a struct to represent a repayment event of an offering.
+// Distributions and allocations are immutable, corrections are recorded as new events
+type Distribution struct {
+	ID                string                    `json:"id" gorm:"column:id;primary_key"`
+	OfferingID        string                    `json:"offering_id" gorm:"column:offering_id"`
+	OrganisationID    string                    `json:"organisation_id" gorm:"column:organisation_id"`
+	Currency          string                    `json:"currency" gorm:"column:currency"`
+	Principal         Decimal                   `json:"principal" gorm:"column:principal"`
+	Interest          Decimal                   `json:"interest" gorm:"column:interest"`
+	PaymentDate       time.Time                 `json:"payment_date" gorm:"column:payment_date"`
+	Reference         string                    `json:"reference" gorm:"column:reference"`
+	BankTransactionID string                    `json:"bank_transaction_id,omitempty" gorm:"column:bank_transaction_id"`
+	CreatedBy         string                    `json:"created_by" gorm:"column:created_by"`
+	CreatedAt         time.Time                 `json:"created_at" gorm:"column:created_at"`
+	Allocations       []*DistributionAllocation `json:"allocations,omitempty" gorm:"foreignkey:DistributionID"`
+}
+
+// TableName returns table name for struct
+func (*Distribution) TableName() string {
+	return "distribution"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Distribution) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// DistributionAllocation is a struct to represent investor part of a distribution
+type DistributionAllocation struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	DistributionID string    `json:"distribution_id" gorm:"column:distribution_id"`
+	OfferingID     string    `json:"offering_id" gorm:"column:offering_id"`
+	UserID         string    `json:"user_id" gorm:"column:user_id"`
+	Currency       string    `json:"currency" gorm:"column:currency"`
+	HoldingAmount  Decimal   `json:"holding_amount" gorm:"column:holding_amount"`
+	Principal      Decimal   `json:"principal" gorm:"column:principal"`
+	Interest       Decimal   `json:"interest" gorm:"column:interest"`
+	Total          Decimal   `json:"total" gorm:"column:total"`
+	PaymentDate    time.Time `json:"payment_date" gorm:"column:payment_date"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*DistributionAllocation) TableName() string {
+	return "distribution_allocation"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*DistributionAllocation) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create allocates distribution to investors pro-rata to their outstanding principal and
+// inserts the distribution with allocations. Principal part reduces holdings and allocations are posted
+// to investor cash accounts in the same transaction. Bank payment funding the distribution is resolved as well
+func (distribution *Distribution) Create() *cigExchange.APIError {
+
+	places := CurrencyDecimalPlaces(distribution.Currency)
+	if distribution.Principal.Sign() < 0 || distribution.Interest.Sign() < 0 {
+		return cigExchange.NewInvalidFieldError("principal, interest", "Amounts can't be negative")
+	}
+	if distribution.Principal.Add(distribution.Interest).IsZero() {
+		return cigExchange.NewInvalidFieldError("principal, interest", "Distribution amount must be positive")
+	}
+	if distribution.Principal.Round(places).Cmp(distribution.Principal) != 0 || distribution.Interest.Round(places).Cmp(distribution.Interest) != 0 {
+		return cigExchange.NewInvalidFieldError("principal, interest", "Amounts have more decimal places than "+distribution.Currency+" allows")
+	}
+
+	tx := cigExchange.GetDB().Begin()
+
+	if len(distribution.Reference) > 0 {
+		count := 0
+		db := tx.Model(&Distribution{}).Where(&Distribution{OfferingID: distribution.OfferingID, Reference: distribution.Reference}).Count(&count)
+		if db.Error != nil {
+			tx.Rollback()
+			return cigExchange.NewDatabaseError("Fetch distributions failed", db.Error)
+		}
+		if count > 0 {
+			tx.Rollback()
+			return cigExchange.NewInvalidFieldError("reference", "Distribution with the reference is already recorded")
+		}
+	}
+
+	receipt := (*BankTransaction)(nil)
+	if len(distribution.BankTransactionID) > 0 {
+		var apiError *cigExchange.APIError
+		receipt, apiError = lockDistributionReceipt(tx, distribution)
+		if apiError != nil {
+			tx.Rollback()
+			return apiError
+		}
+	}
+
+	// lock holdings, allocations must match principal they reduce
+	holdings := make([]*Holding, 0)
+	db := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("offering_id = ? AND principal > 0", distribution.OfferingID).
+		Order("user_id asc").
+		Find(&holdings)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Fetch holdings failed", db.Error)
+	}
+	if len(holdings) == 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("offering_id", "Offering has no investors with outstanding principal")
+	}
+
+	weights := make([]Decimal, 0, len(holdings))
+	outstanding := Decimal{}
+	for _, holding := range holdings {
+		weights = append(weights, holding.Principal)
+		outstanding = outstanding.Add(holding.Principal)
+	}
+	if distribution.Principal.Cmp(outstanding) > 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("principal", "Principal exceeds outstanding principal of "+outstanding.String()+" "+distribution.Currency)
+	}
+
+	principalParts := AllocateProRata(distribution.Principal, weights, places)
+	interestParts := AllocateProRata(distribution.Interest, weights, places)
+
+	db = tx.Set("gorm:save_associations", false).Create(distribution)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create distribution failed", db.Error)
+	}
+
+	distribution.Allocations = make([]*DistributionAllocation, 0, len(holdings))
+	for i, holding := range holdings {
+		allocation := &DistributionAllocation{
+			DistributionID: distribution.ID,
+			OfferingID:     distribution.OfferingID,
+			UserID:         holding.UserID,
+			Currency:       distribution.Currency,
+			HoldingAmount:  holding.Principal,
+			Principal:      principalParts[i],
+			Interest:       interestParts[i],
+			Total:          principalParts[i].Add(interestParts[i]),
+			PaymentDate:    distribution.PaymentDate,
+		}
+		db = tx.Create(allocation)
+		if db.Error != nil {
+			tx.Rollback()
+			return cigExchange.NewDatabaseError("Create distribution allocation failed", db.Error)
+		}
+		distribution.Allocations = append(distribution.Allocations, allocation)
+
+		if principalParts[i].IsZero() {
+			continue
+		}
+		db = tx.Model(holding).Update("principal", holding.Principal.Sub(principalParts[i]))
+		if db.Error != nil {
+			tx.Rollback()
+			return cigExchange.NewDatabaseError("Update holding failed", db.Error)
+		}
+	}
+
+	entry, apiError := postDistribution(tx, distribution)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	if receipt != nil {
+		apiError = receipt.resolveDistributionReceipt(tx, distribution, entry)
+		if apiError != nil {
+			tx.Rollback()
+			return apiError
+		}
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create distribution failed", db.Error)
+	}
+	return nil
+}
+
+// AllocateProRata splits amount between weights using the largest remainder method.
+// Every part is rounded down to the decimal places and the remaining minor units go to
+// the largest fractional remainders, ties are resolved by larger weight and then by order.
+// Parts always sum up to the amount
+func AllocateProRata(amount Decimal, weights []Decimal, places int) []Decimal {
+
+	parts := make([]Decimal, len(weights))
+	total := Decimal{}
+	for _, weight := range weights {
+		total = total.Add(weight)
+	}
+	if total.IsZero() || amount.IsZero() {
+		return parts
+	}
+
+	remainders := make([]Decimal, len(weights))
+	allocated := Decimal{}
+	for i, weight := range weights {
+		exact := amount.Mul(weight).Div(total)
+		parts[i] = exact.Truncate(places)
+		remainders[i] = exact.Sub(parts[i])
+		allocated = allocated.Add(parts[i])
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if c := remainders[order[a]].Cmp(remainders[order[b]]); c != 0 {
+			return c > 0
+		}
+		return weights[order[a]].Cmp(weights[order[b]]) > 0
+	})
+
+	unit := MinorUnit(places)
+	for i := 0; amount.Sub(allocated).Cmp(unit) >= 0; i++ {
+		index := order[i%len(order)]
+		parts[index] = parts[index].Add(unit)
+		allocated = allocated.Add(unit)
+	}
+	return parts
+}
+
+// HasDistributions checks if any distribution was recorded for the offering
+func HasDistributions(offeringID string) (bool, *cigExchange.APIError) {
+
+	count := 0
+	db := cigExchange.GetDB().Model(&Distribution{}).Where(&Distribution{OfferingID: offeringID}).Count(&count)
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Fetch distributions failed", db.Error)
+	}
+	return count > 0, nil
+}
+
+// GetDistribution queries offering distribution with allocations
+func GetDistribution(offeringID, distributionID string) (*Distribution, *cigExchange.APIError) {
+
+	distribution := &Distribution{}
+	db := cigExchange.GetDB().Preload("Allocations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("user_id asc")
+	}).Where(&Distribution{ID: distributionID, OfferingID: offeringID}).First(distribution)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("distribution_id", "Distribution doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch distribution failed", db.Error)
+	}
+	return distribution, nil
+}
+
+// GetOfferingDistributions queries all offering distributions without allocations, newest first
+func GetOfferingDistributions(offeringID string) ([]*Distribution, *cigExchange.APIError) {
+
+	distributions := make([]*Distribution, 0)
+	db := cigExchange.GetDB().Where(&Distribution{OfferingID: offeringID}).Order("payment_date desc, created_at desc").Find(&distributions)
+	if db.Error != nil {
+		return distributions, cigExchange.NewDatabaseError("Fetch distributions failed", db.Error)
+	}
+	return distributions, nil
+}
+
+// GetUserDistributionAllocations queries all distributions received by the investor, newest first
+func GetUserDistributionAllocations(userID string) ([]*DistributionAllocation, *cigExchange.APIError) {
+
+	allocations := make([]*DistributionAllocation, 0)
+	db := cigExchange.GetDB().Where(&DistributionAllocation{UserID: userID}).Order("payment_date desc, created_at desc").Find(&allocations)
+	if db.Error != nil {
+		return allocations, cigExchange.NewDatabaseError("Fetch distribution allocations failed", db.Error)
+	}
+	return allocations, nil
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Constants defining the outbox email status
+const (
+	EmailOutboxStatusPending = "pending"
+	EmailOutboxStatusSent    = "sent"
+	EmailOutboxStatusDead    = "dead"
+)
+
+// Constants defining the outbox email reference types
+const (
+	EmailOutboxReferenceOrganisationUser = "organisation_user"
+	EmailOutboxReferenceNotification     = "notification"
+	EmailOutboxReferenceSavedSearch      = "saved_search"
+	EmailOutboxReferenceBankAccount      = "bank_account_change"
+	EmailOutboxReferenceSignature        = "signature_request"
+)
+
+// redactedValue replaces content of sensitive emails
+const redactedValue = "[redacted]"
+
+// EmailOutbox is a struct to represent an email waiting for delivery.
+// Either TemplateType with Parameters or Subject with Text must be set.
+// Sensitive emails carry codes or tokens, their content is redacted after delivery
+type EmailOutbox struct {
+	ID            string         `json:"id" gorm:"column:id;primary_key"`
+	ReferenceType string         `json:"reference_type" gorm:"column:reference_type"`
+	ReferenceID   string         `json:"reference_id" gorm:"column:reference_id"`
+	ToEmail       string         `json:"to_email" gorm:"column:to_email"`
+	ToName        string         `json:"to_name" gorm:"column:to_name"`
+	TemplateType  *int           `json:"template_type" gorm:"column:template_type"`
+	Parameters    postgres.Jsonb `json:"parameters" gorm:"column:parameters"`
+	Subject       string         `json:"subject" gorm:"column:subject"`
+	Text          string         `json:"text" gorm:"column:text"`
+	HTML          string         `json:"html" gorm:"column:html"`
+	Sensitive     bool           `json:"sensitive" gorm:"column:sensitive"`
+	Status        string         `json:"status" gorm:"column:status"`
+	Attempts      int            `json:"attempts" gorm:"column:attempts"`
+	LastError     string         `json:"last_error" gorm:"column:last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	SentAt        *time.Time     `json:"sent_at" gorm:"column:sent_at"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*EmailOutbox) TableName() string {
+	return "email_outbox"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*EmailOutbox) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// NewTemplateEmailOutbox prepares outbox record for a template email
+func NewTemplateEmailOutbox(emailType int, toEmail string, parameters map[string]string) (*EmailOutbox, *cigExchange.APIError) {
+
+	parametersBytes, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, cigExchange.NewJSONEncodingError("Unable to encode email parameters", err)
+	}
+
+	outbox := &EmailOutbox{
+		ToEmail:      toEmail,
+		TemplateType: &emailType,
+		Parameters:   postgres.Jsonb{RawMessage: parametersBytes},
+	}
+	return outbox, nil
+}
+
+// Create inserts new outbox email into db, pass transaction to create it together with other records
+func (outbox *EmailOutbox) Create(tx *gorm.DB) *cigExchange.APIError {
+
+	if tx == nil {
+		tx = cigExchange.GetDB()
+	}
+
+	// invalidate the uuid
+	outbox.ID = ""
+	outbox.Status = EmailOutboxStatusPending
+	outbox.Attempts = 0
+	outbox.NextAttemptAt = time.Now()
+
+	db := tx.Create(outbox)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create outbox email failed", db.Error)
+	}
+	return nil
+}
+
+// Save updates outbox email in db
+func (outbox *EmailOutbox) Save(tx *gorm.DB) *cigExchange.APIError {
+
+	if tx == nil {
+		tx = cigExchange.GetDB()
+	}
+
+	db := tx.Save(outbox)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save outbox email failed", db.Error)
+	}
+	return nil
+}
+
+// GetParameters decodes template parameters
+func (outbox *EmailOutbox) GetParameters() (map[string]string, error) {
+
+	parameters := make(map[string]string)
+	if len(outbox.Parameters.RawMessage) == 0 {
+		return parameters, nil
+	}
+	err := json.Unmarshal(outbox.Parameters.RawMessage, &parameters)
+	return parameters, err
+}
+
+// Redact replaces parameter values, text and html of sensitive emails
+func (outbox *EmailOutbox) Redact() {
+
+	if !outbox.Sensitive {
+		return
+	}
+
+	parameters, err := outbox.GetParameters()
+	if err == nil && len(parameters) > 0 {
+		for key := range parameters {
+			parameters[key] = redactedValue
+		}
+		parametersBytes, err := json.Marshal(parameters)
+		if err == nil {
+			outbox.Parameters = postgres.Jsonb{RawMessage: parametersBytes}
+		}
+	}
+	if len(outbox.Text) > 0 {
+		outbox.Text = redactedValue
+	}
+	if len(outbox.HTML) > 0 {
+		outbox.HTML = redactedValue
+	}
+}
+
+// ClaimDueEmailOutbox returns pending emails ready for delivery and postpones
+// their next attempt by claimTimeout, so other workers skip them while they are sent.
+// Emails are claimed in a short transaction, no locks are held during delivery
+func ClaimDueEmailOutbox(limit int, claimTimeout time.Duration) ([]*EmailOutbox, *cigExchange.APIError) {
+
+	emails := make([]*EmailOutbox, 0)
+
+	tx := cigExchange.GetDB().Begin()
+	if tx.Error != nil {
+		return emails, cigExchange.NewDatabaseError("Unable to start transaction", tx.Error)
+	}
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		Where("status = ? AND next_attempt_at <= ?", EmailOutboxStatusPending, time.Now()).
+		Order("next_attempt_at asc").Limit(limit).Find(&emails)
+	if db.Error != nil {
+		tx.Rollback()
+		return emails, cigExchange.NewDatabaseError("Fetch outbox emails failed", db.Error)
+	}
+	if len(emails) == 0 {
+		tx.Rollback()
+		return emails, nil
+	}
+
+	emailIDs := make([]string, 0, len(emails))
+	for _, email := range emails {
+		emailIDs = append(emailIDs, email.ID)
+	}
+
+	db = tx.Model(&EmailOutbox{}).Where("id IN (?)", emailIDs).Update("next_attempt_at", time.Now().Add(claimTimeout))
+	if db.Error != nil {
+		tx.Rollback()
+		return emails, cigExchange.NewDatabaseError("Claim outbox emails failed", db.Error)
+	}
+
+	err := tx.Commit().Error
+	if err != nil {
+		return emails, cigExchange.NewDatabaseError("Unable to commit transaction", err)
+	}
+	return emails, nil
+}
+
+// GetEmailOutbox queries a single outbox email from db
+func GetEmailOutbox(outboxID string) (*EmailOutbox, *cigExchange.APIError) {
+
+	outbox := &EmailOutbox{}
+	db := cigExchange.GetDB().Where(&EmailOutbox{ID: outboxID}).First(outbox)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("outbox_id", "Outbox email with provided id doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch outbox email failed", db.Error)
+	}
+	return outbox, nil
+}
+
+// GetDeadEmailOutbox returns emails which exhausted all delivery attempts
+func GetDeadEmailOutbox() ([]*EmailOutbox, *cigExchange.APIError) {
+
+	emails := make([]*EmailOutbox, 0)
+	db := cigExchange.GetDB().Where(&EmailOutbox{Status: EmailOutboxStatusDead}).Order("updated_at desc").Find(&emails)
+	if db.Error != nil {
+		return emails, cigExchange.NewDatabaseError("Fetch outbox emails failed", db.Error)
+	}
+	return emails, nil
+}
+
+// GetLatestEmailOutboxForReferences returns the most recent outbox email for each reference id
+func GetLatestEmailOutboxForReferences(referenceType string, referenceIDs []string) (map[string]*EmailOutbox, *cigExchange.APIError) {
+
+	latest := make(map[string]*EmailOutbox)
+	if len(referenceIDs) == 0 {
+		return latest, nil
+	}
+
+	emails := make([]*EmailOutbox, 0)
+	db := cigExchange.GetDB().Where("reference_type = ? AND reference_id IN (?)", referenceType, referenceIDs).Order("created_at asc").Find(&emails)
+	if db.Error != nil {
+		return latest, cigExchange.NewDatabaseError("Fetch outbox emails failed", db.Error)
+	}
+
+	for _, email := range emails {
+		latest[email.ReferenceID] = email
+	}
+	return latest, nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Holding is a struct to represent investor position in an offering.
+// Principal is the outstanding part of the invested amount, it decreases with principal distributions
+type Holding struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	OfferingID     string    `json:"offering_id" gorm:"column:offering_id"`
+	UserID         string    `json:"user_id" gorm:"column:user_id"`
+	Currency       string    `json:"currency" gorm:"column:currency"`
+	InvestedAmount Decimal   `json:"invested_amount" gorm:"column:invested_amount"`
+	Principal      Decimal   `json:"principal" gorm:"column:principal"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Holding) TableName() string {
+	return "offering_holding"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Holding) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Save inserts or updates holding in db
+func (holding *Holding) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(holding)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save holding failed", db.Error)
+	}
+	return nil
+}
+
+// GetHolding queries investor holding in the offering, nil is returned if investor has none
+func GetHolding(offeringID, userID string) (*Holding, *cigExchange.APIError) {
+
+	holding := &Holding{}
+	db := cigExchange.GetDB().Where(&Holding{OfferingID: offeringID, UserID: userID}).First(holding)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch holding failed", db.Error)
+	}
+	return holding, nil
+}
+
+// GetOfferingHoldings queries all holdings of the offering
+func GetOfferingHoldings(offeringID string) ([]*Holding, *cigExchange.APIError) {
+
+	holdings := make([]*Holding, 0)
+	db := cigExchange.GetDB().Where(&Holding{OfferingID: offeringID}).Order("user_id asc").Find(&holdings)
+	if db.Error != nil {
+		return holdings, cigExchange.NewDatabaseError("Fetch holdings failed", db.Error)
+	}
+	return holdings, nil
+}
+
+// GetUserHoldings queries all holdings of the investor
+func GetUserHoldings(userID string) ([]*Holding, *cigExchange.APIError) {
+
+	holdings := make([]*Holding, 0)
+	db := cigExchange.GetDB().Where(&Holding{UserID: userID}).Order("created_at desc").Find(&holdings)
+	if db.Error != nil {
+		return holdings, cigExchange.NewDatabaseError("Fetch holdings failed", db.Error)
+	}
+	return holdings, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"regexp"
+	"strings"
+)
+
+// ibanFormats defines BBAN structure of countries using IBAN in SWIFT registry notation:
+// n - digits, a - upper case letters, c - digits and upper case letters
+var ibanFormats = map[string]string{
+	"AD": "8n12c", "AE": "19n", "AL": "8n16c", "AT": "16n", "AZ": "4a20c", "BA": "16n", "BE": "12n",
+	"BG": "4a6n8c", "BH": "4a14c", "BR": "23n1a1c", "BY": "4c4n16c", "CH": "5n12c", "CR": "18n",
+	"CY": "8n16c", "CZ": "20n", "DE": "18n", "DK": "14n", "DO": "4c20n", "EE": "16n", "EG": "25n",
+	"ES": "20n", "FI": "14n", "FO": "14n", "FR": "10n11c2n", "GB": "4a14n", "GE": "2a16n", "GI": "4a15c",
+	"GL": "14n", "GR": "7n16c", "GT": "4c20c", "HR": "17n", "HU": "24n", "IE": "4a14n", "IL": "19n",
+	"IQ": "4a15n", "IS": "22n", "IT": "1a10n12c", "JO": "4a4n18c", "KW": "4a22c", "KZ": "3n13c",
+	"LB": "4n20c", "LC": "4a24c", "LI": "5n12c", "LT": "16n", "LU": "3n13c", "LV": "4a13c",
+	"MC": "10n11c2n", "MD": "20c", "ME": "18n", "MK": "3n10c2n", "MR": "23n", "MT": "4a5n18c",
+	"MU": "4a19n3a", "NL": "4a10n", "NO": "11n", "PK": "4a16c", "PL": "24n", "PS": "4a21c", "PT": "21n",
+	"QA": "4a21c", "RO": "4a16c", "RS": "18n", "SA": "2n18c", "SC": "4a20n3a", "SE": "20n", "SI": "15n",
+	"SK": "20n", "SM": "1a10n12c", "ST": "21n", "SV": "4a20n", "TL": "19n", "TN": "20n", "TR": "6n16c",
+	"UA": "6n19c", "VA": "18n", "VG": "4a16n", "XK": "16n",
+}
+
+// ibanRegexps are compiled from ibanFormats, they match the whole IBAN of the country
+var ibanRegexps = compileIBANFormats()
+
+var bbanPartRegexp = regexp.MustCompile("([0-9]+)([nac])")
+
+func compileIBANFormats() map[string]*regexp.Regexp {
+
+	classes := map[string]string{"n": "[0-9]", "a": "[A-Z]", "c": "[A-Z0-9]"}
+	regexps := make(map[string]*regexp.Regexp, len(ibanFormats))
+	for country, format := range ibanFormats {
+		pattern := "^" + country + "[0-9]{2}"
+		for _, part := range bbanPartRegexp.FindAllStringSubmatch(format, -1) {
+			pattern += classes[part[2]] + "{" + part[1] + "}"
+		}
+		regexps[country] = regexp.MustCompile(pattern + "$")
+	}
+	return regexps
+}
+
+// bicRegexp matches 8 or 11 characters BIC: institution, country, location and optional branch code
+var bicRegexp = regexp.MustCompile("^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$")
+
+// NormalizeIBAN removes spaces from IBAN and converts it to upper case
+func NormalizeIBAN(iban string) string {
+
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// IsValidIBAN checks country format and ISO 7064 check digits of IBAN
+func IsValidIBAN(iban string) bool {
+
+	return len(CheckIBAN(iban)) == 0
+}
+
+// CheckIBAN validates IBAN, empty string is returned for valid IBAN, otherwise the reason why it's invalid
+func CheckIBAN(iban string) string {
+
+	iban = NormalizeIBAN(iban)
+	if len(iban) < 5 {
+		return "IBAN is too short"
+	}
+	format, ok := ibanRegexps[iban[:2]]
+	if !ok {
+		return "IBAN country " + iban[:2] + " is not supported"
+	}
+	if !format.MatchString(iban) {
+		return "IBAN doesn't match " + iban[:2] + " format"
+	}
+	remainder, ok := mod97(iban[4:] + iban[:4])
+	if !ok || remainder != 1 {
+		return "IBAN checksum is invalid"
+	}
+	return ""
+}
+
+// IsValidBIC checks format of BIC
+func IsValidBIC(bic string) bool {
+
+	return bicRegexp.MatchString(NormalizeIBAN(bic))
+}
+
+// validateBIC checks format of normalized BIC and that its country uses IBAN
+func validateBIC(bic string) *cigExchange.APIError {
+
+	if !IsValidBIC(bic) {
+		return cigExchange.NewInvalidFieldError("bic", "Invalid BIC")
+	}
+	if _, ok := ibanFormats[bic[4:6]]; !ok {
+		return cigExchange.NewInvalidFieldError("bic", "BIC country doesn't use IBAN")
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining the investor profile verification status
+const (
+	KYCStatusDraft    = "draft"
+	KYCStatusPending  = "pending"
+	KYCStatusApproved = "approved"
+	KYCStatusRejected = "rejected"
+)
+
+// Constants defining the verification level granted on approval
+const (
+	KYCLevelNone     = 0
+	KYCLevelBasic    = 1 // identity verified
+	KYCLevelEnhanced = 2 // identity and residence address verified
+)
+
+// Constants defining the investor classification
+const (
+	InvestorClassificationRetail        = "retail"
+	InvestorClassificationProfessional  = "professional"
+	InvestorClassificationInstitutional = "institutional"
+)
+
+// InvestorClassifications lists supported investor classifications
+var InvestorClassifications = []string{
+	InvestorClassificationRetail,
+	InvestorClassificationProfessional,
+	InvestorClassificationInstitutional,
+}
+
+// Constants defining the KYC document types
+const (
+	KYCDocumentTypePassport       = "passport"
+	KYCDocumentTypeIDCard         = "id_card"
+	KYCDocumentTypeProofOfAddress = "proof_of_address"
+	KYCDocumentTypeOther          = "other"
+)
+
+// KYCDocumentTypes lists supported KYC document types
+var KYCDocumentTypes = []string{
+	KYCDocumentTypePassport,
+	KYCDocumentTypeIDCard,
+	KYCDocumentTypeProofOfAddress,
+	KYCDocumentTypeOther,
+}
+
+// MediaTypeKYCDocument is media type of investor identity documents
+const MediaTypeKYCDocument = "kyc-document"
+
+var countryCodeRegexp = regexp.MustCompile("^[A-Z]{2}$")
+
+// InvestorProfile is a struct to represent investor identity data and verification state
+type InvestorProfile struct {
+	UserID           string         `json:"user_id" gorm:"column:user_id;primary_key"`
+	FirstName        string         `json:"first_name" gorm:"column:first_name"`
+	LastName         string         `json:"last_name" gorm:"column:last_name"`
+	DateOfBirth      *time.Time     `json:"date_of_birth" gorm:"column:date_of_birth"`
+	Nationality      string         `json:"nationality" gorm:"column:nationality"`
+	ResidenceCountry string         `json:"residence_country" gorm:"column:residence_country"`
+	Street           string         `json:"street" gorm:"column:street"`
+	PostalCode       string         `json:"postal_code" gorm:"column:postal_code"`
+	City             string         `json:"city" gorm:"column:city"`
+	Classification   string         `json:"classification" gorm:"column:classification"`
+	Status           string         `json:"status" gorm:"column:status"`
+	KYCLevel         int            `json:"kyc_level" gorm:"column:kyc_level"`
+	RejectionReason  string         `json:"rejection_reason" gorm:"column:rejection_reason"`
+	SubmittedAt      *time.Time     `json:"submitted_at" gorm:"column:submitted_at"`
+	ReviewedAt       *time.Time     `json:"reviewed_at" gorm:"column:reviewed_at"`
+	ReviewedBy       string         `json:"reviewed_by" gorm:"column:reviewed_by"`
+	Documents        []*KYCDocument `json:"documents" gorm:"foreignkey:UserID;association_foreignkey:UserID"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*InvestorProfile) TableName() string {
+	return "investor_profile"
+}
+
+// Save inserts or updates investor profile in db
+func (profile *InvestorProfile) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Set("gorm:save_associations", false).Save(profile)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save investor profile failed", db.Error)
+	}
+	return nil
+}
+
+// IsEditable checks if investor can change profile data and documents
+func (profile *InvestorProfile) IsEditable() bool {
+
+	return profile.Status == KYCStatusDraft || profile.Status == KYCStatusRejected
+}
+
+// Validate checks profile data, all fields are required for submission
+func (profile *InvestorProfile) Validate() *cigExchange.APIError {
+
+	missingFields := make([]string, 0)
+	if len(profile.FirstName) == 0 {
+		missingFields = append(missingFields, "first_name")
+	}
+	if len(profile.LastName) == 0 {
+		missingFields = append(missingFields, "last_name")
+	}
+	if profile.DateOfBirth == nil {
+		missingFields = append(missingFields, "date_of_birth")
+	}
+	if len(profile.Nationality) == 0 {
+		missingFields = append(missingFields, "nationality")
+	}
+	if len(profile.ResidenceCountry) == 0 {
+		missingFields = append(missingFields, "residence_country")
+	}
+	if len(profile.Street) == 0 {
+		missingFields = append(missingFields, "street")
+	}
+	if len(profile.PostalCode) == 0 {
+		missingFields = append(missingFields, "postal_code")
+	}
+	if len(profile.City) == 0 {
+		missingFields = append(missingFields, "city")
+	}
+	if len(profile.Classification) == 0 {
+		missingFields = append(missingFields, "classification")
+	}
+	if len(missingFields) > 0 {
+		return cigExchange.NewRequiredFieldError(missingFields)
+	}
+
+	hasIdentityDocument := false
+	for _, document := range profile.Documents {
+		if document.Type == KYCDocumentTypePassport || document.Type == KYCDocumentTypeIDCard {
+			hasIdentityDocument = true
+		}
+	}
+	if !hasIdentityDocument {
+		return cigExchange.NewInvalidFieldError("documents", "Passport or ID card is required")
+	}
+	return nil
+}
+
+// Submit sends profile for review
+func (profile *InvestorProfile) Submit() *cigExchange.APIError {
+
+	if !profile.IsEditable() {
+		return cigExchange.NewInvalidFieldError("status", "Profile is already "+profile.Status)
+	}
+
+	apiError := profile.Validate()
+	if apiError != nil {
+		return apiError
+	}
+
+	now := time.Now()
+	profile.Status = KYCStatusPending
+	profile.SubmittedAt = &now
+	return profile.Save()
+}
+
+// Review approves or rejects pending profile and stores the decision in review history
+func (profile *InvestorProfile) Review(reviewerID string, approve bool, level int, reason string) *cigExchange.APIError {
+
+	if profile.Status != KYCStatusPending {
+		return cigExchange.NewInvalidFieldError("status", "Only pending profiles can be reviewed")
+	}
+
+	now := time.Now()
+	review := &KYCReview{
+		UserID:     profile.UserID,
+		ReviewerID: reviewerID,
+		Reason:     reason,
+	}
+	if approve {
+		profile.Status = KYCStatusApproved
+		profile.KYCLevel = level
+		profile.RejectionReason = ""
+		review.Decision = KYCStatusApproved
+		review.KYCLevel = level
+	} else {
+		profile.Status = KYCStatusRejected
+		profile.KYCLevel = KYCLevelNone
+		profile.RejectionReason = reason
+		review.Decision = KYCStatusRejected
+	}
+	profile.ReviewedAt = &now
+	profile.ReviewedBy = reviewerID
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Set("gorm:save_associations", false).Save(profile)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Save investor profile failed", db.Error)
+	}
+	db = tx.Create(review)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create KYC review failed", db.Error)
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Review investor profile failed", db.Error)
+	}
+	return nil
+}
+
+// GetDocument returns profile document with the id
+func (profile *InvestorProfile) GetDocument(documentID string) (*KYCDocument, *cigExchange.APIError) {
+
+	for _, document := range profile.Documents {
+		if document.ID == documentID {
+			return document, nil
+		}
+	}
+	return nil, cigExchange.NewInvalidFieldError("document_id", "Document with provided id doesn't exist")
+}
+
+// preloadDocuments loads profile documents with media in upload order
+func preloadDocuments(db *gorm.DB) *gorm.DB {
+
+	return db.Preload("Documents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	}).Preload("Documents.Media")
+}
+
+// GetInvestorProfile queries investor profile from db, profile in draft state is returned if user has none yet
+func GetInvestorProfile(userID string) (*InvestorProfile, *cigExchange.APIError) {
+
+	profile := &InvestorProfile{}
+	db := preloadDocuments(cigExchange.GetDB()).Where(&InvestorProfile{UserID: userID}).First(profile)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return &InvestorProfile{UserID: userID, Status: KYCStatusDraft, Documents: make([]*KYCDocument, 0)}, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch investor profile failed", db.Error)
+	}
+	return profile, nil
+}
+
+// GetInvestorProfilesByStatus returns profiles in the status, oldest submission first
+func GetInvestorProfilesByStatus(status string) ([]*InvestorProfile, *cigExchange.APIError) {
+
+	profiles := make([]*InvestorProfile, 0)
+	db := preloadDocuments(cigExchange.GetDB()).Where(&InvestorProfile{Status: status}).Order("submitted_at asc").Find(&profiles)
+	if db.Error != nil {
+		return profiles, cigExchange.NewDatabaseError("Fetch investor profiles failed", db.Error)
+	}
+	return profiles, nil
+}
+
+// RequireKYCLevel checks that user profile is approved with at least the given verification level
+func RequireKYCLevel(userID string, level int) *cigExchange.APIError {
+
+	profile, apiError := GetInvestorProfile(userID)
+	if apiError != nil {
+		return apiError
+	}
+	if profile.Status != KYCStatusApproved || profile.KYCLevel < level {
+		return cigExchange.NewAccessRightsError("Investor profile verification is required")
+	}
+	return nil
+}
+
+// IsValidCountryCode checks ISO 3166-1 alpha-2 country code format
+func IsValidCountryCode(code string) bool {
+
+	return countryCodeRegexp.MatchString(code)
+}
+
+// IsValidInvestorClassification checks investor classification
+func IsValidInvestorClassification(classification string) bool {
+
+	for _, c := range InvestorClassifications {
+		if c == classification {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidKYCDocumentType checks KYC document type
+func IsValidKYCDocumentType(documentType string) bool {
+
+	for _, t := range KYCDocumentTypes {
+		if t == documentType {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeCountryCode trims and upper cases country code
+func NormalizeCountryCode(code string) string {
+
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// KYCDocument is a struct to represent uploaded identity document, file is stored as media
+type KYCDocument struct {
+	ID        string       `json:"id" gorm:"column:id;primary_key"`
+	UserID    string       `json:"user_id" gorm:"column:user_id"`
+	Type      string       `json:"type" gorm:"column:type"`
+	MediaID   string       `json:"media_id" gorm:"column:media_id"`
+	Media     models.Media `json:"media" gorm:"foreignkey:MediaID;association_autoupdate:false;association_autocreate:false"`
+	CreatedAt time.Time    `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*KYCDocument) TableName() string {
+	return "kyc_document"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*KYCDocument) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new document into db
+func (document *KYCDocument) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	document.ID = ""
+
+	db := cigExchange.GetDB().Create(document)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create KYC document failed", db.Error)
+	}
+	return nil
+}
+
+// Delete removes document and its media record from db
+func (document *KYCDocument) Delete() *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Delete(document)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete KYC document failed", db.Error)
+	}
+	db = tx.Delete(&models.Media{ID: document.MediaID})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete KYC document media failed", db.Error)
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete KYC document failed", db.Error)
+	}
+	return nil
+}
+
+// KYCReview is a struct to represent admin decision on investor profile
+type KYCReview struct {
+	ID         string    `json:"id" gorm:"column:id;primary_key"`
+	UserID     string    `json:"user_id" gorm:"column:user_id"`
+	ReviewerID string    `json:"reviewer_id" gorm:"column:reviewer_id"`
+	Decision   string    `json:"decision" gorm:"column:decision"`
+	KYCLevel   int       `json:"kyc_level" gorm:"column:kyc_level"`
+	Reason     string    `json:"reason" gorm:"column:reason"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*KYCReview) TableName() string {
+	return "kyc_review"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*KYCReview) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// GetKYCReviews returns review history of the user profile, newest first
+func GetKYCReviews(userID string) ([]*KYCReview, *cigExchange.APIError) {
+
+	reviews := make([]*KYCReview, 0)
+	db := cigExchange.GetDB().Where(&KYCReview{UserID: userID}).Order("created_at desc").Find(&reviews)
+	if db.Error != nil {
+		return reviews, cigExchange.NewDatabaseError("Fetch KYC reviews failed", db.Error)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,563 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining ledger account owners
+const (
+	AccountOwnerUser     = "user"
+	AccountOwnerOffering = "offering"
+	AccountOwnerPlatform = "platform"
+)
+
+// Constants defining ledger account types
+const (
+	AccountTypeCash          = "cash"          // investor wallet
+	AccountTypeSubscriptions = "subscriptions" // investor funds committed to the offering
+	AccountTypeBank          = "bank"          // platform bank account, counterpart of money entering and leaving the platform
+)
+
+// Constants defining journal entry types
+const (
+	JournalEntryTypeDeposit    = "deposit"
+	JournalEntryTypeWithdrawal = "withdrawal"
+	JournalEntryTypeCommitment = "commitment"
+	JournalEntryTypeRepayment  = "repayment"
+)
+
+// platformOwnerID is the owner id of platform accounts
+const platformOwnerID = "platform"
+
+// Account is a struct to represent a ledger account.
+// Balance is the sum of account postings, positive balance of investor cash account is available money
+type Account struct {
+	ID        string    `json:"id" gorm:"column:id;primary_key"`
+	OwnerType string    `json:"owner_type" gorm:"column:owner_type"`
+	OwnerID   string    `json:"owner_id" gorm:"column:owner_id"`
+	Type      string    `json:"type" gorm:"column:type"`
+	Currency  string    `json:"currency" gorm:"column:currency"`
+	Balance   Decimal   `json:"balance" gorm:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*Account) TableName() string {
+	return "ledger_account"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Account) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// JournalEntry is a struct to represent a balanced ledger transaction.
+// Entries are immutable and idempotency key makes repeated requests post the entry only once
+type JournalEntry struct {
+	ID             string     `json:"id" gorm:"column:id;primary_key"`
+	IdempotencyKey string     `json:"idempotency_key" gorm:"column:idempotency_key"`
+	Type           string     `json:"type" gorm:"column:type"`
+	Currency       string     `json:"currency" gorm:"column:currency"`
+	Amount         Decimal    `json:"amount" gorm:"column:amount"`
+	OfferingID     string     `json:"offering_id,omitempty" gorm:"column:offering_id"`
+	Description    string     `json:"description" gorm:"column:description"`
+	EffectiveDate  time.Time  `json:"effective_date" gorm:"column:effective_date"`
+	CreatedBy      string     `json:"created_by" gorm:"column:created_by"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+	Postings       []*Posting `json:"postings,omitempty" gorm:"foreignkey:EntryID"`
+}
+
+// TableName returns table name for struct
+func (*JournalEntry) TableName() string {
+	return "journal_entry"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*JournalEntry) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Posting is a struct to represent a journal entry amount booked to an account.
+// Positive amounts increase account balance, postings of an entry always sum up to zero
+type Posting struct {
+	ID            string    `json:"id" gorm:"column:id;primary_key"`
+	EntryID       string    `json:"entry_id" gorm:"column:entry_id"`
+	AccountID     string    `json:"account_id" gorm:"column:account_id"`
+	Amount        Decimal   `json:"amount" gorm:"column:amount"`
+	EffectiveDate time.Time `json:"effective_date" gorm:"column:effective_date"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*Posting) TableName() string {
+	return "journal_posting"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Posting) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// AccountEntry is a struct to represent a journal entry as seen from an account
+type AccountEntry struct {
+	EntryID       string    `json:"entry_id"`
+	Type          string    `json:"type"`
+	Description   string    `json:"description"`
+	OfferingID    string    `json:"offering_id,omitempty"`
+	Amount        Decimal   `json:"amount"`
+	Balance       Decimal   `json:"balance"`
+	EffectiveDate time.Time `json:"effective_date"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ledgerLeg is an unresolved posting of a journal entry
+type ledgerLeg struct {
+	ownerType   string
+	ownerID     string
+	accountType string
+	amount      Decimal
+}
+
+// lockAccount creates the account if needed and locks it until the transaction ends
+func lockAccount(tx *gorm.DB, ownerType, ownerID, accountType, currency string) (*Account, *cigExchange.APIError) {
+
+	db := tx.Exec("INSERT INTO ledger_account (id, owner_type, owner_id, type, currency) VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+		cigExchange.RandomUUID(), ownerType, ownerID, accountType, currency)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Create account failed", db.Error)
+	}
+
+	account := &Account{}
+	db = tx.Set("gorm:query_option", "FOR UPDATE").
+		Where(&Account{OwnerType: ownerType, OwnerID: ownerID, Type: accountType, Currency: currency}).
+		First(account)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch account failed", db.Error)
+	}
+	return account, nil
+}
+
+// accountBalance sums account postings effective until the date
+func accountBalance(db *gorm.DB, accountID string, asOf time.Time) (Decimal, *cigExchange.APIError) {
+
+	balance := Decimal{}
+	err := db.Raw("SELECT COALESCE(SUM(amount), 0) FROM journal_posting WHERE account_id = ? AND effective_date <= ?", accountID, asOf).
+		Row().Scan(&balance)
+	if err != nil {
+		return balance, cigExchange.NewDatabaseError("Fetch account balance failed", err)
+	}
+	return balance, nil
+}
+
+// findPostedEntry returns the entry already posted with the idempotency key, nil is returned if there is none.
+// Repeated request must describe the same entry, reuse of the key for another entry is an error
+func findPostedEntry(tx *gorm.DB, entry *JournalEntry) (*JournalEntry, *cigExchange.APIError) {
+
+	posted := &JournalEntry{}
+	db := tx.Preload("Postings").Where(&JournalEntry{IdempotencyKey: entry.IdempotencyKey}).First(posted)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch journal entry failed", db.Error)
+	}
+	if posted.Type != entry.Type || posted.Currency != entry.Currency ||
+		posted.Amount.Cmp(entry.Amount) != 0 || posted.OfferingID != entry.OfferingID {
+		return nil, cigExchange.NewInvalidFieldError("idempotency_key", "Idempotency key was already used for another entry")
+	}
+	return posted, nil
+}
+
+// postEntry inserts balanced journal entry with postings to the accounts of the legs.
+// Entry amount is the sum of positive postings
+func postEntry(tx *gorm.DB, entry *JournalEntry, legs []*ledgerLeg) *cigExchange.APIError {
+
+	if len(entry.IdempotencyKey) == 0 {
+		return cigExchange.NewRequiredFieldError([]string{"idempotency_key"})
+	}
+	if !IsValidCurrency(entry.Currency) {
+		return cigExchange.NewInvalidFieldError("currency", "Unsupported currency "+entry.Currency)
+	}
+
+	places := CurrencyDecimalPlaces(entry.Currency)
+	sum := Decimal{}
+	amount := Decimal{}
+	for _, leg := range legs {
+		if leg.amount.Round(places).Cmp(leg.amount) != 0 {
+			return cigExchange.NewInvalidFieldError("amount", "Amount has more decimal places than "+entry.Currency+" allows")
+		}
+		sum = sum.Add(leg.amount)
+		if leg.amount.Sign() > 0 {
+			amount = amount.Add(leg.amount)
+		}
+	}
+	if !sum.IsZero() {
+		return cigExchange.NewInvalidFieldError("amount", "Journal entry postings must sum up to zero")
+	}
+	if amount.IsZero() {
+		return cigExchange.NewInvalidFieldError("amount", "Journal entry amount must be positive")
+	}
+	entry.Amount = amount
+	if entry.EffectiveDate.IsZero() {
+		entry.EffectiveDate = time.Now()
+	}
+
+	db := tx.Set("gorm:save_associations", false).Create(entry)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create journal entry failed", db.Error)
+	}
+
+	entry.Postings = make([]*Posting, 0, len(legs))
+	for _, leg := range legs {
+		if leg.amount.IsZero() {
+			continue
+		}
+		account, apiError := lockAccount(tx, leg.ownerType, leg.ownerID, leg.accountType, entry.Currency)
+		if apiError != nil {
+			return apiError
+		}
+		posting := &Posting{
+			EntryID:       entry.ID,
+			AccountID:     account.ID,
+			Amount:        leg.amount,
+			EffectiveDate: entry.EffectiveDate,
+		}
+		db = tx.Create(posting)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Create journal posting failed", db.Error)
+		}
+		entry.Postings = append(entry.Postings, posting)
+	}
+	return nil
+}
+
+// postOnce posts ledger operation in the transaction unless the idempotency key was already posted.
+// Posted entry replaces the entry content in that case
+func postOnce(tx *gorm.DB, entry *JournalEntry, post func() *cigExchange.APIError) *cigExchange.APIError {
+
+	posted, apiError := findPostedEntry(tx, entry)
+	if apiError != nil {
+		return apiError
+	}
+	if posted != nil {
+		*entry = *posted
+		return nil
+	}
+	return post()
+}
+
+// runLedger runs ledger operation in a new transaction
+func runLedger(operation func(tx *gorm.DB) *cigExchange.APIError) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+
+	apiError := operation(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	db := tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create journal entry failed", db.Error)
+	}
+	return nil
+}
+
+// lockUserCash locks investor cash account and checks that it covers the amount
+func lockUserCash(tx *gorm.DB, userID, currency string, amount Decimal) *cigExchange.APIError {
+
+	account, apiError := lockAccount(tx, AccountOwnerUser, userID, AccountTypeCash, currency)
+	if apiError != nil {
+		return apiError
+	}
+	// future dated postings are reserved as well
+	balance, apiError := accountBalance(tx, account.ID, time.Now().AddDate(100, 0, 0))
+	if apiError != nil {
+		return apiError
+	}
+	if balance.Cmp(amount) < 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Insufficient balance of "+balance.String()+" "+currency)
+	}
+	return nil
+}
+
+// Deposit credits investor cash account with money received on the platform bank account
+func Deposit(entry *JournalEntry, userID string, amount Decimal) *cigExchange.APIError {
+
+	return runLedger(func(tx *gorm.DB) *cigExchange.APIError {
+		return deposit(tx, entry, userID, amount)
+	})
+}
+
+// deposit posts the deposit in the transaction
+func deposit(tx *gorm.DB, entry *JournalEntry, userID string, amount Decimal) *cigExchange.APIError {
+
+	if amount.Sign() <= 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount must be positive")
+	}
+	entry.Type = JournalEntryTypeDeposit
+	entry.Amount = amount
+
+	return postOnce(tx, entry, func() *cigExchange.APIError {
+		return postEntry(tx, entry, []*ledgerLeg{
+			{AccountOwnerPlatform, platformOwnerID, AccountTypeBank, amount.Neg()},
+			{AccountOwnerUser, userID, AccountTypeCash, amount},
+		})
+	})
+}
+
+// Withdraw debits investor cash account with money paid out from the platform bank account
+func Withdraw(entry *JournalEntry, userID string, amount Decimal) *cigExchange.APIError {
+
+	return runLedger(func(tx *gorm.DB) *cigExchange.APIError {
+		return withdraw(tx, entry, userID, amount)
+	})
+}
+
+// withdraw posts the withdrawal in the transaction
+func withdraw(tx *gorm.DB, entry *JournalEntry, userID string, amount Decimal) *cigExchange.APIError {
+
+	if amount.Sign() <= 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount must be positive")
+	}
+	entry.Type = JournalEntryTypeWithdrawal
+	entry.Amount = amount
+
+	return postOnce(tx, entry, func() *cigExchange.APIError {
+		apiError := lockUserCash(tx, userID, entry.Currency, amount)
+		if apiError != nil {
+			return apiError
+		}
+		return postEntry(tx, entry, []*ledgerLeg{
+			{AccountOwnerUser, userID, AccountTypeCash, amount.Neg()},
+			{AccountOwnerPlatform, platformOwnerID, AccountTypeBank, amount},
+		})
+	})
+}
+
+// Commit moves investor cash to the offering subscriptions account. Offering amount already taken,
+// remaining amount and investor holding are updated in the same transaction
+func Commit(entry *JournalEntry, userID string, offering *models.Offering, amount Decimal) *cigExchange.APIError {
+
+	return runLedger(func(tx *gorm.DB) *cigExchange.APIError {
+		return commit(tx, entry, userID, offering, amount)
+	})
+}
+
+// commit posts the commitment in the transaction
+func commit(tx *gorm.DB, entry *JournalEntry, userID string, offering *models.Offering, amount Decimal) *cigExchange.APIError {
+
+	if amount.Sign() <= 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount must be positive")
+	}
+	entry.Type = JournalEntryTypeCommitment
+	entry.OfferingID = offering.ID
+	entry.Amount = amount
+
+	return postOnce(tx, entry, func() *cigExchange.APIError {
+
+		// offering account lock serializes commitments of the offering
+		_, apiError := lockAccount(tx, AccountOwnerOffering, offering.ID, AccountTypeSubscriptions, entry.Currency)
+		if apiError != nil {
+			return apiError
+		}
+
+		// offering money lock serializes commitments with offering updates
+		money := &OfferingMoney{}
+		db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&OfferingMoney{OfferingID: offering.ID}).First(money)
+		if db.Error != nil {
+			if !db.RecordNotFound() {
+				return cigExchange.NewDatabaseError("Fetch offering money failed", db.Error)
+			}
+			money = newOfferingMoney(offering)
+		}
+		if money.Currency != entry.Currency {
+			return cigExchange.NewInvalidFieldError("currency", "Offering currency is "+money.Currency)
+		}
+
+		holding := &Holding{}
+		db = tx.Set("gorm:query_option", "FOR UPDATE").Where(&Holding{OfferingID: offering.ID, UserID: userID}).First(holding)
+		if db.Error != nil {
+			if !db.RecordNotFound() {
+				return cigExchange.NewDatabaseError("Fetch holding failed", db.Error)
+			}
+			holding = &Holding{OfferingID: offering.ID, UserID: userID, Currency: money.Currency}
+		}
+
+		if money.MinimumInvestment != nil && amount.Cmp(*money.MinimumInvestment) < 0 {
+			return cigExchange.NewInvalidFieldError("amount", "Amount is below minimum investment of "+money.MinimumInvestment.String()+" "+money.Currency)
+		}
+		invested := holding.InvestedAmount.Add(amount)
+		if money.MaximumInvestment != nil && !money.MaximumInvestment.IsZero() && invested.Cmp(*money.MaximumInvestment) > 0 {
+			return cigExchange.NewInvalidFieldError("amount", "Investments exceed maximum investment of "+money.MaximumInvestment.String()+" "+money.Currency)
+		}
+		if money.Amount != nil && amount.Cmp(money.Remaining) > 0 {
+			return cigExchange.NewInvalidFieldError("amount", "Amount exceeds remaining amount of "+money.Remaining.String()+" "+money.Currency)
+		}
+
+		apiError = lockUserCash(tx, userID, entry.Currency, amount)
+		if apiError != nil {
+			return apiError
+		}
+
+		apiError = postEntry(tx, entry, []*ledgerLeg{
+			{AccountOwnerUser, userID, AccountTypeCash, amount.Neg()},
+			{AccountOwnerOffering, offering.ID, AccountTypeSubscriptions, amount},
+		})
+		if apiError != nil {
+			return apiError
+		}
+
+		taken := amount
+		if money.AmountAlreadyTaken != nil {
+			taken = taken.Add(*money.AmountAlreadyTaken)
+		}
+		money.AmountAlreadyTaken = &taken
+		money.UpdateRemaining()
+		db = tx.Save(money)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Save offering money failed", db.Error)
+		}
+
+		// legacy offering columns follow the ledger
+		db = tx.Table("offering").Where("id = ?", offering.ID).Updates(map[string]interface{}{
+			"amount_already_taken": taken.Float64(),
+			"remaining":            money.Remaining.Float64(),
+		})
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Update offering failed", db.Error)
+		}
+
+		holding.InvestedAmount = invested
+		holding.Principal = holding.Principal.Add(amount)
+		db = tx.Save(holding)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Save holding failed", db.Error)
+		}
+		return nil
+	})
+}
+
+// postDistribution credits investor cash accounts with distribution allocations paid in on the platform bank account.
+// It runs in the distribution transaction, so allocations are never recorded without the postings
+func postDistribution(tx *gorm.DB, distribution *Distribution) (*JournalEntry, *cigExchange.APIError) {
+
+	entry := &JournalEntry{
+		IdempotencyKey: "distribution:" + distribution.ID,
+		Type:           JournalEntryTypeRepayment,
+		Currency:       distribution.Currency,
+		Amount:         distribution.Principal.Add(distribution.Interest),
+		OfferingID:     distribution.OfferingID,
+		Description:    distribution.Reference,
+		EffectiveDate:  distribution.PaymentDate,
+		CreatedBy:      distribution.CreatedBy,
+	}
+
+	legs := []*ledgerLeg{{AccountOwnerPlatform, platformOwnerID, AccountTypeBank, entry.Amount.Neg()}}
+	for _, allocation := range distribution.Allocations {
+		legs = append(legs, &ledgerLeg{AccountOwnerUser, allocation.UserID, AccountTypeCash, allocation.Total})
+	}
+
+	apiError := postEntry(tx, entry, legs)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return entry, nil
+}
+
+// HasCommitments checks if investors committed money to the offering through the ledger
+func HasCommitments(offeringID string) (bool, *cigExchange.APIError) {
+
+	count := 0
+	db := cigExchange.GetDB().Model(&JournalEntry{}).Where(&JournalEntry{OfferingID: offeringID, Type: JournalEntryTypeCommitment}).Count(&count)
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Fetch journal entries failed", db.Error)
+	}
+	return count > 0, nil
+}
+
+// getAccounts queries owner accounts with balances as of the date
+func getAccounts(ownerType, ownerID string, asOf time.Time) ([]*Account, *cigExchange.APIError) {
+
+	accounts := make([]*Account, 0)
+	db := cigExchange.GetDB().Where(&Account{OwnerType: ownerType, OwnerID: ownerID}).Order("type asc, currency asc").Find(&accounts)
+	if db.Error != nil {
+		return accounts, cigExchange.NewDatabaseError("Fetch accounts failed", db.Error)
+	}
+
+	for _, account := range accounts {
+		balance, apiError := accountBalance(cigExchange.GetDB(), account.ID, asOf)
+		if apiError != nil {
+			return accounts, apiError
+		}
+		account.Balance = balance
+	}
+	return accounts, nil
+}
+
+// GetUserAccounts queries investor accounts with balances as of the date
+func GetUserAccounts(userID string, asOf time.Time) ([]*Account, *cigExchange.APIError) {
+
+	return getAccounts(AccountOwnerUser, userID, asOf)
+}
+
+// GetOfferingAccounts queries offering accounts with balances as of the date
+func GetOfferingAccounts(offeringID string, asOf time.Time) ([]*Account, *cigExchange.APIError) {
+
+	return getAccounts(AccountOwnerOffering, offeringID, asOf)
+}
+
+// GetUserAccount queries investor account with balance as of the date
+func GetUserAccount(userID, accountID string, asOf time.Time) (*Account, *cigExchange.APIError) {
+
+	account := &Account{}
+	db := cigExchange.GetDB().Where(&Account{ID: accountID, OwnerType: AccountOwnerUser, OwnerID: userID}).First(account)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("account_id", "Account doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch account failed", db.Error)
+	}
+
+	balance, apiError := accountBalance(cigExchange.GetDB(), account.ID, asOf)
+	if apiError != nil {
+		return nil, apiError
+	}
+	account.Balance = balance
+	return account, nil
+}
+
+// GetAccountEntries queries account journal entries effective in the period with running balance, oldest first
+func GetAccountEntries(account *Account, from, to time.Time) ([]*AccountEntry, *cigExchange.APIError) {
+
+	entries := make([]*AccountEntry, 0)
+
+	balance, apiError := accountBalance(cigExchange.GetDB(), account.ID, from.Add(-time.Nanosecond))
+	if apiError != nil {
+		return entries, apiError
+	}
+
+	rows := make([]*AccountEntry, 0)
+	db := cigExchange.GetDB().Raw(`SELECT e.id AS entry_id, e.type, e.description, e.offering_id, p.amount, p.effective_date, p.created_at
+		FROM journal_posting p JOIN journal_entry e ON e.id = p.entry_id
+		WHERE p.account_id = ? AND p.effective_date >= ? AND p.effective_date <= ?
+		ORDER BY p.effective_date asc, p.created_at asc`, account.ID, from, to).Scan(&rows)
+	if db.Error != nil {
+		return entries, cigExchange.NewDatabaseError("Fetch journal entries failed", db.Error)
+	}
+
+	for _, row := range rows {
+		balance = balance.Add(row.Amount)
+		row.Balance = balance
+		entries = append(entries, row)
+	}
+	return entries, nil
+}
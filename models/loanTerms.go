@@ -0,0 +1,290 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"strings"
+	"time"
+)
+
+// Constants defining day count conventions
+const (
+	DayCount30E360 = "30E/360"
+	DayCountACT360 = "ACT/360"
+	DayCountACT365 = "ACT/365"
+)
+
+// Constants defining repayment types
+const (
+	RepaymentTypeBullet  = "bullet"
+	RepaymentTypeAnnuity = "annuity"
+	RepaymentTypeLinear  = "linear"
+)
+
+// Constants defining payment frequencies
+const (
+	PaymentFrequencyMonthly    = "monthly"
+	PaymentFrequencyQuarterly  = "quarterly"
+	PaymentFrequencySemiAnnual = "semi_annual"
+	PaymentFrequencyAnnual     = "annual"
+)
+
+// DayCountConventions lists supported day count conventions
+var DayCountConventions = []string{DayCount30E360, DayCountACT360, DayCountACT365}
+
+// RepaymentTypes lists supported repayment types
+var RepaymentTypes = []string{RepaymentTypeBullet, RepaymentTypeAnnuity, RepaymentTypeLinear}
+
+// PaymentFrequencies lists supported payment frequencies
+var PaymentFrequencies = []string{PaymentFrequencyMonthly, PaymentFrequencyQuarterly, PaymentFrequencySemiAnnual, PaymentFrequencyAnnual}
+
+// paymentFrequencyMonths defines number of months between payments
+var paymentFrequencyMonths = map[string]int{
+	PaymentFrequencyMonthly:    1,
+	PaymentFrequencyQuarterly:  3,
+	PaymentFrequencySemiAnnual: 6,
+	PaymentFrequencyAnnual:     12,
+}
+
+// maxTenorMonths limits schedule length
+const maxTenorMonths = 600
+
+// LoanTerms is a struct to represent structured financial terms of a lending offering
+type LoanTerms struct {
+	OfferingID       string     `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	InterestRate     Decimal    `json:"interest_rate" gorm:"column:interest_rate"`
+	DayCount         string     `json:"day_count" gorm:"column:day_count"`
+	TenorMonths      int        `json:"tenor_months" gorm:"column:tenor_months"`
+	RepaymentType    string     `json:"repayment_type" gorm:"column:repayment_type"`
+	PaymentFrequency string     `json:"payment_frequency" gorm:"column:payment_frequency"`
+	StartDate        *time.Time `json:"start_date" gorm:"column:start_date"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*LoanTerms) TableName() string {
+	return "loan_terms"
+}
+
+// Save validates and inserts or updates loan terms in db
+func (terms *LoanTerms) Save() *cigExchange.APIError {
+
+	apiError := terms.Validate()
+	if apiError != nil {
+		return apiError
+	}
+
+	db := cigExchange.GetDB().Save(terms)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save loan terms failed", db.Error)
+	}
+	return nil
+}
+
+// Validate checks loan terms values
+func (terms *LoanTerms) Validate() *cigExchange.APIError {
+
+	if terms.InterestRate.Sign() < 0 || terms.InterestRate.Cmp(NewDecimal(100)) > 0 {
+		return cigExchange.NewInvalidFieldError("interest_rate", "Interest rate must be between 0 and 100 percent")
+	}
+	if !containsString(DayCountConventions, terms.DayCount) {
+		return cigExchange.NewInvalidFieldError("day_count", "Day count must be one of: "+strings.Join(DayCountConventions, ", "))
+	}
+	if !containsString(RepaymentTypes, terms.RepaymentType) {
+		return cigExchange.NewInvalidFieldError("repayment_type", "Repayment type must be one of: "+strings.Join(RepaymentTypes, ", "))
+	}
+	if !containsString(PaymentFrequencies, terms.PaymentFrequency) {
+		return cigExchange.NewInvalidFieldError("payment_frequency", "Payment frequency must be one of: "+strings.Join(PaymentFrequencies, ", "))
+	}
+	if terms.TenorMonths <= 0 || terms.TenorMonths > maxTenorMonths {
+		return cigExchange.NewInvalidFieldError("tenor_months", "Tenor must be between 1 and 600 months")
+	}
+	if terms.TenorMonths%paymentFrequencyMonths[terms.PaymentFrequency] != 0 {
+		return cigExchange.NewInvalidFieldError("tenor_months", "Tenor must be a multiple of the payment period")
+	}
+	return nil
+}
+
+// IsConfigured checks if offering has loan terms
+func (terms *LoanTerms) IsConfigured() bool {
+
+	return len(terms.RepaymentType) > 0
+}
+
+// GetLoanTerms queries offering loan terms, terms without repayment type are returned if offering has none
+func GetLoanTerms(offering *models.Offering) (*LoanTerms, *cigExchange.APIError) {
+
+	terms := &LoanTerms{}
+	db := cigExchange.GetDB().Where(&LoanTerms{OfferingID: offering.ID}).First(terms)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			terms = &LoanTerms{OfferingID: offering.ID, DayCount: DayCount30E360}
+			// legacy offering interest is the best guess for the rate
+			if offering.Interest != nil {
+				terms.InterestRate = DecimalFromFloat(*offering.Interest)
+			}
+			return terms, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch loan terms failed", db.Error)
+	}
+	return terms, nil
+}
+
+// SchedulePayment is a struct to represent a single row of amortization schedule
+type SchedulePayment struct {
+	Number         int       `json:"number"`
+	Date           time.Time `json:"date"`
+	Days           int       `json:"days"`
+	OpeningBalance Decimal   `json:"opening_balance"`
+	Principal      Decimal   `json:"principal"`
+	Interest       Decimal   `json:"interest"`
+	Payment        Decimal   `json:"payment"`
+	ClosingBalance Decimal   `json:"closing_balance"`
+}
+
+// Schedule is a struct to represent amortization schedule of a principal amount
+type Schedule struct {
+	Currency       string             `json:"currency"`
+	Principal      Decimal            `json:"principal"`
+	StartDate      time.Time          `json:"start_date"`
+	TotalInterest  Decimal            `json:"total_interest"`
+	TotalRepayment Decimal            `json:"total_repayment"`
+	Terms          *LoanTerms         `json:"terms"`
+	Payments       []*SchedulePayment `json:"payments"`
+}
+
+// GenerateSchedule calculates amortization schedule of the principal starting at the date.
+// Interest of every period is calculated with the day count convention and rounded to currency minor units,
+// annuity payment is calculated with the nominal periodic rate and the last payment settles the rest
+func (terms *LoanTerms) GenerateSchedule(principal Decimal, currency string, startDate time.Time) (*Schedule, *cigExchange.APIError) {
+
+	if !terms.IsConfigured() {
+		return nil, cigExchange.NewInvalidFieldError("offering_id", "Offering has no loan terms")
+	}
+	apiError := terms.Validate()
+	if apiError != nil {
+		return nil, apiError
+	}
+	if principal.Sign() <= 0 {
+		return nil, cigExchange.NewInvalidFieldError("amount", "Amount must be positive")
+	}
+
+	places := CurrencyDecimalPlaces(currency)
+	periodMonths := paymentFrequencyMonths[terms.PaymentFrequency]
+	periods := terms.TenorMonths / periodMonths
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	// annual rate in percent to fraction
+	annualRate := terms.InterestRate.Div(NewDecimal(100))
+	periodicRate := annualRate.Mul(NewDecimal(int64(periodMonths))).Div(NewDecimal(12))
+
+	annuityPayment := Decimal{}
+	if terms.RepaymentType == RepaymentTypeAnnuity {
+		annuityPayment = annuity(principal, periodicRate, periods).Round(places)
+	}
+	linearPrincipal := principal.Div(NewDecimal(int64(periods))).Round(places)
+
+	schedule := &Schedule{
+		Currency:  currency,
+		Principal: principal,
+		StartDate: startDate,
+		Terms:     terms,
+		Payments:  make([]*SchedulePayment, 0, periods),
+	}
+
+	balance := principal
+	periodStart := startDate
+	for number := 1; number <= periods; number++ {
+		periodEnd := addMonths(startDate, number*periodMonths)
+
+		interest := balance.Mul(annualRate).Mul(yearFraction(terms.DayCount, periodStart, periodEnd)).Round(places)
+
+		principalPart := Decimal{}
+		switch terms.RepaymentType {
+		case RepaymentTypeAnnuity:
+			principalPart = annuityPayment.Sub(interest)
+		case RepaymentTypeLinear:
+			principalPart = linearPrincipal
+		}
+		if number == periods || principalPart.Cmp(balance) > 0 {
+			principalPart = balance
+		}
+		if principalPart.Sign() < 0 {
+			principalPart = Decimal{}
+		}
+
+		payment := &SchedulePayment{
+			Number:         number,
+			Date:           periodEnd,
+			Days:           int(periodEnd.Sub(periodStart).Hours() / 24),
+			OpeningBalance: balance,
+			Principal:      principalPart,
+			Interest:       interest,
+			Payment:        principalPart.Add(interest),
+			ClosingBalance: balance.Sub(principalPart),
+		}
+		schedule.Payments = append(schedule.Payments, payment)
+		schedule.TotalInterest = schedule.TotalInterest.Add(interest)
+
+		balance = payment.ClosingBalance
+		periodStart = periodEnd
+	}
+
+	schedule.TotalRepayment = principal.Add(schedule.TotalInterest)
+	return schedule, nil
+}
+
+// annuity calculates constant payment: principal * r / (1 - (1 + r)^-n)
+func annuity(principal, rate Decimal, periods int) Decimal {
+
+	if rate.IsZero() {
+		return principal.Div(NewDecimal(int64(periods)))
+	}
+
+	one := NewDecimal(1)
+	growth := one
+	for i := 0; i < periods; i++ {
+		growth = growth.Mul(one.Add(rate)).Round(maxDecimalPlaces)
+	}
+	// r * (1 + r)^n / ((1 + r)^n - 1) is the same formula without negative power
+	return principal.Mul(rate).Mul(growth).Div(growth.Sub(one))
+}
+
+// yearFraction calculates part of the year between dates according to the day count convention
+func yearFraction(dayCount string, from, to time.Time) Decimal {
+
+	switch dayCount {
+	case DayCount30E360:
+		d1 := from.Day()
+		if d1 > 30 {
+			d1 = 30
+		}
+		d2 := to.Day()
+		if d2 > 30 {
+			d2 = 30
+		}
+		days := 360*(to.Year()-from.Year()) + 30*(int(to.Month())-int(from.Month())) + d2 - d1
+		return NewDecimal(int64(days)).Div(NewDecimal(360))
+	case DayCountACT360:
+		return NewDecimal(actualDays(from, to)).Div(NewDecimal(360))
+	}
+	return NewDecimal(actualDays(from, to)).Div(NewDecimal(365))
+}
+
+func actualDays(from, to time.Time) int64 {
+
+	return int64(to.Sub(from).Hours() / 24)
+}
+
+// addMonths adds months to the date keeping the day, days missing in the target month are moved to the month end
+func addMonths(date time.Time, months int) time.Time {
+
+	firstOfMonth := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day, 0, 0, 0, 0, time.UTC)
+}
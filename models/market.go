@@ -0,0 +1,733 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining secondary market order statuses
+const (
+	MarketOrderStatusOpen      = "open"
+	MarketOrderStatusFilled    = "filled"
+	MarketOrderStatusCancelled = "cancelled"
+)
+
+// JournalEntryTypeTrade is a journal entry type of secondary market settlements
+const JournalEntryTypeTrade = "trade"
+
+// pricePlaces is the number of decimal places of secondary market prices
+const pricePlaces = 2
+
+// maxPrice limits secondary market prices in percent of principal
+const maxPrice = 1000
+
+// Listing is a struct to represent a sell order of holding principal on the secondary market.
+// Price is in percent of principal, 100 sells principal at par
+type Listing struct {
+	ID                 string    `json:"id" gorm:"column:id;primary_key"`
+	OfferingID         string    `json:"offering_id" gorm:"column:offering_id"`
+	SellerID           string    `json:"seller_id" gorm:"column:seller_id"`
+	Currency           string    `json:"currency" gorm:"column:currency"`
+	Principal          Decimal   `json:"principal" gorm:"column:principal"`
+	RemainingPrincipal Decimal   `json:"remaining_principal" gorm:"column:remaining_principal"`
+	Price              Decimal   `json:"price" gorm:"column:price"`
+	Status             string    `json:"status" gorm:"column:status"`
+	CreatedAt          time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Listing) TableName() string {
+	return "market_listing"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Listing) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Bid is a struct to represent a buy order of offering principal on the secondary market
+type Bid struct {
+	ID                 string    `json:"id" gorm:"column:id;primary_key"`
+	OfferingID         string    `json:"offering_id" gorm:"column:offering_id"`
+	BuyerID            string    `json:"buyer_id" gorm:"column:buyer_id"`
+	Currency           string    `json:"currency" gorm:"column:currency"`
+	Principal          Decimal   `json:"principal" gorm:"column:principal"`
+	RemainingPrincipal Decimal   `json:"remaining_principal" gorm:"column:remaining_principal"`
+	Price              Decimal   `json:"price" gorm:"column:price"`
+	Status             string    `json:"status" gorm:"column:status"`
+	CreatedAt          time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Bid) TableName() string {
+	return "market_bid"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Bid) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Trade is a struct to represent a settled secondary market trade
+type Trade struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	OfferingID     string    `json:"offering_id" gorm:"column:offering_id"`
+	ListingID      string    `json:"listing_id" gorm:"column:listing_id"`
+	BidID          string    `json:"bid_id" gorm:"column:bid_id"`
+	SellerID       string    `json:"seller_id" gorm:"column:seller_id"`
+	BuyerID        string    `json:"buyer_id" gorm:"column:buyer_id"`
+	Currency       string    `json:"currency" gorm:"column:currency"`
+	Principal      Decimal   `json:"principal" gorm:"column:principal"`
+	Price          Decimal   `json:"price" gorm:"column:price"`
+	Amount         Decimal   `json:"amount" gorm:"column:amount"`
+	JournalEntryID string    `json:"journal_entry_id" gorm:"column:journal_entry_id"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*Trade) TableName() string {
+	return "market_trade"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Trade) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// PricePoint is a struct to represent a trade in offering price history
+type PricePoint struct {
+	Price     Decimal   `json:"price"`
+	Principal Decimal   `json:"principal"`
+	TradedAt  time.Time `json:"traded_at"`
+}
+
+// OrderBookLevel is a struct to represent open orders aggregated by price
+type OrderBookLevel struct {
+	Price     Decimal `json:"price"`
+	Principal Decimal `json:"principal"`
+	Orders    int     `json:"orders"`
+}
+
+// OrderBook is a struct to represent anonymous secondary market depth of an offering
+type OrderBook struct {
+	OfferingID string            `json:"offering_id"`
+	Currency   string            `json:"currency"`
+	Asks       []*OrderBookLevel `json:"asks"`
+	Bids       []*OrderBookLevel `json:"bids"`
+	LastPrice  *Decimal          `json:"last_price"`
+}
+
+// MarketSummary is a struct to represent secondary market state shown next to an offering
+type MarketSummary struct {
+	OpenListings int      `json:"open_listings"`
+	BestAsk      *Decimal `json:"best_ask"`
+	BestBid      *Decimal `json:"best_bid"`
+	LastPrice    *Decimal `json:"last_price"`
+}
+
+// IsFunded checks that the offering is fully taken, only funded offerings are traded on the secondary market
+func (money *OfferingMoney) IsFunded() bool {
+
+	return money.Amount != nil && money.Amount.Sign() > 0 && money.Remaining.IsZero()
+}
+
+// tradeAmount calculates cash amount of principal traded at the price
+func tradeAmount(principal, price Decimal, currency string) Decimal {
+
+	return principal.Mul(price).Div(NewDecimal(100)).Round(CurrencyDecimalPlaces(currency))
+}
+
+// validateOrder checks principal and price of a market order
+func validateOrder(principal, price Decimal, currency string) *cigExchange.APIError {
+
+	places := CurrencyDecimalPlaces(currency)
+	if principal.Sign() <= 0 || principal.Round(places).Cmp(principal) != 0 {
+		return cigExchange.NewInvalidFieldError("principal", "Principal must be a positive "+currency+" amount")
+	}
+	if price.Sign() <= 0 || price.Cmp(NewDecimal(maxPrice)) > 0 || price.Round(pricePlaces).Cmp(price) != 0 {
+		return cigExchange.NewInvalidFieldError("price", "Price must be a percentage of principal between 0 and 1000 with at most 2 decimal places")
+	}
+	if tradeAmount(principal, price, currency).IsZero() {
+		return cigExchange.NewInvalidFieldError("principal", "Order amount is too small")
+	}
+	return nil
+}
+
+// lockMarket serializes secondary market operations of the offering until the transaction ends
+func lockMarket(tx *gorm.DB, offeringID string) *cigExchange.APIError {
+
+	db := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "market:"+offeringID)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Lock market failed", db.Error)
+	}
+	return nil
+}
+
+// lockHoldings locks holdings of the users in user id order, missing holdings aren't returned
+func lockHoldings(tx *gorm.DB, offeringID string, userIDs ...string) (map[string]*Holding, *cigExchange.APIError) {
+
+	holdings := make([]*Holding, 0)
+	db := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("offering_id = ? AND user_id IN (?)", offeringID, userIDs).
+		Order("user_id asc").
+		Find(&holdings)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch holdings failed", db.Error)
+	}
+
+	result := make(map[string]*Holding)
+	for _, holding := range holdings {
+		result[holding.UserID] = holding
+	}
+	return result, nil
+}
+
+// listedPrincipal sums remaining principal of seller open listings
+func listedPrincipal(tx *gorm.DB, offeringID, sellerID string) (Decimal, *cigExchange.APIError) {
+
+	listed := Decimal{}
+	err := tx.Raw("SELECT COALESCE(SUM(remaining_principal), 0) FROM market_listing WHERE offering_id = ? AND seller_id = ? AND status = ?",
+		offeringID, sellerID, MarketOrderStatusOpen).Row().Scan(&listed)
+	if err != nil {
+		return listed, cigExchange.NewDatabaseError("Fetch listings failed", err)
+	}
+	return listed, nil
+}
+
+// reservedCash sums cost of buyer open bids in the currency
+func reservedCash(tx *gorm.DB, buyerID, currency string) (Decimal, *cigExchange.APIError) {
+
+	bids := make([]*Bid, 0)
+	db := tx.Where(&Bid{BuyerID: buyerID, Currency: currency, Status: MarketOrderStatusOpen}).Find(&bids)
+	if db.Error != nil {
+		return Decimal{}, cigExchange.NewDatabaseError("Fetch bids failed", db.Error)
+	}
+
+	reserved := Decimal{}
+	for _, bid := range bids {
+		reserved = reserved.Add(tradeAmount(bid.RemainingPrincipal, bid.Price, currency))
+	}
+	return reserved, nil
+}
+
+// settle transfers principal from the seller holding to the buyer holding and buyer cash to the seller
+// in the open transaction. Nil trade is returned if seller or buyer can't settle anymore, the order
+// which can't be settled is cancelled then
+func settle(tx *gorm.DB, listing *Listing, bid *Bid, price Decimal) (*Trade, *cigExchange.APIError) {
+
+	principal := listing.RemainingPrincipal
+	if bid.RemainingPrincipal.Cmp(principal) < 0 {
+		principal = bid.RemainingPrincipal
+	}
+	amount := tradeAmount(principal, price, listing.Currency)
+
+	holdings, apiError := lockHoldings(tx, listing.OfferingID, listing.SellerID, bid.BuyerID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	// principal could be reduced by distributions after listing
+	sellerHolding, ok := holdings[listing.SellerID]
+	if !ok || sellerHolding.Principal.Cmp(principal) < 0 {
+		return nil, cancelOrder(tx, listing)
+	}
+
+	cash, apiError := lockAccount(tx, AccountOwnerUser, bid.BuyerID, AccountTypeCash, bid.Currency)
+	if apiError != nil {
+		return nil, apiError
+	}
+	balance, apiError := accountBalance(tx, cash.ID, time.Now().AddDate(100, 0, 0))
+	if apiError != nil {
+		return nil, apiError
+	}
+	if balance.Cmp(amount) < 0 {
+		return nil, cancelOrder(tx, bid)
+	}
+
+	entry := &JournalEntry{
+		IdempotencyKey: "trade:" + listing.ID + ":" + bid.ID,
+		Type:           JournalEntryTypeTrade,
+		Currency:       listing.Currency,
+		OfferingID:     listing.OfferingID,
+		Description:    "Secondary market trade",
+		EffectiveDate:  time.Now(),
+		CreatedBy:      bid.BuyerID,
+	}
+	apiError = postEntry(tx, entry, []*ledgerLeg{
+		{AccountOwnerUser, bid.BuyerID, AccountTypeCash, amount.Neg()},
+		{AccountOwnerUser, listing.SellerID, AccountTypeCash, amount},
+	})
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	// seller cost basis is reduced pro-rata to the principal sold
+	soldInvested := sellerHolding.InvestedAmount.Mul(principal).Div(sellerHolding.Principal).Round(CurrencyDecimalPlaces(listing.Currency))
+	sellerHolding.InvestedAmount = sellerHolding.InvestedAmount.Sub(soldInvested)
+	sellerHolding.Principal = sellerHolding.Principal.Sub(principal)
+	db := tx.Save(sellerHolding)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Save holding failed", db.Error)
+	}
+
+	buyerHolding, ok := holdings[bid.BuyerID]
+	if !ok {
+		buyerHolding = &Holding{OfferingID: listing.OfferingID, UserID: bid.BuyerID, Currency: listing.Currency}
+	}
+	buyerHolding.InvestedAmount = buyerHolding.InvestedAmount.Add(amount)
+	buyerHolding.Principal = buyerHolding.Principal.Add(principal)
+	db = tx.Save(buyerHolding)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Save holding failed", db.Error)
+	}
+
+	listing.RemainingPrincipal = listing.RemainingPrincipal.Sub(principal)
+	if listing.RemainingPrincipal.IsZero() {
+		listing.Status = MarketOrderStatusFilled
+	}
+	db = tx.Save(listing)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Save listing failed", db.Error)
+	}
+
+	bid.RemainingPrincipal = bid.RemainingPrincipal.Sub(principal)
+	if bid.RemainingPrincipal.IsZero() {
+		bid.Status = MarketOrderStatusFilled
+	}
+	db = tx.Save(bid)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Save bid failed", db.Error)
+	}
+
+	trade := &Trade{
+		OfferingID:     listing.OfferingID,
+		ListingID:      listing.ID,
+		BidID:          bid.ID,
+		SellerID:       listing.SellerID,
+		BuyerID:        bid.BuyerID,
+		Currency:       listing.Currency,
+		Principal:      principal,
+		Price:          price,
+		Amount:         amount,
+		JournalEntryID: entry.ID,
+	}
+	db = tx.Create(trade)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Create trade failed", db.Error)
+	}
+	return trade, nil
+}
+
+// cancelOrder cancels open listing or bid in the open transaction. Order status is checked by the update,
+// orders filled since they were read aren't cancelled
+func cancelOrder(tx *gorm.DB, order interface{}) *cigExchange.APIError {
+
+	db := tx.Model(order).Where("status = ?", MarketOrderStatusOpen).Update("status", MarketOrderStatusCancelled)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Cancel order failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		if _, ok := order.(*Bid); ok {
+			return cigExchange.NewInvalidFieldError("bid_id", "Bid is not open")
+		}
+		return cigExchange.NewInvalidFieldError("listing_id", "Listing is not open")
+	}
+	switch o := order.(type) {
+	case *Listing:
+		o.Status = MarketOrderStatusCancelled
+	case *Bid:
+		o.Status = MarketOrderStatusCancelled
+	}
+	return nil
+}
+
+// runMarket runs secondary market operation of the offering in a transaction
+func runMarket(offeringID string, operation func(tx *gorm.DB) *cigExchange.APIError) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+
+	apiError := lockMarket(tx, offeringID)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	apiError = operation(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	db := tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Commit market operation failed", db.Error)
+	}
+	return nil
+}
+
+// Create validates and inserts the listing and matches it against open bids with the best price first.
+// Trades are settled at the bid price
+func (listing *Listing) Create() ([]*Trade, *cigExchange.APIError) {
+
+	trades := make([]*Trade, 0)
+
+	apiError := validateOrder(listing.Principal, listing.Price, listing.Currency)
+	if apiError != nil {
+		return trades, apiError
+	}
+	listing.RemainingPrincipal = listing.Principal
+	listing.Status = MarketOrderStatusOpen
+
+	apiError = runMarket(listing.OfferingID, func(tx *gorm.DB) *cigExchange.APIError {
+
+		holdings, apiError := lockHoldings(tx, listing.OfferingID, listing.SellerID)
+		if apiError != nil {
+			return apiError
+		}
+		holding, ok := holdings[listing.SellerID]
+		if !ok {
+			return cigExchange.NewInvalidFieldError("offering_id", "You have no holding in the offering")
+		}
+		listed, apiError := listedPrincipal(tx, listing.OfferingID, listing.SellerID)
+		if apiError != nil {
+			return apiError
+		}
+		if listed.Add(listing.Principal).Cmp(holding.Principal) > 0 {
+			return cigExchange.NewInvalidFieldError("principal", "Principal exceeds unlisted holding principal of "+holding.Principal.Sub(listed).String()+" "+listing.Currency)
+		}
+
+		db := tx.Create(listing)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Create listing failed", db.Error)
+		}
+
+		bids := make([]*Bid, 0)
+		db = tx.Where("offering_id = ? AND status = ? AND price >= ? AND buyer_id <> ?",
+			listing.OfferingID, MarketOrderStatusOpen, listing.Price, listing.SellerID).
+			Order("price desc, created_at asc").
+			Find(&bids)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Fetch bids failed", db.Error)
+		}
+
+		for _, bid := range bids {
+			if listing.Status != MarketOrderStatusOpen {
+				break
+			}
+			trade, apiError := settle(tx, listing, bid, bid.Price)
+			if apiError != nil {
+				return apiError
+			}
+			if trade != nil {
+				trades = append(trades, trade)
+			}
+		}
+		return nil
+	})
+	return trades, apiError
+}
+
+// Create validates and inserts the bid and matches it against open listings with the best price first.
+// Trades are settled at the listing price, bid cost must be covered by buyer cash not reserved by other bids
+func (bid *Bid) Create() ([]*Trade, *cigExchange.APIError) {
+
+	trades := make([]*Trade, 0)
+
+	apiError := validateOrder(bid.Principal, bid.Price, bid.Currency)
+	if apiError != nil {
+		return trades, apiError
+	}
+	bid.RemainingPrincipal = bid.Principal
+	bid.Status = MarketOrderStatusOpen
+
+	apiError = runMarket(bid.OfferingID, func(tx *gorm.DB) *cigExchange.APIError {
+
+		cash, apiError := lockAccount(tx, AccountOwnerUser, bid.BuyerID, AccountTypeCash, bid.Currency)
+		if apiError != nil {
+			return apiError
+		}
+		balance, apiError := accountBalance(tx, cash.ID, time.Now().AddDate(100, 0, 0))
+		if apiError != nil {
+			return apiError
+		}
+		reserved, apiError := reservedCash(tx, bid.BuyerID, bid.Currency)
+		if apiError != nil {
+			return apiError
+		}
+		if reserved.Add(tradeAmount(bid.Principal, bid.Price, bid.Currency)).Cmp(balance) > 0 {
+			return cigExchange.NewInvalidFieldError("principal", "Insufficient balance, "+balance.Sub(reserved).String()+" "+bid.Currency+" is not reserved by open bids")
+		}
+
+		db := tx.Create(bid)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Create bid failed", db.Error)
+		}
+
+		listings := make([]*Listing, 0)
+		db = tx.Where("offering_id = ? AND status = ? AND price <= ? AND seller_id <> ?",
+			bid.OfferingID, MarketOrderStatusOpen, bid.Price, bid.BuyerID).
+			Order("price asc, created_at asc").
+			Find(&listings)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Fetch listings failed", db.Error)
+		}
+
+		for _, listing := range listings {
+			if bid.Status != MarketOrderStatusOpen {
+				break
+			}
+			trade, apiError := settle(tx, listing, bid, listing.Price)
+			if apiError != nil {
+				return apiError
+			}
+			if trade != nil {
+				trades = append(trades, trade)
+			}
+		}
+		return nil
+	})
+	return trades, apiError
+}
+
+// Cancel cancels open listing
+func (listing *Listing) Cancel() *cigExchange.APIError {
+
+	if listing.Status != MarketOrderStatusOpen {
+		return cigExchange.NewInvalidFieldError("listing_id", "Listing is not open")
+	}
+	return runMarket(listing.OfferingID, func(tx *gorm.DB) *cigExchange.APIError {
+		return cancelOrder(tx, listing)
+	})
+}
+
+// Cancel cancels open bid
+func (bid *Bid) Cancel() *cigExchange.APIError {
+
+	if bid.Status != MarketOrderStatusOpen {
+		return cigExchange.NewInvalidFieldError("bid_id", "Bid is not open")
+	}
+	return runMarket(bid.OfferingID, func(tx *gorm.DB) *cigExchange.APIError {
+		return cancelOrder(tx, bid)
+	})
+}
+
+// GetUserListing queries seller listing
+func GetUserListing(sellerID, listingID string) (*Listing, *cigExchange.APIError) {
+
+	listing := &Listing{}
+	db := cigExchange.GetDB().Where(&Listing{ID: listingID, SellerID: sellerID}).First(listing)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("listing_id", "Listing doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch listing failed", db.Error)
+	}
+	return listing, nil
+}
+
+// GetUserListings queries all seller listings, newest first
+func GetUserListings(sellerID string) ([]*Listing, *cigExchange.APIError) {
+
+	listings := make([]*Listing, 0)
+	db := cigExchange.GetDB().Where(&Listing{SellerID: sellerID}).Order("created_at desc").Find(&listings)
+	if db.Error != nil {
+		return listings, cigExchange.NewDatabaseError("Fetch listings failed", db.Error)
+	}
+	return listings, nil
+}
+
+// GetUserBid queries buyer bid
+func GetUserBid(buyerID, bidID string) (*Bid, *cigExchange.APIError) {
+
+	bid := &Bid{}
+	db := cigExchange.GetDB().Where(&Bid{ID: bidID, BuyerID: buyerID}).First(bid)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("bid_id", "Bid doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch bid failed", db.Error)
+	}
+	return bid, nil
+}
+
+// GetUserBids queries all buyer bids, newest first
+func GetUserBids(buyerID string) ([]*Bid, *cigExchange.APIError) {
+
+	bids := make([]*Bid, 0)
+	db := cigExchange.GetDB().Where(&Bid{BuyerID: buyerID}).Order("created_at desc").Find(&bids)
+	if db.Error != nil {
+		return bids, cigExchange.NewDatabaseError("Fetch bids failed", db.Error)
+	}
+	return bids, nil
+}
+
+// GetUserTrades queries all trades where the user was seller or buyer, newest first
+func GetUserTrades(userID string) ([]*Trade, *cigExchange.APIError) {
+
+	trades := make([]*Trade, 0)
+	db := cigExchange.GetDB().Where("seller_id = ? OR buyer_id = ?", userID, userID).Order("created_at desc").Find(&trades)
+	if db.Error != nil {
+		return trades, cigExchange.NewDatabaseError("Fetch trades failed", db.Error)
+	}
+	return trades, nil
+}
+
+// HasTrades checks if offering holdings were traded on the secondary market
+func HasTrades(offeringID string) (bool, *cigExchange.APIError) {
+
+	count := 0
+	db := cigExchange.GetDB().Model(&Trade{}).Where(&Trade{OfferingID: offeringID}).Count(&count)
+	if db.Error != nil {
+		return false, cigExchange.NewDatabaseError("Fetch trades failed", db.Error)
+	}
+	return count > 0, nil
+}
+
+// GetPriceHistory queries offering trade prices, oldest first
+func GetPriceHistory(offeringID string) ([]*PricePoint, *cigExchange.APIError) {
+
+	points := make([]*PricePoint, 0)
+
+	trades := make([]*Trade, 0)
+	db := cigExchange.GetDB().Where(&Trade{OfferingID: offeringID}).Order("created_at asc").Find(&trades)
+	if db.Error != nil {
+		return points, cigExchange.NewDatabaseError("Fetch trades failed", db.Error)
+	}
+
+	for _, trade := range trades {
+		points = append(points, &PricePoint{Price: trade.Price, Principal: trade.Principal, TradedAt: trade.CreatedAt})
+	}
+	return points, nil
+}
+
+// aggregateOrders sums remaining principal of orders by price keeping the order of prices
+func aggregateOrders(prices, principals []Decimal) []*OrderBookLevel {
+
+	levels := make([]*OrderBookLevel, 0)
+	for i, price := range prices {
+		if len(levels) > 0 && levels[len(levels)-1].Price.Cmp(price) == 0 {
+			level := levels[len(levels)-1]
+			level.Principal = level.Principal.Add(principals[i])
+			level.Orders++
+			continue
+		}
+		levels = append(levels, &OrderBookLevel{Price: price, Principal: principals[i], Orders: 1})
+	}
+	return levels
+}
+
+// lastPrice queries price of the latest offering trade, nil is returned if offering was never traded
+func lastPrice(offeringID string) (*Decimal, *cigExchange.APIError) {
+
+	trade := &Trade{}
+	db := cigExchange.GetDB().Where(&Trade{OfferingID: offeringID}).Order("created_at desc").First(trade)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch trades failed", db.Error)
+	}
+	return &trade.Price, nil
+}
+
+// GetOrderBook queries open orders of the offering aggregated by price without order owners
+func GetOrderBook(offeringID, currency string) (*OrderBook, *cigExchange.APIError) {
+
+	book := &OrderBook{OfferingID: offeringID, Currency: currency}
+
+	listings := make([]*Listing, 0)
+	db := cigExchange.GetDB().Where(&Listing{OfferingID: offeringID, Status: MarketOrderStatusOpen}).Order("price asc").Find(&listings)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch listings failed", db.Error)
+	}
+	prices := make([]Decimal, 0, len(listings))
+	principals := make([]Decimal, 0, len(listings))
+	for _, listing := range listings {
+		prices = append(prices, listing.Price)
+		principals = append(principals, listing.RemainingPrincipal)
+	}
+	book.Asks = aggregateOrders(prices, principals)
+
+	bids := make([]*Bid, 0)
+	db = cigExchange.GetDB().Where(&Bid{OfferingID: offeringID, Status: MarketOrderStatusOpen}).Order("price desc").Find(&bids)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch bids failed", db.Error)
+	}
+	prices = make([]Decimal, 0, len(bids))
+	principals = make([]Decimal, 0, len(bids))
+	for _, bid := range bids {
+		prices = append(prices, bid.Price)
+		principals = append(principals, bid.RemainingPrincipal)
+	}
+	book.Bids = aggregateOrders(prices, principals)
+
+	price, apiError := lastPrice(offeringID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	book.LastPrice = price
+	return book, nil
+}
+
+// GetMarketSummaries queries secondary market summaries of the offerings mapped by offering id.
+// Offerings without open orders and trades are not included
+func GetMarketSummaries(offeringIDs []string) (map[string]*MarketSummary, *cigExchange.APIError) {
+
+	summaries := make(map[string]*MarketSummary)
+	if len(offeringIDs) == 0 {
+		return summaries, nil
+	}
+	summary := func(offeringID string) *MarketSummary {
+		if _, ok := summaries[offeringID]; !ok {
+			summaries[offeringID] = &MarketSummary{}
+		}
+		return summaries[offeringID]
+	}
+
+	listings := make([]*Listing, 0)
+	db := cigExchange.GetDB().Where("offering_id IN (?) AND status = ?", offeringIDs, MarketOrderStatusOpen).Find(&listings)
+	if db.Error != nil {
+		return summaries, cigExchange.NewDatabaseError("Fetch listings failed", db.Error)
+	}
+	for _, listing := range listings {
+		s := summary(listing.OfferingID)
+		s.OpenListings++
+		if s.BestAsk == nil || listing.Price.Cmp(*s.BestAsk) < 0 {
+			price := listing.Price
+			s.BestAsk = &price
+		}
+	}
+
+	bids := make([]*Bid, 0)
+	db = cigExchange.GetDB().Where("offering_id IN (?) AND status = ?", offeringIDs, MarketOrderStatusOpen).Find(&bids)
+	if db.Error != nil {
+		return summaries, cigExchange.NewDatabaseError("Fetch bids failed", db.Error)
+	}
+	for _, bid := range bids {
+		s := summary(bid.OfferingID)
+		if s.BestBid == nil || bid.Price.Cmp(*s.BestBid) > 0 {
+			price := bid.Price
+			s.BestBid = &price
+		}
+	}
+
+	// latest trade of every offering
+	trades := make([]*Trade, 0)
+	db = cigExchange.GetDB().Raw(`SELECT DISTINCT ON (offering_id) * FROM market_trade
+		WHERE offering_id IN (?) ORDER BY offering_id, created_at desc`, offeringIDs).Scan(&trades)
+	if db.Error != nil {
+		return summaries, cigExchange.NewDatabaseError("Fetch trades failed", db.Error)
+	}
+	for _, trade := range trades {
+		price := trade.Price
+		summary(trade.OfferingID).LastPrice = &price
+	}
+	return summaries, nil
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Constants defining the notification types
+const (
+	NotificationTypeOrganisationRoleChanged = "organisation_role_changed"
+	NotificationTypeOrganisationUserRemoved = "organisation_user_removed"
+	NotificationTypeOfferingCreated         = "offering_created"
+	NotificationTypeOfferingUpdated         = "offering_updated"
+	NotificationTypeOfferingDeleted         = "offering_deleted"
+	NotificationTypeQuestionAsked           = "offering_question_asked"
+	NotificationTypeQuestionAnswered        = "offering_question_answered"
+	NotificationTypeKYCReviewed             = "kyc_reviewed"
+	NotificationTypeDistributionPaid        = "distribution_paid"
+	NotificationTypeTradeSettled            = "market_trade_settled"
+	NotificationTypeBankAccountChanged      = "bank_account_changed"
+	NotificationTypeSignatureRequested      = "signature_requested"
+	NotificationTypeSignatureCompleted      = "signature_completed"
+)
+
+// NotificationTypes lists all supported notification types
+var NotificationTypes = []string{
+	NotificationTypeOrganisationRoleChanged,
+	NotificationTypeOrganisationUserRemoved,
+	NotificationTypeOfferingCreated,
+	NotificationTypeOfferingUpdated,
+	NotificationTypeOfferingDeleted,
+	NotificationTypeQuestionAsked,
+	NotificationTypeQuestionAnswered,
+	NotificationTypeKYCReviewed,
+	NotificationTypeDistributionPaid,
+	NotificationTypeTradeSettled,
+	NotificationTypeBankAccountChanged,
+	NotificationTypeSignatureRequested,
+	NotificationTypeSignatureCompleted,
+}
+
+// Notification is a struct to represent an in-app user notification
+type Notification struct {
+	ID        string         `json:"id" gorm:"column:id;primary_key"`
+	UserID    string         `json:"user_id" gorm:"column:user_id"`
+	Type      string         `json:"type" gorm:"column:type"`
+	Title     string         `json:"title" gorm:"column:title"`
+	Message   string         `json:"message" gorm:"column:message"`
+	Data      postgres.Jsonb `json:"data" gorm:"column:data"`
+	ReadAt    *time.Time     `json:"read_at" gorm:"column:read_at"`
+	CreatedAt time.Time      `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*Notification) TableName() string {
+	return "notification"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Notification) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new notification into db
+func (notification *Notification) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	notification.ID = ""
+
+	db := cigExchange.GetDB().Create(notification)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create notification failed", db.Error)
+	}
+	return nil
+}
+
+// GetNotifications queries user notifications from db, newest first
+func GetNotifications(userID string, unreadOnly bool, limit, offset int) ([]*Notification, *cigExchange.APIError) {
+
+	notifications := make([]*Notification, 0)
+	db := cigExchange.GetDB().Where(&Notification{UserID: userID})
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+	db = db.Order("created_at desc").Limit(limit).Offset(offset).Find(&notifications)
+	if db.Error != nil {
+		return notifications, cigExchange.NewDatabaseError("Fetch notifications failed", db.Error)
+	}
+	return notifications, nil
+}
+
+// GetUnreadNotificationsCount returns amount of unread user notifications
+func GetUnreadNotificationsCount(userID string) (int64, *cigExchange.APIError) {
+
+	count := int64(0)
+	db := cigExchange.GetDB().Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count)
+	if db.Error != nil {
+		return 0, cigExchange.NewDatabaseError("Count notifications failed", db.Error)
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks single user notification as read
+func MarkNotificationRead(userID, notificationID string) *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Model(&Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, now())"))
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update notification failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		return cigExchange.NewInvalidFieldError("notification_id", "Notification with provided id doesn't exist")
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks all user notifications as read
+func MarkAllNotificationsRead(userID string) *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update notifications failed", db.Error)
+	}
+	return nil
+}
+
+// NotificationPreference is a struct to represent user channels for a notification type
+type NotificationPreference struct {
+	UserID string `json:"-" gorm:"column:user_id;primary_key"`
+	Type   string `json:"type" gorm:"column:type;primary_key"`
+	InApp  bool   `json:"in_app" gorm:"column:in_app"`
+	Email  bool   `json:"email" gorm:"column:email"`
+}
+
+// TableName returns table name for struct
+func (*NotificationPreference) TableName() string {
+	return "notification_preference"
+}
+
+// Save inserts or updates notification preference in db
+func (preference *NotificationPreference) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(preference)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save notification preference failed", db.Error)
+	}
+	return nil
+}
+
+// GetNotificationPreferences returns preferences for all notification types.
+// Types without saved preference use defaults: in-app on, email off
+func GetNotificationPreferences(userID string) ([]*NotificationPreference, *cigExchange.APIError) {
+
+	saved := make([]*NotificationPreference, 0)
+	db := cigExchange.GetDB().Where(&NotificationPreference{UserID: userID}).Find(&saved)
+	if db.Error != nil {
+		return saved, cigExchange.NewDatabaseError("Fetch notification preferences failed", db.Error)
+	}
+
+	savedMap := make(map[string]*NotificationPreference)
+	for _, preference := range saved {
+		savedMap[preference.Type] = preference
+	}
+
+	preferences := make([]*NotificationPreference, 0, len(NotificationTypes))
+	for _, notificationType := range NotificationTypes {
+		preference, ok := savedMap[notificationType]
+		if !ok {
+			preference = &NotificationPreference{UserID: userID, Type: notificationType, InApp: true, Email: false}
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, nil
+}
+
+// GetNotificationPreference returns preference for a single notification type
+func GetNotificationPreference(userID, notificationType string) (*NotificationPreference, *cigExchange.APIError) {
+
+	preferences, apiError := GetNotificationPreferences(userID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	for _, preference := range preferences {
+		if preference.Type == notificationType {
+			return preference, nil
+		}
+	}
+	return &NotificationPreference{UserID: userID, Type: notificationType, InApp: true, Email: false}, nil
+}
+
+// IsValidNotificationType checks notification type
+func IsValidNotificationType(notificationType string) bool {
+
+	for _, t := range NotificationTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
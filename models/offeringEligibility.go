@@ -0,0 +1,139 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Constants defining why investor is not eligible for an offering
+const (
+	EligibilityReasonProfileNotVerified = "profile_not_verified"
+	EligibilityReasonInvestorCategory   = "investor_category"
+	EligibilityReasonCountry            = "country"
+	EligibilityReasonKYCLevel           = "kyc_level"
+)
+
+// OfferingEligibility is a struct to represent investor restrictions of an offering, empty lists don't restrict
+type OfferingEligibility struct {
+	OfferingID         string         `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	InvestorCategories pq.StringArray `json:"investor_categories" gorm:"column:investor_categories"`
+	AllowedCountries   pq.StringArray `json:"allowed_countries" gorm:"column:allowed_countries"`
+	BlockedCountries   pq.StringArray `json:"blocked_countries" gorm:"column:blocked_countries"`
+	MinKYCLevel        int            `json:"min_kyc_level" gorm:"column:min_kyc_level"`
+	CreatedAt          time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt          time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*OfferingEligibility) TableName() string {
+	return "offering_eligibility"
+}
+
+// Save inserts or updates offering eligibility rules in db
+func (eligibility *OfferingEligibility) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(eligibility)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save offering eligibility failed", db.Error)
+	}
+	return nil
+}
+
+// IsRestricted checks if the offering has any eligibility rules
+func (eligibility *OfferingEligibility) IsRestricted() bool {
+
+	return len(eligibility.InvestorCategories) > 0 ||
+		len(eligibility.AllowedCountries) > 0 ||
+		len(eligibility.BlockedCountries) > 0 ||
+		eligibility.MinKYCLevel > KYCLevelNone
+}
+
+// IsCountryBlocked checks residence country against country rules
+func (eligibility *OfferingEligibility) IsCountryBlocked(country string) bool {
+
+	if containsString(eligibility.BlockedCountries, country) {
+		return true
+	}
+	return len(eligibility.AllowedCountries) > 0 && !containsString(eligibility.AllowedCountries, country)
+}
+
+// Evaluate checks investor profile against the rules and returns reasons why investor is not eligible.
+// Profile data is trusted only after approval, so restricted offerings require verified profile
+func (eligibility *OfferingEligibility) Evaluate(profile *InvestorProfile) []string {
+
+	reasons := make([]string, 0)
+	if !eligibility.IsRestricted() {
+		return reasons
+	}
+	if profile.Status != KYCStatusApproved {
+		return append(reasons, EligibilityReasonProfileNotVerified)
+	}
+	if len(eligibility.InvestorCategories) > 0 && !containsString(eligibility.InvestorCategories, profile.Classification) {
+		reasons = append(reasons, EligibilityReasonInvestorCategory)
+	}
+	if eligibility.IsCountryBlocked(profile.ResidenceCountry) {
+		reasons = append(reasons, EligibilityReasonCountry)
+	}
+	if profile.KYCLevel < eligibility.MinKYCLevel {
+		reasons = append(reasons, EligibilityReasonKYCLevel)
+	}
+	return reasons
+}
+
+// GetOfferingEligibility queries offering rules from db, unrestricted rules are returned if offering has none
+func GetOfferingEligibility(offeringID string) (*OfferingEligibility, *cigExchange.APIError) {
+
+	eligibility := &OfferingEligibility{}
+	db := cigExchange.GetDB().Where(&OfferingEligibility{OfferingID: offeringID}).First(eligibility)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return newOfferingEligibility(offeringID), nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch offering eligibility failed", db.Error)
+	}
+	return eligibility, nil
+}
+
+// GetOfferingsEligibility returns rules for all offerings with the ids
+func GetOfferingsEligibility(offeringIDs []string) (map[string]*OfferingEligibility, *cigExchange.APIError) {
+
+	result := make(map[string]*OfferingEligibility)
+	for _, offeringID := range offeringIDs {
+		result[offeringID] = newOfferingEligibility(offeringID)
+	}
+	if len(offeringIDs) == 0 {
+		return result, nil
+	}
+
+	rows := make([]*OfferingEligibility, 0)
+	db := cigExchange.GetDB().Where("offering_id IN (?)", offeringIDs).Find(&rows)
+	if db.Error != nil {
+		return result, cigExchange.NewDatabaseError("Fetch offering eligibility failed", db.Error)
+	}
+	for _, row := range rows {
+		result[row.OfferingID] = row
+	}
+	return result, nil
+}
+
+func newOfferingEligibility(offeringID string) *OfferingEligibility {
+
+	return &OfferingEligibility{
+		OfferingID:         offeringID,
+		InvestorCategories: pq.StringArray{},
+		AllowedCountries:   pq.StringArray{},
+		BlockedCountries:   pq.StringArray{},
+	}
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
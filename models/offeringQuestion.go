@@ -0,0 +1,248 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining the offering question moderation status
+const (
+	OfferingQuestionStatusVisible = "visible"
+	OfferingQuestionStatusHidden  = "hidden"
+)
+
+// OfferingQuestion is a struct to represent investor question thread on an offering
+type OfferingQuestion struct {
+	ID             string                   `json:"id" gorm:"column:id;primary_key"`
+	OfferingID     string                   `json:"offering_id" gorm:"column:offering_id"`
+	OrganisationID string                   `json:"organisation_id" gorm:"column:organisation_id"`
+	UserID         string                   `json:"user_id" gorm:"column:user_id"`
+	Question       string                   `json:"question" gorm:"column:question"`
+	IsPublic       bool                     `json:"is_public" gorm:"column:is_public"`
+	Status         string                   `json:"status" gorm:"column:status"`
+	AnsweredAt     *time.Time               `json:"answered_at" gorm:"column:answered_at"`
+	Replies        []*OfferingQuestionReply `json:"replies" gorm:"foreignkey:QuestionID"`
+	CreatedAt      time.Time                `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time                `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*OfferingQuestion) TableName() string {
+	return "offering_question"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*OfferingQuestion) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new offering question into db
+func (question *OfferingQuestion) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	question.ID = ""
+	question.Status = OfferingQuestionStatusVisible
+	question.AnsweredAt = nil
+	question.Replies = nil
+
+	db := cigExchange.GetDB().Create(question)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create offering question failed", db.Error)
+	}
+	return nil
+}
+
+// Update saves moderation fields of offering question
+func (question *OfferingQuestion) Update() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Model(question).Updates(map[string]interface{}{
+		"is_public": question.IsPublic,
+		"status":    question.Status,
+	})
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update offering question failed", db.Error)
+	}
+	return nil
+}
+
+// Delete removes offering question with all replies
+func (question *OfferingQuestion) Delete() *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Where(&OfferingQuestionReply{QuestionID: question.ID}).Delete(&OfferingQuestionReply{})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete offering question replies failed", db.Error)
+	}
+	db = tx.Delete(question)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete offering question failed", db.Error)
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete offering question failed", db.Error)
+	}
+	return nil
+}
+
+// preloadReplies loads thread replies in chronological order
+func preloadReplies(db *gorm.DB) *gorm.DB {
+
+	return db.Preload("Replies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	})
+}
+
+// GetOfferingQuestion queries a single question thread from db
+func GetOfferingQuestion(questionID string) (*OfferingQuestion, *cigExchange.APIError) {
+
+	question := &OfferingQuestion{}
+	db := preloadReplies(cigExchange.GetDB()).Where(&OfferingQuestion{ID: questionID}).First(question)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("question_id", "Question with provided id doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch offering question failed", db.Error)
+	}
+	return question, nil
+}
+
+// GetOfferingQuestions returns all question threads of the offering, newest first
+func GetOfferingQuestions(offeringID string) ([]*OfferingQuestion, *cigExchange.APIError) {
+
+	questions := make([]*OfferingQuestion, 0)
+	db := preloadReplies(cigExchange.GetDB()).Where(&OfferingQuestion{OfferingID: offeringID}).Order("created_at desc").Find(&questions)
+	if db.Error != nil {
+		return questions, cigExchange.NewDatabaseError("Fetch offering questions failed", db.Error)
+	}
+	return questions, nil
+}
+
+// GetPublicOfferingQuestions returns answered public question threads which are not hidden by moderators
+func GetPublicOfferingQuestions(offeringID string) ([]*OfferingQuestion, *cigExchange.APIError) {
+
+	questions := make([]*OfferingQuestion, 0)
+	db := preloadReplies(cigExchange.GetDB()).
+		Where("offering_id = ? AND is_public = ? AND status = ? AND answered_at IS NOT NULL", offeringID, true, OfferingQuestionStatusVisible).
+		Order("answered_at desc").Find(&questions)
+	if db.Error != nil {
+		return questions, cigExchange.NewDatabaseError("Fetch offering questions failed", db.Error)
+	}
+	return questions, nil
+}
+
+// GetUserOfferingQuestions returns all question threads started by the user, newest first
+func GetUserOfferingQuestions(userID string) ([]*OfferingQuestion, *cigExchange.APIError) {
+
+	questions := make([]*OfferingQuestion, 0)
+	db := preloadReplies(cigExchange.GetDB()).Where(&OfferingQuestion{UserID: userID}).Order("created_at desc").Find(&questions)
+	if db.Error != nil {
+		return questions, cigExchange.NewDatabaseError("Fetch offering questions failed", db.Error)
+	}
+	return questions, nil
+}
+
+// OfferingQuestionReply is a struct to represent a message in question thread
+type OfferingQuestionReply struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	QuestionID     string    `json:"question_id" gorm:"column:question_id"`
+	UserID         string    `json:"user_id" gorm:"column:user_id"`
+	IsOrganisation bool      `json:"is_organisation" gorm:"column:is_organisation"`
+	Message        string    `json:"message" gorm:"column:message"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*OfferingQuestionReply) TableName() string {
+	return "offering_question_reply"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*OfferingQuestionReply) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new reply into db, organisation replies mark the question answered
+func (reply *OfferingQuestionReply) Create(question *OfferingQuestion) *cigExchange.APIError {
+
+	// invalidate the uuid
+	reply.ID = ""
+	reply.QuestionID = question.ID
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Create(reply)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create question reply failed", db.Error)
+	}
+
+	if reply.IsOrganisation && question.AnsweredAt == nil {
+		now := time.Now()
+		db = tx.Model(question).Update("answered_at", now)
+		if db.Error != nil {
+			tx.Rollback()
+			return cigExchange.NewDatabaseError("Update offering question failed", db.Error)
+		}
+		question.AnsweredAt = &now
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create question reply failed", db.Error)
+	}
+	question.Replies = append(question.Replies, reply)
+	return nil
+}
+
+// DeleteReply removes reply from question thread, question stays answered only while organisation replies remain
+func (question *OfferingQuestion) DeleteReply(replyID string) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Where(&OfferingQuestionReply{ID: replyID, QuestionID: question.ID}).Delete(&OfferingQuestionReply{})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Delete question reply failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("reply_id", "Reply with provided id doesn't exist")
+	}
+
+	// first remaining organisation reply defines the answer time
+	answer := &OfferingQuestionReply{}
+	db = tx.Where("question_id = ? AND is_organisation = ?", question.ID, true).Order("created_at asc").First(answer)
+	if db.Error != nil && !db.RecordNotFound() {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Fetch question replies failed", db.Error)
+	}
+	question.AnsweredAt = nil
+	if !db.RecordNotFound() {
+		question.AnsweredAt = &answer.CreatedAt
+	}
+
+	db = tx.Model(question).Update("answered_at", question.AnsweredAt)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update offering question failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete question reply failed", db.Error)
+	}
+
+	replies := make([]*OfferingQuestionReply, 0)
+	for _, reply := range question.Replies {
+		if reply.ID != replyID {
+			replies = append(replies, reply)
+		}
+	}
+	question.Replies = replies
+	return nil
+}
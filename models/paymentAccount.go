@@ -0,0 +1,129 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// PaymentAccount is a struct to represent the bank account receiving subscription payments of organisation offerings.
+// Organisations without own account receive payments on the platform escrow account
+type PaymentAccount struct {
+	OrganisationID string    `json:"organisation_id" gorm:"column:organisation_id;primary_key"`
+	IBAN           string    `json:"iban" gorm:"column:iban"`
+	Name           string    `json:"name" gorm:"column:name"`
+	Street         string    `json:"street" gorm:"column:street"`
+	BuildingNumber string    `json:"building_number" gorm:"column:building_number"`
+	PostalCode     string    `json:"postal_code" gorm:"column:postal_code"`
+	Town           string    `json:"town" gorm:"column:town"`
+	Country        string    `json:"country" gorm:"column:country"`
+	BIC            string    `json:"-" gorm:"-"` // escrow account BIC used by payout orders
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*PaymentAccount) TableName() string {
+	return "organisation_payment_account"
+}
+
+// Validate normalizes and validates account holder address and IBAN
+func (account *PaymentAccount) Validate() *cigExchange.APIError {
+
+	account.IBAN = NormalizeIBAN(account.IBAN)
+	account.Name = strings.TrimSpace(account.Name)
+	account.Town = strings.TrimSpace(account.Town)
+	account.Country = strings.ToUpper(strings.TrimSpace(account.Country))
+
+	missingFields := make([]string, 0)
+	if len(account.IBAN) == 0 {
+		missingFields = append(missingFields, "iban")
+	}
+	if len(account.Name) == 0 {
+		missingFields = append(missingFields, "name")
+	}
+	if len(account.Town) == 0 {
+		missingFields = append(missingFields, "town")
+	}
+	if len(account.Country) == 0 {
+		missingFields = append(missingFields, "country")
+	}
+	if len(missingFields) > 0 {
+		return cigExchange.NewRequiredFieldError(missingFields)
+	}
+
+	if !IsValidIBAN(account.IBAN) {
+		return cigExchange.NewInvalidFieldError("iban", "Invalid IBAN")
+	}
+	if !countryCodeRegexp.MatchString(account.Country) {
+		return cigExchange.NewInvalidFieldError("country", "Country must be ISO 3166 alpha-2 code")
+	}
+	return nil
+}
+
+// Save validates and inserts or updates organisation payment account
+func (account *PaymentAccount) Save() *cigExchange.APIError {
+
+	apiError := account.Validate()
+	if apiError != nil {
+		return apiError
+	}
+
+	db := cigExchange.GetDB().Save(account)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save payment account failed", db.Error)
+	}
+	return nil
+}
+
+// GetOrganisationPaymentAccount queries organisation payment account, nil is returned if there is none
+func GetOrganisationPaymentAccount(organisationID string) (*PaymentAccount, *cigExchange.APIError) {
+
+	account := &PaymentAccount{}
+	db := cigExchange.GetDB().Where(&PaymentAccount{OrganisationID: organisationID}).First(account)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch payment account failed", db.Error)
+	}
+	return account, nil
+}
+
+// GetEscrowPaymentAccount returns platform escrow account configured with ESCROW_* environment variables,
+// nil is returned if escrow IBAN is not configured
+func GetEscrowPaymentAccount() *PaymentAccount {
+
+	iban := os.Getenv("ESCROW_IBAN")
+	if len(iban) == 0 {
+		return nil
+	}
+	return &PaymentAccount{
+		IBAN:           NormalizeIBAN(iban),
+		Name:           os.Getenv("ESCROW_NAME"),
+		Street:         os.Getenv("ESCROW_STREET"),
+		BuildingNumber: os.Getenv("ESCROW_BUILDING_NUMBER"),
+		PostalCode:     os.Getenv("ESCROW_POSTAL_CODE"),
+		Town:           os.Getenv("ESCROW_TOWN"),
+		Country:        os.Getenv("ESCROW_COUNTRY"),
+		BIC:            os.Getenv("ESCROW_BIC"),
+	}
+}
+
+// GetOfferingPaymentAccount returns the account receiving subscription payments of the offering,
+// organisation account has priority over escrow account
+func GetOfferingPaymentAccount(offering *models.Offering) (*PaymentAccount, *cigExchange.APIError) {
+
+	account, apiError := GetOrganisationPaymentAccount(offering.OrganisationID)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if account == nil {
+		account = GetEscrowPaymentAccount()
+	}
+	if account == nil {
+		return nil, cigExchange.NewInvalidFieldError("offering_id", "Offering has no payment account")
+	}
+	return account, nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Constants defining structured payment reference types
+const (
+	ReferenceTypeSCOR = "SCOR" // ISO 11649 creditor reference
+	ReferenceTypeQRR  = "QRR"  // Swiss QR reference
+)
+
+// qrrCarryTable is the table of the recursive modulo 10 check digit algorithm
+var qrrCarryTable = []int{0, 9, 4, 6, 8, 2, 7, 1, 3, 5}
+
+// NormalizeReference removes spaces from structured payment reference and converts it to upper case
+func NormalizeReference(reference string) string {
+
+	return strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+}
+
+// mod97 calculates ISO 7064 MOD 97-10 remainder of alphanumeric string, letters are converted to numbers A=10..Z=35
+func mod97(value string) (int, bool) {
+
+	remainder := 0
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return 0, false
+		}
+	}
+	return remainder, true
+}
+
+// NewCreditorReference creates ISO 11649 creditor reference with check digits for the payload
+func NewCreditorReference(payload string) string {
+
+	payload = NormalizeReference(payload)
+	remainder, _ := mod97(payload + "RF00")
+	check := strconv.Itoa(98 - remainder)
+	if len(check) == 1 {
+		check = "0" + check
+	}
+	return "RF" + check + payload
+}
+
+// IsValidCreditorReference checks format and check digits of ISO 11649 creditor reference
+func IsValidCreditorReference(reference string) bool {
+
+	reference = NormalizeReference(reference)
+	if len(reference) < 5 || len(reference) > 25 || !strings.HasPrefix(reference, "RF") {
+		return false
+	}
+	remainder, ok := mod97(reference[4:] + reference[:4])
+	return ok && remainder == 1
+}
+
+// qrrCheckDigit calculates recursive modulo 10 check digit of digits
+func qrrCheckDigit(digits string) int {
+
+	carry := 0
+	for _, r := range digits {
+		carry = qrrCarryTable[(carry+int(r-'0'))%10]
+	}
+	return (10 - carry) % 10
+}
+
+// IsValidQRReference checks format and check digit of 27 digits Swiss QR reference
+func IsValidQRReference(reference string) bool {
+
+	reference = NormalizeReference(reference)
+	if len(reference) != 27 {
+		return false
+	}
+	for _, r := range reference {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return qrrCheckDigit(reference[:26]) == int(reference[26]-'0')
+}
+
+// ReferenceType detects type of structured payment reference, empty string is returned for unstructured references
+func ReferenceType(reference string) string {
+
+	switch {
+	case IsValidQRReference(reference):
+		return ReferenceTypeQRR
+	case IsValidCreditorReference(reference):
+		return ReferenceTypeSCOR
+	}
+	return ""
+}
+
+// randomDigits generates random numeric string of the length
+func randomDigits(length int) (string, error) {
+
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
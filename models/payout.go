@@ -0,0 +1,436 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-p2p-backend/iso20022"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Constants defining payout statuses
+const (
+	PayoutStatusPending   = "pending"
+	PayoutStatusBatched   = "batched"
+	PayoutStatusExecuted  = "executed"
+	PayoutStatusCancelled = "cancelled"
+)
+
+// Constants defining payout batch statuses
+const (
+	PayoutBatchStatusExported = "exported"
+	PayoutBatchStatusExecuted = "executed"
+	PayoutBatchStatusRejected = "rejected"
+)
+
+// Payout is a struct to represent investor cash paid out by bank transfer.
+// Cash is withdrawn from the investor cash account when the payout is requested,
+// bank details are copied from the investor bank account so later account changes don't affect it
+type Payout struct {
+	ID              string    `json:"id" gorm:"column:id;primary_key"`
+	UserID          string    `json:"user_id" gorm:"column:user_id"`
+	Currency        string    `json:"currency" gorm:"column:currency"`
+	Amount          Decimal   `json:"amount" gorm:"column:amount"`
+	BankAccountID   string    `json:"bank_account_id" gorm:"column:bank_account_id"`
+	CreditorName    string    `json:"creditor_name" gorm:"column:creditor_name"`
+	IBAN            string    `json:"iban" gorm:"column:iban"`
+	BIC             string    `json:"bic" gorm:"column:bic"`
+	Remittance      string    `json:"remittance" gorm:"column:remittance"`
+	ExecutionDate   time.Time `json:"execution_date" gorm:"column:execution_date"`
+	Status          string    `json:"status" gorm:"column:status"`
+	BatchID         string    `json:"batch_id" gorm:"column:batch_id"`
+	EndToEndID      string    `json:"end_to_end_id" gorm:"column:end_to_end_id"`
+	JournalEntryID  string    `json:"journal_entry_id" gorm:"column:journal_entry_id"`
+	ValidationError string    `json:"validation_error" gorm:"column:validation_error"`
+	CreatedBy       string    `json:"created_by" gorm:"column:created_by"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Payout) TableName() string {
+	return "payout"
+}
+
+// PayoutBatch is a struct to represent exported pain.001 payment order
+type PayoutBatch struct {
+	ID           string     `json:"id" gorm:"column:id;primary_key"`
+	MessageID    string     `json:"message_id" gorm:"column:message_id"`
+	Status       string     `json:"status" gorm:"column:status"`
+	Transactions int        `json:"transactions" gorm:"column:transactions"`
+	Document     string     `json:"-" gorm:"column:document"`
+	Note         string     `json:"note" gorm:"column:note"`
+	CreatedBy    string     `json:"created_by" gorm:"column:created_by"`
+	ProcessedBy  string     `json:"processed_by" gorm:"column:processed_by"`
+	ProcessedAt  *time.Time `json:"processed_at" gorm:"column:processed_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	Payouts      []*Payout  `json:"payouts,omitempty" gorm:"foreignkey:BatchID"`
+}
+
+// TableName returns table name for struct
+func (*PayoutBatch) TableName() string {
+	return "payout_batch"
+}
+
+// today returns start of the current day
+func today() time.Time {
+
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// validateBankDetails normalizes and validates creditor bank account of the payout
+func (payout *Payout) validateBankDetails() *cigExchange.APIError {
+
+	payout.IBAN = NormalizeIBAN(payout.IBAN)
+	payout.BIC = NormalizeIBAN(payout.BIC)
+	payout.CreditorName = strings.TrimSpace(payout.CreditorName)
+
+	if len(payout.CreditorName) == 0 {
+		return cigExchange.NewRequiredFieldError([]string{"creditor_name"})
+	}
+	if !IsValidIBAN(payout.IBAN) {
+		return cigExchange.NewInvalidFieldError("iban", "Invalid IBAN")
+	}
+	if len(payout.BIC) > 0 {
+		return validateBIC(payout.BIC)
+	}
+	return nil
+}
+
+// Create validates payout, inserts it and withdraws the amount from investor cash account in one transaction
+func (payout *Payout) Create() *cigExchange.APIError {
+
+	places := CurrencyDecimalPlaces(payout.Currency)
+	if payout.Amount.Sign() <= 0 || payout.Amount.Round(places).Cmp(payout.Amount) != 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount must be a positive "+payout.Currency+" amount")
+	}
+	apiError := payout.validateBankDetails()
+	if apiError != nil {
+		return apiError
+	}
+	if payout.ExecutionDate.IsZero() {
+		payout.ExecutionDate = today()
+	}
+	if payout.ExecutionDate.Before(today()) {
+		return cigExchange.NewInvalidFieldError("execution_date", "Execution date can't be in the past")
+	}
+	payout.Remittance = strings.TrimSpace(payout.Remittance)
+	if len([]rune(payout.Remittance)) > 140 {
+		return cigExchange.NewInvalidFieldError("remittance", "Remittance information must have at most 140 characters")
+	}
+
+	// end to end id is limited to 35 characters
+	payout.ID = cigExchange.RandomUUID()
+	payout.EndToEndID = strings.Replace(payout.ID, "-", "", -1)
+	payout.Status = PayoutStatusPending
+
+	tx := cigExchange.GetDB().Begin()
+
+	withdrawal := &JournalEntry{
+		IdempotencyKey: "payout:" + payout.ID,
+		Currency:       payout.Currency,
+		Description:    "Payout to " + payout.IBAN,
+		EffectiveDate:  time.Now(),
+		CreatedBy:      payout.CreatedBy,
+	}
+	apiError = withdraw(tx, withdrawal, payout.UserID, payout.Amount)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	payout.JournalEntryID = withdrawal.ID
+	db := tx.Create(payout)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create payout failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create payout failed", db.Error)
+	}
+	return nil
+}
+
+// Cancel cancels pending payout and deposits the amount back to investor cash account in one transaction
+func (payout *Payout) Cancel(cancelledBy string) *cigExchange.APIError {
+
+	if payout.Status != PayoutStatusPending {
+		return cigExchange.NewInvalidFieldError("payout_id", "Only pending payouts can be cancelled")
+	}
+
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Model(payout).Where("status = ?", PayoutStatusPending).Update("status", PayoutStatusCancelled)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Cancel payout failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("payout_id", "Only pending payouts can be cancelled")
+	}
+
+	reversal := &JournalEntry{
+		IdempotencyKey: "payout:" + payout.ID + ":reversal",
+		Currency:       payout.Currency,
+		Description:    "Cancelled payout to " + payout.IBAN,
+		EffectiveDate:  time.Now(),
+		CreatedBy:      cancelledBy,
+	}
+	apiError := deposit(tx, reversal, payout.UserID, payout.Amount)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Cancel payout failed", db.Error)
+	}
+	return nil
+}
+
+// CreatePayoutBatch exports pending payouts due until the date as pain.001 payment order debiting the account.
+// Payouts with invalid bank details stay pending with validation error and are returned separately.
+// Transfers are grouped by execution date and currency, past execution dates are moved to today
+func CreatePayoutBatch(until time.Time, debtor *PaymentAccount, createdBy string) (*PayoutBatch, []*Payout, *cigExchange.APIError) {
+
+	invalid := make([]*Payout, 0)
+	tx := cigExchange.GetDB().Begin()
+
+	payouts := make([]*Payout, 0)
+	db := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("status = ? AND execution_date <= ?", PayoutStatusPending, until).
+		Order("execution_date asc, created_at asc").
+		Find(&payouts)
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, invalid, cigExchange.NewDatabaseError("Fetch payouts failed", db.Error)
+	}
+
+	valid := make([]*Payout, 0, len(payouts))
+	for _, payout := range payouts {
+		validationError := ""
+		if apiError := payout.validateBankDetails(); apiError != nil {
+			validationError = apiError.ToString()
+			invalid = append(invalid, payout)
+		} else {
+			valid = append(valid, payout)
+		}
+		if validationError == payout.ValidationError {
+			continue
+		}
+		payout.ValidationError = validationError
+		db = tx.Model(payout).Update("validation_error", validationError)
+		if db.Error != nil {
+			tx.Rollback()
+			return nil, invalid, cigExchange.NewDatabaseError("Update payout failed", db.Error)
+		}
+	}
+	if len(valid) == 0 {
+		db = tx.Commit()
+		if db.Error != nil {
+			return nil, invalid, cigExchange.NewDatabaseError("Update payouts failed", db.Error)
+		}
+		return nil, invalid, cigExchange.NewInvalidFieldError("until", "No valid pending payouts to export")
+	}
+
+	// message id is derived from batch id, so batch id is set before insert
+	batch := &PayoutBatch{
+		ID:           cigExchange.RandomUUID(),
+		Status:       PayoutBatchStatusExported,
+		Transactions: len(valid),
+		CreatedBy:    createdBy,
+	}
+	batch.MessageID = strings.Replace(batch.ID, "-", "", -1)
+
+	groups := make(map[string]*iso20022.PaymentGroup)
+	keys := make([]string, 0)
+	for _, payout := range valid {
+		executionDate := payout.ExecutionDate
+		if executionDate.Before(today()) {
+			executionDate = today()
+		}
+		// banks expect single currency payment groups
+		key := executionDate.Format("2006-01-02") + payout.Currency
+		group, ok := groups[key]
+		if !ok {
+			group = &iso20022.PaymentGroup{ExecutionDate: executionDate, Transfers: make([]*iso20022.CreditTransfer, 0)}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Transfers = append(group.Transfers, &iso20022.CreditTransfer{
+			EndToEndID: payout.EndToEndID,
+			Amount:     payout.Amount.StringFixed(CurrencyDecimalPlaces(payout.Currency)),
+			Currency:   payout.Currency,
+			Creditor:   iso20022.Party{Name: payout.CreditorName, IBAN: payout.IBAN, BIC: payout.BIC},
+			Remittance: payout.Remittance,
+		})
+	}
+	sort.Strings(keys)
+
+	order := &iso20022.PaymentOrder{
+		MessageID: batch.MessageID,
+		CreatedAt: time.Now(),
+		Debtor:    iso20022.Party{Name: debtor.Name, IBAN: debtor.IBAN, BIC: debtor.BIC},
+	}
+	for _, key := range keys {
+		order.Groups = append(order.Groups, groups[key])
+	}
+	document, err := order.Pain001()
+	if err != nil {
+		tx.Rollback()
+		return nil, invalid, cigExchange.NewInvalidFieldError("payouts", "Unable to create payment order: "+err.Error())
+	}
+	batch.Document = string(document)
+
+	db = tx.Set("gorm:save_associations", false).Create(batch)
+	if db.Error != nil {
+		tx.Rollback()
+		return nil, invalid, cigExchange.NewDatabaseError("Create payout batch failed", db.Error)
+	}
+
+	for _, payout := range valid {
+		payout.Status = PayoutStatusBatched
+		payout.BatchID = batch.ID
+		db = tx.Model(payout).Updates(map[string]interface{}{"status": payout.Status, "batch_id": payout.BatchID})
+		if db.Error != nil {
+			tx.Rollback()
+			return nil, invalid, cigExchange.NewDatabaseError("Update payout failed", db.Error)
+		}
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return nil, invalid, cigExchange.NewDatabaseError("Create payout batch failed", db.Error)
+	}
+	batch.Payouts = valid
+	return batch, invalid, nil
+}
+
+// process marks exported batch executed or rejected and updates its payouts in one transaction
+func (batch *PayoutBatch) process(status, payoutStatus, note, processedBy string) *cigExchange.APIError {
+
+	if batch.Status != PayoutBatchStatusExported {
+		return cigExchange.NewInvalidFieldError("batch_id", "Payout batch is already "+batch.Status)
+	}
+
+	now := time.Now()
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Model(batch).Where("status = ?", PayoutBatchStatusExported).Updates(map[string]interface{}{
+		"status":       status,
+		"note":         note,
+		"processed_by": processedBy,
+		"processed_at": now,
+	})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update payout batch failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("batch_id", "Payout batch was already processed")
+	}
+
+	db = tx.Model(&Payout{}).Where(&Payout{BatchID: batch.ID, Status: PayoutStatusBatched}).Update("status", payoutStatus)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update payouts failed", db.Error)
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update payout batch failed", db.Error)
+	}
+
+	batch.Status = status
+	batch.Note = note
+	batch.ProcessedBy = processedBy
+	batch.ProcessedAt = &now
+	return nil
+}
+
+// Execute marks batch executed by the bank, payouts of the batch are executed
+func (batch *PayoutBatch) Execute(note, processedBy string) *cigExchange.APIError {
+
+	return batch.process(PayoutBatchStatusExecuted, PayoutStatusExecuted, note, processedBy)
+}
+
+// Reject marks batch rejected by the bank, payouts of the batch return to pending and can be exported again or cancelled
+func (batch *PayoutBatch) Reject(note, processedBy string) *cigExchange.APIError {
+
+	return batch.process(PayoutBatchStatusRejected, PayoutStatusPending, note, processedBy)
+}
+
+// GetPayoutBatch queries payout batch with payouts
+func GetPayoutBatch(batchID string) (*PayoutBatch, *cigExchange.APIError) {
+
+	batch := &PayoutBatch{}
+	db := cigExchange.GetDB().Where(&PayoutBatch{ID: batchID}).First(batch)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("batch_id", "Payout batch doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch payout batch failed", db.Error)
+	}
+
+	// payouts of rejected batch are listed until they are exported again
+	batch.Payouts = make([]*Payout, 0)
+	db = cigExchange.GetDB().Where(&Payout{BatchID: batch.ID}).Order("execution_date asc, created_at asc").Find(&batch.Payouts)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch payouts failed", db.Error)
+	}
+	return batch, nil
+}
+
+// GetPayoutBatches queries payout batches without payouts, newest first
+func GetPayoutBatches() ([]*PayoutBatch, *cigExchange.APIError) {
+
+	batches := make([]*PayoutBatch, 0)
+	db := cigExchange.GetDB().Order("created_at desc").Find(&batches)
+	if db.Error != nil {
+		return batches, cigExchange.NewDatabaseError("Fetch payout batches failed", db.Error)
+	}
+	return batches, nil
+}
+
+// GetPayoutsByStatus queries payouts with the status, earliest execution date first
+func GetPayoutsByStatus(status string) ([]*Payout, *cigExchange.APIError) {
+
+	payouts := make([]*Payout, 0)
+	db := cigExchange.GetDB().Where(&Payout{Status: status}).Order("execution_date asc, created_at asc").Find(&payouts)
+	if db.Error != nil {
+		return payouts, cigExchange.NewDatabaseError("Fetch payouts failed", db.Error)
+	}
+	return payouts, nil
+}
+
+// GetUserPayout queries investor payout
+func GetUserPayout(userID, payoutID string) (*Payout, *cigExchange.APIError) {
+
+	payout := &Payout{}
+	db := cigExchange.GetDB().Where(&Payout{ID: payoutID, UserID: userID}).First(payout)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("payout_id", "Payout doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch payout failed", db.Error)
+	}
+	return payout, nil
+}
+
+// GetUserPayouts queries all investor payouts, newest first
+func GetUserPayouts(userID string) ([]*Payout, *cigExchange.APIError) {
+
+	payouts := make([]*Payout, 0)
+	db := cigExchange.GetDB().Where(&Payout{UserID: userID}).Order("created_at desc").Find(&payouts)
+	if db.Error != nil {
+		return payouts, cigExchange.NewDatabaseError("Fetch payouts failed", db.Error)
+	}
+	return payouts, nil
+}
@@ -0,0 +1,325 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Constants defining the saved search digest frequency
+const (
+	SavedSearchFrequencyDaily  = "daily"
+	SavedSearchFrequencyWeekly = "weekly"
+)
+
+// SavedSearchLanguages lists languages digest emails are available in
+var SavedSearchLanguages = i18n.SupportedLanguages
+
+// digestDueSlack lets digests go out on the scheduled run even if the previous run finished a bit later
+const digestDueSlack = time.Hour
+
+// SavedSearchCriteria is a struct to represent offering filter, empty fields match everything
+type SavedSearchCriteria struct {
+	Types       []string `json:"types"`
+	Query       string   `json:"query"`
+	MinAmount   *float64 `json:"min_amount"`
+	MaxAmount   *float64 `json:"max_amount"`
+	MinInterest *float64 `json:"min_interest"`
+	MaxInterest *float64 `json:"max_interest"`
+	MinPeriod   *float64 `json:"min_period"`
+	MaxPeriod   *float64 `json:"max_period"`
+}
+
+// SavedSearch is a struct to represent investor search with new offering alerts
+type SavedSearch struct {
+	ID               string         `json:"id" gorm:"column:id;primary_key"`
+	UserID           string         `json:"user_id" gorm:"column:user_id"`
+	Name             string         `json:"name" gorm:"column:name"`
+	Criteria         postgres.Jsonb `json:"criteria" gorm:"column:criteria"`
+	Frequency        string         `json:"frequency" gorm:"column:frequency"`
+	Language         string         `json:"language" gorm:"column:language"`
+	EmailEnabled     bool           `json:"email_enabled" gorm:"column:email_enabled"`
+	UnsubscribeToken string         `json:"-" gorm:"column:unsubscribe_token"`
+	LastDigestAt     *time.Time     `json:"last_digest_at" gorm:"column:last_digest_at"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*SavedSearch) TableName() string {
+	return "saved_search"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*SavedSearch) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new saved search into db
+func (search *SavedSearch) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	search.ID = ""
+
+	db := cigExchange.GetDB().Create(search)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create saved search failed", db.Error)
+	}
+	return nil
+}
+
+// Save updates saved search in db
+func (search *SavedSearch) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(search)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save saved search failed", db.Error)
+	}
+	return nil
+}
+
+// Delete removes saved search from db
+func (search *SavedSearch) Delete() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Delete(search)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete saved search failed", db.Error)
+	}
+	return nil
+}
+
+// GetCriteria decodes search criteria
+func (search *SavedSearch) GetCriteria() (*SavedSearchCriteria, error) {
+
+	criteria := &SavedSearchCriteria{}
+	if len(search.Criteria.RawMessage) == 0 {
+		return criteria, nil
+	}
+	err := json.Unmarshal(search.Criteria.RawMessage, criteria)
+	return criteria, err
+}
+
+// IsDigestDue checks if digest email should be sent at the given time
+func (search *SavedSearch) IsDigestDue(now time.Time) bool {
+
+	if !search.EmailEnabled {
+		return false
+	}
+	if search.LastDigestAt == nil {
+		return true
+	}
+
+	period := 24 * time.Hour
+	if search.Frequency == SavedSearchFrequencyWeekly {
+		period = 7 * 24 * time.Hour
+	}
+	return now.Sub(*search.LastDigestAt) >= period-digestDueSlack
+}
+
+// GetSavedSearch queries a single user saved search from db
+func GetSavedSearch(userID, searchID string) (*SavedSearch, *cigExchange.APIError) {
+
+	search := &SavedSearch{}
+	db := cigExchange.GetDB().Where(&SavedSearch{ID: searchID, UserID: userID}).First(search)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("search_id", "Saved search with provided id doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch saved search failed", db.Error)
+	}
+	return search, nil
+}
+
+// GetSavedSearches queries all user saved searches from db
+func GetSavedSearches(userID string) ([]*SavedSearch, *cigExchange.APIError) {
+
+	searches := make([]*SavedSearch, 0)
+	db := cigExchange.GetDB().Where(&SavedSearch{UserID: userID}).Order("created_at asc").Find(&searches)
+	if db.Error != nil {
+		return searches, cigExchange.NewDatabaseError("Fetch saved searches failed", db.Error)
+	}
+	return searches, nil
+}
+
+// GetSavedSearchesWithEmail returns all saved searches with enabled digest emails
+func GetSavedSearchesWithEmail() ([]*SavedSearch, *cigExchange.APIError) {
+
+	searches := make([]*SavedSearch, 0)
+	db := cigExchange.GetDB().Where("email_enabled = ?", true).Order("user_id asc, created_at asc").Find(&searches)
+	if db.Error != nil {
+		return searches, cigExchange.NewDatabaseError("Fetch saved searches failed", db.Error)
+	}
+	return searches, nil
+}
+
+// UnsubscribeSavedSearches disables digest emails of the search with the token,
+// all searches of the token owner are unsubscribed if 'all' is set
+func UnsubscribeSavedSearches(token string, all bool) *cigExchange.APIError {
+
+	search := &SavedSearch{}
+	db := cigExchange.GetDB().Where(&SavedSearch{UnsubscribeToken: token}).First(search)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return cigExchange.NewInvalidFieldError("token", "Unsubscribe link is invalid")
+		}
+		return cigExchange.NewDatabaseError("Fetch saved search failed", db.Error)
+	}
+
+	query := cigExchange.GetDB().Model(&SavedSearch{}).Where("id = ?", search.ID)
+	if all {
+		query = cigExchange.GetDB().Model(&SavedSearch{}).Where("user_id = ?", search.UserID)
+	}
+	db = query.Update("email_enabled", false)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Unsubscribe saved search failed", db.Error)
+	}
+	return nil
+}
+
+// IsValidSavedSearchLanguage checks digest email language
+func IsValidSavedSearchLanguage(language string) bool {
+
+	for _, l := range SavedSearchLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks ranges of search criteria
+func (criteria *SavedSearchCriteria) Validate() *cigExchange.APIError {
+
+	if criteria.MinAmount != nil && criteria.MaxAmount != nil && *criteria.MinAmount > *criteria.MaxAmount {
+		return cigExchange.NewInvalidFieldError("criteria", "min_amount can't be greater than max_amount")
+	}
+	if criteria.MinInterest != nil && criteria.MaxInterest != nil && *criteria.MinInterest > *criteria.MaxInterest {
+		return cigExchange.NewInvalidFieldError("criteria", "min_interest can't be greater than max_interest")
+	}
+	if criteria.MinPeriod != nil && criteria.MaxPeriod != nil && *criteria.MinPeriod > *criteria.MaxPeriod {
+		return cigExchange.NewInvalidFieldError("criteria", "min_period can't be greater than max_period")
+	}
+	return nil
+}
+
+// Matches checks offering against the criteria. Offering is passed as json map
+// so the same filter works for both db models and api responses
+func (criteria *SavedSearchCriteria) Matches(offering map[string]interface{}) bool {
+
+	if len(criteria.Types) > 0 {
+		offeringTypes, _ := offering["type"].([]interface{})
+		found := false
+		for _, offeringType := range offeringTypes {
+			for _, t := range criteria.Types {
+				if s, ok := offeringType.(string); ok && strings.EqualFold(s, t) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(criteria.Query) > 0 {
+		// title is a multilang map, any translation can match
+		titleBytes, _ := json.Marshal(offering["title"])
+		if !strings.Contains(strings.ToLower(string(titleBytes)), strings.ToLower(criteria.Query)) {
+			return false
+		}
+	}
+
+	return matchesRange(offering["amount"], criteria.MinAmount, criteria.MaxAmount) &&
+		matchesRange(offering["interest"], criteria.MinInterest, criteria.MaxInterest) &&
+		matchesRange(offering["period"], criteria.MinPeriod, criteria.MaxPeriod)
+}
+
+// matchesRange checks json number against optional bounds, missing values only match open ranges
+func matchesRange(value interface{}, min, max *float64) bool {
+
+	if min == nil && max == nil {
+		return true
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return false
+	}
+	if min != nil && number < *min {
+		return false
+	}
+	if max != nil && number > *max {
+		return false
+	}
+	return true
+}
+
+// OfferingVisibility is a struct to remember when offering was first seen on the trading platform
+type OfferingVisibility struct {
+	OfferingID     string    `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	FirstVisibleAt time.Time `json:"first_visible_at" gorm:"column:first_visible_at"`
+}
+
+// TableName returns table name for struct
+func (*OfferingVisibility) TableName() string {
+	return "offering_visibility"
+}
+
+// RecordOfferingsVisible stores current time for offerings seen visible for the first time
+func RecordOfferingsVisible(offeringIDs []string) *cigExchange.APIError {
+
+	now := time.Now()
+	for _, offeringID := range offeringIDs {
+		db := cigExchange.GetDB().Exec("INSERT INTO offering_visibility (offering_id, first_visible_at) VALUES (?, ?) ON CONFLICT DO NOTHING", offeringID, now)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Record offering visibility failed", db.Error)
+		}
+	}
+	return nil
+}
+
+// GetOfferingsFirstVisible returns first visibility time for all known offerings
+func GetOfferingsFirstVisible() (map[string]time.Time, *cigExchange.APIError) {
+
+	result := make(map[string]time.Time)
+	rows := make([]*OfferingVisibility, 0)
+	db := cigExchange.GetDB().Find(&rows)
+	if db.Error != nil {
+		return result, cigExchange.NewDatabaseError("Fetch offering visibility failed", db.Error)
+	}
+	for _, row := range rows {
+		result[row.OfferingID] = row.FirstVisibleAt
+	}
+	return result, nil
+}
+
+// IsOfferingListed checks if offering is shown on the trading platform
+func IsOfferingListed(offering *models.Offering) bool {
+
+	return offering.IsVisible && offering.Organisation.Status != models.OrganisationStatusUnverified
+}
+
+// RefreshOfferingsVisibility records all currently listed offerings and returns them
+func RefreshOfferingsVisibility() ([]*models.Offering, *cigExchange.APIError) {
+
+	listed := make([]*models.Offering, 0)
+	offerings, apiError := models.GetOfferings()
+	if apiError != nil {
+		return listed, apiError
+	}
+
+	offeringIDs := make([]string, 0)
+	for _, offering := range offerings {
+		if IsOfferingListed(offering) {
+			listed = append(listed, offering)
+			offeringIDs = append(offeringIDs, offering.ID)
+		}
+	}
+	return listed, RecordOfferingsVisible(offeringIDs)
+}
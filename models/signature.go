@@ -0,0 +1,675 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/documents"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MediaTypeSignedDocument is media type of signed documents with the signature certificate
+const MediaTypeSignedDocument = "signed-document"
+
+// Constants defining signature request status
+const (
+	SignatureStatusPending   = "pending"
+	SignatureStatusSigned    = "signed"
+	SignatureStatusDeclined  = "declined"
+	SignatureStatusExpired   = "expired"
+	SignatureStatusCancelled = "cancelled"
+)
+
+// Constants defining signed document types
+const (
+	SignatureDocumentSubscriptionAgreement = "subscription_agreement"
+	SignatureDocumentNDA                   = "nda"
+)
+
+// Constants defining signature audit trail events
+const (
+	SignatureEventCreated    = "created"
+	SignatureEventCodeSent   = "code_sent"
+	SignatureEventCodeFailed = "code_failed"
+	SignatureEventSigned     = "signed"
+	SignatureEventDeclined   = "declined"
+	SignatureEventReminded   = "reminded"
+	SignatureEventExpired    = "expired"
+	SignatureEventCancelled  = "cancelled"
+)
+
+// Constants defining signature request lifetime and confirmation
+const (
+	SignatureRequestExpiration = 14 * 24 * time.Hour
+	SignatureReminderInterval  = 3 * 24 * time.Hour
+	SignatureMaxReminders      = 3
+	SignatureCodeLength        = 6
+	SignatureCodeExpiration    = 10 * time.Minute
+	SignatureCodeMaxAttempts   = 5
+)
+
+// SignatureRequest is a struct to represent a document waiting for the signature of a user.
+// Document text is copied to the request, so the signed PDF reproduces exactly the document the signer has seen
+type SignatureRequest struct {
+	ID                string       `json:"id" gorm:"column:id;primary_key"`
+	OrganisationID    string       `json:"organisation_id" gorm:"column:organisation_id"`
+	UserID            string       `json:"user_id" gorm:"column:user_id"`
+	DocumentType      string       `json:"document_type" gorm:"column:document_type"`
+	DocumentID        string       `json:"document_id" gorm:"column:document_id"`
+	OfferingID        string       `json:"offering_id" gorm:"column:offering_id"`
+	Title             string       `json:"title" gorm:"column:title"`
+	Body              string       `json:"-" gorm:"column:body"`
+	Footer            string       `json:"-" gorm:"column:footer"`
+	DocumentCreatedAt time.Time    `json:"document_created_at" gorm:"column:document_created_at"`
+	DocumentHash      string       `json:"document_hash" gorm:"column:document_hash"`
+	Provider          string       `json:"provider" gorm:"column:provider"`
+	Status            string       `json:"status" gorm:"column:status"`
+	ExpiresAt         time.Time    `json:"expires_at" gorm:"column:expires_at"`
+	RemindersSent     int          `json:"reminders_sent" gorm:"column:reminders_sent"`
+	RemindedAt        *time.Time   `json:"reminded_at" gorm:"column:reminded_at"`
+	CodeHash          string       `json:"-" gorm:"column:code_hash"`
+	CodeAttempts      int          `json:"-" gorm:"column:code_attempts"`
+	CodeExpiresAt     *time.Time   `json:"-" gorm:"column:code_expires_at"`
+	SignerName        string       `json:"signer_name" gorm:"column:signer_name"`
+	SignerEmail       string       `json:"signer_email" gorm:"column:signer_email"`
+	SignerAddr        string       `json:"-" gorm:"column:signer_addr"`
+	SignedAt          *time.Time   `json:"signed_at" gorm:"column:signed_at"`
+	SignedMediaID     *string      `json:"signed_media_id" gorm:"column:signed_media_id"`
+	SignedMedia       models.Media `json:"-" gorm:"foreignkey:SignedMediaID;association_foreignkey:ID"`
+	SignedHash        string       `json:"signed_hash" gorm:"column:signed_hash"`
+	DeclineReason     string       `json:"decline_reason" gorm:"column:decline_reason"`
+	CreatedBy         string       `json:"created_by" gorm:"column:created_by"`
+	CreatedAt         time.Time    `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt         time.Time    `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*SignatureRequest) TableName() string {
+	return "signature_request"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*SignatureRequest) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// SignatureEvent is a struct to represent an audit trail entry of a signature request
+type SignatureEvent struct {
+	ID         string    `json:"id" gorm:"column:id;primary_key"`
+	RequestID  string    `json:"request_id" gorm:"column:request_id"`
+	Type       string    `json:"type" gorm:"column:type"`
+	Details    string    `json:"details" gorm:"column:details"`
+	RemoteAddr string    `json:"-" gorm:"column:remote_addr"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*SignatureEvent) TableName() string {
+	return "signature_event"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*SignatureEvent) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// newSignatureEvent prepares audit trail entry, timestamps are truncated to seconds as printed in the certificate
+func newSignatureEvent(requestID, eventType, details, remoteAddr string) *SignatureEvent {
+
+	return &SignatureEvent{
+		RequestID:  requestID,
+		Type:       eventType,
+		Details:    details,
+		RemoteAddr: remoteAddr,
+		CreatedAt:  time.Now().Truncate(time.Second),
+	}
+}
+
+// create inserts audit trail entry in the transaction
+func (event *SignatureEvent) create(tx *gorm.DB) *cigExchange.APIError {
+
+	db := tx.Create(event)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create signature event failed", db.Error)
+	}
+	return nil
+}
+
+// NewAgreementSignatureRequest prepares signature request of the generated subscription agreement
+func NewAgreementSignatureRequest(agreement *SubscriptionAgreement, createdBy string) *SignatureRequest {
+
+	return &SignatureRequest{
+		OrganisationID:    agreement.OrganisationID,
+		UserID:            agreement.UserID,
+		DocumentType:      SignatureDocumentSubscriptionAgreement,
+		DocumentID:        agreement.ID,
+		OfferingID:        agreement.OfferingID,
+		Title:             agreement.Title,
+		Body:              agreement.Body,
+		Footer:            agreement.Footer,
+		DocumentCreatedAt: agreement.CreatedAt,
+		DocumentHash:      agreement.Hash,
+		CreatedBy:         createdBy,
+	}
+}
+
+// NewNDASignatureRequest fills NDA template with the data and prepares signature request of the rendered document
+func NewNDASignatureRequest(agreementTemplate *AgreementTemplate, data *AgreementData, userID, offeringID, createdBy string) (*SignatureRequest, *cigExchange.APIError) {
+
+	if agreementTemplate.Kind != AgreementTemplateKindNDA {
+		return nil, cigExchange.NewInvalidFieldError("template_id", "Template is not an NDA template")
+	}
+
+	body, err := agreementTemplate.Render(data)
+	if err != nil {
+		return nil, cigExchange.NewInvalidFieldError("template_id", "Unable to fill NDA template: "+err.Error())
+	}
+
+	request := &SignatureRequest{
+		OrganisationID:    agreementTemplate.OrganisationID,
+		UserID:            userID,
+		DocumentType:      SignatureDocumentNDA,
+		DocumentID:        agreementTemplate.ID,
+		OfferingID:        offeringID,
+		Title:             agreementTemplate.Title,
+		Body:              body,
+		Footer:            data.Organisation + " - " + agreementTemplate.Title + " v" + strconv.Itoa(agreementTemplate.Version) + " - " + data.Investor.Name,
+		DocumentCreatedAt: time.Now().Truncate(time.Second),
+		CreatedBy:         createdBy,
+	}
+
+	document, err := request.Document().PDF()
+	if err != nil {
+		return nil, cigExchange.NewInvalidFieldError("template_id", "Unable to render NDA: "+err.Error())
+	}
+	hash := sha256.Sum256(document)
+	request.DocumentHash = hex.EncodeToString(hash[:])
+	return request, nil
+}
+
+// Document returns the unsigned document
+func (request *SignatureRequest) Document() *documents.Document {
+
+	return &documents.Document{
+		Title:     request.Title,
+		Body:      request.Body,
+		Footer:    request.Footer,
+		CreatedAt: request.DocumentCreatedAt,
+	}
+}
+
+// VerifyDocument renders the unsigned document and checks it still matches the document hash
+func (request *SignatureRequest) VerifyDocument() ([]byte, *cigExchange.APIError) {
+
+	document, err := request.Document().PDF()
+	if err != nil {
+		return nil, cigExchange.NewInvalidFieldError("request_id", "Unable to render document: "+err.Error())
+	}
+	hash := sha256.Sum256(document)
+	if hex.EncodeToString(hash[:]) != request.DocumentHash {
+		return nil, cigExchange.NewInvalidFieldError("request_id", "Document doesn't match its hash")
+	}
+	return document, nil
+}
+
+// SignedDocument returns the document with the signature certificate built from the audit trail
+func (request *SignatureRequest) SignedDocument(events []*SignatureEvent) *documents.Document {
+
+	document := request.Document()
+	certificate := &documents.Certificate{
+		Title: "Signature certificate",
+		Fields: []documents.CertificateField{
+			{Label: "Document", Value: request.Title},
+			{Label: "Document SHA-256", Value: request.DocumentHash},
+			{Label: "Signature request", Value: request.ID},
+			{Label: "Signer", Value: request.SignerName},
+			{Label: "Signer email", Value: request.SignerEmail},
+			{Label: "Signer IP address", Value: request.SignerAddr},
+			{Label: "Signature method", Value: request.Provider},
+		},
+		Events: make([]documents.CertificateEvent, 0, len(events)),
+	}
+	if request.SignedAt != nil {
+		certificate.Fields = append(certificate.Fields, documents.CertificateField{Label: "Signed at", Value: documents.FormatCertificateTime(*request.SignedAt)})
+	}
+	for _, event := range events {
+		description := strings.Replace(event.Type, "_", " ", -1)
+		if len(event.Details) > 0 {
+			description += ": " + event.Details
+		}
+		if len(event.RemoteAddr) > 0 {
+			description += " (" + event.RemoteAddr + ")"
+		}
+		certificate.Events = append(certificate.Events, documents.CertificateEvent{Time: event.CreatedAt, Description: description})
+	}
+	document.Certificate = certificate
+	// signed document is dated with the signature
+	if request.SignedAt != nil {
+		document.CreatedAt = *request.SignedAt
+	}
+	return document
+}
+
+// Create inserts pending request sent through the provider, a document can have only one pending or signed request
+func (request *SignatureRequest) Create(provider string) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+
+	count := 0
+	db := tx.Model(&SignatureRequest{}).
+		Where("user_id = ? AND document_type = ? AND document_id = ? AND status IN (?)",
+			request.UserID, request.DocumentType, request.DocumentID, []string{SignatureStatusPending, SignatureStatusSigned}).
+		Count(&count)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Fetch signature requests failed", db.Error)
+	}
+	if count > 0 {
+		tx.Rollback()
+		return cigExchange.NewInvalidFieldError("document_id", "Document already has a pending or signed signature request")
+	}
+
+	// invalidate the uuid
+	request.ID = ""
+	request.Provider = provider
+	request.Status = SignatureStatusPending
+	request.ExpiresAt = time.Now().Add(SignatureRequestExpiration)
+	db = tx.Set("gorm:save_associations", false).Create(request)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Create signature request failed", db.Error)
+	}
+
+	apiError := newSignatureEvent(request.ID, SignatureEventCreated, request.Title+" sent to the signer", "").create(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create signature request failed", db.Error)
+	}
+	return nil
+}
+
+// hashSignatureCode hashes confirmation code together with request id
+func hashSignatureCode(requestID, code string) string {
+
+	hash := sha256.Sum256([]byte(requestID + ":" + code))
+	return hex.EncodeToString(hash[:])
+}
+
+// Expired returns true if pending request is past its expiration
+func (request *SignatureRequest) Expired(now time.Time) bool {
+
+	return request.Status == SignatureStatusPending && !now.Before(request.ExpiresAt)
+}
+
+// ReminderDue returns true if pending request got no reminder or signer notification in the reminder interval
+func (request *SignatureRequest) ReminderDue(now time.Time) bool {
+
+	if request.Status != SignatureStatusPending || request.Expired(now) || request.RemindersSent >= SignatureMaxReminders {
+		return false
+	}
+	last := request.CreatedAt
+	if request.RemindedAt != nil {
+		last = *request.RemindedAt
+	}
+	return !last.After(now.Add(-SignatureReminderInterval))
+}
+
+// checkSignable returns an error if the request can't be signed or declined anymore
+func (request *SignatureRequest) checkSignable(now time.Time) *cigExchange.APIError {
+
+	if request.Status != SignatureStatusPending {
+		return cigExchange.NewInvalidFieldError("request_id", "Signature request is "+request.Status)
+	}
+	if request.Expired(now) {
+		return cigExchange.NewInvalidFieldError("request_id", "Signature request expired")
+	}
+	return nil
+}
+
+// issueCode generates a one time signature code replacing the previous one, only its hash is kept in the request
+func (request *SignatureRequest) issueCode(now time.Time) (string, *cigExchange.APIError) {
+
+	apiError := request.checkSignable(now)
+	if apiError != nil {
+		return "", apiError
+	}
+
+	code, err := randomDigits(SignatureCodeLength)
+	if err != nil {
+		return "", cigExchange.NewInvalidFieldError("code", "Unable to generate signature code")
+	}
+
+	expiresAt := now.Add(SignatureCodeExpiration)
+	request.CodeHash = hashSignatureCode(request.ID, code)
+	request.CodeAttempts = 0
+	request.CodeExpiresAt = &expiresAt
+	return code, nil
+}
+
+// verifyCode checks the signature code. Wrong code increases the attempts,
+// correct code is cleared from the request so it can be used only once
+func (request *SignatureRequest) verifyCode(code string, now time.Time) *cigExchange.APIError {
+
+	apiError := request.checkSignable(now)
+	if apiError != nil {
+		return apiError
+	}
+	if len(request.CodeHash) == 0 || request.CodeExpiresAt == nil || now.After(*request.CodeExpiresAt) {
+		return cigExchange.NewInvalidFieldError("code", "Signature code expired, request a new code")
+	}
+	if request.CodeAttempts >= SignatureCodeMaxAttempts {
+		return cigExchange.NewInvalidFieldError("code", "Too many wrong signature codes, request a new code")
+	}
+
+	hash := hashSignatureCode(request.ID, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(request.CodeHash)) != 1 {
+		request.CodeAttempts++
+		return cigExchange.NewInvalidFieldError("code", "Invalid signature code")
+	}
+	request.CodeHash = ""
+	return nil
+}
+
+// NewCode generates a one time signature code replacing the previous one, the code must be sent to the signer
+func (request *SignatureRequest) NewCode(remoteAddr string) (string, *cigExchange.APIError) {
+
+	code, apiError := request.issueCode(time.Now())
+	if apiError != nil {
+		return "", apiError
+	}
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Model(request).Updates(map[string]interface{}{"code_hash": request.CodeHash, "code_attempts": 0, "code_expires_at": request.CodeExpiresAt})
+	if db.Error != nil {
+		tx.Rollback()
+		return "", cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	apiError = newSignatureEvent(request.ID, SignatureEventCodeSent, "", remoteAddr).create(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return "", apiError
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return "", cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	return code, nil
+}
+
+// CheckCode verifies the signature code, wrong codes are counted and a correct code can be used only once
+func (request *SignatureRequest) CheckCode(code, remoteAddr string) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE").Where(&SignatureRequest{ID: request.ID}).First(request)
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Fetch signature request failed", db.Error)
+	}
+
+	attempts := request.CodeAttempts
+	apiError := request.verifyCode(code, time.Now())
+	if apiError != nil && request.CodeAttempts == attempts {
+		tx.Rollback()
+		return apiError
+	}
+	if apiError != nil {
+		// wrong code is counted
+		db = tx.Model(request).Update("code_attempts", request.CodeAttempts)
+		if db.Error != nil {
+			tx.Rollback()
+			return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+		}
+		eventError := newSignatureEvent(request.ID, SignatureEventCodeFailed, "", remoteAddr).create(tx)
+		if eventError != nil {
+			tx.Rollback()
+			return eventError
+		}
+		db = tx.Commit()
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+		}
+		return apiError
+	}
+
+	db = tx.Model(request).Update("code_hash", "")
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	return nil
+}
+
+// transition moves pending request to the final status in the transaction and records the event.
+// Conditional update fails if the request status was changed concurrently
+func (request *SignatureRequest) transition(tx *gorm.DB, status string, updates map[string]interface{}, event *SignatureEvent) *cigExchange.APIError {
+
+	updates["status"] = status
+	db := tx.Model(&SignatureRequest{}).Where("id = ? AND status = ?", request.ID, SignatureStatusPending).Updates(updates)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		return cigExchange.NewInvalidFieldError("request_id", "Signature request is no longer pending")
+	}
+	request.Status = status
+
+	return event.create(tx)
+}
+
+// MarkSigned stores signed document media and marks the request signed in the transaction.
+// Signer fields and SignedAt must be set, the file must be stored by the caller before commit
+func (request *SignatureRequest) MarkSigned(tx *gorm.DB, size int, signedHash string) *cigExchange.APIError {
+
+	// url stays empty as signed documents are served only to the signer and organisation members
+	request.SignedMedia = models.Media{
+		Type:          MediaTypeSignedDocument,
+		Title:         request.Title,
+		MimeType:      "application/pdf",
+		FileExtension: ".pdf",
+		FileSize:      size,
+	}
+	db := tx.Create(&request.SignedMedia)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create media failed", db.Error)
+	}
+	request.SignedMediaID = &request.SignedMedia.ID
+	request.SignedHash = signedHash
+
+	updates := map[string]interface{}{
+		"signer_name":     request.SignerName,
+		"signer_email":    request.SignerEmail,
+		"signer_addr":     request.SignerAddr,
+		"signed_at":       request.SignedAt,
+		"signed_media_id": request.SignedMediaID,
+		"signed_hash":     request.SignedHash,
+		"code_hash":       "",
+	}
+	return request.transition(tx, SignatureStatusSigned, updates, request.SignedEvent())
+}
+
+// SignedEvent returns the audit trail entry MarkSigned records, used to print the certificate before it's stored
+func (request *SignatureRequest) SignedEvent() *SignatureEvent {
+
+	event := newSignatureEvent(request.ID, SignatureEventSigned, "signed by "+request.SignerName, request.SignerAddr)
+	event.CreatedAt = *request.SignedAt
+	return event
+}
+
+// finish moves pending request to the final status in its own transaction
+func (request *SignatureRequest) finish(status string, updates map[string]interface{}, event *SignatureEvent) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	apiError := request.transition(tx, status, updates, event)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+	db := tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	return nil
+}
+
+// Decline marks the request declined by the signer
+func (request *SignatureRequest) Decline(reason, remoteAddr string) *cigExchange.APIError {
+
+	apiError := request.checkSignable(time.Now())
+	if apiError != nil {
+		return apiError
+	}
+
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > 1000 {
+		return cigExchange.NewInvalidFieldError("reason", "Reason must have at most 1000 characters")
+	}
+	request.DeclineReason = reason
+	return request.finish(SignatureStatusDeclined, map[string]interface{}{"decline_reason": reason, "code_hash": ""},
+		newSignatureEvent(request.ID, SignatureEventDeclined, reason, remoteAddr))
+}
+
+// Cancel withdraws pending request
+func (request *SignatureRequest) Cancel(userID, remoteAddr string) *cigExchange.APIError {
+
+	if request.Status != SignatureStatusPending {
+		return cigExchange.NewInvalidFieldError("request_id", "Signature request is "+request.Status)
+	}
+	return request.finish(SignatureStatusCancelled, map[string]interface{}{"code_hash": ""},
+		newSignatureEvent(request.ID, SignatureEventCancelled, "cancelled by "+userID, remoteAddr))
+}
+
+// Expire marks pending request past its expiration expired
+func (request *SignatureRequest) Expire() *cigExchange.APIError {
+
+	return request.finish(SignatureStatusExpired, map[string]interface{}{"code_hash": ""},
+		newSignatureEvent(request.ID, SignatureEventExpired, "", ""))
+}
+
+// MarkReminded counts reminder sent to the signer
+func (request *SignatureRequest) MarkReminded() *cigExchange.APIError {
+
+	now := time.Now()
+	request.RemindersSent++
+	request.RemindedAt = &now
+
+	tx := cigExchange.GetDB().Begin()
+	db := tx.Model(request).Updates(map[string]interface{}{"reminders_sent": request.RemindersSent, "reminded_at": request.RemindedAt})
+	if db.Error != nil {
+		tx.Rollback()
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	apiError := newSignatureEvent(request.ID, SignatureEventReminded, "reminder "+strconv.Itoa(request.RemindersSent), "").create(tx)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+	db = tx.Commit()
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Update signature request failed", db.Error)
+	}
+	return nil
+}
+
+// GetSignatureEvents queries audit trail of the request, oldest first
+func GetSignatureEvents(requestID string) ([]*SignatureEvent, *cigExchange.APIError) {
+
+	events := make([]*SignatureEvent, 0)
+	db := cigExchange.GetDB().Where(&SignatureEvent{RequestID: requestID}).Order("created_at asc").Find(&events)
+	if db.Error != nil {
+		return events, cigExchange.NewDatabaseError("Fetch signature events failed", db.Error)
+	}
+	return events, nil
+}
+
+// GetUserSignatureRequests queries requests the user has to sign, newest first. Empty status returns all requests
+func GetUserSignatureRequests(userID, status string) ([]*SignatureRequest, *cigExchange.APIError) {
+
+	requests := make([]*SignatureRequest, 0)
+	db := cigExchange.GetDB().Where(&SignatureRequest{UserID: userID, Status: status}).Order("created_at desc").Find(&requests)
+	if db.Error != nil {
+		return requests, cigExchange.NewDatabaseError("Fetch signature requests failed", db.Error)
+	}
+	return requests, nil
+}
+
+// GetUserSignatureRequest queries single request the user has to sign
+func GetUserSignatureRequest(userID, requestID string) (*SignatureRequest, *cigExchange.APIError) {
+
+	request := &SignatureRequest{}
+	db := cigExchange.GetDB().Preload("SignedMedia").Where(&SignatureRequest{ID: requestID, UserID: userID}).First(request)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("request_id", "Signature request doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch signature request failed", db.Error)
+	}
+	return request, nil
+}
+
+// GetPendingSignatureRequest queries pending request of the document, nil is returned if there is none
+func GetPendingSignatureRequest(documentType, documentID string) (*SignatureRequest, *cigExchange.APIError) {
+
+	request := &SignatureRequest{}
+	db := cigExchange.GetDB().Where(&SignatureRequest{DocumentType: documentType, DocumentID: documentID, Status: SignatureStatusPending}).First(request)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch signature request failed", db.Error)
+	}
+	return request, nil
+}
+
+// GetOrganisationSignatureRequests queries organisation requests, newest first. Empty status returns all requests
+func GetOrganisationSignatureRequests(organisationID, status string) ([]*SignatureRequest, *cigExchange.APIError) {
+
+	requests := make([]*SignatureRequest, 0)
+	db := cigExchange.GetDB().Where(&SignatureRequest{OrganisationID: organisationID, Status: status}).Order("created_at desc").Find(&requests)
+	if db.Error != nil {
+		return requests, cigExchange.NewDatabaseError("Fetch signature requests failed", db.Error)
+	}
+	return requests, nil
+}
+
+// GetOrganisationSignatureRequest queries single organisation request
+func GetOrganisationSignatureRequest(organisationID, requestID string) (*SignatureRequest, *cigExchange.APIError) {
+
+	request := &SignatureRequest{}
+	db := cigExchange.GetDB().Preload("SignedMedia").Where(&SignatureRequest{ID: requestID, OrganisationID: organisationID}).First(request)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("request_id", "Signature request doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch signature request failed", db.Error)
+	}
+	return request, nil
+}
+
+// GetPendingSignatureRequests queries all pending requests, the scheduled task checks their expiration and reminders
+func GetPendingSignatureRequests() ([]*SignatureRequest, *cigExchange.APIError) {
+
+	requests := make([]*SignatureRequest, 0)
+	db := cigExchange.GetDB().Where(&SignatureRequest{Status: SignatureStatusPending}).Order("created_at asc").Find(&requests)
+	if db.Error != nil {
+		return requests, cigExchange.NewDatabaseError("Fetch signature requests failed", db.Error)
+	}
+	return requests, nil
+}
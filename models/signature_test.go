@@ -0,0 +1,157 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func newPendingSignatureRequest(now time.Time) *SignatureRequest {
+
+	return &SignatureRequest{
+		ID:        "fdb283d4-7341-4517-b501-371d22d27cfc",
+		Status:    SignatureStatusPending,
+		ExpiresAt: now.Add(SignatureRequestExpiration),
+		CreatedAt: now,
+	}
+}
+
+func TestSignatureCode(t *testing.T) {
+
+	now := time.Now()
+	request := newPendingSignatureRequest(now)
+
+	code, apiError := request.issueCode(now)
+	if apiError != nil {
+		t.Fatalf("unexpected error: %v", apiError.ToString())
+	}
+	if len(code) != SignatureCodeLength || len(request.CodeHash) == 0 || request.CodeHash == code {
+		t.Fatalf("unexpected code %q with hash %q", code, request.CodeHash)
+	}
+
+	// wrong code is counted
+	if request.verifyCode("wrong", now) == nil || request.CodeAttempts != 1 {
+		t.Errorf("expected wrong code to fail, attempts %d", request.CodeAttempts)
+	}
+
+	// code is accepted once, surrounding spaces are ignored
+	if apiError = request.verifyCode(" "+code+" ", now); apiError != nil {
+		t.Errorf("expected code to be accepted: %v", apiError.ToString())
+	}
+	if len(request.CodeHash) != 0 {
+		t.Errorf("expected code hash to be cleared")
+	}
+	attempts := request.CodeAttempts
+	if request.verifyCode(code, now) == nil || request.CodeAttempts != attempts {
+		t.Errorf("expected used code to fail without counting an attempt")
+	}
+}
+
+func TestSignatureCodeExpired(t *testing.T) {
+
+	now := time.Now()
+	request := newPendingSignatureRequest(now)
+
+	code, apiError := request.issueCode(now)
+	if apiError != nil {
+		t.Fatalf("unexpected error: %v", apiError.ToString())
+	}
+	if request.verifyCode(code, now.Add(SignatureCodeExpiration+time.Second)) == nil {
+		t.Errorf("expected expired code to fail")
+	}
+
+	// new code replaces the previous one and resets the attempts
+	request.CodeAttempts = SignatureCodeMaxAttempts
+	previous := code
+	code, apiError = request.issueCode(now)
+	if apiError != nil {
+		t.Fatalf("unexpected error: %v", apiError.ToString())
+	}
+	if request.CodeAttempts != 0 {
+		t.Errorf("expected attempts to be reset, got %d", request.CodeAttempts)
+	}
+	if previous != code && request.verifyCode(previous, now) == nil {
+		t.Errorf("expected previous code to fail")
+	}
+}
+
+func TestSignatureCodeAttempts(t *testing.T) {
+
+	now := time.Now()
+	request := newPendingSignatureRequest(now)
+
+	code, apiError := request.issueCode(now)
+	if apiError != nil {
+		t.Fatalf("unexpected error: %v", apiError.ToString())
+	}
+	for i := 0; i < SignatureCodeMaxAttempts; i++ {
+		request.verifyCode("wrong", now)
+	}
+
+	// correct code is rejected after too many wrong codes
+	if request.verifyCode(code, now) == nil || request.CodeAttempts != SignatureCodeMaxAttempts {
+		t.Errorf("expected code to fail after %d attempts", request.CodeAttempts)
+	}
+}
+
+func TestSignatureRequestExpired(t *testing.T) {
+
+	now := time.Now()
+	request := newPendingSignatureRequest(now)
+
+	if request.Expired(request.ExpiresAt.Add(-time.Second)) {
+		t.Errorf("expected request to be pending before its expiration")
+	}
+	if !request.Expired(request.ExpiresAt) {
+		t.Errorf("expected request to expire at its expiration")
+	}
+
+	// expired request can't be signed or get a new code
+	later := request.ExpiresAt.Add(time.Hour)
+	if request.checkSignable(later) == nil {
+		t.Errorf("expected expired request to be unsignable")
+	}
+	if _, apiError := request.issueCode(later); apiError == nil {
+		t.Errorf("expected no code for expired request")
+	}
+
+	// final requests don't expire
+	request.Status = SignatureStatusSigned
+	if request.Expired(later) {
+		t.Errorf("expected signed request not to expire")
+	}
+}
+
+func TestSignatureRequestReminderDue(t *testing.T) {
+
+	now := time.Now()
+	request := newPendingSignatureRequest(now)
+
+	if request.ReminderDue(now.Add(SignatureReminderInterval - time.Second)) {
+		t.Errorf("expected no reminder before the reminder interval")
+	}
+	due := now.Add(SignatureReminderInterval)
+	if !request.ReminderDue(due) {
+		t.Errorf("expected reminder after the reminder interval")
+	}
+
+	// interval starts again with the last reminder
+	request.RemindersSent = 1
+	request.RemindedAt = &due
+	if request.ReminderDue(due.Add(time.Hour)) {
+		t.Errorf("expected no reminder right after the last one")
+	}
+	if !request.ReminderDue(due.Add(SignatureReminderInterval)) {
+		t.Errorf("expected reminder after the reminder interval")
+	}
+
+	request.RemindersSent = SignatureMaxReminders
+	if request.ReminderDue(due.Add(SignatureReminderInterval)) {
+		t.Errorf("expected no reminder over the limit")
+	}
+
+	// expired request gets no reminder
+	request.RemindersSent = 0
+	if request.ReminderDue(request.ExpiresAt) {
+		t.Errorf("expected no reminder for expired request")
+	}
+}
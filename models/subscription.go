@@ -0,0 +1,276 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/qrbill"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Constants defining subscription statuses
+const (
+	SubscriptionStatusPending   = "pending"
+	SubscriptionStatusPaid      = "paid"
+	SubscriptionStatusCancelled = "cancelled"
+)
+
+// referenceAttempts limits generation of unique payment references
+const referenceAttempts = 5
+
+// Subscription is a struct to represent investor commitment to an offering paid by bank transfer.
+// Reference is the structured payment reference used to match the bank credit, QR reference is
+// generated with the first QR-bill payable to QR-IBAN and matches the bank credit as well
+type Subscription struct {
+	ID                string     `json:"id" gorm:"column:id;primary_key"`
+	OfferingID        string     `json:"offering_id" gorm:"column:offering_id"`
+	UserID            string     `json:"user_id" gorm:"column:user_id"`
+	Currency          string     `json:"currency" gorm:"column:currency"`
+	Amount            Decimal    `json:"amount" gorm:"column:amount"`
+	Status            string     `json:"status" gorm:"column:status"`
+	Reference         string     `json:"reference" gorm:"column:reference"`
+	ReferenceType     string     `json:"reference_type" gorm:"column:reference_type"`
+	QRReference       string     `json:"qr_reference,omitempty" gorm:"column:qr_reference"`
+	BankTransactionID string     `json:"bank_transaction_id,omitempty" gorm:"column:bank_transaction_id"`
+	PaidAt            *time.Time `json:"paid_at" gorm:"column:paid_at"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Subscription) TableName() string {
+	return "subscription"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Subscription) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create validates subscription against offering investment limits and inserts it with a unique creditor reference
+func (subscription *Subscription) Create(money *OfferingMoney) *cigExchange.APIError {
+
+	places := CurrencyDecimalPlaces(money.Currency)
+	if subscription.Amount.Sign() <= 0 || subscription.Amount.Round(places).Cmp(subscription.Amount) != 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount must be a positive "+money.Currency+" amount")
+	}
+	if money.MinimumInvestment != nil && subscription.Amount.Cmp(*money.MinimumInvestment) < 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount is below minimum investment of "+money.MinimumInvestment.String()+" "+money.Currency)
+	}
+	if money.MaximumInvestment != nil && !money.MaximumInvestment.IsZero() && subscription.Amount.Cmp(*money.MaximumInvestment) > 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount is above maximum investment of "+money.MaximumInvestment.String()+" "+money.Currency)
+	}
+	if money.Amount != nil && subscription.Amount.Cmp(money.Remaining) > 0 {
+		return cigExchange.NewInvalidFieldError("amount", "Amount exceeds remaining amount of "+money.Remaining.String()+" "+money.Currency)
+	}
+
+	subscription.Currency = money.Currency
+	subscription.Status = SubscriptionStatusPending
+	subscription.ReferenceType = ReferenceTypeSCOR
+
+	for attempt := 0; attempt < referenceAttempts; attempt++ {
+		digits, err := randomDigits(16)
+		if err != nil {
+			return cigExchange.NewInvalidFieldError("reference", "Unable to generate payment reference")
+		}
+		subscription.Reference = NewCreditorReference(digits)
+
+		count := 0
+		db := cigExchange.GetDB().Model(&Subscription{}).Where(&Subscription{Reference: subscription.Reference}).Count(&count)
+		if db.Error != nil {
+			return cigExchange.NewDatabaseError("Fetch subscriptions failed", db.Error)
+		}
+		if count == 0 {
+			db = cigExchange.GetDB().Create(subscription)
+			if db.Error != nil {
+				return cigExchange.NewDatabaseError("Create subscription failed", db.Error)
+			}
+			return nil
+		}
+	}
+	return cigExchange.NewInvalidFieldError("reference", "Unable to generate unique payment reference")
+}
+
+// Cancel cancels pending subscription
+func (subscription *Subscription) Cancel() *cigExchange.APIError {
+
+	if subscription.Status != SubscriptionStatusPending {
+		return cigExchange.NewInvalidFieldError("subscription_id", "Only pending subscriptions can be cancelled")
+	}
+
+	db := cigExchange.GetDB().Model(subscription).Where("status = ?", SubscriptionStatusPending).Update("status", SubscriptionStatusCancelled)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Cancel subscription failed", db.Error)
+	}
+	// payment could settle the subscription since it was read
+	if db.RowsAffected == 0 {
+		return cigExchange.NewInvalidFieldError("subscription_id", "Only pending subscriptions can be cancelled")
+	}
+	return nil
+}
+
+// newQRReference generates unique QR reference, 26 random digits are followed by the check digit
+func newQRReference() (string, *cigExchange.APIError) {
+
+	for attempt := 0; attempt < referenceAttempts; attempt++ {
+		digits, err := randomDigits(26)
+		if err != nil {
+			return "", cigExchange.NewInvalidFieldError("qr_reference", "Unable to generate QR reference")
+		}
+		reference := digits + strconv.Itoa(qrrCheckDigit(digits))
+
+		count := 0
+		db := cigExchange.GetDB().Model(&Subscription{}).Where(&Subscription{QRReference: reference}).Count(&count)
+		if db.Error != nil {
+			return "", cigExchange.NewDatabaseError("Fetch subscriptions failed", db.Error)
+		}
+		if count == 0 {
+			return reference, nil
+		}
+	}
+	return "", cigExchange.NewInvalidFieldError("qr_reference", "Unable to generate unique QR reference")
+}
+
+// QRBill creates swiss QR-bill of pending subscription payable to the account, investor profile address is used
+// as debtor if it is complete. QR-IBAN requires QR reference, other accounts use the creditor reference
+func (subscription *Subscription) QRBill(account *PaymentAccount, profile *InvestorProfile) (*qrbill.Bill, *cigExchange.APIError) {
+
+	if subscription.Status != SubscriptionStatusPending {
+		return nil, cigExchange.NewInvalidFieldError("subscription_id", "QR-bill is available only for pending subscriptions")
+	}
+
+	bill := &qrbill.Bill{
+		Account: account.IBAN,
+		Creditor: qrbill.Address{
+			Name:           account.Name,
+			Street:         account.Street,
+			BuildingNumber: account.BuildingNumber,
+			PostalCode:     account.PostalCode,
+			Town:           account.Town,
+			Country:        account.Country,
+		},
+		Amount:        subscription.Amount.StringFixed(2),
+		Currency:      subscription.Currency,
+		ReferenceType: qrbill.ReferenceTypeSCOR,
+		Reference:     subscription.Reference,
+		Message:       "Subscription " + subscription.Reference,
+	}
+	if profile != nil && len(profile.FirstName+profile.LastName) > 0 && len(profile.City) > 0 && len(profile.ResidenceCountry) > 0 {
+		bill.Debtor = &qrbill.Address{
+			Name:       profile.FirstName + " " + profile.LastName,
+			Street:     profile.Street,
+			PostalCode: profile.PostalCode,
+			Town:       profile.City,
+			Country:    profile.ResidenceCountry,
+		}
+	}
+
+	if qrbill.IsQRIBAN(account.IBAN) {
+		if len(subscription.QRReference) == 0 {
+			reference, apiError := newQRReference()
+			if apiError != nil {
+				return nil, apiError
+			}
+			db := cigExchange.GetDB().Model(subscription).Update("qr_reference", reference)
+			if db.Error != nil {
+				return nil, cigExchange.NewDatabaseError("Update subscription failed", db.Error)
+			}
+			subscription.QRReference = reference
+		}
+		bill.ReferenceType = qrbill.ReferenceTypeQRR
+		bill.Reference = subscription.QRReference
+	}
+
+	err := bill.Validate()
+	if err != nil {
+		return nil, cigExchange.NewInvalidFieldError("qr_bill", "Unable to create QR-bill: "+err.Error())
+	}
+	return bill, nil
+}
+
+// settle books paid subscription to the ledger in the transaction: payment is deposited to investor cash account
+// and committed to the offering. Ledger entries are idempotent, so repeated settlement doesn't post twice
+func (subscription *Subscription) settle(tx *gorm.DB, bankTransactionID string, paidAt time.Time, createdBy string) (*JournalEntry, *cigExchange.APIError) {
+
+	offering, apiError := models.GetOffering(subscription.OfferingID)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	depositEntry := &JournalEntry{
+		IdempotencyKey: "subscription:" + subscription.ID + ":deposit",
+		Currency:       subscription.Currency,
+		Description:    "Subscription payment " + subscription.Reference,
+		EffectiveDate:  paidAt,
+		CreatedBy:      createdBy,
+	}
+	apiError = deposit(tx, depositEntry, subscription.UserID, subscription.Amount)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	commitment := &JournalEntry{
+		IdempotencyKey: "subscription:" + subscription.ID + ":commitment",
+		Currency:       subscription.Currency,
+		Description:    "Subscription " + subscription.Reference,
+		EffectiveDate:  paidAt,
+		CreatedBy:      createdBy,
+	}
+	apiError = commit(tx, commitment, subscription.UserID, offering, subscription.Amount)
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	db := tx.Model(subscription).Updates(map[string]interface{}{
+		"status":              SubscriptionStatusPaid,
+		"bank_transaction_id": bankTransactionID,
+		"paid_at":             paidAt,
+	})
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Update subscription failed", db.Error)
+	}
+	return depositEntry, nil
+}
+
+// GetUserSubscription queries investor subscription
+func GetUserSubscription(userID, subscriptionID string) (*Subscription, *cigExchange.APIError) {
+
+	subscription := &Subscription{}
+	db := cigExchange.GetDB().Where(&Subscription{ID: subscriptionID, UserID: userID}).First(subscription)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("subscription_id", "Subscription doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch subscription failed", db.Error)
+	}
+	return subscription, nil
+}
+
+// GetUserSubscriptions queries all investor subscriptions, newest first
+func GetUserSubscriptions(userID string) ([]*Subscription, *cigExchange.APIError) {
+
+	subscriptions := make([]*Subscription, 0)
+	db := cigExchange.GetDB().Where(&Subscription{UserID: userID}).Order("created_at desc").Find(&subscriptions)
+	if db.Error != nil {
+		return subscriptions, cigExchange.NewDatabaseError("Fetch subscriptions failed", db.Error)
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptionByReference queries subscription with the creditor or QR reference, nil is returned if there is none
+func GetSubscriptionByReference(reference string) (*Subscription, *cigExchange.APIError) {
+
+	reference = NormalizeReference(reference)
+	subscription := &Subscription{}
+	db := cigExchange.GetDB().Where("reference = ? OR qr_reference = ?", reference, reference).First(subscription)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch subscription failed", db.Error)
+	}
+	return subscription, nil
+}
@@ -0,0 +1,262 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-p2p-backend/i18n"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// OfferingMultilangFields lists translatable offering fields
+var OfferingMultilangFields = []string{
+	"title",
+	"description",
+	"location",
+	"tagline1",
+	"tagline2",
+	"tagline3",
+	"current_debt_level",
+	"offering_direct_url",
+}
+
+// OrganisationMultilangFields lists translatable organisation fields
+var OrganisationMultilangFields = []string{
+	"offering_rating_description",
+}
+
+// LanguageCoverage is a struct to represent translation state of a single language
+type LanguageCoverage struct {
+	Translated int      `json:"translated"`
+	Total      int      `json:"total"`
+	Percent    float64  `json:"percent"`
+	Missing    []string `json:"missing"`
+}
+
+// TranslationCoverage is a struct to represent translation state of a model by language
+type TranslationCoverage struct {
+	Languages map[string]*LanguageCoverage `json:"languages"`
+}
+
+// IsComplete checks that all fields are translated in the languages
+func (coverage *TranslationCoverage) IsComplete(languages []string) bool {
+
+	return len(coverage.MissingIn(languages)) == 0
+}
+
+// MissingIn returns missing fields by language for the languages
+func (coverage *TranslationCoverage) MissingIn(languages []string) map[string][]string {
+
+	missing := make(map[string][]string)
+	for _, language := range languages {
+		languageCoverage, ok := coverage.Languages[language]
+		if ok && len(languageCoverage.Missing) > 0 {
+			missing[language] = languageCoverage.Missing
+		}
+	}
+	return missing
+}
+
+// ModelToMap converts db model to json map
+func ModelToMap(model interface{}) (map[string]interface{}, *cigExchange.APIError) {
+
+	modelMap := make(map[string]interface{})
+	modelBytes, err := json.Marshal(model)
+	if err != nil {
+		return modelMap, cigExchange.NewJSONEncodingError("Unable to encode model", err)
+	}
+	err = json.Unmarshal(modelBytes, &modelMap)
+	if err != nil {
+		return modelMap, cigExchange.NewJSONEncodingError("Unable to decode model", err)
+	}
+	return modelMap, nil
+}
+
+// GetTranslations returns translations of multilang field from json map
+func GetTranslations(modelMap map[string]interface{}, field string) map[string]string {
+
+	translations := make(map[string]string)
+	values, ok := modelMap[field].(map[string]interface{})
+	if !ok {
+		return translations
+	}
+	for language, value := range values {
+		if s, ok := value.(string); ok && len(strings.TrimSpace(s)) > 0 {
+			translations[language] = s
+		}
+	}
+	return translations
+}
+
+// ComputeTranslationCoverage calculates translation coverage of multilang fields in json map.
+// Fields empty in all languages are not counted, there is nothing to translate
+func ComputeTranslationCoverage(modelMap map[string]interface{}, fields []string) *TranslationCoverage {
+
+	coverage := &TranslationCoverage{Languages: make(map[string]*LanguageCoverage)}
+	for _, language := range i18n.SupportedLanguages {
+		coverage.Languages[language] = &LanguageCoverage{Missing: make([]string, 0), Percent: 100}
+	}
+
+	for _, field := range fields {
+		translations := GetTranslations(modelMap, field)
+		if len(translations) == 0 {
+			continue
+		}
+		for _, language := range i18n.SupportedLanguages {
+			languageCoverage := coverage.Languages[language]
+			languageCoverage.Total++
+			if _, ok := translations[language]; ok {
+				languageCoverage.Translated++
+			} else {
+				languageCoverage.Missing = append(languageCoverage.Missing, field)
+			}
+		}
+	}
+
+	for _, languageCoverage := range coverage.Languages {
+		if languageCoverage.Total > 0 {
+			languageCoverage.Percent = float64(languageCoverage.Translated*10000/languageCoverage.Total) / 100
+		}
+	}
+	return coverage
+}
+
+// TranslationSettings is a struct to represent organisation translation requirements
+type TranslationSettings struct {
+	OrganisationID    string         `json:"organisation_id" gorm:"column:organisation_id;primary_key"`
+	RequiredLanguages pq.StringArray `json:"required_languages" gorm:"column:required_languages"`
+	BlockPublication  bool           `json:"block_publication" gorm:"column:block_publication"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt         time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*TranslationSettings) TableName() string {
+	return "translation_settings"
+}
+
+// Save inserts or updates translation settings in db
+func (settings *TranslationSettings) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(settings)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save translation settings failed", db.Error)
+	}
+	return nil
+}
+
+// CheckPublication returns error if visible model misses translations in required languages
+func (settings *TranslationSettings) CheckPublication(modelMap map[string]interface{}, fields []string) *cigExchange.APIError {
+
+	if !settings.BlockPublication {
+		return nil
+	}
+	if visible, _ := modelMap["is_visible"].(bool); !visible {
+		return nil
+	}
+
+	missing := ComputeTranslationCoverage(modelMap, fields).MissingIn(settings.RequiredLanguages)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	languages := make([]string, 0, len(missing))
+	for language, fields := range missing {
+		languages = append(languages, language+" ("+strings.Join(fields, ", ")+")")
+	}
+	sort.Strings(languages)
+	return cigExchange.NewInvalidFieldError("is_visible", "Offering can't be published with missing translations: "+strings.Join(languages, "; "))
+}
+
+// GetTranslationSettings queries organisation translation settings, defaults are returned if organisation has none
+func GetTranslationSettings(organisationID string) (*TranslationSettings, *cigExchange.APIError) {
+
+	settings := &TranslationSettings{}
+	db := cigExchange.GetDB().Where(&TranslationSettings{OrganisationID: organisationID}).First(settings)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return &TranslationSettings{
+				OrganisationID:    organisationID,
+				RequiredLanguages: pq.StringArray{i18n.DefaultLanguage},
+			}, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch translation settings failed", db.Error)
+	}
+	return settings, nil
+}
+
+// Translator is a struct to represent user allowed to translate organisation content, empty languages allow all
+type Translator struct {
+	ID             string         `json:"id" gorm:"column:id;primary_key"`
+	OrganisationID string         `json:"organisation_id" gorm:"column:organisation_id"`
+	UserID         string         `json:"user_id" gorm:"column:user_id"`
+	Languages      pq.StringArray `json:"languages" gorm:"column:languages"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*Translator) TableName() string {
+	return "translator"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*Translator) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Save inserts or updates translator in db
+func (translator *Translator) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(translator)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save translator failed", db.Error)
+	}
+	return nil
+}
+
+// Delete removes translator from db
+func (translator *Translator) Delete() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Delete(translator)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete translator failed", db.Error)
+	}
+	return nil
+}
+
+// CanTranslate checks if translator is allowed to fill the language
+func (translator *Translator) CanTranslate(language string) bool {
+
+	return len(translator.Languages) == 0 || containsString(translator.Languages, language)
+}
+
+// GetTranslator queries organisation translator, nil is returned if user is not a translator
+func GetTranslator(organisationID, userID string) (*Translator, *cigExchange.APIError) {
+
+	translator := &Translator{}
+	db := cigExchange.GetDB().Where(&Translator{OrganisationID: organisationID, UserID: userID}).First(translator)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch translator failed", db.Error)
+	}
+	return translator, nil
+}
+
+// GetTranslators queries all organisation translators
+func GetTranslators(organisationID string) ([]*Translator, *cigExchange.APIError) {
+
+	translators := make([]*Translator, 0)
+	db := cigExchange.GetDB().Where(&Translator{OrganisationID: organisationID}).Order("created_at asc").Find(&translators)
+	if db.Error != nil {
+		return translators, cigExchange.NewDatabaseError("Fetch translators failed", db.Error)
+	}
+	return translators, nil
+}
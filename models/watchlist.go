@@ -0,0 +1,95 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+)
+
+// WatchlistItem is a struct to represent offering bookmarked by investor
+type WatchlistItem struct {
+	UserID     string    `json:"user_id" gorm:"column:user_id;primary_key"`
+	OfferingID string    `json:"offering_id" gorm:"column:offering_id;primary_key"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns table name for struct
+func (*WatchlistItem) TableName() string {
+	return "watchlist_item"
+}
+
+// AddToWatchlist inserts offering into user watchlist, adding watched offering again does nothing
+func AddToWatchlist(userID, offeringID string) *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Exec("INSERT INTO watchlist_item (user_id, offering_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING", userID, offeringID, time.Now())
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Add offering to watchlist failed", db.Error)
+	}
+	return nil
+}
+
+// RemoveFromWatchlist deletes offering from user watchlist
+func RemoveFromWatchlist(userID, offeringID string) *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Where(&WatchlistItem{UserID: userID, OfferingID: offeringID}).Delete(&WatchlistItem{})
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Remove offering from watchlist failed", db.Error)
+	}
+	if db.RowsAffected == 0 {
+		return cigExchange.NewInvalidFieldError("offering_id", "Offering isn't in the watchlist")
+	}
+	return nil
+}
+
+// GetWatchlist returns user watchlist, most recently added first
+func GetWatchlist(userID string) ([]*WatchlistItem, *cigExchange.APIError) {
+
+	items := make([]*WatchlistItem, 0)
+	db := cigExchange.GetDB().Where(&WatchlistItem{UserID: userID}).Order("created_at desc").Find(&items)
+	if db.Error != nil {
+		return items, cigExchange.NewDatabaseError("Fetch watchlist failed", db.Error)
+	}
+	return items, nil
+}
+
+// GetWatchedOfferingIDs returns set of offering ids watched by the user
+func GetWatchedOfferingIDs(userID string) (map[string]bool, *cigExchange.APIError) {
+
+	watched := make(map[string]bool)
+	items, apiError := GetWatchlist(userID)
+	if apiError != nil {
+		return watched, apiError
+	}
+	for _, item := range items {
+		watched[item.OfferingID] = true
+	}
+	return watched, nil
+}
+
+// watchersCount is used to scan grouped watchlist rows
+type watchersCount struct {
+	OfferingID string `gorm:"column:offering_id"`
+	Count      int64  `gorm:"column:count"`
+}
+
+// GetWatchersCount returns number of watchers per offering for the given offering ids
+func GetWatchersCount(offeringIDs []string) (map[string]int64, *cigExchange.APIError) {
+
+	counts := make(map[string]int64)
+	if len(offeringIDs) == 0 {
+		return counts, nil
+	}
+
+	rows := make([]*watchersCount, 0)
+	db := cigExchange.GetDB().Table("watchlist_item").
+		Select("offering_id, count(*) AS count").
+		Where("offering_id IN (?)", offeringIDs).
+		Group("offering_id").Scan(&rows)
+	if db.Error != nil {
+		return counts, cigExchange.NewDatabaseError("Fetch watchers count failed", db.Error)
+	}
+
+	for _, row := range rows {
+		counts[row.OfferingID] = row.Count
+	}
+	return counts, nil
+}
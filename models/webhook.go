@@ -0,0 +1,268 @@
+package models
+
+import (
+	cigExchange "cig-exchange-libs"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
+)
+
+// Constants defining the webhook event types
+const (
+	WebhookEventOfferingCreated    = "offering.created"
+	WebhookEventOfferingUpdated    = "offering.updated"
+	WebhookEventOfferingDeleted    = "offering.deleted"
+	WebhookEventMediaUploaded      = "media.uploaded"
+	WebhookEventMemberJoined       = "member.joined"
+	WebhookEventMemberLeft         = "member.left"
+	WebhookEventInvitationAccepted = "invitation.accepted"
+	WebhookEventPing               = "ping"
+)
+
+// WebhookEvents lists events organisations can subscribe to
+var WebhookEvents = []string{
+	WebhookEventOfferingCreated,
+	WebhookEventOfferingUpdated,
+	WebhookEventOfferingDeleted,
+	WebhookEventMediaUploaded,
+	WebhookEventMemberJoined,
+	WebhookEventMemberLeft,
+	WebhookEventInvitationAccepted,
+}
+
+// Constants defining the webhook delivery status
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusDelivered = "delivered"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// WebhookSubscription is a struct to represent organisation webhook endpoint
+type WebhookSubscription struct {
+	ID             string         `json:"id" gorm:"column:id;primary_key"`
+	OrganisationID string         `json:"organisation_id" gorm:"column:organisation_id"`
+	URL            string         `json:"url" gorm:"column:url"`
+	Secret         string         `json:"-" gorm:"column:secret"`
+	Events         pq.StringArray `json:"events" gorm:"column:events;type:varchar(64)[]"`
+	IsActive       bool           `json:"is_active" gorm:"column:is_active"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	DeletedAt      *time.Time     `json:"-" gorm:"column:deleted_at"`
+}
+
+// TableName returns table name for struct
+func (*WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*WebhookSubscription) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new webhook subscription into db
+func (subscription *WebhookSubscription) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	subscription.ID = ""
+
+	db := cigExchange.GetDB().Create(subscription)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create webhook failed", db.Error)
+	}
+	return nil
+}
+
+// Save updates webhook subscription in db
+func (subscription *WebhookSubscription) Save() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Save(subscription)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save webhook failed", db.Error)
+	}
+	return nil
+}
+
+// Delete soft deletes webhook subscription, delivery log is kept
+func (subscription *WebhookSubscription) Delete() *cigExchange.APIError {
+
+	db := cigExchange.GetDB().Delete(subscription)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Delete webhook failed", db.Error)
+	}
+	return nil
+}
+
+// IsSubscribed checks if subscription receives the event
+func (subscription *WebhookSubscription) IsSubscribed(event string) bool {
+
+	for _, e := range subscription.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebhookSubscription queries a single organisation webhook from db
+func GetWebhookSubscription(organisationID, subscriptionID string) (*WebhookSubscription, *cigExchange.APIError) {
+
+	subscription := &WebhookSubscription{}
+	db := cigExchange.GetDB().Where(&WebhookSubscription{ID: subscriptionID, OrganisationID: organisationID}).First(subscription)
+	if db.Error != nil {
+		if db.RecordNotFound() {
+			return nil, cigExchange.NewInvalidFieldError("webhook_id", "Webhook with provided id doesn't exist")
+		}
+		return nil, cigExchange.NewDatabaseError("Fetch webhook failed", db.Error)
+	}
+	return subscription, nil
+}
+
+// GetWebhookSubscriptions queries all organisation webhooks from db
+func GetWebhookSubscriptions(organisationID string) ([]*WebhookSubscription, *cigExchange.APIError) {
+
+	subscriptions := make([]*WebhookSubscription, 0)
+	db := cigExchange.GetDB().Where(&WebhookSubscription{OrganisationID: organisationID}).Order("created_at asc").Find(&subscriptions)
+	if db.Error != nil {
+		return subscriptions, cigExchange.NewDatabaseError("Fetch webhooks failed", db.Error)
+	}
+	return subscriptions, nil
+}
+
+// IsValidWebhookEvent checks webhook event type
+func IsValidWebhookEvent(event string) bool {
+
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is a struct to represent a single webhook event delivery
+type WebhookDelivery struct {
+	ID             string         `json:"id" gorm:"column:id;primary_key"`
+	SubscriptionID string         `json:"webhook_id" gorm:"column:subscription_id"`
+	OrganisationID string         `json:"organisation_id" gorm:"column:organisation_id"`
+	Event          string         `json:"event" gorm:"column:event"`
+	Payload        postgres.Jsonb `json:"payload" gorm:"column:payload"`
+	Status         string         `json:"status" gorm:"column:status"`
+	Attempts       int            `json:"attempts" gorm:"column:attempts"`
+	ResponseCode   int            `json:"response_code" gorm:"column:response_code"`
+	LastError      string         `json:"last_error" gorm:"column:last_error"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at" gorm:"column:delivered_at"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName returns table name for struct
+func (*WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+// BeforeCreate generates new unique UUIDs for new db records
+func (*WebhookDelivery) BeforeCreate(scope *gorm.Scope) error {
+
+	return scope.SetColumn("ID", cigExchange.RandomUUID())
+}
+
+// Create inserts new pending webhook delivery into db, it is due immediately unless NextAttemptAt is set
+func (delivery *WebhookDelivery) Create() *cigExchange.APIError {
+
+	// invalidate the uuid
+	delivery.ID = ""
+	delivery.Status = WebhookDeliveryStatusPending
+	delivery.Attempts = 0
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = time.Now()
+	}
+
+	db := cigExchange.GetDB().Create(delivery)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Create webhook delivery failed", db.Error)
+	}
+	return nil
+}
+
+// Save updates webhook delivery in db
+func (delivery *WebhookDelivery) Save(tx *gorm.DB) *cigExchange.APIError {
+
+	if tx == nil {
+		tx = cigExchange.GetDB()
+	}
+
+	db := tx.Save(delivery)
+	if db.Error != nil {
+		return cigExchange.NewDatabaseError("Save webhook delivery failed", db.Error)
+	}
+	return nil
+}
+
+// ClaimDueWebhookDeliveries returns pending deliveries ready to be sent and postpones
+// their next attempt by claimTimeout, so other workers skip them while they are sent.
+// Deliveries are claimed in a short transaction, no locks are held during the http calls
+func ClaimDueWebhookDeliveries(limit int, claimTimeout time.Duration) ([]*WebhookDelivery, *cigExchange.APIError) {
+
+	deliveries := make([]*WebhookDelivery, 0)
+
+	tx := cigExchange.GetDB().Begin()
+	if tx.Error != nil {
+		return deliveries, cigExchange.NewDatabaseError("Unable to start transaction", tx.Error)
+	}
+
+	db := tx.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryStatusPending, time.Now()).
+		Order("next_attempt_at asc").Limit(limit).Find(&deliveries)
+	if db.Error != nil {
+		tx.Rollback()
+		return deliveries, cigExchange.NewDatabaseError("Fetch webhook deliveries failed", db.Error)
+	}
+	if len(deliveries) == 0 {
+		tx.Rollback()
+		return deliveries, nil
+	}
+
+	deliveryIDs := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	db = tx.Model(&WebhookDelivery{}).Where("id IN (?)", deliveryIDs).Update("next_attempt_at", time.Now().Add(claimTimeout))
+	if db.Error != nil {
+		tx.Rollback()
+		return deliveries, cigExchange.NewDatabaseError("Claim webhook deliveries failed", db.Error)
+	}
+
+	err := tx.Commit().Error
+	if err != nil {
+		return deliveries, cigExchange.NewDatabaseError("Unable to commit transaction", err)
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDeliveries returns delivery log of the webhook, newest first
+func GetWebhookDeliveries(subscriptionID string, limit int) ([]*WebhookDelivery, *cigExchange.APIError) {
+
+	deliveries := make([]*WebhookDelivery, 0)
+	db := cigExchange.GetDB().Where(&WebhookDelivery{SubscriptionID: subscriptionID}).Order("created_at desc").Limit(limit).Find(&deliveries)
+	if db.Error != nil {
+		return deliveries, cigExchange.NewDatabaseError("Fetch webhook deliveries failed", db.Error)
+	}
+	return deliveries, nil
+}
+
+// GetWebhookSubscriptionByID queries webhook from db including deleted ones
+func GetWebhookSubscriptionByID(subscriptionID string) (*WebhookSubscription, *cigExchange.APIError) {
+
+	subscription := &WebhookSubscription{}
+	db := cigExchange.GetDB().Unscoped().Where(&WebhookSubscription{ID: subscriptionID}).First(subscription)
+	if db.Error != nil {
+		return nil, cigExchange.NewDatabaseError("Fetch webhook failed", db.Error)
+	}
+	return subscription, nil
+}
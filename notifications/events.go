@@ -0,0 +1,227 @@
+package notifications
+
+import (
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	p2pModels "cig-exchange-p2p-backend/models"
+)
+
+// getOrganisationName returns organisation name or a generic text if it can't be loaded
+func getOrganisationName(organisationID string) string {
+
+	org, apiError := models.GetOrganisation(organisationID)
+	if apiError != nil || len(org.Name) == 0 {
+		return "your organisation"
+	}
+	return org.Name
+}
+
+// getOfferingTitle returns offering title in the default language or first available translation
+func getOfferingTitle(offering *models.Offering) string {
+
+	title := i18n.TranslateJSON(offering.Title.RawMessage, i18n.FallbackChain())
+	if len(title) == 0 {
+		return "An offering"
+	}
+	return title
+}
+
+// OrganisationRoleChanged notifies user about his new organisation role
+func OrganisationRoleChanged(userID, organisationID, role string) {
+
+	orgName := getOrganisationName(organisationID)
+	data := map[string]interface{}{
+		"organisation_id": organisationID,
+		"role":            role,
+	}
+	Notify(userID, p2pModels.NotificationTypeOrganisationRoleChanged, organisationID,
+		"Organisation role changed",
+		"Your role in "+orgName+" was changed to '"+role+"'.",
+		data)
+}
+
+// OrganisationUserRemoved notifies user that he was removed from organisation
+func OrganisationUserRemoved(userID, organisationID string) {
+
+	orgName := getOrganisationName(organisationID)
+	data := map[string]interface{}{
+		"organisation_id": organisationID,
+	}
+	Notify(userID, p2pModels.NotificationTypeOrganisationUserRemoved, organisationID,
+		"Removed from organisation",
+		"You were removed from "+orgName+".",
+		data)
+}
+
+// OfferingChanged notifies organisation members (except the acting user) about offering changes
+func OfferingChanged(notificationType string, offering *models.Offering, actorUserID string) {
+
+	action := "updated"
+	switch notificationType {
+	case p2pModels.NotificationTypeOfferingCreated:
+		action = "created"
+	case p2pModels.NotificationTypeOfferingDeleted:
+		action = "deleted"
+	}
+
+	data := map[string]interface{}{
+		"organisation_id": offering.OrganisationID,
+		"offering_id":     offering.ID,
+	}
+	NotifyOrganisation(offering.OrganisationID, actorUserID, notificationType, offering.ID,
+		"Offering "+action,
+		getOfferingTitle(offering)+" was "+action+".",
+		data)
+}
+
+// QuestionAsked notifies organisation members about new question or investor reply in question thread
+func QuestionAsked(question *p2pModels.OfferingQuestion, offering *models.Offering, isReply bool) {
+
+	title := "New question"
+	message := "A new question was asked about " + getOfferingTitle(offering) + "."
+	if isReply {
+		title = "New reply to question"
+		message = "An investor replied in a question thread about " + getOfferingTitle(offering) + "."
+	}
+
+	data := map[string]interface{}{
+		"organisation_id": question.OrganisationID,
+		"offering_id":     question.OfferingID,
+		"question_id":     question.ID,
+	}
+	NotifyOrganisation(question.OrganisationID, question.UserID, p2pModels.NotificationTypeQuestionAsked, question.ID, title, message, data)
+}
+
+// QuestionAnswered notifies investor that organisation replied to his question
+func QuestionAnswered(question *p2pModels.OfferingQuestion, offering *models.Offering) {
+
+	data := map[string]interface{}{
+		"offering_id": question.OfferingID,
+		"question_id": question.ID,
+	}
+	Notify(question.UserID, p2pModels.NotificationTypeQuestionAnswered, question.ID,
+		"Question answered",
+		"Your question about "+getOfferingTitle(offering)+" was answered.",
+		data)
+}
+
+// KYCReviewed notifies investor about verification decision on his profile
+func KYCReviewed(profile *p2pModels.InvestorProfile) {
+
+	data := map[string]interface{}{
+		"status":    profile.Status,
+		"kyc_level": profile.KYCLevel,
+	}
+	if profile.Status == p2pModels.KYCStatusApproved {
+		Notify(profile.UserID, p2pModels.NotificationTypeKYCReviewed, profile.UserID,
+			"Profile verified",
+			"Your investor profile was verified.",
+			data)
+		return
+	}
+	Notify(profile.UserID, p2pModels.NotificationTypeKYCReviewed, profile.UserID,
+		"Profile verification rejected",
+		"Your investor profile was rejected: "+profile.RejectionReason,
+		data)
+}
+
+// DistributionPaid notifies investors about their part of an offering distribution
+func DistributionPaid(distribution *p2pModels.Distribution, offering *models.Offering) {
+
+	for _, allocation := range distribution.Allocations {
+		data := map[string]interface{}{
+			"offering_id":     offering.ID,
+			"distribution_id": distribution.ID,
+			"principal":       allocation.Principal,
+			"interest":        allocation.Interest,
+			"currency":        allocation.Currency,
+		}
+		Notify(allocation.UserID, p2pModels.NotificationTypeDistributionPaid, allocation.ID,
+			"Distribution received",
+			"You receive "+allocation.Total.StringFixed(p2pModels.CurrencyDecimalPlaces(allocation.Currency))+" "+allocation.Currency+" from "+getOfferingTitle(offering)+".",
+			data)
+	}
+}
+
+// TradeSettled notifies seller and buyer about a settled secondary market trade
+func TradeSettled(trade *p2pModels.Trade, offering *models.Offering) {
+
+	data := map[string]interface{}{
+		"offering_id": offering.ID,
+		"trade_id":    trade.ID,
+		"principal":   trade.Principal,
+		"price":       trade.Price,
+		"amount":      trade.Amount,
+		"currency":    trade.Currency,
+	}
+	amount := trade.Amount.StringFixed(p2pModels.CurrencyDecimalPlaces(trade.Currency)) + " " + trade.Currency
+	title := getOfferingTitle(offering)
+	Notify(trade.SellerID, p2pModels.NotificationTypeTradeSettled, trade.ID,
+		"Listing sold",
+		"You sold principal of "+title+" for "+amount+".",
+		data)
+	Notify(trade.BuyerID, p2pModels.NotificationTypeTradeSettled, trade.ID,
+		"Bid filled",
+		"You bought principal of "+title+" for "+amount+".",
+		data)
+}
+
+// BankAccountChanged notifies investor about confirmed change of his payout bank accounts
+func BankAccountChanged(change *p2pModels.BankAccountChange, account *p2pModels.BankAccount) {
+
+	data := map[string]interface{}{
+		"action":          change.Action,
+		"bank_account_id": change.BankAccountID,
+	}
+	iban := account.IBAN
+	if len(iban) > 4 {
+		iban = iban[len(iban)-4:]
+	}
+
+	message := ""
+	switch change.Action {
+	case p2pModels.BankAccountChangeCreate:
+		message = "Bank account ending with " + iban + " was added."
+	case p2pModels.BankAccountChangeUpdate:
+		message = "Bank account ending with " + iban + " was updated."
+	case p2pModels.BankAccountChangeDelete:
+		message = "Bank account ending with " + iban + " was removed."
+	default:
+		message = "Payouts are now paid to bank account ending with " + iban + "."
+	}
+	Notify(change.UserID, p2pModels.NotificationTypeBankAccountChanged, change.ID,
+		"Payout bank account changed",
+		message+" If you didn't make this change, contact support immediately.",
+		data)
+}
+
+// SignatureRequested notifies user about a document waiting for his signature
+func SignatureRequested(request *p2pModels.SignatureRequest) {
+
+	data := map[string]interface{}{
+		"request_id":      request.ID,
+		"organisation_id": request.OrganisationID,
+		"document_type":   request.DocumentType,
+	}
+	Notify(request.UserID, p2pModels.NotificationTypeSignatureRequested, request.ID,
+		"Signature requested",
+		getOrganisationName(request.OrganisationID)+" asked you to sign \""+request.Title+"\".",
+		data)
+}
+
+// SignatureCompleted notifies the user who created the request that the signer signed or declined it
+func SignatureCompleted(request *p2pModels.SignatureRequest) {
+
+	if request.CreatedBy == request.UserID {
+		return
+	}
+	data := map[string]interface{}{
+		"request_id":      request.ID,
+		"organisation_id": request.OrganisationID,
+		"status":          request.Status,
+	}
+	Notify(request.CreatedBy, p2pModels.NotificationTypeSignatureCompleted, request.ID,
+		"Signature request "+request.Status,
+		"\""+request.Title+"\" was "+request.Status+" by the signer.",
+		data)
+}
@@ -0,0 +1,100 @@
+package notifications
+
+import (
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Notify creates notification for the user using his channel preferences.
+// sourceID is the id of the record which triggered the notification (offering, trade, ...).
+// Errors are only logged, notifications never fail the calling request
+func Notify(userID, notificationType, sourceID, title, message string, data map[string]interface{}) {
+
+	preference, apiError := p2pModels.GetNotificationPreference(userID, notificationType)
+	if apiError != nil {
+		fmt.Println("Notify: " + apiError.ToString())
+		return
+	}
+
+	notification := &p2pModels.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	}
+	if data != nil {
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			fmt.Println("Notify: unable to encode notification data:")
+			fmt.Println(err.Error())
+			return
+		}
+		notification.Data = postgres.Jsonb{RawMessage: dataBytes}
+	}
+
+	if preference.InApp {
+		apiError = notification.Create()
+		if apiError != nil {
+			fmt.Println("Notify: " + apiError.ToString())
+			notification.ID = ""
+		}
+	}
+
+	if preference.Email {
+		queueNotificationEmail(notification, sourceID)
+	}
+}
+
+// NotifyOrganisation notifies all active organisation members except excludedUserID (usually the acting user)
+func NotifyOrganisation(organisationID, excludedUserID, notificationType, sourceID, title, message string, data map[string]interface{}) {
+
+	orgUsers, apiError := models.GetOrganisationUsersForOrganisation(organisationID)
+	if apiError != nil {
+		fmt.Println("NotifyOrganisation: " + apiError.ToString())
+		return
+	}
+
+	for _, orgUser := range orgUsers {
+		if orgUser.UserID == excludedUserID || orgUser.Status != models.OrganisationUserStatusActive {
+			continue
+		}
+		Notify(orgUser.UserID, notificationType, sourceID, title, message, data)
+	}
+}
+
+func queueNotificationEmail(notification *p2pModels.Notification, sourceID string) {
+
+	user, apiError := models.GetUser(notification.UserID)
+	if apiError != nil {
+		fmt.Println("Notify: " + apiError.ToString())
+		return
+	}
+	if user.LoginEmail == nil || len(user.LoginEmail.Value1) == 0 {
+		return
+	}
+
+	// without in-app notification the email references the record which triggered it
+	referenceType := p2pModels.EmailOutboxReferenceNotification
+	referenceID := notification.ID
+	if len(referenceID) == 0 {
+		referenceType = notification.Type
+		referenceID = sourceID
+	}
+
+	outbox := &p2pModels.EmailOutbox{
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		ToEmail:       user.LoginEmail.Value1,
+		ToName:        user.Name,
+		Subject:       notification.Title,
+		Text:          notification.Message,
+	}
+	apiError = outbox.Create(nil)
+	if apiError != nil {
+		fmt.Println("Notify: " + apiError.ToString())
+	}
+}
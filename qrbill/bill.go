@@ -0,0 +1,191 @@
+package qrbill
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constants defining reference types of the swiss payment standard
+const (
+	ReferenceTypeQRR  = "QRR"  // QR reference, required by QR-IBAN
+	ReferenceTypeSCOR = "SCOR" // ISO 11649 creditor reference
+	ReferenceTypeNON  = "NON"  // without reference
+)
+
+// Constants defining header of the swiss QR code payload
+const (
+	qrType      = "SPC"
+	qrVersion   = "0200"
+	qrCoding    = "1" // UTF-8 restricted to the latin character set
+	qrTrailer   = "EPD"
+	addressType = "S" // structured address
+)
+
+// maxPayloadLength is the maximum number of characters of the swiss QR code payload
+const maxPayloadLength = 997
+
+var amountRegexp = regexp.MustCompile(`^[0-9]{1,9}\.[0-9]{2}$`)
+var countryRegexp = regexp.MustCompile("^[A-Z]{2}$")
+
+// Address is a structured address of creditor or debtor
+type Address struct {
+	Name           string
+	Street         string
+	BuildingNumber string
+	PostalCode     string
+	Town           string
+	Country        string
+}
+
+// Bill is a swiss QR-bill. Amount is a decimal with two places, empty amount leaves it to the debtor.
+// Debtor is optional
+type Bill struct {
+	Account       string
+	Creditor      Address
+	Amount        string
+	Currency      string
+	Debtor        *Address
+	ReferenceType string
+	Reference     string
+	Message       string
+}
+
+// IsQRIBAN checks if swiss or liechtenstein IBAN is a QR-IBAN, QR-IBAN institution ids are in range 30000-31999
+func IsQRIBAN(iban string) bool {
+
+	iban = compact(iban)
+	if len(iban) != 21 || (iban[:2] != "CH" && iban[:2] != "LI") {
+		return false
+	}
+	iid, err := strconv.Atoi(iban[4:9])
+	return err == nil && iid >= 30000 && iid <= 31999
+}
+
+// compact removes spaces and converts value to upper case
+func compact(value string) string {
+
+	return strings.ToUpper(strings.Join(strings.Fields(value), ""))
+}
+
+// clean replaces line breaks and repeated spaces of payload field
+func clean(value string) string {
+
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// validate checks mandatory fields and field lengths of the address
+func (address *Address) validate(party string) error {
+
+	if len(clean(address.Name)) == 0 || len(clean(address.Town)) == 0 {
+		return errors.New(party + " name and town are required")
+	}
+	if !countryRegexp.MatchString(address.Country) {
+		return errors.New(party + " country must be a two letter country code")
+	}
+	limits := []struct {
+		value string
+		max   int
+	}{
+		{address.Name, 70},
+		{address.Street, 70},
+		{address.BuildingNumber, 16},
+		{address.PostalCode, 16},
+		{address.Town, 35},
+	}
+	for _, limit := range limits {
+		if len([]rune(clean(limit.value))) > limit.max {
+			return errors.New(party + " address field '" + clean(limit.value) + "' is too long")
+		}
+	}
+	return nil
+}
+
+// lines returns address fields of the payload
+func (address *Address) lines() []string {
+
+	if address == nil {
+		return []string{"", "", "", "", "", "", ""}
+	}
+	return []string{
+		addressType,
+		clean(address.Name),
+		clean(address.Street),
+		clean(address.BuildingNumber),
+		clean(address.PostalCode),
+		clean(address.Town),
+		address.Country,
+	}
+}
+
+// Validate checks the bill against the swiss payment standard
+func (bill *Bill) Validate() error {
+
+	account := compact(bill.Account)
+	if len(account) != 21 || (account[:2] != "CH" && account[:2] != "LI") {
+		return errors.New("account must be a swiss or liechtenstein IBAN")
+	}
+	if bill.Currency != "CHF" && bill.Currency != "EUR" {
+		return errors.New("currency must be CHF or EUR")
+	}
+	if len(bill.Amount) > 0 && (!amountRegexp.MatchString(bill.Amount) || bill.Amount == "0.00") {
+		return errors.New("amount must be between 0.01 and 999999999.99")
+	}
+
+	err := bill.Creditor.validate("creditor")
+	if err != nil {
+		return err
+	}
+	if bill.Debtor != nil {
+		err = bill.Debtor.validate("debtor")
+		if err != nil {
+			return err
+		}
+	}
+
+	reference := compact(bill.Reference)
+	switch bill.ReferenceType {
+	case ReferenceTypeQRR:
+		if len(reference) != 27 {
+			return errors.New("QR reference must have 27 digits")
+		}
+		if !IsQRIBAN(account) {
+			return errors.New("QR reference requires QR-IBAN")
+		}
+	case ReferenceTypeSCOR:
+		if len(reference) < 5 || len(reference) > 25 {
+			return errors.New("creditor reference must have 5 to 25 characters")
+		}
+	case ReferenceTypeNON:
+		if len(reference) > 0 {
+			return errors.New("reference is not allowed without reference type")
+		}
+	default:
+		return errors.New("reference type must be one of: " + ReferenceTypeQRR + ", " + ReferenceTypeSCOR + ", " + ReferenceTypeNON)
+	}
+	if bill.ReferenceType != ReferenceTypeQRR && IsQRIBAN(account) {
+		return errors.New("QR-IBAN requires QR reference")
+	}
+
+	if len([]rune(clean(bill.Message))) > 140 {
+		return errors.New("message must have at most 140 characters")
+	}
+	if len([]rune(bill.Payload())) > maxPayloadLength {
+		return errors.New("QR code payload is too long")
+	}
+	return nil
+}
+
+// Payload returns swiss QR code payload of the bill, fields are separated by line feeds
+func (bill *Bill) Payload() string {
+
+	fields := []string{qrType, qrVersion, qrCoding, compact(bill.Account)}
+	fields = append(fields, bill.Creditor.lines()...)
+	// ultimate creditor is reserved for future use and must stay empty
+	fields = append(fields, (*Address)(nil).lines()...)
+	fields = append(fields, bill.Amount, bill.Currency)
+	fields = append(fields, bill.Debtor.lines()...)
+	fields = append(fields, bill.ReferenceType, compact(bill.Reference), clean(bill.Message), qrTrailer)
+	return strings.Join(fields, "\n")
+}
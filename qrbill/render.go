@@ -0,0 +1,403 @@
+package qrbill
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// Constants defining payment part dimensions in millimetres
+const (
+	slipWidth      = 210.0
+	slipHeight     = 105.0
+	receiptWidth   = 62.0
+	qrSize         = 46.0
+	swissCrossSize = 7.0
+	pointToMM      = 25.4 / 72
+)
+
+// maxQRVersion is the highest QR code version allowed by the swiss payment standard
+const maxQRVersion = 25
+
+// labels contains payment part texts in the languages of the swiss payment standard
+var labels = map[string]map[string]string{
+	"en": {
+		"receipt":          "Receipt",
+		"payment_part":     "Payment part",
+		"account":          "Account / Payable to",
+		"reference":        "Reference",
+		"information":      "Additional information",
+		"payable_by":       "Payable by",
+		"payable_by_blank": "Payable by (name/address)",
+		"currency":         "Currency",
+		"amount":           "Amount",
+		"acceptance_point": "Acceptance point",
+	},
+	"de": {
+		"receipt":          "Empfangsschein",
+		"payment_part":     "Zahlteil",
+		"account":          "Konto / Zahlbar an",
+		"reference":        "Referenz",
+		"information":      "Zusätzliche Informationen",
+		"payable_by":       "Zahlbar durch",
+		"payable_by_blank": "Zahlbar durch (Name/Adresse)",
+		"currency":         "Währung",
+		"amount":           "Betrag",
+		"acceptance_point": "Annahmestelle",
+	},
+	"fr": {
+		"receipt":          "Récépissé",
+		"payment_part":     "Section paiement",
+		"account":          "Compte / Payable à",
+		"reference":        "Référence",
+		"information":      "Informations supplémentaires",
+		"payable_by":       "Payable par",
+		"payable_by_blank": "Payable par (nom/adresse)",
+		"currency":         "Monnaie",
+		"amount":           "Montant",
+		"acceptance_point": "Point de dépôt",
+	},
+	"it": {
+		"receipt":          "Ricevuta",
+		"payment_part":     "Sezione pagamento",
+		"account":          "Conto / Pagabile a",
+		"reference":        "Riferimento",
+		"information":      "Informazioni supplementari",
+		"payable_by":       "Pagabile da",
+		"payable_by_blank": "Pagabile da (nome/indirizzo)",
+		"currency":         "Valuta",
+		"amount":           "Importo",
+		"acceptance_point": "Punto di accettazione",
+	},
+}
+
+// Constants defining kinds of layout elements
+const (
+	elementText = iota
+	elementRect
+	elementLine
+)
+
+// element is a text, filled rectangle or dashed line of the payment part, coordinates are in millimetres.
+// Text position is the baseline start, or the baseline end of right aligned text
+type element struct {
+	kind  int
+	x, y  float64
+	w, h  float64
+	text  string
+	size  float64
+	bold  bool
+	right bool
+	white bool
+}
+
+// layout collects elements of the payment part
+type layout struct {
+	elements []*element
+}
+
+func (l *layout) text(x, y float64, text string, size float64, bold bool) {
+	l.elements = append(l.elements, &element{kind: elementText, x: x, y: y, text: text, size: size, bold: bold})
+}
+
+func (l *layout) rect(x, y, w, h float64, white bool) {
+	l.elements = append(l.elements, &element{kind: elementRect, x: x, y: y, w: w, h: h, white: white})
+}
+
+func (l *layout) line(x1, y1, x2, y2 float64) {
+	l.elements = append(l.elements, &element{kind: elementLine, x: x1, y: y1, w: x2 - x1, h: y2 - y1})
+}
+
+// block adds heading with value lines wrapped to the width and returns baseline of the next block
+func (l *layout) block(x, y, width float64, heading string, values []string, headingSize, valueSize float64) float64 {
+
+	l.text(x, y, heading, headingSize, true)
+	lineHeight := valueSize*pointToMM + 0.6
+	// average helvetica character is about half as wide as the font size
+	characters := int(width / (valueSize * pointToMM * 0.5))
+	for _, value := range values {
+		for _, line := range wrap(value, characters) {
+			y += lineHeight
+			l.text(x, y, line, valueSize, false)
+		}
+	}
+	return y + lineHeight*1.6
+}
+
+// formatAmount formats amount with space thousands separators for printing
+func formatAmount(amount string) string {
+
+	parts := strings.SplitN(amount, ".", 2)
+	integer := parts[0]
+	for i := len(integer) - 3; i > 0; i -= 3 {
+		integer = integer[:i] + " " + integer[i:]
+	}
+	if len(parts) == 2 {
+		return integer + "." + parts[1]
+	}
+	return integer
+}
+
+// group splits value with spaces in groups of the size, first group has the length of head if set
+func group(value string, size, head int) string {
+
+	groups := make([]string, 0)
+	if head > 0 && head < len(value) {
+		groups = append(groups, value[:head])
+		value = value[head:]
+	}
+	for len(value) > size {
+		groups = append(groups, value[:size])
+		value = value[size:]
+	}
+	if len(value) > 0 {
+		groups = append(groups, value)
+	}
+	return strings.Join(groups, " ")
+}
+
+// formatReference formats reference for printing, QR reference is grouped in blocks of five digits from the right
+func formatReference(referenceType, reference string) string {
+
+	reference = compact(reference)
+	if referenceType == ReferenceTypeQRR {
+		return group(reference, 5, len(reference)%5)
+	}
+	return group(reference, 4, 0)
+}
+
+// printLines returns address lines for printing
+func (address *Address) printLines() []string {
+
+	lines := []string{clean(address.Name)}
+	street := strings.TrimSpace(clean(address.Street) + " " + clean(address.BuildingNumber))
+	if len(street) > 0 {
+		lines = append(lines, street)
+	}
+	town := strings.TrimSpace(clean(address.PostalCode) + " " + clean(address.Town))
+	if address.Country != "CH" && address.Country != "LI" {
+		town = address.Country + "-" + town
+	}
+	return append(lines, town)
+}
+
+// layout lays out receipt and payment part of the bill in the language, english is used for unsupported languages
+func (bill *Bill) layout(language string) (*layout, error) {
+
+	err := bill.Validate()
+	if err != nil {
+		return nil, err
+	}
+	text, ok := labels[language]
+	if !ok {
+		text = labels["en"]
+	}
+
+	code, err := qrcode.New(bill.Payload(), qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	if code.VersionNumber > maxQRVersion {
+		return nil, errors.New("QR code payload is too long")
+	}
+	code.DisableBorder = true
+
+	l := &layout{elements: make([]*element, 0)}
+	account := append([]string{group(compact(bill.Account), 4, 0)}, bill.Creditor.printLines()...)
+	reference := make([]string, 0)
+	if bill.ReferenceType != ReferenceTypeNON {
+		reference = append(reference, formatReference(bill.ReferenceType, bill.Reference))
+	}
+	debtorHeading := text["payable_by_blank"]
+	debtor := make([]string, 0)
+	if bill.Debtor != nil {
+		debtorHeading = text["payable_by"]
+		debtor = bill.Debtor.printLines()
+	}
+
+	// separation lines
+	l.line(0, 0, slipWidth, 0)
+	l.line(receiptWidth, 0, receiptWidth, slipHeight)
+
+	// receipt
+	l.text(5, 9, text["receipt"], 11, true)
+	y := l.block(5, 16, 52, text["account"], account, 6, 8)
+	if len(reference) > 0 {
+		y = l.block(5, y, 52, text["reference"], reference, 6, 8)
+	}
+	l.block(5, y, 52, debtorHeading, debtor, 6, 8)
+	l.text(5, 70, text["currency"], 6, true)
+	l.text(5, 74, bill.Currency, 8, false)
+	l.text(18, 70, text["amount"], 6, true)
+	l.text(18, 74, formatAmount(bill.Amount), 8, false)
+	acceptance := &element{kind: elementText, x: receiptWidth - 5, y: 86, text: text["acceptance_point"], size: 6, bold: true, right: true}
+	l.elements = append(l.elements, acceptance)
+
+	// payment part
+	l.text(receiptWidth+5, 9, text["payment_part"], 11, true)
+	bitmap := code.Bitmap()
+	module := qrSize / float64(len(bitmap))
+	qrX, qrY := receiptWidth+5, 17.0
+	for row, modules := range bitmap {
+		// adjacent dark modules are merged to keep documents small
+		for column := 0; column < len(modules); column++ {
+			if !modules[column] {
+				continue
+			}
+			start := column
+			for column+1 < len(modules) && modules[column+1] {
+				column++
+			}
+			l.rect(qrX+float64(start)*module, qrY+float64(row)*module, float64(column-start+1)*module, module, false)
+		}
+	}
+	// swiss cross in the middle of the QR code
+	crossX, crossY := qrX+(qrSize-swissCrossSize)/2, qrY+(qrSize-swissCrossSize)/2
+	l.rect(crossX, crossY, swissCrossSize, swissCrossSize, true)
+	l.rect(crossX+0.5, crossY+0.5, swissCrossSize-1, swissCrossSize-1, false)
+	l.rect(crossX+2.9, crossY+1.55, 1.2, 3.9, true)
+	l.rect(crossX+1.55, crossY+2.9, 3.9, 1.2, true)
+
+	l.text(receiptWidth+5, 70, text["currency"], 8, true)
+	l.text(receiptWidth+5, 75, bill.Currency, 10, false)
+	l.text(receiptWidth+18, 70, text["amount"], 8, true)
+	l.text(receiptWidth+18, 75, formatAmount(bill.Amount), 10, false)
+
+	y = l.block(118, 9, 87, text["account"], account, 8, 10)
+	if len(reference) > 0 {
+		y = l.block(118, y, 87, text["reference"], reference, 8, 10)
+	}
+	if message := clean(bill.Message); len(message) > 0 {
+		y = l.block(118, y, 87, text["information"], []string{message}, 8, 10)
+	}
+	l.block(118, y, 87, debtorHeading, debtor, 8, 10)
+
+	return l, nil
+}
+
+// wrap splits text in lines of at most width characters at spaces
+func wrap(text string, width int) []string {
+
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if len(line) > 0 && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if len(line) > 0 {
+			line += " "
+		}
+		line += word
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatMM formats millimetres for SVG attributes
+func formatMM(value float64) string {
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// SVG renders receipt and payment part of the bill as SVG document of 210 x 105 mm
+func (bill *Bill) SVG(language string) ([]byte, error) {
+
+	l, err := bill.layout(language)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buffer.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="210mm" height="105mm" viewBox="0 0 210 105">` + "\n")
+	buffer.WriteString(`<rect x="0" y="0" width="210" height="105" fill="#fff"/>` + "\n")
+	for _, e := range l.elements {
+		switch e.kind {
+		case elementText:
+			attributes := `x="` + formatMM(e.x) + `" y="` + formatMM(e.y) + `" font-size="` + formatMM(e.size*pointToMM) + `"`
+			if e.bold {
+				attributes += ` font-weight="bold"`
+			}
+			if e.right {
+				attributes += ` text-anchor="end"`
+			}
+			buffer.WriteString(`<text font-family="Helvetica, Arial, sans-serif" ` + attributes + `>`)
+			xml.EscapeText(buffer, []byte(e.text))
+			buffer.WriteString("</text>\n")
+		case elementRect:
+			fill := "#000"
+			if e.white {
+				fill = "#fff"
+			}
+			buffer.WriteString(`<rect x="` + formatMM(e.x) + `" y="` + formatMM(e.y) + `" width="` + formatMM(e.w) + `" height="` + formatMM(e.h) + `" fill="` + fill + `"/>` + "\n")
+		case elementLine:
+			buffer.WriteString(`<line x1="` + formatMM(e.x) + `" y1="` + formatMM(e.y) + `" x2="` + formatMM(e.x+e.w) + `" y2="` + formatMM(e.y+e.h) + `" stroke="#000" stroke-width="0.2" stroke-dasharray="1 1"/>` + "\n")
+		}
+	}
+	buffer.WriteString("</svg>\n")
+	return buffer.Bytes(), nil
+}
+
+// PDF renders receipt and payment part of the bill as PDF document of 210 x 105 mm
+func (bill *Bill) PDF(language string) ([]byte, error) {
+
+	l, err := bill.layout(language)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "L",
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: slipHeight, Ht: slipWidth},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	// core fonts use cp1252 encoding
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, e := range l.elements {
+		switch e.kind {
+		case elementText:
+			style := ""
+			if e.bold {
+				style = "B"
+			}
+			pdf.SetFont("Helvetica", style, e.size)
+			text := translate(e.text)
+			x := e.x
+			if e.right {
+				x -= pdf.GetStringWidth(text)
+			}
+			pdf.Text(x, e.y, text)
+		case elementRect:
+			if e.white {
+				pdf.SetFillColor(255, 255, 255)
+			} else {
+				pdf.SetFillColor(0, 0, 0)
+			}
+			pdf.Rect(e.x, e.y, e.w, e.h, "F")
+		case elementLine:
+			pdf.SetLineWidth(0.2)
+			pdf.SetDashPattern([]float64{1, 1}, 0)
+			pdf.Line(e.x, e.y, e.x+e.w, e.y+e.h)
+			pdf.SetDashPattern([]float64{}, 0)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	err = pdf.Output(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package signatures
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+)
+
+// OTPProvider is the built-in click-to-sign provider. Signer reviews the document on CIG Exchange
+// and signs it with a one time code sent to the login email
+type OTPProvider struct{}
+
+// queueSignerEmail queues email to the signer login email, content of sensitive emails is redacted after delivery
+func queueSignerEmail(request *p2pModels.SignatureRequest, subject, text string, sensitive bool) *cigExchange.APIError {
+
+	user, apiError := models.GetUser(request.UserID)
+	if apiError != nil {
+		return apiError
+	}
+	if user.LoginEmail == nil || len(user.LoginEmail.Value1) == 0 {
+		return cigExchange.NewInvalidFieldError("user_id", "Signer has no email")
+	}
+
+	outbox := &p2pModels.EmailOutbox{
+		ReferenceType: p2pModels.EmailOutboxReferenceSignature,
+		ReferenceID:   request.ID,
+		ToEmail:       user.LoginEmail.Value1,
+		ToName:        user.Name,
+		Subject:       subject,
+		Text:          text,
+		Sensitive:     sensitive,
+	}
+	return outbox.Create(nil)
+}
+
+// Name implements Provider interface
+func (*OTPProvider) Name() string {
+	return ProviderOTP
+}
+
+// Send implements Provider interface
+func (*OTPProvider) Send(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+
+	return queueSignerEmail(request, "Please sign: "+request.Title,
+		"You have been asked to sign \""+request.Title+"\" on CIG Exchange.\n\n"+
+			"Review the document and sign it with a one time code sent to this email address. "+
+			"The request expires on "+request.ExpiresAt.Format("2006-01-02")+".", false)
+}
+
+// Remind implements Provider interface
+func (*OTPProvider) Remind(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+
+	return queueSignerEmail(request, "Reminder: please sign "+request.Title,
+		"\""+request.Title+"\" is still waiting for your signature on CIG Exchange.\n\n"+
+			"The request expires on "+request.ExpiresAt.Format("2006-01-02")+".", false)
+}
+
+// RequestCode implements Provider interface
+func (*OTPProvider) RequestCode(request *p2pModels.SignatureRequest, remoteAddr string) (string, *cigExchange.APIError) {
+
+	code, apiError := request.NewCode(remoteAddr)
+	if apiError != nil {
+		return "", apiError
+	}
+
+	apiError = queueSignerEmail(request, "Your signature code",
+		"Your signature code is "+code+".\n\n"+
+			"Entering it on CIG Exchange signs \""+request.Title+"\". The code expires in 10 minutes.\n\n"+
+			"If you didn't request the code, don't share it with anyone and contact support.", true)
+	if apiError != nil {
+		return "", apiError
+	}
+	return code, nil
+}
+
+// Verify implements Provider interface
+func (*OTPProvider) Verify(request *p2pModels.SignatureRequest, code, remoteAddr string) *cigExchange.APIError {
+
+	return request.CheckCode(code, remoteAddr)
+}
+
+// Withdraw implements Provider interface
+func (*OTPProvider) Withdraw(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+
+	reason := "was cancelled"
+	if request.Status == p2pModels.SignatureStatusExpired {
+		reason = "expired"
+	}
+	return queueSignerEmail(request, "Signature request "+reason+": "+request.Title,
+		"The request to sign \""+request.Title+"\" "+reason+". No further action is needed.", false)
+}
@@ -0,0 +1,66 @@
+package signatures
+
+import (
+	cigExchange "cig-exchange-libs"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Supported SIGNATURE_PROVIDER values
+const (
+	ProviderOTP = "otp"
+)
+
+// Provider delivers signature requests to signers and verifies their signatures
+type Provider interface {
+	// Name is stored with signature requests sent through the provider
+	Name() string
+	// Send notifies the signer about the new request
+	Send(request *p2pModels.SignatureRequest) *cigExchange.APIError
+	// Remind notifies the signer about the pending request
+	Remind(request *p2pModels.SignatureRequest) *cigExchange.APIError
+	// RequestCode issues one time code the signer confirms the signature with.
+	// Returned code is only shown in DEV environment
+	RequestCode(request *p2pModels.SignatureRequest, remoteAddr string) (string, *cigExchange.APIError)
+	// Verify checks the signature code entered by the signer
+	Verify(request *p2pModels.SignatureRequest, code, remoteAddr string) *cigExchange.APIError
+	// Withdraw notifies the signer that the request was cancelled or expired
+	Withdraw(request *p2pModels.SignatureRequest) *cigExchange.APIError
+}
+
+var providers map[string]Provider
+var providersOnce sync.Once
+
+// getProviders creates the supported providers once
+func getProviders() map[string]Provider {
+
+	providersOnce.Do(func() {
+		providers = map[string]Provider{
+			ProviderOTP: &OTPProvider{},
+		}
+	})
+	return providers
+}
+
+// Get returns provider selected by SIGNATURE_PROVIDER env variable, click-to-sign with OTP is used by default
+func Get() Provider {
+
+	provider, ok := getProviders()[os.Getenv("SIGNATURE_PROVIDER")]
+	if !ok {
+		provider = getProviders()[ProviderOTP]
+	}
+	return provider
+}
+
+// ForRequest returns provider the request was sent through, requests keep their provider when the env changes
+func ForRequest(request *p2pModels.SignatureRequest) Provider {
+
+	provider, ok := getProviders()[request.Provider]
+	if !ok {
+		fmt.Printf("Signatures: unknown provider '%v' of request %v, using default\n", request.Provider, request.ID)
+		return Get()
+	}
+	return provider
+}
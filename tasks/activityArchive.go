@@ -0,0 +1,153 @@
+package tasks
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// default values for activity retention settings
+const (
+	defaultActivityRetentionDays = 90
+	defaultActivityArchivePath   = "/user_data/activity_archive/"
+)
+
+// getActivityRetentionDays reads ACTIVITY_RETENTION_DAYS env variable
+func getActivityRetentionDays() int {
+
+	days, err := strconv.Atoi(os.Getenv("ACTIVITY_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultActivityRetentionDays
+	}
+	return days
+}
+
+// getActivityArchivePath reads ACTIVITY_ARCHIVE_PATH env variable
+func getActivityArchivePath() string {
+
+	archivePath := os.Getenv("ACTIVITY_ARCHIVE_PATH")
+	if len(archivePath) == 0 {
+		return defaultActivityArchivePath
+	}
+	return archivePath
+}
+
+// archiveActivities rolls up, archives and purges raw activities older than the retention window.
+// Activities are processed one full day at a time, so a failure leaves the remaining days untouched
+func archiveActivities() {
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	cutoff := today.AddDate(0, 0, -getActivityRetentionDays())
+
+	archivePath := getActivityArchivePath()
+	err := os.MkdirAll(archivePath, 0755)
+	if err != nil {
+		fmt.Println("ArchiveActivities: unable to create archive folder:")
+		fmt.Println(err.Error())
+		return
+	}
+
+	for {
+		day, apiError := p2pModels.GetOldestActivityDay(cutoff)
+		if apiError != nil {
+			fmt.Println("ArchiveActivities: " + apiError.ToString())
+			return
+		}
+		// nothing left to archive
+		if day == nil {
+			return
+		}
+
+		apiError = archiveActivitiesForDay(*day, archivePath)
+		if apiError != nil {
+			fmt.Println("ArchiveActivities: " + day.Format("2006-01-02") + ": " + apiError.ToString())
+			return
+		}
+	}
+}
+
+// archiveActivitiesForDay handles a single day inside one transaction
+func archiveActivitiesForDay(day time.Time, archivePath string) *cigExchange.APIError {
+
+	tx := cigExchange.GetDB().Begin()
+	if tx.Error != nil {
+		return cigExchange.NewDatabaseError("Unable to start transaction", tx.Error)
+	}
+
+	activities, apiError := p2pModels.GetActivitiesForDay(tx, day)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	apiError = p2pModels.RollupActivitiesForDay(tx, day)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	// write the archive before deleting anything
+	fileName := path.Join(archivePath, "user_activity_"+day.Format("2006-01-02")+".jsonl.gz")
+	err := writeActivitiesArchive(fileName, activities)
+	if err != nil {
+		tx.Rollback()
+		return cigExchange.NewReadError("Failed to write activity archive", err)
+	}
+
+	apiError = p2pModels.DeleteActivitiesForDay(tx, day)
+	if apiError != nil {
+		tx.Rollback()
+		return apiError
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return cigExchange.NewDatabaseError("Unable to commit transaction", err)
+	}
+	return nil
+}
+
+// writeActivitiesArchive saves activities as gzip compressed json lines.
+// Existing file is replaced, so a day can safely be archived again after a failed commit
+func writeActivitiesArchive(fileName string, activities []*models.UserActivity) error {
+
+	tmpFileName := fileName + ".tmp"
+	file, err := os.Create(tmpFileName)
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gzipWriter)
+	for _, activity := range activities {
+		err = encoder.Encode(activity)
+		if err != nil {
+			file.Close()
+			os.Remove(tmpFileName)
+			return err
+		}
+	}
+
+	err = gzipWriter.Close()
+	if err != nil {
+		file.Close()
+		os.Remove(tmpFileName)
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		os.Remove(tmpFileName)
+		return err
+	}
+
+	return os.Rename(tmpFileName, fileName)
+}
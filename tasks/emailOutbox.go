@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"cig-exchange-p2p-backend/mailer"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"fmt"
+	"time"
+)
+
+// email outbox delivery settings
+const (
+	emailOutboxPollInterval = 10 * time.Second
+	emailOutboxBatchSize    = 50
+	emailOutboxMaxAttempts  = 8
+	emailOutboxBaseBackoff  = time.Minute
+	emailOutboxMaxBackoff   = 12 * time.Hour
+	// claimed emails become due again after this timeout if the worker stopped mid-batch
+	emailOutboxClaimTimeout = 10 * time.Minute
+)
+
+func emailOutboxTask() {
+
+	for {
+		time.Sleep(emailOutboxPollInterval)
+		drainEmailOutbox()
+	}
+}
+
+// getEmailOutboxBackoff returns delay before the next attempt: 1m, 2m, 4m, ... capped at 12h
+func getEmailOutboxBackoff(attempts int) time.Duration {
+
+	backoff := emailOutboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= emailOutboxMaxBackoff {
+			return emailOutboxMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// drainEmailOutbox sends all due emails. Emails are claimed first, so a failed
+// update doesn't roll back the state of emails which were already sent
+func drainEmailOutbox() {
+
+	emails, apiError := p2pModels.ClaimDueEmailOutbox(emailOutboxBatchSize, emailOutboxClaimTimeout)
+	if apiError != nil {
+		fmt.Println("EmailOutbox: " + apiError.ToString())
+		return
+	}
+
+	for _, email := range emails {
+		err := sendOutboxEmail(email)
+		email.Attempts++
+		if err == nil {
+			now := time.Now()
+			email.Status = p2pModels.EmailOutboxStatusSent
+			email.SentAt = &now
+			email.LastError = ""
+			// codes and tokens aren't kept after delivery
+			email.Redact()
+		} else {
+			email.LastError = err.Error()
+			// rejected emails won't be accepted on retry either
+			_, rejected := err.(*mailer.RejectedError)
+			if rejected || email.Attempts >= emailOutboxMaxAttempts {
+				email.Status = p2pModels.EmailOutboxStatusDead
+				fmt.Printf("EmailOutbox: email %v to %v moved to dead letters: %v\n", email.ID, email.ToEmail, err.Error())
+			} else {
+				email.NextAttemptAt = time.Now().Add(getEmailOutboxBackoff(email.Attempts))
+			}
+		}
+
+		apiError = email.Save(nil)
+		if apiError != nil {
+			fmt.Println("EmailOutbox: " + apiError.ToString())
+		}
+	}
+}
+
+func sendOutboxEmail(email *p2pModels.EmailOutbox) error {
+
+	if email.TemplateType != nil {
+		parameters, err := email.GetParameters()
+		if err != nil {
+			return err
+		}
+		return mailer.Get().SendTemplate(*email.TemplateType, email.ToEmail, parameters)
+	}
+
+	return mailer.Get().Send(&mailer.Email{
+		ToEmail:   email.ToEmail,
+		ToName:    email.ToName,
+		FromEmail: mailer.GetDefaultFromEmail(),
+		FromName:  "CIG Exchange",
+		Subject:   email.Subject,
+		Text:      email.Text,
+		HTML:      email.HTML,
+	})
+}
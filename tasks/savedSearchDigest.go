@@ -0,0 +1,240 @@
+package tasks
+
+import (
+	cigExchange "cig-exchange-libs"
+	"cig-exchange-libs/models"
+	"cig-exchange-p2p-backend/i18n"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// digestTexts contains localized digest email strings
+type digestTexts struct {
+	Subject        string
+	Intro          string
+	Amount         string
+	Interest       string
+	Unsubscribe    string
+	UnsubscribeAll string
+}
+
+var digestTranslations = map[string]*digestTexts{
+	"en": {
+		Subject:        "New offerings matching \"%v\"",
+		Intro:          "The following offerings matching your saved search \"%v\" are now available:",
+		Amount:         "Amount",
+		Interest:       "Interest",
+		Unsubscribe:    "Stop emails for this search",
+		UnsubscribeAll: "Stop all saved search emails",
+	},
+	"fr": {
+		Subject:        "Nouvelles offres correspondant à \"%v\"",
+		Intro:          "Les offres suivantes correspondant à votre recherche \"%v\" sont maintenant disponibles :",
+		Amount:         "Montant",
+		Interest:       "Intérêt",
+		Unsubscribe:    "Ne plus recevoir d'e-mails pour cette recherche",
+		UnsubscribeAll: "Ne plus recevoir aucun e-mail de recherche",
+	},
+	"it": {
+		Subject:        "Nuove offerte per \"%v\"",
+		Intro:          "Le seguenti offerte corrispondenti alla tua ricerca \"%v\" sono ora disponibili:",
+		Amount:         "Importo",
+		Interest:       "Interesse",
+		Unsubscribe:    "Non ricevere più e-mail per questa ricerca",
+		UnsubscribeAll: "Non ricevere più e-mail per le ricerche salvate",
+	},
+	"de": {
+		Subject:        "Neue Angebote für \"%v\"",
+		Intro:          "Die folgenden Angebote passend zu Ihrer gespeicherten Suche \"%v\" sind jetzt verfügbar:",
+		Amount:         "Betrag",
+		Interest:       "Zins",
+		Unsubscribe:    "Keine E-Mails mehr für diese Suche",
+		UnsubscribeAll: "Keine E-Mails mehr für gespeicherte Suchen",
+	},
+}
+
+func savedSearchDigestTask() {
+
+	for {
+		// sleep till noon
+		time.Sleep(getDurationTillNoon())
+		sendSavedSearchDigests()
+	}
+}
+
+// digestOffering is a listed offering prepared for matching and rendering
+type digestOffering struct {
+	Offering       *models.Offering
+	Fields         map[string]interface{}
+	FirstVisibleAt time.Time
+}
+
+// sendSavedSearchDigests queues digest emails with offerings listed since the previous digest
+func sendSavedSearchDigests() {
+
+	listed, apiError := p2pModels.RefreshOfferingsVisibility()
+	if apiError != nil {
+		fmt.Println("SavedSearchDigest: " + apiError.ToString())
+		return
+	}
+
+	firstVisible, apiError := p2pModels.GetOfferingsFirstVisible()
+	if apiError != nil {
+		fmt.Println("SavedSearchDigest: " + apiError.ToString())
+		return
+	}
+
+	offerings := make([]*digestOffering, 0)
+	for _, offering := range listed {
+		offeringBytes, err := json.Marshal(offering)
+		if err != nil {
+			fmt.Println("SavedSearchDigest: unable to encode offering:")
+			fmt.Println(err.Error())
+			continue
+		}
+		fields := make(map[string]interface{})
+		err = json.Unmarshal(offeringBytes, &fields)
+		if err != nil {
+			fmt.Println("SavedSearchDigest: unable to decode offering:")
+			fmt.Println(err.Error())
+			continue
+		}
+		offerings = append(offerings, &digestOffering{Offering: offering, Fields: fields, FirstVisibleAt: firstVisible[offering.ID]})
+	}
+
+	offeringIDs := make([]string, 0, len(offerings))
+	for _, offering := range offerings {
+		offeringIDs = append(offeringIDs, offering.Offering.ID)
+	}
+	eligibilities, apiError := p2pModels.GetOfferingsEligibility(offeringIDs)
+	if apiError != nil {
+		fmt.Println("SavedSearchDigest: " + apiError.ToString())
+		return
+	}
+
+	searches, apiError := p2pModels.GetSavedSearchesWithEmail()
+	if apiError != nil {
+		fmt.Println("SavedSearchDigest: " + apiError.ToString())
+		return
+	}
+
+	now := time.Now()
+	profiles := make(map[string]*p2pModels.InvestorProfile)
+	for _, search := range searches {
+		if !search.IsDigestDue(now) {
+			continue
+		}
+
+		// restricted offerings are sent only to eligible investors
+		profile, ok := profiles[search.UserID]
+		if !ok {
+			profile, apiError = p2pModels.GetInvestorProfile(search.UserID)
+			if apiError != nil {
+				fmt.Println("SavedSearchDigest: " + apiError.ToString())
+				continue
+			}
+			profiles[search.UserID] = profile
+		}
+
+		criteria, err := search.GetCriteria()
+		if err != nil {
+			fmt.Printf("SavedSearchDigest: invalid criteria of search %v: %v\n", search.ID, err.Error())
+			continue
+		}
+
+		// offerings listed before the search was saved were already visible to the user
+		since := search.CreatedAt
+		if search.LastDigestAt != nil {
+			since = *search.LastDigestAt
+		}
+
+		matched := make([]*digestOffering, 0)
+		for _, offering := range offerings {
+			if len(eligibilities[offering.Offering.ID].Evaluate(profile)) > 0 {
+				continue
+			}
+			if offering.FirstVisibleAt.After(since) && criteria.Matches(offering.Fields) {
+				matched = append(matched, offering)
+			}
+		}
+
+		if len(matched) > 0 {
+			apiError = queueSavedSearchDigest(search, matched)
+			if apiError != nil {
+				fmt.Println("SavedSearchDigest: " + apiError.ToString())
+				continue
+			}
+		}
+
+		search.LastDigestAt = &now
+		apiError = search.Save()
+		if apiError != nil {
+			fmt.Println("SavedSearchDigest: " + apiError.ToString())
+		}
+	}
+}
+
+// queueSavedSearchDigest renders localized digest and puts it into email outbox
+func queueSavedSearchDigest(search *p2pModels.SavedSearch, offerings []*digestOffering) *cigExchange.APIError {
+
+	user, apiError := models.GetUser(search.UserID)
+	if apiError != nil {
+		return apiError
+	}
+	if user.LoginEmail == nil || len(user.LoginEmail.Value1) == 0 {
+		return nil
+	}
+
+	texts, ok := digestTranslations[search.Language]
+	if !ok {
+		texts = digestTranslations[i18n.DefaultLanguage]
+	}
+	chain := i18n.FallbackChain(search.Language)
+
+	unsubscribeURL := cigExchange.GetServerURL() + "/invest/api/saved-searches/unsubscribe?token=" + url.QueryEscape(search.UnsubscribeToken)
+	unsubscribeAllURL := unsubscribeURL + "&all=true"
+
+	text := &strings.Builder{}
+	htmlBody := &strings.Builder{}
+	fmt.Fprintf(text, texts.Intro+"\n\n", search.Name)
+	fmt.Fprintf(htmlBody, "<p>"+texts.Intro+"</p>\n<ul>\n", html.EscapeString(search.Name))
+
+	for _, offering := range offerings {
+		title := i18n.TranslateJSON(offering.Offering.Title.RawMessage, chain)
+		link := i18n.TranslateJSON(offering.Offering.OfferingDirectURL.RawMessage, chain)
+		if len(link) == 0 {
+			link = cigExchange.GetServerURL() + "/invest/" + search.Language + "/"
+		}
+
+		details := make([]string, 0)
+		if amount, ok := offering.Fields["amount"].(float64); ok {
+			details = append(details, fmt.Sprintf("%v: %.0f", texts.Amount, amount))
+		}
+		if interest, ok := offering.Fields["interest"].(float64); ok {
+			details = append(details, fmt.Sprintf("%v: %v%%", texts.Interest, interest))
+		}
+
+		fmt.Fprintf(text, "- %v (%v)\n  %v\n", title, strings.Join(details, ", "), link)
+		fmt.Fprintf(htmlBody, "<li><a href=\"%v\">%v</a> (%v)</li>\n", html.EscapeString(link), html.EscapeString(title), html.EscapeString(strings.Join(details, ", ")))
+	}
+
+	fmt.Fprintf(text, "\n%v: %v\n%v: %v\n", texts.Unsubscribe, unsubscribeURL, texts.UnsubscribeAll, unsubscribeAllURL)
+	fmt.Fprintf(htmlBody, "</ul>\n<p><a href=\"%v\">%v</a> | <a href=\"%v\">%v</a></p>\n",
+		html.EscapeString(unsubscribeURL), texts.Unsubscribe, html.EscapeString(unsubscribeAllURL), texts.UnsubscribeAll)
+
+	outbox := &p2pModels.EmailOutbox{
+		ReferenceType: p2pModels.EmailOutboxReferenceSavedSearch,
+		ReferenceID:   search.ID,
+		ToEmail:       user.LoginEmail.Value1,
+		ToName:        user.Name,
+		Subject:       fmt.Sprintf(texts.Subject, search.Name),
+		Text:          text.String(),
+		HTML:          htmlBody.String(),
+	}
+	return outbox.Create(nil)
+}
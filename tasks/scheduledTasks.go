@@ -25,8 +25,22 @@ func invitationExpirationTask() {
 	}
 }
 
+func activityRetentionTask() {
+
+	for {
+		// sleep till noon
+		time.Sleep(getDurationTillNoon())
+		archiveActivities()
+	}
+}
+
 // ScheduleTasks starts goroutines for sheduled tasks
 func ScheduleTasks() {
 
 	go invitationExpirationTask()
+	go activityRetentionTask()
+	go emailOutboxTask()
+	go webhookDeliveryTask()
+	go savedSearchDigestTask()
+	go signatureRequestsTask()
 }
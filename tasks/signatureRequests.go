@@ -0,0 +1,66 @@
+package tasks
+
+import (
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/signatures"
+	"fmt"
+	"time"
+)
+
+// signaturePollInterval is how often pending signature requests are checked for reminders and expiration
+const signaturePollInterval = time.Hour
+
+// signature request operations used by the task, replaced in tests
+var (
+	getPendingSignatureRequests  = p2pModels.GetPendingSignatureRequests
+	expireSignatureRequest       = (*p2pModels.SignatureRequest).Expire
+	markSignatureRequestReminded = (*p2pModels.SignatureRequest).MarkReminded
+	getSignatureProvider         = signatures.ForRequest
+)
+
+func signatureRequestsTask() {
+
+	for {
+		time.Sleep(signaturePollInterval)
+		processSignatureRequests(time.Now())
+	}
+}
+
+// processSignatureRequests expires pending requests past their expiration and notifies signers,
+// signers of the other requests are reminded once per reminder interval
+func processSignatureRequests(now time.Time) {
+
+	requests, apiError := getPendingSignatureRequests()
+	if apiError != nil {
+		fmt.Println("Signatures: " + apiError.ToString())
+		return
+	}
+
+	for _, request := range requests {
+		if request.Expired(now) {
+			apiError = expireSignatureRequest(request)
+			if apiError != nil {
+				fmt.Println("Signatures: " + apiError.ToString())
+				continue
+			}
+			apiError = getSignatureProvider(request).Withdraw(request)
+			if apiError != nil {
+				fmt.Println("Signatures: " + apiError.ToString())
+			}
+			continue
+		}
+
+		if !request.ReminderDue(now) {
+			continue
+		}
+		apiError = getSignatureProvider(request).Remind(request)
+		if apiError != nil {
+			fmt.Println("Signatures: " + apiError.ToString())
+			continue
+		}
+		apiError = markSignatureRequestReminded(request)
+		if apiError != nil {
+			fmt.Println("Signatures: " + apiError.ToString())
+		}
+	}
+}
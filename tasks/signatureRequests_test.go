@@ -0,0 +1,131 @@
+package tasks
+
+import (
+	cigExchange "cig-exchange-libs"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/signatures"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSignatureProvider doesn't contact the signer, calls are recorded as "method request_id"
+type fakeSignatureProvider struct {
+	calls []string
+	fail  bool
+}
+
+func (p *fakeSignatureProvider) record(method string, request *p2pModels.SignatureRequest) *cigExchange.APIError {
+
+	p.calls = append(p.calls, method+" "+request.ID)
+	if p.fail {
+		return cigExchange.NewInvalidFieldError("request_id", "Provider failed")
+	}
+	return nil
+}
+
+func (*fakeSignatureProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeSignatureProvider) Send(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+	return p.record("send", request)
+}
+
+func (p *fakeSignatureProvider) Remind(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+	return p.record("remind", request)
+}
+
+func (p *fakeSignatureProvider) RequestCode(request *p2pModels.SignatureRequest, remoteAddr string) (string, *cigExchange.APIError) {
+	return "123456", p.record("request_code", request)
+}
+
+func (p *fakeSignatureProvider) Verify(request *p2pModels.SignatureRequest, code, remoteAddr string) *cigExchange.APIError {
+	return p.record("verify", request)
+}
+
+func (p *fakeSignatureProvider) Withdraw(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+	return p.record("withdraw", request)
+}
+
+// fakeSignatureRequests replaces db operations of the task, updates are recorded like provider calls
+func fakeSignatureRequests(provider *fakeSignatureProvider, requests ...*p2pModels.SignatureRequest) *[]string {
+
+	updates := make([]string, 0)
+	getPendingSignatureRequests = func() ([]*p2pModels.SignatureRequest, *cigExchange.APIError) {
+		return requests, nil
+	}
+	expireSignatureRequest = func(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+		request.Status = p2pModels.SignatureStatusExpired
+		updates = append(updates, "expire "+request.ID)
+		return nil
+	}
+	markSignatureRequestReminded = func(request *p2pModels.SignatureRequest) *cigExchange.APIError {
+		request.RemindersSent++
+		updates = append(updates, "mark_reminded "+request.ID)
+		return nil
+	}
+	getSignatureProvider = func(*p2pModels.SignatureRequest) signatures.Provider {
+		return provider
+	}
+	return &updates
+}
+
+// restoreSignatureRequests restores db operations and provider lookup of the task
+func restoreSignatureRequests() {
+
+	getPendingSignatureRequests = p2pModels.GetPendingSignatureRequests
+	expireSignatureRequest = (*p2pModels.SignatureRequest).Expire
+	markSignatureRequestReminded = (*p2pModels.SignatureRequest).MarkReminded
+	getSignatureProvider = signatures.ForRequest
+}
+
+func TestProcessSignatureRequests(t *testing.T) {
+
+	now := time.Now()
+	created := now.Add(-p2pModels.SignatureReminderInterval - time.Hour)
+	reminded := now.Add(-time.Hour)
+
+	expired := &p2pModels.SignatureRequest{ID: "expired", Status: p2pModels.SignatureStatusPending, ExpiresAt: now.Add(-time.Minute), CreatedAt: created}
+	due := &p2pModels.SignatureRequest{ID: "due", Status: p2pModels.SignatureStatusPending, ExpiresAt: now.Add(time.Hour), CreatedAt: created}
+	recent := &p2pModels.SignatureRequest{ID: "recent", Status: p2pModels.SignatureStatusPending, ExpiresAt: now.Add(time.Hour), CreatedAt: created, RemindersSent: 1, RemindedAt: &reminded}
+
+	provider := &fakeSignatureProvider{}
+	updates := fakeSignatureRequests(provider, expired, due, recent)
+	defer restoreSignatureRequests()
+
+	processSignatureRequests(now)
+
+	// expired request is withdrawn after the update, due request is marked after the reminder
+	if strings.Join(provider.calls, ",") != "withdraw expired,remind due" {
+		t.Errorf("unexpected provider calls %v", provider.calls)
+	}
+	if strings.Join(*updates, ",") != "expire expired,mark_reminded due" {
+		t.Errorf("unexpected updates %v", *updates)
+	}
+	if expired.Status != p2pModels.SignatureStatusExpired || due.RemindersSent != 1 || recent.RemindersSent != 1 {
+		t.Errorf("unexpected requests %+v %+v %+v", *expired, *due, *recent)
+	}
+}
+
+func TestProcessSignatureRequestsReminderFailed(t *testing.T) {
+
+	now := time.Now()
+	due := &p2pModels.SignatureRequest{
+		ID:        "due",
+		Status:    p2pModels.SignatureStatusPending,
+		ExpiresAt: now.Add(time.Hour),
+		CreatedAt: now.Add(-p2pModels.SignatureReminderInterval),
+	}
+
+	provider := &fakeSignatureProvider{fail: true}
+	updates := fakeSignatureRequests(provider, due)
+	defer restoreSignatureRequests()
+
+	processSignatureRequests(now)
+
+	// failed reminder isn't counted, so it's sent again with the next run
+	if len(provider.calls) != 1 || len(*updates) != 0 || !due.ReminderDue(now) {
+		t.Errorf("unexpected provider calls %v and updates %v", provider.calls, *updates)
+	}
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	p2pModels "cig-exchange-p2p-backend/models"
+	"cig-exchange-p2p-backend/webhooks"
+	"fmt"
+	"time"
+)
+
+// webhook delivery worker settings
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	// claimed deliveries become due again after this timeout if the worker stopped mid-batch
+	webhookClaimTimeout = 10 * time.Minute
+)
+
+func webhookDeliveryTask() {
+
+	for {
+		time.Sleep(webhookPollInterval)
+		drainWebhookDeliveries()
+	}
+}
+
+// drainWebhookDeliveries sends all due webhook deliveries. Deliveries are claimed first,
+// no database locks are held during the http calls
+func drainWebhookDeliveries() {
+
+	deliveries, apiError := p2pModels.ClaimDueWebhookDeliveries(webhookBatchSize, webhookClaimTimeout)
+	if apiError != nil {
+		fmt.Println("Webhooks: " + apiError.ToString())
+		return
+	}
+
+	// batch usually contains several events for the same webhook
+	subscriptions := make(map[string]*p2pModels.WebhookSubscription)
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, apiError = p2pModels.GetWebhookSubscriptionByID(delivery.SubscriptionID)
+			if apiError != nil {
+				fmt.Println("Webhooks: " + apiError.ToString())
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		apiError = webhooks.Attempt(subscription, delivery)
+		if apiError != nil {
+			fmt.Println("Webhooks: " + apiError.ToString())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"cig-exchange-libs/models"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"encoding/json"
+	"fmt"
+)
+
+// toMap converts model to generic map so it can be embedded into event data
+func toMap(model interface{}) map[string]interface{} {
+
+	result := make(map[string]interface{})
+	modelBytes, err := json.Marshal(model)
+	if err != nil {
+		fmt.Println("Webhooks: unable to encode event data:")
+		fmt.Println(err.Error())
+		return result
+	}
+	err = json.Unmarshal(modelBytes, &result)
+	if err != nil {
+		fmt.Println("Webhooks: unable to decode event data:")
+		fmt.Println(err.Error())
+	}
+	return result
+}
+
+// OfferingChanged publishes offering.created, offering.updated or offering.deleted event
+func OfferingChanged(event string, offering *models.Offering) {
+
+	data := map[string]interface{}{
+		"offering": toMap(offering),
+	}
+	Publish(offering.OrganisationID, event, data)
+}
+
+// MediaUploaded publishes media.uploaded event
+func MediaUploaded(organisationID, offeringID string, media interface{}) {
+
+	data := map[string]interface{}{
+		"offering_id": offeringID,
+		"media":       toMap(media),
+	}
+	Publish(organisationID, p2pModels.WebhookEventMediaUploaded, data)
+}
+
+// MemberJoined publishes member.joined event
+func MemberJoined(orgUser *models.OrganisationUser) {
+
+	data := map[string]interface{}{
+		"user_id":           orgUser.UserID,
+		"organisation_role": orgUser.OrganisationRole,
+	}
+	Publish(orgUser.OrganisationID, p2pModels.WebhookEventMemberJoined, data)
+}
+
+// MemberLeft publishes member.left event
+func MemberLeft(orgUser *models.OrganisationUser) {
+
+	data := map[string]interface{}{
+		"user_id":           orgUser.UserID,
+		"organisation_role": orgUser.OrganisationRole,
+	}
+	Publish(orgUser.OrganisationID, p2pModels.WebhookEventMemberLeft, data)
+}
+
+// InvitationAccepted publishes invitation.accepted and member.joined events
+func InvitationAccepted(orgUser *models.OrganisationUser) {
+
+	data := map[string]interface{}{
+		"user_id":           orgUser.UserID,
+		"organisation_role": orgUser.OrganisationRole,
+	}
+	Publish(orgUser.OrganisationID, p2pModels.WebhookEventInvitationAccepted, data)
+	MemberJoined(orgUser)
+}
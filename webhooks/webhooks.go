@@ -0,0 +1,330 @@
+package webhooks
+
+import (
+	"bytes"
+	cigExchange "cig-exchange-libs"
+	p2pModels "cig-exchange-p2p-backend/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Constants defining the headers sent with every webhook request
+const (
+	HeaderEvent     = "X-CIG-Event"
+	HeaderDelivery  = "X-CIG-Delivery"
+	HeaderTimestamp = "X-CIG-Timestamp"
+	HeaderSignature = "X-CIG-Signature"
+)
+
+// deliveryTimeout limits a single webhook request
+const deliveryTimeout = 10 * time.Second
+
+// httpClient checks every dialed address, so a webhook host can't be switched
+// to an internal address after validation. Redirects are not followed
+var httpClient = &http.Client{
+	Timeout: deliveryTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: deliveryTimeout,
+			Control: checkDialAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: deliveryTimeout,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// blockedNetworks can't receive webhooks: loopback, private (RFC 1918, RFC 4193),
+// link-local, carrier-grade NAT and unspecified addresses
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isBlockedIP returns true for addresses webhooks must not be sent to.
+// Local addresses are allowed in dev environment for testing
+func isBlockedIP(ip net.IP) bool {
+
+	if cigExchange.IsDevEnv() {
+		return false
+	}
+	if ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHost resolves webhook host and returns error if any of its addresses is internal
+func CheckHost(host string) error {
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return fmt.Errorf("unable to resolve webhook host %v", host)
+		}
+	}
+
+	for _, ip := range ips {
+		if isBlockedIP(ip) {
+			return fmt.Errorf("webhook host %v resolves to a not allowed address", host)
+		}
+	}
+	return nil
+}
+
+// checkDialAddress is called with the resolved address right before connecting
+func checkDialAddress(network, address string, c syscall.RawConn) error {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedIP(ip) {
+		return fmt.Errorf("webhook address %v is not allowed", host)
+	}
+	return nil
+}
+
+// Event is the body posted to subscribed urls
+type Event struct {
+	ID             string                 `json:"id"`
+	Event          string                 `json:"event"`
+	OrganisationID string                 `json:"organisation_id"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Data           map[string]interface{} `json:"data"`
+}
+
+// GenerateSecret returns new random signing secret
+func GenerateSecret() (string, error) {
+
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns HMAC-SHA256 signature of "timestamp.body" in the 'sha256=<hex>' form.
+// Receivers should recompute it and reject old timestamps to prevent replays
+func Sign(secret, timestamp string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues the event for all active organisation webhooks subscribed to it.
+// Errors are only logged, webhooks never fail the calling request
+func Publish(organisationID, event string, data map[string]interface{}) {
+
+	subscriptions, apiError := p2pModels.GetWebhookSubscriptions(organisationID)
+	if apiError != nil {
+		fmt.Println("Webhooks: " + apiError.ToString())
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if !subscription.IsActive || !subscription.IsSubscribed(event) {
+			continue
+		}
+		_, apiError = queue(subscription, event, data, time.Now())
+		if apiError != nil {
+			fmt.Println("Webhooks: " + apiError.ToString())
+		}
+	}
+}
+
+// SendTestEvent sends 'ping' event to the webhook right away and returns the delivery.
+// Failed test delivery is retried like any other event
+func SendTestEvent(subscription *p2pModels.WebhookSubscription, data map[string]interface{}) (*p2pModels.WebhookDelivery, *cigExchange.APIError) {
+
+	// schedule the retry upfront so the worker doesn't pick the delivery while it's being sent
+	delivery, apiError := queue(subscription, p2pModels.WebhookEventPing, data, time.Now().Add(baseBackoff))
+	if apiError != nil {
+		return nil, apiError
+	}
+
+	apiError = Attempt(subscription, delivery)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return delivery, nil
+}
+
+// queue creates pending delivery of the event for a single webhook
+func queue(subscription *p2pModels.WebhookSubscription, event string, data map[string]interface{}, nextAttemptAt time.Time) (*p2pModels.WebhookDelivery, *cigExchange.APIError) {
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	delivery := &p2pModels.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		OrganisationID: subscription.OrganisationID,
+		Event:          event,
+		NextAttemptAt:  nextAttemptAt,
+	}
+
+	// delivery id is generated on create, payload keeps it empty and gets it on send
+	payload := &Event{
+		Event:          event,
+		OrganisationID: subscription.OrganisationID,
+		CreatedAt:      time.Now().UTC(),
+		Data:           data,
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, cigExchange.NewJSONEncodingError("Unable to encode webhook payload", err)
+	}
+	delivery.Payload = postgres.Jsonb{RawMessage: payloadBytes}
+
+	apiError := delivery.Create()
+	if apiError != nil {
+		return nil, apiError
+	}
+	return delivery, nil
+}
+
+// Deliver posts the delivery payload to the webhook url and records the response code.
+// Any non 2xx response is an error, redirects included
+func Deliver(subscription *p2pModels.WebhookSubscription, delivery *p2pModels.WebhookDelivery) error {
+
+	payload := &Event{}
+	err := json.Unmarshal(delivery.Payload.RawMessage, payload)
+	if err != nil {
+		return err
+	}
+	payload.ID = delivery.ID
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CIG-Exchange-Webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		delivery.ResponseCode = 0
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.ResponseCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook endpoint responded with status %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhook retry settings
+const (
+	maxAttempts = 6
+	baseBackoff = time.Minute
+	maxBackoff  = 6 * time.Hour
+)
+
+// getBackoff returns delay before the next attempt: 1m, 2m, 4m, ... capped at 6h
+func getBackoff(attempts int) time.Duration {
+
+	backoff := baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// Attempt sends the delivery once and saves the outcome. Failed deliveries are rescheduled
+// with exponential backoff till maxAttempts is reached
+func Attempt(subscription *p2pModels.WebhookSubscription, delivery *p2pModels.WebhookDelivery) *cigExchange.APIError {
+
+	// pending deliveries of removed webhooks are dropped without sending
+	if subscription.DeletedAt != nil || !subscription.IsActive {
+		delivery.Status = p2pModels.WebhookDeliveryStatusFailed
+		delivery.LastError = "webhook is deleted or disabled"
+		return delivery.Save(nil)
+	}
+
+	err := Deliver(subscription, delivery)
+	delivery.Attempts++
+	if err == nil {
+		now := time.Now()
+		delivery.Status = p2pModels.WebhookDeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = p2pModels.WebhookDeliveryStatusFailed
+			fmt.Printf("Webhooks: delivery %v of '%v' to %v failed: %v\n", delivery.ID, delivery.Event, subscription.URL, err.Error())
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(getBackoff(delivery.Attempts))
+		}
+	}
+
+	return delivery.Save(nil)
+}